@@ -0,0 +1,205 @@
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ConfigClient is a struct that represents the configuration for Client
+type ConfigClient struct {
+	// BaseURL is the address of the garage service, e.g. http://localhost:8080
+	BaseURL string
+	// HTTPClient is the client used for the requests, default a client with a 30s timeout
+	HTTPClient *http.Client
+	// Retries is the number of retries of an idempotent request after a network error,
+	// a 429 or a 502, 503 or 504 response, default 3, negative disables them
+	Retries int
+	// BackoffBase is the wait before the first retry, doubled on each retry, default 200ms
+	BackoffBase time.Duration
+	// BackoffMax is the maximum wait between retries, default 5s
+	BackoffMax time.Duration
+}
+
+// NewClient is a function that returns a new instance of Client
+func NewClient(cfg *ConfigClient) *Client {
+	// default values
+	defaultConfig := &ConfigClient{
+		BaseURL:     "http://localhost:8080",
+		HTTPClient:  &http.Client{Timeout: 30 * time.Second},
+		Retries:     3,
+		BackoffBase: 200 * time.Millisecond,
+		BackoffMax:  5 * time.Second,
+	}
+	if cfg != nil {
+		if cfg.BaseURL != "" {
+			defaultConfig.BaseURL = cfg.BaseURL
+		}
+		if cfg.HTTPClient != nil {
+			defaultConfig.HTTPClient = cfg.HTTPClient
+		}
+		if cfg.Retries != 0 {
+			defaultConfig.Retries = max(cfg.Retries, 0)
+		}
+		if cfg.BackoffBase > 0 {
+			defaultConfig.BackoffBase = cfg.BackoffBase
+		}
+		if cfg.BackoffMax > 0 {
+			defaultConfig.BackoffMax = cfg.BackoffMax
+		}
+	}
+
+	return &Client{
+		baseURL:     strings.TrimRight(defaultConfig.BaseURL, "/"),
+		hc:          defaultConfig.HTTPClient,
+		retries:     defaultConfig.Retries,
+		backoffBase: defaultConfig.BackoffBase,
+		backoffMax:  defaultConfig.BackoffMax,
+	}
+}
+
+// Client is a struct that talks to the HTTP API of the garage service
+type Client struct {
+	// baseURL is the address of the service without trailing slash
+	baseURL string
+	// hc is the http client
+	hc *http.Client
+	// retries is the number of retries of an idempotent request
+	retries int
+	// backoffBase is the wait before the first retry
+	backoffBase time.Duration
+	// backoffMax is the maximum wait between retries
+	backoffMax time.Duration
+}
+
+// request is a struct that represents a request to the service
+type request struct {
+	method string
+	path   string
+	query  url.Values
+	// body is encoded as JSON unless it is an io.Reader, sent as is with contentType and never retried
+	body        any
+	contentType string
+	// field is the member of the response body decoded into out, default data
+	field string
+}
+
+// response is a struct that represents a decoded response of the service
+type response struct {
+	code   int
+	fields map[string]json.RawMessage
+}
+
+// do is a method that sends a request, retrying idempotent ones on transient failures,
+// and decodes the requested field of the response into out when it is not nil
+func (c *Client) do(ctx context.Context, req request, out any) (resp response, err error) {
+	// body
+	var payload []byte
+	stream, isStream := req.body.(io.Reader)
+	if req.body != nil && !isStream {
+		if payload, err = json.Marshal(req.body); err != nil {
+			return
+		}
+		req.contentType = "application/json"
+	}
+	idempotent := !isStream && (req.method == http.MethodGet || req.method == http.MethodPut || req.method == http.MethodDelete)
+
+	// attempts
+	for attempt := 0; ; attempt++ {
+		var body io.Reader = stream
+		if payload != nil {
+			body = bytes.NewReader(payload)
+		}
+		var retryAfter time.Duration
+		resp, retryAfter, err = c.send(ctx, req, body)
+		if !idempotent || attempt >= c.retries || !retryable(resp.code, err) || ctx.Err() != nil {
+			break
+		}
+
+		wait := min(c.backoffBase<<attempt, c.backoffMax)
+		wait = wait/2 + time.Duration(rand.Int63n(int64(wait/2)+1))
+		if retryAfter > 0 {
+			wait = min(retryAfter, c.backoffMax)
+		}
+		select {
+		case <-ctx.Done():
+			return resp, ctx.Err()
+		case <-time.After(wait):
+		}
+	}
+	if err != nil {
+		return
+	}
+
+	// result
+	field := req.field
+	if field == "" {
+		field = "data"
+	}
+	if resp.code >= 400 {
+		var msg string
+		_ = json.Unmarshal(resp.fields["error"], &msg)
+		return resp, newAPIError(resp.code, msg)
+	}
+	if out != nil && len(resp.fields[field]) > 0 {
+		err = json.Unmarshal(resp.fields[field], out)
+	}
+	return
+}
+
+// send is a method that sends a single request and reads the whole response
+func (c *Client) send(ctx context.Context, req request, body io.Reader) (resp response, retryAfter time.Duration, err error) {
+	u := c.baseURL + req.path
+	if len(req.query) > 0 {
+		u += "?" + req.query.Encode()
+	}
+	hr, err := http.NewRequestWithContext(ctx, req.method, u, body)
+	if err != nil {
+		return
+	}
+	hr.Header.Set("Accept", "application/json")
+	if req.contentType != "" {
+		hr.Header.Set("Content-Type", req.contentType)
+	}
+
+	hresp, err := c.hc.Do(hr)
+	if err != nil {
+		return
+	}
+	defer hresp.Body.Close()
+	resp.code = hresp.StatusCode
+	if s, err := strconv.Atoi(hresp.Header.Get("Retry-After")); err == nil && s > 0 {
+		retryAfter = time.Duration(s) * time.Second
+	}
+	data, err := io.ReadAll(hresp.Body)
+	if err != nil {
+		return
+	}
+	if len(bytes.TrimSpace(data)) > 0 {
+		if jerr := json.Unmarshal(data, &resp.fields); jerr != nil && resp.code < 400 {
+			err = fmt.Errorf("garage: %d: invalid response: %w", resp.code, jerr)
+		}
+	}
+	return
+}
+
+// retryable is a function that reports whether a failed attempt may succeed when repeated
+func retryable(code int, err error) bool {
+	if err != nil {
+		return !errors.Is(err, context.Canceled) && !errors.Is(err, context.DeadlineExceeded)
+	}
+	switch code {
+	case http.StatusTooManyRequests, http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return true
+	}
+	return false
+}
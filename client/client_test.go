@@ -0,0 +1,296 @@
+package client
+
+import (
+	"app/internal/application"
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// fleet is the vehicles file the test servers are loaded with
+const fleet = `[
+{"id":1,"brand":"Toyota","model":"Corolla","registration":"ABC1234","year":2015,"color":"Red","max_speed":180,"fuel_type":"gasoline","transmission":"manual","passengers":5,"height":1.45,"length":4.6,"width":1.75,"weight":1300},
+{"id":2,"brand":"Ford","model":"Focus","registration":"XYZ9876","year":2018,"color":"Blue","max_speed":190,"fuel_type":"diesel","transmission":"automatic","passengers":5,"height":1.47,"length":4.4,"width":1.82,"weight":1350}
+]`
+
+// newServer is a function that serves the router of the application over a fresh fleet,
+// each request going through wrap first when it is not nil
+func newServer(t *testing.T, wrap func(next http.Handler) http.Handler) *httptest.Server {
+	t.Helper()
+	dir := t.TempDir()
+	path := filepath.Join(dir, "vehicles.json")
+	if err := os.WriteFile(path, []byte(fleet), 0o644); err != nil {
+		t.Fatalf("write fleet: %v", err)
+	}
+	app := application.NewServerChi(&application.ConfigServerChi{
+		LoaderFilePath: path,
+		BackupDir:      filepath.Join(dir, "backups"),
+	})
+	done := make(chan struct{})
+	h, err := app.Router(done)
+	if err != nil {
+		close(done)
+		t.Fatalf("router: %v", err)
+	}
+	if wrap != nil {
+		h = wrap(h)
+	}
+	srv := httptest.NewServer(h)
+	t.Cleanup(func() {
+		srv.Close()
+		close(done)
+	})
+	return srv
+}
+
+// newClient is a function that returns a client of the server with fast retries
+func newClient(srv *httptest.Server, retries int) *Client {
+	return NewClient(&ConfigClient{
+		BaseURL:     srv.URL,
+		HTTPClient:  srv.Client(),
+		Retries:     retries,
+		BackoffBase: time.Millisecond,
+		BackoffMax:  5 * time.Millisecond,
+	})
+}
+
+// failing is a function that returns a middleware answering the first n requests with code,
+// counting every request in calls
+func failing(n int32, code int, calls *atomic.Int32) func(next http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if calls.Add(1) <= n {
+				http.Error(w, `{"error":"unavailable"}`, code)
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+func TestClient_Errors(t *testing.T) {
+	srv := newServer(t, nil)
+	c := newClient(srv, 0)
+	ctx := context.Background()
+
+	cases := []struct {
+		name string
+		call func() error
+		code int
+		kind error
+	}{
+		{
+			name: "missing vehicle",
+			call: func() error { return c.Delete(ctx, 999) },
+			code: http.StatusNotFound,
+			kind: ErrNotFound,
+		},
+		{
+			name: "missing registration",
+			call: func() error { _, err := c.FindByRegistration(ctx, "NOP0000"); return err },
+			code: http.StatusNotFound,
+			kind: ErrNotFound,
+		},
+		{
+			name: "duplicate id",
+			call: func() error {
+				return c.Create(ctx, Vehicle{ID: 1, Brand: "Fiat", Model: "Uno", Registration: "FIA0001", Color: "White", FabricationYear: 2010})
+			},
+			code: http.StatusConflict,
+			kind: ErrConflict,
+		},
+		{
+			name: "duplicate registration",
+			call: func() error {
+				return c.Create(ctx, Vehicle{ID: 3, Brand: "Fiat", Model: "Uno", Registration: "abc-1234", Color: "White", FabricationYear: 2010})
+			},
+			code: http.StatusConflict,
+			kind: ErrConflict,
+		},
+		{
+			name: "invalid VIN",
+			call: func() error { _, err := c.DecodeVIN(ctx, "not-a-vin"); return err },
+			code: http.StatusUnprocessableEntity,
+			kind: ErrInvalid,
+		},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := tc.call()
+			var apiErr *APIError
+			if !errors.As(err, &apiErr) {
+				t.Fatalf("error = %v, want an APIError", err)
+			}
+			if apiErr.StatusCode != tc.code || apiErr.Message == "" {
+				t.Errorf("error = %+v, want status %d with a message", apiErr, tc.code)
+			}
+			if !errors.Is(err, tc.kind) {
+				t.Errorf("error = %v, want it to match %v", err, tc.kind)
+			}
+		})
+	}
+}
+
+func TestClient_RetryServerError(t *testing.T) {
+	t.Run("idempotent request succeeds after transient failures", func(t *testing.T) {
+		var calls atomic.Int32
+		srv := newServer(t, failing(2, http.StatusServiceUnavailable, &calls))
+		c := newClient(srv, 3)
+
+		v, err := c.FindById(context.Background(), 1)
+		if err != nil {
+			t.Fatalf("find: %v", err)
+		}
+		if v.ID != 1 || v.Brand != "Toyota" {
+			t.Errorf("vehicle = %+v, want vehicle 1", v)
+		}
+		if n := calls.Load(); n != 3 {
+			t.Errorf("server got %d requests, want 3", n)
+		}
+	})
+
+	t.Run("retries are exhausted", func(t *testing.T) {
+		var calls atomic.Int32
+		srv := newServer(t, failing(100, http.StatusBadGateway, &calls))
+		c := newClient(srv, 2)
+
+		_, err := c.FindAll(context.Background())
+		var apiErr *APIError
+		if !errors.As(err, &apiErr) || apiErr.StatusCode != http.StatusBadGateway || !errors.Is(err, ErrServer) {
+			t.Errorf("error = %v, want a 502 matching ErrServer", err)
+		}
+		if n := calls.Load(); n != 3 {
+			t.Errorf("server got %d requests, want 3", n)
+		}
+	})
+
+	t.Run("non idempotent request is not retried", func(t *testing.T) {
+		var calls atomic.Int32
+		srv := newServer(t, failing(1, http.StatusServiceUnavailable, &calls))
+		c := newClient(srv, 3)
+
+		err := c.Create(context.Background(), Vehicle{ID: 3, Brand: "Fiat", Model: "Uno", Registration: "FIA0001"})
+		if !errors.Is(err, ErrServer) {
+			t.Errorf("error = %v, want ErrServer", err)
+		}
+		if n := calls.Load(); n != 1 {
+			t.Errorf("server got %d requests, want 1", n)
+		}
+	})
+
+	t.Run("internal server error is not retried", func(t *testing.T) {
+		var calls atomic.Int32
+		srv := newServer(t, failing(1, http.StatusInternalServerError, &calls))
+		c := newClient(srv, 3)
+
+		if _, err := c.FindAll(context.Background()); !errors.Is(err, ErrServer) {
+			t.Errorf("error = %v, want ErrServer", err)
+		}
+		if n := calls.Load(); n != 1 {
+			t.Errorf("server got %d requests, want 1", n)
+		}
+	})
+}
+
+func TestClient_ContextCancel(t *testing.T) {
+	t.Run("canceled before the request", func(t *testing.T) {
+		var calls atomic.Int32
+		srv := newServer(t, failing(0, http.StatusOK, &calls))
+		c := newClient(srv, 3)
+
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		if _, err := c.FindAll(ctx); !errors.Is(err, context.Canceled) {
+			t.Errorf("error = %v, want context.Canceled", err)
+		}
+		if n := calls.Load(); n != 0 {
+			t.Errorf("server got %d requests, want 0", n)
+		}
+	})
+
+	t.Run("canceled while waiting to retry", func(t *testing.T) {
+		var calls atomic.Int32
+		srv := newServer(t, failing(100, http.StatusServiceUnavailable, &calls))
+		c := NewClient(&ConfigClient{
+			BaseURL:     srv.URL,
+			HTTPClient:  srv.Client(),
+			Retries:     3,
+			BackoffBase: time.Hour,
+			BackoffMax:  time.Hour,
+		})
+
+		ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+		defer cancel()
+		start := time.Now()
+		_, err := c.FindAll(ctx)
+		if !errors.Is(err, context.DeadlineExceeded) {
+			t.Errorf("error = %v, want context.DeadlineExceeded", err)
+		}
+		if elapsed := time.Since(start); elapsed > 5*time.Second {
+			t.Errorf("request returned after %s, want it to stop with the context", elapsed)
+		}
+		if n := calls.Load(); n != 1 {
+			t.Errorf("server got %d requests, want 1", n)
+		}
+	})
+}
+
+func TestClient_V2Routes(t *testing.T) {
+	var paths []string
+	srv := newServer(t, func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			paths = append(paths, r.URL.Path)
+			next.ServeHTTP(w, r)
+		})
+	})
+	c := newClient(srv, 0)
+	ctx := context.Background()
+
+	if err := c.Create(ctx, Vehicle{ID: 3, Brand: "Toyota", Model: "Yaris", Registration: "TOY0003", Color: "Red", FabricationYear: 2015, Capacity: 4}); err != nil {
+		t.Fatalf("create: %v", err)
+	}
+	if err := c.UpdateSpeed(ctx, 3, 170); err != nil {
+		t.Fatalf("update speed: %v", err)
+	}
+	if err := c.UpdateFuelType(ctx, 3, "hybrid"); err != nil {
+		t.Fatalf("update fuel type: %v", err)
+	}
+	v, err := c.FindById(ctx, 3)
+	if err != nil || v.MaxSpeed != 170 || v.FuelType != "hybrid" {
+		t.Errorf("vehicle = %+v, %v, want both updates", v, err)
+	}
+	if found, err := c.FindByColorAndYear(ctx, "Red", 2015); err != nil || len(found) != 2 || found[0].ID != 1 || found[1].ID != 3 {
+		t.Errorf("red vehicles of 2015 = %+v, %v, want 1 and 3", found, err)
+	}
+	if found, err := c.FindByWeight(ctx, 1340, 1400); err != nil || len(found) != 1 || found[0].ID != 2 {
+		t.Errorf("vehicles by weight = %+v, %v, want 2", found, err)
+	}
+
+	// - the average capacity is not rounded
+	if avg, err := c.FindByBrandAverageCapacity(ctx, "Toyota"); err != nil || avg != 4.5 {
+		t.Errorf("average capacity = %v, %v, want 4.5", avg, err)
+	}
+	if avg, err := c.FindByBrandAverageSpeed(ctx, "Toyota"); err != nil || avg != 175 {
+		t.Errorf("average speed = %v, %v, want 175", avg, err)
+	}
+
+	if err = c.Delete(ctx, 3); err != nil {
+		t.Fatalf("delete: %v", err)
+	}
+	if _, err = c.FindById(ctx, 3); !errors.Is(err, ErrNotFound) {
+		t.Errorf("find deleted vehicle: %v, want ErrNotFound", err)
+	}
+
+	for _, p := range paths {
+		if !strings.HasPrefix(p, "/v2/") {
+			t.Errorf("request to %s, want a /v2 route", p)
+		}
+	}
+}
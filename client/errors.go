@@ -0,0 +1,64 @@
+package client
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+)
+
+var (
+	// ErrInvalid is matched by responses rejecting the request, status 400 or 422
+	ErrInvalid = errors.New("client: invalid request")
+	// ErrNotFound is matched by responses for a missing resource, status 404
+	ErrNotFound = errors.New("client: not found")
+	// ErrConflict is matched by responses for a conflicting request such as a duplicate id, status 409
+	ErrConflict = errors.New("client: conflict")
+	// ErrUnsupported is matched by responses refusing the encoding of the request, status 406 or 415
+	ErrUnsupported = errors.New("client: unsupported media type")
+	// ErrServer is matched by responses reporting a failure of the service, status 5xx
+	ErrServer = errors.New("client: server error")
+	// ErrBatchAborted is matched by the error of an atomic batch that was not applied
+	ErrBatchAborted = errors.New("client: batch aborted")
+)
+
+// APIError is a struct that represents an error response of the service.
+// It matches the sentinel error of its status code with errors.Is.
+type APIError struct {
+	// StatusCode is the http status code of the response
+	StatusCode int
+	// Message is the error reported by the service, the status text when there is none
+	Message string
+	// kind is the sentinel error of the status code, nil for other codes
+	kind error
+}
+
+// newAPIError is a function that returns the error of a response
+func newAPIError(code int, message string) *APIError {
+	if message == "" {
+		message = http.StatusText(code)
+	}
+	e := &APIError{StatusCode: code, Message: message}
+	switch {
+	case code == http.StatusBadRequest || code == http.StatusUnprocessableEntity:
+		e.kind = ErrInvalid
+	case code == http.StatusNotFound:
+		e.kind = ErrNotFound
+	case code == http.StatusConflict:
+		e.kind = ErrConflict
+	case code == http.StatusNotAcceptable || code == http.StatusUnsupportedMediaType:
+		e.kind = ErrUnsupported
+	case code >= 500:
+		e.kind = ErrServer
+	}
+	return e
+}
+
+// Error is a method that returns the error message
+func (e *APIError) Error() string {
+	return fmt.Sprintf("garage: %d: %s", e.StatusCode, e.Message)
+}
+
+// Unwrap is a method that returns the sentinel error of the status code
+func (e *APIError) Unwrap() error {
+	return e.kind
+}
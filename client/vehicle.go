@@ -0,0 +1,484 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Vehicle is a struct that represents a vehicle of the service
+type Vehicle struct {
+	ID              int     `json:"id"`
+	Brand           string  `json:"brand"`
+	Model           string  `json:"model"`
+	Registration    string  `json:"registration"`
+	Color           string  `json:"color"`
+	FabricationYear int     `json:"year"`
+	Capacity        int     `json:"passengers"`
+	MaxSpeed        float64 `json:"max_speed"`
+	FuelType        string  `json:"fuel_type"`
+	Transmission    string  `json:"transmission"`
+	Weight          float64 `json:"weight"`
+	Height          float64 `json:"height"`
+	Length          float64 `json:"length"`
+	Width           float64 `json:"width"`
+	// BrandRaw and ColorRaw are the values the vehicle was received with,
+	// set by the server when it normalized them
+	VIN      string `json:"vin,omitempty"`
+	BrandRaw string `json:"brand_raw,omitempty"`
+	ColorRaw string `json:"color_raw,omitempty"`
+}
+
+// VehicleFilter is a struct that represents the criteria of a vehicle search, zero values are unset
+type VehicleFilter struct {
+	Brand        string
+	Model        string
+	Color        string
+	FuelType     string
+	Transmission string
+	YearMin      int
+	YearMax      int
+	CapacityMin  int
+	CapacityMax  int
+	SpeedMin     float64
+	SpeedMax     float64
+	WeightMin    float64
+	WeightMax    float64
+	LengthMin    float64
+	LengthMax    float64
+	WidthMin     float64
+	WidthMax     float64
+}
+
+// Query is a method that returns the filter as query parameters
+func (f VehicleFilter) Query() url.Values {
+	q := url.Values{}
+	for name, value := range map[string]string{
+		"brand": f.Brand, "model": f.Model, "color": f.Color,
+		"fuel_type": f.FuelType, "transmission": f.Transmission,
+	} {
+		if value != "" {
+			q.Set(name, value)
+		}
+	}
+	for name, value := range map[string]int{
+		"year_min": f.YearMin, "year_max": f.YearMax,
+		"passengers_min": f.CapacityMin, "passengers_max": f.CapacityMax,
+	} {
+		if value != 0 {
+			q.Set(name, strconv.Itoa(value))
+		}
+	}
+	for name, value := range map[string]float64{
+		"max_speed_min": f.SpeedMin, "max_speed_max": f.SpeedMax,
+		"weight_min": f.WeightMin, "weight_max": f.WeightMax,
+		"length_min": f.LengthMin, "length_max": f.LengthMax,
+		"width_min": f.WidthMin, "width_max": f.WidthMax,
+	} {
+		if value != 0 {
+			q.Set(name, strconv.FormatFloat(value, 'f', -1, 64))
+		}
+	}
+	return q
+}
+
+// JobRowError is a struct that represents a row rejected by an import
+type JobRowError struct {
+	Line    int    `json:"line"`
+	Message string `json:"message"`
+}
+
+// Job is a struct that represents a background job of the service
+type Job struct {
+	ID              int           `json:"id"`
+	Kind            string        `json:"kind"`
+	Status          string        `json:"status"`
+	Progress        float64       `json:"progress"`
+	BytesTotal      int64         `json:"bytes_total"`
+	BytesRead       int64         `json:"bytes_read"`
+	Processed       int           `json:"processed"`
+	Succeeded       int           `json:"succeeded"`
+	Failed          int           `json:"failed"`
+	Errors          []JobRowError `json:"errors"`
+	ErrorsTruncated bool          `json:"errors_truncated"`
+	Error           string        `json:"error"`
+	CreatedAt       time.Time     `json:"created_at"`
+	FinishedAt      *time.Time    `json:"finished_at"`
+}
+
+// Done is a method that reports whether the job finished
+func (j Job) Done() bool {
+	return j.Status != "running"
+}
+
+// BatchOperation is a struct that represents an operation of a batch.
+// Op is create, update or delete, a delete only needs the id.
+type BatchOperation struct {
+	Op      string   `json:"op"`
+	ID      int      `json:"id"`
+	Vehicle *Vehicle `json:"vehicle,omitempty"`
+}
+
+// BatchResult is a struct that represents the outcome of an operation of a batch
+// - status is created, updated, deleted, failed or skipped (valid but not applied in an aborted batch)
+type BatchResult struct {
+	Index  int    `json:"index"`
+	Op     string `json:"op"`
+	ID     int    `json:"id"`
+	Status string `json:"status"`
+	Error  string `json:"error,omitempty"`
+}
+
+// FindAll is a method that returns all the vehicles keyed by id
+func (c *Client) FindAll(ctx context.Context) (v map[int]Vehicle, err error) {
+	list, err := c.FindByFilter(ctx, VehicleFilter{})
+	if err != nil {
+		return
+	}
+	v = make(map[int]Vehicle, len(list))
+	for _, vh := range list {
+		v[vh.ID] = vh
+	}
+	return
+}
+
+// FindByFilter is a method that returns the vehicles matching the filter in id order
+func (c *Client) FindByFilter(ctx context.Context, f VehicleFilter) ([]Vehicle, error) {
+	return c.list(ctx, "/v2/vehicles/", f.Query())
+}
+
+// FindById is a method that returns the vehicle with the given id
+func (c *Client) FindById(ctx context.Context, id int) (v Vehicle, err error) {
+	_, err = c.do(ctx, request{method: http.MethodGet, path: fmt.Sprintf("/v2/vehicles/%d", id)}, &v)
+	return
+}
+
+// FindByRegistration is a method that returns the vehicle holding a registration, regardless of case and separators.
+// A registration shared by several vehicles of a loaded fleet matches ErrConflict.
+func (c *Client) FindByRegistration(ctx context.Context, registration string) (v Vehicle, err error) {
+	_, err = c.do(ctx, request{method: http.MethodGet, path: "/v2/vehicles/registration/" + url.PathEscape(registration)}, &v)
+	return
+}
+
+// FindByVIN is a method that returns the vehicle holding a VIN, regardless of case, spaces and hyphens.
+// A VIN shared by several vehicles of a loaded fleet matches ErrConflict.
+func (c *Client) FindByVIN(ctx context.Context, vin string) (v Vehicle, err error) {
+	_, err = c.do(ctx, request{method: http.MethodGet, path: "/v2/vehicles/vin/" + url.PathEscape(vin)}, &v)
+	return
+}
+
+// VIN is a struct that represents the data decoded from a VIN.
+// ModelYears are the model years the VIN may stand for, the most likely first.
+type VIN struct {
+	VIN          string   `json:"vin"`
+	WMI          string   `json:"wmi"`
+	Manufacturer string   `json:"manufacturer"`
+	Brands       []string `json:"brands"`
+	Country      string   `json:"country"`
+	Region       string   `json:"region"`
+	ModelYears   []int    `json:"model_years"`
+}
+
+// DecodeVIN is a method that checks a VIN and returns its manufacturer, country and model years.
+// An invalid VIN matches ErrInvalid.
+func (c *Client) DecodeVIN(ctx context.Context, vin string) (d VIN, err error) {
+	_, err = c.do(ctx, request{method: http.MethodGet, path: "/v2/vins/" + url.PathEscape(vin)}, &d)
+	return
+}
+
+// Create is a method that adds a vehicle to the fleet
+func (c *Client) Create(ctx context.Context, v Vehicle) (err error) {
+	_, err = c.do(ctx, request{method: http.MethodPost, path: "/v2/vehicles/", body: v}, nil)
+	return
+}
+
+// CreateBatch is a method that adds the vehicles to the fleet, none when one of them is rejected
+func (c *Client) CreateBatch(ctx context.Context, v []Vehicle) (err error) {
+	ops := make([]BatchOperation, 0, len(v))
+	for i := range v {
+		ops = append(ops, BatchOperation{Op: "create", Vehicle: &v[i]})
+	}
+	_, err = c.ApplyBatch(ctx, ops, true)
+	return
+}
+
+// ApplyBatch is a method that applies a batch of mixed operations and returns the outcome of each one.
+// An atomic batch with a rejected operation returns the results along with ErrBatchAborted,
+// a best effort batch with failures returns the results with no error.
+func (c *Client) ApplyBatch(ctx context.Context, ops []BatchOperation, atomic bool) (res []BatchResult, err error) {
+	mode := "best_effort"
+	if atomic {
+		mode = "atomic"
+	}
+	body := map[string]any{"mode": mode, "operations": ops}
+	resp, err := c.do(ctx, request{method: http.MethodPost, path: "/v2/vehicles/batch", body: body}, &res)
+	if resp.code == http.StatusConflict && len(resp.fields["data"]) > 0 {
+		var msg string
+		_ = json.Unmarshal(resp.fields["message"], &msg)
+		_ = json.Unmarshal(resp.fields["data"], &res)
+		apiErr := newAPIError(resp.code, msg)
+		apiErr.kind = ErrBatchAborted
+		err = apiErr
+	}
+	return
+}
+
+// Delete is a method that removes the vehicle with the given id
+func (c *Client) Delete(ctx context.Context, id int) (err error) {
+	_, err = c.do(ctx, request{method: http.MethodDelete, path: fmt.Sprintf("/v2/vehicles/%d", id)}, nil)
+	return
+}
+
+// UpdateSpeed is a method that sets the max speed of a vehicle
+func (c *Client) UpdateSpeed(ctx context.Context, id int, speed float64) (err error) {
+	body := map[string]float64{"max_speed": speed}
+	_, err = c.do(ctx, request{method: http.MethodPatch, path: fmt.Sprintf("/v2/vehicles/%d", id), body: body}, nil)
+	return
+}
+
+// UpdateFuelType is a method that sets the fuel type of a vehicle
+func (c *Client) UpdateFuelType(ctx context.Context, id int, fuelType string) (err error) {
+	body := map[string]string{"fuel_type": fuelType}
+	_, err = c.do(ctx, request{method: http.MethodPatch, path: fmt.Sprintf("/v2/vehicles/%d", id), body: body}, nil)
+	return
+}
+
+// FindByColorAndYear is a method that returns the vehicles of a color made in a year
+func (c *Client) FindByColorAndYear(ctx context.Context, color string, year int) ([]Vehicle, error) {
+	return c.FindByFilter(ctx, VehicleFilter{Color: color, YearMin: year, YearMax: year})
+}
+
+// FindByFuelType is a method that returns the vehicles of a fuel type
+func (c *Client) FindByFuelType(ctx context.Context, fuelType string) ([]Vehicle, error) {
+	return c.FindByFilter(ctx, VehicleFilter{FuelType: fuelType})
+}
+
+// FindByTransmissionType is a method that returns the vehicles of a transmission type
+func (c *Client) FindByTransmissionType(ctx context.Context, transmission string) ([]Vehicle, error) {
+	return c.FindByFilter(ctx, VehicleFilter{Transmission: transmission})
+}
+
+// FindByBrandAndBetweenYear is a method that returns the vehicles of a brand made between two years
+func (c *Client) FindByBrandAndBetweenYear(ctx context.Context, brand string, start, end int) ([]Vehicle, error) {
+	return c.FindByFilter(ctx, VehicleFilter{Brand: brand, YearMin: start, YearMax: end})
+}
+
+// BrandAverages is a struct that represents the averages of the vehicles of a brand
+type BrandAverages struct {
+	Brand    string  `json:"brand"`
+	MaxSpeed float64 `json:"max_speed"`
+	Capacity float64 `json:"passengers"`
+}
+
+// FindBrandAverages is a method that returns the average max speed and capacity of the vehicles of a brand.
+// A brand without vehicles matches ErrNotFound.
+func (c *Client) FindBrandAverages(ctx context.Context, brand string) (avg BrandAverages, err error) {
+	_, err = c.do(ctx, request{method: http.MethodGet, path: "/v2/brands/" + url.PathEscape(brand) + "/averages"}, &avg)
+	return
+}
+
+// FindByBrandAverageSpeed is a method that returns the average max speed of the vehicles of a brand
+func (c *Client) FindByBrandAverageSpeed(ctx context.Context, brand string) (avg float64, err error) {
+	a, err := c.FindBrandAverages(ctx, brand)
+	return a.MaxSpeed, err
+}
+
+// FindByBrandAverageCapacity is a method that returns the average capacity of the vehicles of a brand
+func (c *Client) FindByBrandAverageCapacity(ctx context.Context, brand string) (avg float64, err error) {
+	a, err := c.FindBrandAverages(ctx, brand)
+	return a.Capacity, err
+}
+
+// FindByDimensions is a method that returns the vehicles within a range of length and width
+func (c *Client) FindByDimensions(ctx context.Context, lengthMin, lengthMax, widthMin, widthMax float64) ([]Vehicle, error) {
+	return c.FindByFilter(ctx, VehicleFilter{LengthMin: lengthMin, LengthMax: lengthMax, WidthMin: widthMin, WidthMax: widthMax})
+}
+
+// FindByWeight is a method that returns the vehicles within a range of weight
+func (c *Client) FindByWeight(ctx context.Context, min, max float64) ([]Vehicle, error) {
+	return c.FindByFilter(ctx, VehicleFilter{WeightMin: min, WeightMax: max})
+}
+
+// FindByColor is a method that returns the vehicles of a color
+func (c *Client) FindByColor(ctx context.Context, color string) ([]Vehicle, error) {
+	return c.FindByFilter(ctx, VehicleFilter{Color: color})
+}
+
+// list is a method that returns the vehicles of a search endpoint
+func (c *Client) list(ctx context.Context, path string, query url.Values) (v []Vehicle, err error) {
+	_, err = c.do(ctx, request{method: http.MethodGet, path: path, query: query}, &v)
+	return
+}
+
+// ImportVehicles is a method that uploads an NDJSON or CSV stream and returns the job importing it.
+// CSV options are given as query parameters, e.g. delimiter and decimal.
+func (c *Client) ImportVehicles(ctx context.Context, r io.Reader, format string, options url.Values) (j Job, err error) {
+	q := url.Values{"format": {format}}
+	for name, values := range options {
+		q[name] = values
+	}
+	_, err = c.do(ctx, request{method: http.MethodPost, path: "/v2/vehicles/import", query: q, body: r, contentType: importContentType[format]}, &j)
+	return
+}
+
+// importContentType is the content type of each import format
+var importContentType = map[string]string{
+	"csv":    "text/csv",
+	"ndjson": "application/x-ndjson",
+}
+
+// GetJob is a method that returns the progress of a job
+func (c *Client) GetJob(ctx context.Context, id int) (j Job, err error) {
+	_, err = c.do(ctx, request{method: http.MethodGet, path: fmt.Sprintf("/jobs/%d", id)}, &j)
+	return
+}
+
+// WaitJob is a method that polls a job every interval until it finishes or ctx is done
+func (c *Client) WaitJob(ctx context.Context, id int, interval time.Duration) (j Job, err error) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		if j, err = c.GetJob(ctx, id); err != nil || j.Done() {
+			return
+		}
+		select {
+		case <-ctx.Done():
+			return j, ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+// StatsGroup is a struct that represents the aggregates of a group of vehicles.
+// Group holds the value of each group field, a metric without any value to aggregate is nil.
+type StatsGroup struct {
+	Group   map[string]string   `json:"group"`
+	Count   int                 `json:"count"`
+	Metrics map[string]*float64 `json:"metrics"`
+}
+
+// Stats is a method that returns aggregates of the vehicles matching the filter, grouped by the given fields.
+// Metrics are written as func:field, e.g. avg:max_speed or p90:weight, or count.
+func (c *Client) Stats(ctx context.Context, f VehicleFilter, groupBy, metrics []string) (s []StatsGroup, err error) {
+	q := f.Query()
+	if len(groupBy) > 0 {
+		q.Set("group_by", strings.Join(groupBy, ","))
+	}
+	if len(metrics) > 0 {
+		q.Set("metrics", strings.Join(metrics, ","))
+	}
+	_, err = c.do(ctx, request{method: http.MethodGet, path: "/v2/vehicles/stats", query: q}, &s)
+	return
+}
+
+// SearchResult is a struct that represents a vehicle found by a search.
+// Matched are the fields holding the terms of the query.
+type SearchResult struct {
+	Vehicle
+	Score   float64  `json:"score"`
+	Matched []string `json:"matched"`
+}
+
+// Search is a method that returns up to limit vehicles whose brand, model, color or registration match the query,
+// the most relevant first, the service default when limit is 0
+func (c *Client) Search(ctx context.Context, query string, limit int) (res []SearchResult, err error) {
+	q := url.Values{"q": {query}}
+	if limit > 0 {
+		q.Set("limit", strconv.Itoa(limit))
+	}
+	_, err = c.do(ctx, request{method: http.MethodGet, path: "/v2/vehicles/search", query: q}, &res)
+	return
+}
+
+// SimilarResult is a struct that represents a vehicle similar to another one.
+// Distance is the weighted distance between the vehicles, 0 for identical vehicles.
+type SimilarResult struct {
+	Vehicle
+	Distance float64 `json:"distance"`
+}
+
+// Similar is a method that returns up to k vehicles most similar to the vehicle with the given id, the closest first,
+// the service default when k is 0. weights overrides the weights of some fields, e.g. {"brand": 0}.
+func (c *Client) Similar(ctx context.Context, id, k int, weights map[string]float64) (res []SimilarResult, err error) {
+	q := url.Values{}
+	if k > 0 {
+		q.Set("k", strconv.Itoa(k))
+	}
+	if len(weights) > 0 {
+		fields := make([]string, 0, len(weights))
+		for field := range weights {
+			fields = append(fields, field)
+		}
+		sort.Strings(fields)
+		pairs := make([]string, 0, len(fields))
+		for _, field := range fields {
+			pairs = append(pairs, field+":"+strconv.FormatFloat(weights[field], 'g', -1, 64))
+		}
+		q.Set("weights", strings.Join(pairs, ","))
+	}
+	_, err = c.do(ctx, request{method: http.MethodGet, path: fmt.Sprintf("/v2/vehicles/%d/similar", id), query: q}, &res)
+	return
+}
+
+// Duplicate is a struct that represents vehicles that are likely the same vehicle.
+// Kind is registration for vehicles sharing Registration, near for a pair of vehicles with a Score
+// of similarity from 0 to 1 and the Matched fields with the same value.
+type Duplicate struct {
+	Kind         string   `json:"kind"`
+	Ids          []int    `json:"ids"`
+	Registration string   `json:"registration"`
+	Score        float64  `json:"score"`
+	Matched      []string `json:"matched"`
+}
+
+// Merge is a struct that represents the merge of a duplicate into the vehicle kept.
+// Merged is the duplicate as it was before it was deleted, Filled the fields of the kept vehicle filled from it.
+type Merge struct {
+	ID       int       `json:"id"`
+	KeptID   int       `json:"kept_id"`
+	MergedID int       `json:"merged_id"`
+	Merged   Vehicle   `json:"merged"`
+	Filled   []string  `json:"filled"`
+	At       time.Time `json:"at"`
+}
+
+// Duplicates is a method that returns the registration clashes and the near duplicates of the vehicles matching
+// the filter. threshold is the smallest similarity of a near duplicate, the service default when it is 0.
+func (c *Client) Duplicates(ctx context.Context, f VehicleFilter, threshold float64) (d []Duplicate, err error) {
+	q := f.Query()
+	if threshold > 0 {
+		q.Set("threshold", strconv.FormatFloat(threshold, 'g', -1, 64))
+	}
+	_, err = c.do(ctx, request{method: http.MethodGet, path: "/v2/vehicles/duplicates", query: q}, &d)
+	return
+}
+
+// Merge is a method that merges the vehicle with id merge into the vehicle with id keep and returns the recorded
+// merge along with the kept vehicle, whose empty fields were filled from the duplicate
+func (c *Client) Merge(ctx context.Context, keep, merge int) (m Merge, kept Vehicle, err error) {
+	var out struct {
+		Merge   Merge   `json:"merge"`
+		Vehicle Vehicle `json:"vehicle"`
+	}
+	body := map[string]int{"keep": keep, "merge": merge}
+	_, err = c.do(ctx, request{method: http.MethodPost, path: "/v2/vehicles/duplicates/merge", body: body}, &out)
+	return out.Merge, out.Vehicle, err
+}
+
+// Merges is a method that returns the audit log of the merges, the ones that kept or merged a vehicle
+// when vehicleID is not 0
+func (c *Client) Merges(ctx context.Context, vehicleID int) (m []Merge, err error) {
+	q := url.Values{}
+	if vehicleID != 0 {
+		q.Set("vehicle_id", strconv.Itoa(vehicleID))
+	}
+	_, err = c.do(ctx, request{method: http.MethodGet, path: "/v2/vehicles/duplicates/merges", query: q}, &m)
+	return
+}
@@ -0,0 +1,74 @@
+package main
+
+import (
+	"app/client"
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+)
+
+// usage is the help of the command
+const usage = `garagectl drives the garage service from the command line.
+
+Usage:
+  garagectl [-server URL] [-o table|json] vehicles <command> [flags] [args]
+
+Commands:
+  vehicles list    [--brand B] [--color C] [--year-min Y] ... [--sort -year,brand] [--limit N]
+  vehicles get     ID
+  vehicles create  -f FILE        (- reads the vehicle from stdin)
+  vehicles import  [--wait] FILE  (.csv, .ndjson or .jsonl)
+  vehicles stats   [--by brand]   [filter flags of list]
+
+The server defaults to $GARAGE_URL or http://localhost:8080.
+`
+
+func main() {
+	// global flags
+	fs := flag.NewFlagSet("garagectl", flag.ContinueOnError)
+	fs.Usage = func() { fmt.Fprint(os.Stderr, usage) }
+	server := fs.String("server", os.Getenv("GARAGE_URL"), "address of the garage service")
+	output := fs.String("o", "table", "output format, table or json")
+	if err := fs.Parse(os.Args[1:]); err != nil {
+		os.Exit(2)
+	}
+	if *output != "table" && *output != "json" {
+		fmt.Fprintf(os.Stderr, "garagectl: invalid output %q\n", *output)
+		os.Exit(2)
+	}
+
+	// command
+	args := fs.Args()
+	if len(args) < 2 || args[0] != "vehicles" {
+		fmt.Fprint(os.Stderr, usage)
+		os.Exit(2)
+	}
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+	cli := &cli{
+		c:   client.NewClient(&client.ConfigClient{BaseURL: *server}),
+		out: newPrinter(os.Stdout, *output),
+	}
+
+	var err error
+	switch args[1] {
+	case "list":
+		err = cli.list(ctx, args[2:])
+	case "get":
+		err = cli.get(ctx, args[2:])
+	case "create":
+		err = cli.create(ctx, args[2:])
+	case "import":
+		err = cli.importFile(ctx, args[2:])
+	case "stats":
+		err = cli.stats(ctx, args[2:])
+	default:
+		err = fmt.Errorf("unknown command %q", args[1])
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "garagectl:", err)
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,112 @@
+package main
+
+import (
+	"app/client"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+)
+
+// printer is a struct that writes the results of the commands as a table or as JSON
+type printer struct {
+	// w is the output
+	w io.Writer
+	// format is table or json
+	format string
+}
+
+// newPrinter is a function that returns a new instance of printer
+func newPrinter(w io.Writer, format string) *printer {
+	return &printer{w: w, format: format}
+}
+
+// vehicleColumns is the list of columns of a vehicle table
+var vehicleColumns = []string{"ID", "BRAND", "MODEL", "YEAR", "COLOR", "FUEL", "TRANSMISSION", "PASSENGERS", "MAX SPEED"}
+
+// vehicles is a method that prints a list of vehicles
+func (p *printer) vehicles(v []client.Vehicle) error {
+	if p.format == "json" {
+		return p.json(v)
+	}
+	rows := make([][]string, 0, len(v))
+	for _, vh := range v {
+		rows = append(rows, []string{
+			strconv.Itoa(vh.ID), vh.Brand, vh.Model, strconv.Itoa(vh.FabricationYear), vh.Color,
+			vh.FuelType, vh.Transmission, strconv.Itoa(vh.Capacity), formatFloat(vh.MaxSpeed),
+		})
+	}
+	return p.table(vehicleColumns, rows)
+}
+
+// vehicle is a method that prints every attribute of a vehicle
+func (p *printer) vehicle(v client.Vehicle) error {
+	if p.format == "json" {
+		return p.json(v)
+	}
+	return p.table([]string{"FIELD", "VALUE"}, [][]string{
+		{"id", strconv.Itoa(v.ID)},
+		{"brand", v.Brand},
+		{"model", v.Model},
+		{"registration", v.Registration},
+		{"vin", v.VIN},
+		{"color", v.Color},
+		{"year", strconv.Itoa(v.FabricationYear)},
+		{"passengers", strconv.Itoa(v.Capacity)},
+		{"max_speed", formatFloat(v.MaxSpeed)},
+		{"fuel_type", v.FuelType},
+		{"transmission", v.Transmission},
+		{"weight", formatFloat(v.Weight)},
+		{"height", formatFloat(v.Height)},
+		{"length", formatFloat(v.Length)},
+		{"width", formatFloat(v.Width)},
+	})
+}
+
+// job is a method that prints the progress of a job
+func (p *printer) job(j client.Job) error {
+	if p.format == "json" {
+		return p.json(j)
+	}
+	err := p.table([]string{"JOB", "STATUS", "PROCESSED", "SUCCEEDED", "FAILED", "PROGRESS"}, [][]string{{
+		strconv.Itoa(j.ID), j.Status, strconv.Itoa(j.Processed), strconv.Itoa(j.Succeeded), strconv.Itoa(j.Failed),
+		fmt.Sprintf("%.0f%%", j.Progress*100),
+	}})
+	if err != nil {
+		return err
+	}
+	for _, e := range j.Errors {
+		fmt.Fprintf(p.w, "line %d: %s\n", e.Line, e.Message)
+	}
+	if j.ErrorsTruncated {
+		fmt.Fprintln(p.w, "more errors were not kept")
+	}
+	if j.Error != "" {
+		fmt.Fprintln(p.w, "error:", j.Error)
+	}
+	return nil
+}
+
+// table is a method that prints aligned columns
+func (p *printer) table(header []string, rows [][]string) error {
+	tw := tabwriter.NewWriter(p.w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, strings.Join(header, "\t"))
+	for _, row := range rows {
+		fmt.Fprintln(tw, strings.Join(row, "\t"))
+	}
+	return tw.Flush()
+}
+
+// json is a method that prints a value as indented JSON
+func (p *printer) json(v any) error {
+	enc := json.NewEncoder(p.w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(v)
+}
+
+// formatFloat is a function that formats a number without trailing zeros
+func formatFloat(f float64) string {
+	return strconv.FormatFloat(f, 'f', -1, 64)
+}
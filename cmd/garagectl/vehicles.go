@@ -0,0 +1,286 @@
+package main
+
+import (
+	"app/client"
+	"cmp"
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"math"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// cli is a struct that runs the vehicles commands
+type cli struct {
+	// c is the client of the service
+	c *client.Client
+	// out prints the results
+	out *printer
+}
+
+// filterFlags is a function that adds the flags of a vehicle filter to fs
+func filterFlags(fs *flag.FlagSet) *client.VehicleFilter {
+	f := &client.VehicleFilter{}
+	fs.StringVar(&f.Brand, "brand", "", "brand, case insensitive")
+	fs.StringVar(&f.Model, "model", "", "model, case insensitive")
+	fs.StringVar(&f.Color, "color", "", "color, case insensitive")
+	fs.StringVar(&f.FuelType, "fuel-type", "", "fuel type")
+	fs.StringVar(&f.Transmission, "transmission", "", "transmission")
+	fs.IntVar(&f.YearMin, "year-min", 0, "minimum fabrication year")
+	fs.IntVar(&f.YearMax, "year-max", 0, "maximum fabrication year")
+	fs.IntVar(&f.CapacityMin, "passengers-min", 0, "minimum number of passengers")
+	fs.IntVar(&f.CapacityMax, "passengers-max", 0, "maximum number of passengers")
+	fs.Float64Var(&f.SpeedMin, "max-speed-min", 0, "minimum max speed")
+	fs.Float64Var(&f.SpeedMax, "max-speed-max", 0, "maximum max speed")
+	fs.Float64Var(&f.WeightMin, "weight-min", 0, "minimum weight")
+	fs.Float64Var(&f.WeightMax, "weight-max", 0, "maximum weight")
+	fs.Float64Var(&f.LengthMin, "length-min", 0, "minimum length")
+	fs.Float64Var(&f.LengthMax, "length-max", 0, "maximum length")
+	fs.Float64Var(&f.WidthMin, "width-min", 0, "minimum width")
+	fs.Float64Var(&f.WidthMax, "width-max", 0, "maximum width")
+	return f
+}
+
+// list is a method that prints the vehicles matching the filter flags
+func (c *cli) list(ctx context.Context, args []string) (err error) {
+	fs := flag.NewFlagSet("vehicles list", flag.ContinueOnError)
+	f := filterFlags(fs)
+	sortBy := fs.String("sort", "id", "comma separated fields to sort by, a leading - sorts descending, e.g. -year,brand")
+	limit := fs.Int("limit", 0, "maximum number of vehicles, 0 for all")
+	if err = fs.Parse(args); err != nil {
+		return
+	}
+	less, err := vehicleOrder(*sortBy)
+	if err != nil {
+		return
+	}
+
+	v, err := c.c.FindByFilter(ctx, *f)
+	if err != nil {
+		return
+	}
+	sort.SliceStable(v, func(i, j int) bool { return less(v[i], v[j]) })
+	if *limit > 0 && len(v) > *limit {
+		v = v[:*limit]
+	}
+	return c.out.vehicles(v)
+}
+
+// get is a method that prints a vehicle
+func (c *cli) get(ctx context.Context, args []string) (err error) {
+	if len(args) != 1 {
+		return errors.New("usage: vehicles get ID")
+	}
+	id, err := strconv.Atoi(args[0])
+	if err != nil {
+		return fmt.Errorf("invalid id %q", args[0])
+	}
+
+	v, err := c.c.FindById(ctx, id)
+	if err != nil {
+		return
+	}
+	return c.out.vehicle(v)
+}
+
+// create is a method that creates the vehicle described by a JSON file
+func (c *cli) create(ctx context.Context, args []string) (err error) {
+	fs := flag.NewFlagSet("vehicles create", flag.ContinueOnError)
+	file := fs.String("f", "", "JSON file of the vehicle, - for stdin")
+	if err = fs.Parse(args); err != nil {
+		return
+	}
+	if *file == "" {
+		return errors.New("usage: vehicles create -f FILE")
+	}
+
+	var r io.Reader = os.Stdin
+	if *file != "-" {
+		fd, err := os.Open(*file)
+		if err != nil {
+			return err
+		}
+		defer fd.Close()
+		r = fd
+	}
+	dec := json.NewDecoder(r)
+	dec.DisallowUnknownFields()
+	var v client.Vehicle
+	if err = dec.Decode(&v); err != nil {
+		return fmt.Errorf("%s: %w", *file, err)
+	}
+
+	if err = c.c.Create(ctx, v); err != nil {
+		return
+	}
+	return c.out.vehicle(v)
+}
+
+// importFile is a method that uploads a CSV or NDJSON file and optionally waits for the import job
+func (c *cli) importFile(ctx context.Context, args []string) (err error) {
+	fs := flag.NewFlagSet("vehicles import", flag.ContinueOnError)
+	wait := fs.Bool("wait", false, "wait for the import to finish")
+	format := fs.String("format", "", "csv or ndjson, default from the file extension")
+	delimiter := fs.String("delimiter", "", "column separator of a CSV file")
+	decimal := fs.String("decimal", "", "decimal separator of a CSV file")
+	if err = fs.Parse(args); err != nil {
+		return
+	}
+	if fs.NArg() != 1 {
+		return errors.New("usage: vehicles import [--wait] FILE")
+	}
+	path := fs.Arg(0)
+	if *format == "" {
+		switch strings.ToLower(filepath.Ext(path)) {
+		case ".csv":
+			*format = "csv"
+		case ".ndjson", ".jsonl":
+			*format = "ndjson"
+		default:
+			return fmt.Errorf("%s: unknown format, use --format", path)
+		}
+	}
+	options := url.Values{}
+	if *delimiter != "" {
+		options.Set("delimiter", *delimiter)
+	}
+	if *decimal != "" {
+		options.Set("decimal", *decimal)
+	}
+
+	fd, err := os.Open(path)
+	if err != nil {
+		return
+	}
+	defer fd.Close()
+	j, err := c.c.ImportVehicles(ctx, fd, *format, options)
+	if err != nil {
+		return
+	}
+	if *wait {
+		if j, err = c.c.WaitJob(ctx, j.ID, 500*time.Millisecond); err != nil {
+			return
+		}
+	}
+	return c.out.job(j)
+}
+
+// defaultMetrics are the metrics of the stats command when none is given
+var defaultMetrics = []string{"count", "avg:max_speed", "avg:passengers", "avg:weight", "min:year", "max:year"}
+
+// stats is a method that prints aggregates of the vehicles matching the filter flags, computed by the service
+func (c *cli) stats(ctx context.Context, args []string) (err error) {
+	fs := flag.NewFlagSet("vehicles stats", flag.ContinueOnError)
+	f := filterFlags(fs)
+	by := fs.String("by", "", "comma separated fields to group by, e.g. brand,fuel_type")
+	metrics := fs.String("metrics", strings.Join(defaultMetrics, ","), "comma separated metrics as func:field or count, func is sum, avg, min, max, median or p1 to p99")
+	if err = fs.Parse(args); err != nil {
+		return
+	}
+	groupBy := splitList(*by)
+	names := splitList(*metrics)
+
+	stats, err := c.c.Stats(ctx, *f, groupBy, names)
+	if err != nil {
+		return
+	}
+
+	if c.out.format == "json" {
+		return c.out.json(stats)
+	}
+	header := make([]string, 0, len(groupBy)+len(names))
+	for _, field := range append(groupBy, names...) {
+		header = append(header, strings.ToUpper(field))
+	}
+	rows := make([][]string, 0, len(stats))
+	for _, s := range stats {
+		row := make([]string, 0, len(header))
+		for _, field := range groupBy {
+			row = append(row, s.Group[field])
+		}
+		for _, name := range names {
+			value := "-"
+			if v := s.Metrics[name]; v != nil {
+				value = formatFloat(round2(*v))
+			}
+			row = append(row, value)
+		}
+		rows = append(rows, row)
+	}
+	return c.out.table(header, rows)
+}
+
+// splitList is a function that splits a comma separated flag, dropping blank items
+func splitList(s string) (items []string) {
+	for _, item := range strings.Split(s, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return
+}
+
+// round2 is a function that rounds a number to two decimals
+func round2(f float64) float64 {
+	return math.Round(f*100) / 100
+}
+
+// sortKeys is the comparison of each field the list command can sort by
+var sortKeys = map[string]func(a, b client.Vehicle) int{
+	"id": func(a, b client.Vehicle) int { return cmp.Compare(a.ID, b.ID) },
+	"brand": func(a, b client.Vehicle) int {
+		return strings.Compare(strings.ToLower(a.Brand), strings.ToLower(b.Brand))
+	},
+	"model": func(a, b client.Vehicle) int {
+		return strings.Compare(strings.ToLower(a.Model), strings.ToLower(b.Model))
+	},
+	"color": func(a, b client.Vehicle) int {
+		return strings.Compare(strings.ToLower(a.Color), strings.ToLower(b.Color))
+	},
+	"year":         func(a, b client.Vehicle) int { return cmp.Compare(a.FabricationYear, b.FabricationYear) },
+	"passengers":   func(a, b client.Vehicle) int { return cmp.Compare(a.Capacity, b.Capacity) },
+	"max_speed":    func(a, b client.Vehicle) int { return cmp.Compare(a.MaxSpeed, b.MaxSpeed) },
+	"weight":       func(a, b client.Vehicle) int { return cmp.Compare(a.Weight, b.Weight) },
+	"fuel_type":    func(a, b client.Vehicle) int { return strings.Compare(a.FuelType, b.FuelType) },
+	"transmission": func(a, b client.Vehicle) int { return strings.Compare(a.Transmission, b.Transmission) },
+}
+
+// vehicleOrder is a function that parses a sort specification such as "-year,brand"
+func vehicleOrder(spec string) (less func(a, b client.Vehicle) bool, err error) {
+	type key struct {
+		compare func(a, b client.Vehicle) int
+		desc    bool
+	}
+	var keys []key
+	for _, field := range strings.Split(spec, ",") {
+		field = strings.TrimSpace(field)
+		desc := strings.HasPrefix(field, "-")
+		field = strings.TrimPrefix(field, "-")
+		compare, ok := sortKeys[strings.ReplaceAll(field, "-", "_")]
+		if !ok {
+			return nil, fmt.Errorf("invalid sort field %q", field)
+		}
+		keys = append(keys, key{compare: compare, desc: desc})
+	}
+	return func(a, b client.Vehicle) bool {
+		for _, k := range keys {
+			c := k.compare(a, b)
+			if k.desc {
+				c = -c
+			}
+			if c != 0 {
+				return c < 0
+			}
+		}
+		return false
+	}, nil
+}
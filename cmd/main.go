@@ -1,20 +1,224 @@
 package main
 
 import (
+	"app/internal"
 	"app/internal/application"
+	"app/internal/loader"
+	"flag"
 	"fmt"
+	"os"
+	"strings"
+	"time"
+	"unicode/utf8"
 )
 
 func main() {
+	// subcommand
+	// - serve is the default when no subcommand is given
+	cmd, args := "serve", os.Args[1:]
+	if len(args) > 0 && !strings.HasPrefix(args[0], "-") {
+		cmd, args = args[0], args[1:]
+	}
 
-	cfg := &application.ConfigServerChi{
-		ServerAddress:  ":8080",
-		LoaderFilePath: "docs/db/vehicles_100.json",
+	var err error
+	switch cmd {
+	case "serve":
+		err = serve(args)
+	case "rebuild-projection":
+		err = rebuildProjection(args)
+	case "backup":
+		err = backup(args)
+	case "backups":
+		err = backups(args)
+	case "restore":
+		err = restore(args)
+	default:
+		err = fmt.Errorf("unknown command %q", cmd)
+	}
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+}
+
+// config is a function that parses the flags shared by the subcommands
+func config(name string, args []string) (cfg *application.ConfigServerChi, err error) {
+	return configWith(name, args, nil)
+}
+
+// configWith is a function that parses the shared flags along with the ones added by extra
+func configWith(name string, args []string, extra func(fs *flag.FlagSet)) (cfg *application.ConfigServerChi, err error) {
+	cfg = &application.ConfigServerChi{}
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	if extra != nil {
+		extra(fs)
+	}
+	fs.StringVar(&cfg.ServerAddress, "addr", ":8080", "address where the server will be listening")
+	fs.StringVar(&cfg.LoaderFilePath, "data", "docs/db/vehicles_100.json", "path to the file that contains the vehicles")
+	fs.StringVar(&cfg.EventLogPath, "events", "", "path to the event log, enables event sourcing mode")
+	fs.StringVar(&cfg.SnapshotPath, "snapshot", "", "path to the projection snapshot")
+	fs.IntVar(&cfg.SnapshotEvery, "snapshot-every", 100, "number of events between snapshots")
+	fs.StringVar(&cfg.BackupDir, "backup-dir", "backups", "directory of the backups of the fleet")
+	fs.StringVar(&cfg.MergeLogPath, "merge-log", "", "path to the audit log of the merges of duplicate vehicles, empty keeps it in memory and it is lost on restart")
+	fs.StringVar(&cfg.WebhooksPath, "webhooks", "", "path to the file of the webhook subscriptions, empty keeps them in memory and they are lost on restart")
+	fs.StringVar(&cfg.NormalizationPath, "normalization", "", "path to the brand and color normalization dictionaries, empty keeps them in memory and the edits are lost on restart")
+	fs.DurationVar(&cfg.ReloadInterval, "reload", 0, "interval between checks of the vehicles file for hot reload, 0 disables it")
+	fs.BoolVar(&cfg.LoaderStrict, "strict", false, "reject a JSON vehicles file with unknown fields, duplicate ids or missing fields")
+	fs.StringVar(&cfg.QualityReportPath, "quality-report", "", "path where the data quality report of the vehicles file is written")
+	registrationFormats := fs.String("registration-formats", "", "comma separated plate formats accepted for the registrations written through the API, e.g. br or br-old,br-mercosul, empty accepts any")
+	similarity := fs.String("similarity-weights", "", "weights of the fields in the similarity of vehicles overriding the defaults, e.g. max_speed:2,brand:0")
+	v1Sunset := fs.String("v1-sunset", "", "date the v1 vehicle routes stop working, e.g. 2027-04-19, announced in their Sunset header")
+	csvDelimiter := fs.String("csv-delimiter", ",", "column separator of a CSV vehicles file")
+	csvDecimal := fs.String("csv-decimal", ".", "decimal separator of a CSV vehicles file")
+	csvHeader := fs.String("csv-header", "", "column to field mapping of a CSV vehicles file, e.g. capacity=passengers,ano=year")
+	if err = fs.Parse(args); err != nil {
+		return
+	}
+
+	// v1 sunset
+	if *v1Sunset != "" {
+		if cfg.V1Sunset, err = time.Parse(time.DateOnly, *v1Sunset); err != nil {
+			return nil, fmt.Errorf("-v1-sunset: expected a date as 2006-01-02, got %q", *v1Sunset)
+		}
+	}
+
+	// similarity weights
+	if cfg.SimilarityWeights, err = internal.ParseVehicleSimilarityWeights(*similarity); err != nil {
+		return nil, fmt.Errorf("-similarity-weights: %w", err)
+	}
+
+	// registration formats
+	if *registrationFormats != "" {
+		cfg.RegistrationFormats = strings.Split(*registrationFormats, ",")
+	}
+
+	// csv format
+	cfg.LoaderCSV = &loader.ConfigVehicleCSV{Header: make(map[string]string)}
+	if cfg.LoaderCSV.Delimiter, err = flagRune("csv-delimiter", *csvDelimiter); err != nil {
+		return
+	}
+	if cfg.LoaderCSV.DecimalSeparator, err = flagRune("csv-decimal", *csvDecimal); err != nil {
+		return
+	}
+	if *csvHeader != "" {
+		for _, pair := range strings.Split(*csvHeader, ",") {
+			col, field, ok := strings.Cut(pair, "=")
+			if !ok {
+				return nil, fmt.Errorf("-csv-header: invalid mapping %q", pair)
+			}
+			cfg.LoaderCSV.Header[strings.TrimSpace(col)] = strings.TrimSpace(field)
+		}
+	}
+	return
+}
+
+// flagRune is a function that parses a single character flag, "tab" stands for a tab
+func flagRune(name, value string) (r rune, err error) {
+	if value == "tab" {
+		return '\t', nil
+	}
+	if utf8.RuneCountInString(value) != 1 {
+		return 0, fmt.Errorf("-%s: expected a single character, got %q", name, value)
+	}
+	r, _ = utf8.DecodeRuneInString(value)
+	return
+}
+
+// serve is a function that runs the http server
+func serve(args []string) (err error) {
+	cfg, err := config("serve", args)
+	if err != nil {
+		return
 	}
 	app := application.NewServerChi(cfg)
 
-	if err := app.Run(); err != nil {
-		fmt.Println(err)
+	err = app.Run()
+	return
+}
+
+// rebuildProjection is a function that replays the whole event log and writes a fresh snapshot
+func rebuildProjection(args []string) (err error) {
+	cfg, err := config("rebuild-projection", args)
+	if err != nil {
+		return
+	}
+	if cfg.EventLogPath == "" {
+		return fmt.Errorf("rebuild-projection: -events is required")
+	}
+	app := application.NewServerChi(cfg)
+
+	rp := app.EventSourced()
+	n, err := rp.Rebuild()
+	if err != nil {
+		return
+	}
+	db, err := rp.FindAll()
+	if err != nil {
+		return
+	}
+	fmt.Printf("replayed %d events up to sequence %d, %d vehicles in projection\n", n, rp.Sequence(), len(db))
+	return
+}
+
+// backup is a function that takes a backup of the fleet without running the server
+func backup(args []string) (err error) {
+	cfg, err := config("backup", args)
+	if err != nil {
+		return
+	}
+	app := application.NewServerChi(cfg)
+
+	b, err := app.Backup()
+	if err != nil {
+		return
+	}
+	fmt.Printf("backup %s: %d vehicles, %d bytes, sha256 %s\n", b.Id, b.Vehicles, b.Size, b.Checksum)
+	return
+}
+
+// backups is a function that lists the backups of the fleet
+func backups(args []string) (err error) {
+	cfg, err := config("backups", args)
+	if err != nil {
+		return
+	}
+	app := application.NewServerChi(cfg)
+
+	list, err := app.Backups()
+	if err != nil {
+		return
+	}
+	for _, b := range list {
+		fmt.Printf("%s\t%d vehicles\t%d bytes\tsha256 %s\n", b.Id, b.Vehicles, b.Size, b.Checksum)
+	}
+	return
+}
+
+// restore is a function that replaces the fleet with a backup without running the server
+func restore(args []string) (err error) {
+	var id string
+	cfg, err := configWith("restore", args, func(fs *flag.FlagSet) {
+		fs.StringVar(&id, "id", "", "id of the backup to restore, the latest when empty")
+	})
+	if err != nil {
+		return
+	}
+	app := application.NewServerChi(cfg)
+
+	if id == "" {
+		list, err := app.Backups()
+		if err != nil {
+			return err
+		}
+		if len(list) == 0 {
+			return fmt.Errorf("restore: no backup in %s", cfg.BackupDir)
+		}
+		id = list[len(list)-1].Id
+	}
+	b, ops, err := app.Restore(id)
+	if err != nil {
 		return
 	}
+	fmt.Printf("restored backup %s: %d vehicles, %d changes\n", b.Id, b.Vehicles, len(ops))
+	return
 }
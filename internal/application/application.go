@@ -1,10 +1,32 @@
 package application
 
 import (
+	"app/internal"
+	"app/internal/backup"
+	"app/internal/dedup"
+	"app/internal/eventstore"
+	"app/internal/export"
+	"app/internal/feed"
 	"app/internal/handler"
+	"app/internal/job"
 	"app/internal/loader"
+	"app/internal/normalization"
+	"app/internal/registration"
+	"app/internal/search"
+	"app/internal/similarity"
 	"app/internal/vehicle"
+	"app/internal/vin"
+	"app/internal/webhook"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
 	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
 
 	"github.com/go-chi/chi/v5"
 	"github.com/go-chi/chi/v5/middleware"
@@ -14,15 +36,57 @@ import (
 type ConfigServerChi struct {
 	// ServerAddress is the address where the server will be listening
 	ServerAddress string
-	// LoaderFilePath is the path to the file that contains the vehicles
+	// LoaderFilePath is the path to the file that contains the vehicles, a .csv extension selects the CSV loader
 	LoaderFilePath string
+	// LoaderCSV is the format of the vehicles file when it is a CSV file
+	LoaderCSV *loader.ConfigVehicleCSV
+	// LoaderStrict rejects a JSON vehicles file with unknown fields, duplicate ids or missing fields
+	LoaderStrict bool
+	// QualityReportPath is the path where the data quality report of each load is written, optional
+	QualityReportPath string
+	// EventLogPath is the path to the vehicle event log, it enables event sourcing mode
+	EventLogPath string
+	// SnapshotPath is the path to the projection snapshot used in event sourcing mode
+	SnapshotPath string
+	// SnapshotEvery is the number of events between snapshots in event sourcing mode
+	SnapshotEvery int
+	// FeedBufferSize is the number of vehicle changes kept for Last-Event-ID resume
+	FeedBufferSize int
+	// ReloadInterval is the time between checks of the vehicles file for changes, 0 disables hot reload.
+	// Hot reload makes the file the source of truth, so it can't be combined with event sourcing mode.
+	ReloadInterval time.Duration
+	// BackupDir is the directory of the backups of the fleet
+	BackupDir string
+	// NormalizationPath is the path to the brand and color normalization dictionaries,
+	// empty keeps them in memory with their default entries
+	NormalizationPath string
+	// MergeLogPath is the path to the audit log of the merges of duplicate vehicles, empty keeps it in memory
+	MergeLogPath string
+	// WebhooksPath is the path to the file of the webhook subscriptions, empty keeps them in memory
+	// so they are lost on restart
+	WebhooksPath string
+	// SimilarityWeights override the default weights of the fields in the similarity of vehicles
+	SimilarityWeights internal.VehicleSimilarityWeights
+	// RegistrationFormats are the plate formats accepted for the registrations of the vehicles written
+	// through the API, e.g. br or br-mercosul, empty accepts any registration
+	RegistrationFormats []string
+	// V1Sunset is the date the v1 vehicle routes stop working, announced in their Sunset header,
+	// default six months after their deprecation
+	V1Sunset time.Time
 }
 
+// v1Deprecation is the date the v1 vehicle routes were deprecated in favor of /v2
+var v1Deprecation = time.Date(2026, time.October, 19, 0, 0, 0, 0, time.UTC)
+
 // NewServerChi is a function that returns a new instance of ServerChi
 func NewServerChi(cfg *ConfigServerChi) *ServerChi {
 	// default values
 	defaultConfig := &ConfigServerChi{
-		ServerAddress: ":8080",
+		ServerAddress:  ":8080",
+		SnapshotEvery:  100,
+		FeedBufferSize: 1024,
+		BackupDir:      "backups",
+		V1Sunset:       v1Deprecation.AddDate(0, 6, 0),
 	}
 	if cfg != nil {
 		if cfg.ServerAddress != "" {
@@ -31,11 +95,49 @@ func NewServerChi(cfg *ConfigServerChi) *ServerChi {
 		if cfg.LoaderFilePath != "" {
 			defaultConfig.LoaderFilePath = cfg.LoaderFilePath
 		}
+		defaultConfig.LoaderCSV = cfg.LoaderCSV
+		defaultConfig.LoaderStrict = cfg.LoaderStrict
+		defaultConfig.QualityReportPath = cfg.QualityReportPath
+		defaultConfig.EventLogPath = cfg.EventLogPath
+		defaultConfig.SnapshotPath = cfg.SnapshotPath
+		if cfg.SnapshotEvery > 0 {
+			defaultConfig.SnapshotEvery = cfg.SnapshotEvery
+		}
+		if cfg.FeedBufferSize > 0 {
+			defaultConfig.FeedBufferSize = cfg.FeedBufferSize
+		}
+		defaultConfig.ReloadInterval = cfg.ReloadInterval
+		if cfg.BackupDir != "" {
+			defaultConfig.BackupDir = cfg.BackupDir
+		}
+		defaultConfig.NormalizationPath = cfg.NormalizationPath
+		defaultConfig.SimilarityWeights = cfg.SimilarityWeights
+		defaultConfig.MergeLogPath = cfg.MergeLogPath
+		defaultConfig.WebhooksPath = cfg.WebhooksPath
+		defaultConfig.RegistrationFormats = cfg.RegistrationFormats
+		if !cfg.V1Sunset.IsZero() {
+			defaultConfig.V1Sunset = cfg.V1Sunset
+		}
 	}
 
 	return &ServerChi{
 		serverAddress:  defaultConfig.ServerAddress,
 		loaderFilePath: defaultConfig.LoaderFilePath,
+		loaderCSV:      defaultConfig.LoaderCSV,
+		loaderStrict:   defaultConfig.LoaderStrict,
+		qualityReport:  defaultConfig.QualityReportPath,
+		eventLogPath:   defaultConfig.EventLogPath,
+		snapshotPath:   defaultConfig.SnapshotPath,
+		snapshotEvery:  defaultConfig.SnapshotEvery,
+		feedBufferSize: defaultConfig.FeedBufferSize,
+		reloadInterval: defaultConfig.ReloadInterval,
+		backupDir:      defaultConfig.BackupDir,
+		normalization:  defaultConfig.NormalizationPath,
+		mergeLogPath:   defaultConfig.MergeLogPath,
+		webhooksPath:   defaultConfig.WebhooksPath,
+		registration:   defaultConfig.RegistrationFormats,
+		similarity:     internal.DefaultVehicleSimilarityWeights().With(defaultConfig.SimilarityWeights),
+		v1Sunset:       defaultConfig.V1Sunset,
 	}
 }
 
@@ -45,50 +147,465 @@ type ServerChi struct {
 	serverAddress string
 	// loaderFilePath is the path to the file that contains the vehicles
 	loaderFilePath string
+	// loaderCSV is the format of the vehicles file when it is a CSV file
+	loaderCSV *loader.ConfigVehicleCSV
+	// loaderStrict rejects a JSON vehicles file with data quality issues
+	loaderStrict bool
+	// qualityReport is the path where the data quality report is written, empty when it is not
+	qualityReport string
+	// eventLogPath is the path to the vehicle event log, empty when event sourcing is off
+	eventLogPath string
+	// snapshotPath is the path to the projection snapshot
+	snapshotPath string
+	// snapshotEvery is the number of events between snapshots
+	snapshotEvery int
+	// feedBufferSize is the number of vehicle changes kept for Last-Event-ID resume
+	feedBufferSize int
+	// reloadInterval is the time between checks of the vehicles file, 0 when hot reload is off
+	reloadInterval time.Duration
+	// backupDir is the directory of the backups of the fleet
+	backupDir string
+	// normalization is the path to the normalization dictionaries, empty when they are kept in memory
+	normalization string
+	// mergeLogPath is the path to the audit log of the merges, empty when it is kept in memory
+	mergeLogPath string
+	// webhooksPath is the path to the file of the webhook subscriptions, empty when they are kept in memory
+	webhooksPath string
+	// similarity are the weights of the fields in the similarity of vehicles
+	similarity internal.VehicleSimilarityWeights
+	// registration are the accepted plate formats of the registrations
+	registration []string
+	// v1Sunset is the date the v1 vehicle routes stop working
+	v1Sunset time.Time
 }
 
 // Run is a method that runs the application
 func (a *ServerChi) Run() (err error) {
+	done := make(chan struct{})
+	defer close(done)
+	h, err := a.Router(done)
+	if err != nil {
+		return
+	}
+
+	// run server
+	err = http.ListenAndServe(a.serverAddress, h)
+	return
+}
+
+// Router is a method that builds the dependencies of the application and returns its router,
+// the background workers stop when done is closed
+func (a *ServerChi) Router(done <-chan struct{}) (h http.Handler, err error) {
+	if a.reloadInterval > 0 && (a.eventLogPath != "" || a.loaderFilePath == "") {
+		return nil, errors.New("application: hot reload needs a vehicles file and no event log")
+	}
+	if err = a.similarity.Validate(); err != nil {
+		return nil, fmt.Errorf("application: similarity weights: %w", err)
+	}
+	vl, err := registration.NewVehicleRegistrationValidator(a.registration...)
+	if err != nil {
+		return nil, fmt.Errorf("application: %w", err)
+	}
+
 	// dependencies
-	// - loader
-	ld := loader.NewVehicleJSONFile(a.loaderFilePath)
-	db, err := ld.Load()
+	// - normalization dictionaries
+	nm, err := normalization.NewVehicleDictionary(a.normalization)
 	if err != nil {
 		return
 	}
 	// - repository
-	rp := vehicle.NewVehicleMap(db)
-	// - service
-	sv := vehicle.NewVehicleDefault(rp)
+	rp, err := a.repository(nm)
+	if err != nil {
+		return
+	}
+	// - feed
+	fd := feed.NewVehicleBroker(a.feedBufferSize, 0)
+	// - webhooks
+	whRp, err := webhook.NewWebhookMap(&webhook.ConfigWebhookMap{Path: a.webhooksPath})
+	if err != nil {
+		return
+	}
+	whSv := webhook.NewWebhookDefault(whRp, nil)
+	whSv.Start(done)
+	// - search index, seeded with the fleet and kept up to date with the changes published by the service
+	fleet, err := rp.FindAll()
+	if err != nil {
+		return
+	}
+	ix := search.NewVehicleIndex(fleet)
+	// - nearest neighbor index, rebuilt on demand after the changes published by the service
+	kd := similarity.NewVehicleKDTree(fleet)
+	// - VIN decoder, from the embedded table of manufacturers
+	dc, err := vin.NewVehicleVINDecoder()
+	if err != nil {
+		return
+	}
+	// - service, normalizing the brand and color and validating the registration and the VIN of the vehicles it writes
+	// - the VIN is checked against the normalized brand
+	sv := vehicle.NewVehicleRegistrationValidated(vehicle.NewVehicleNormalized(
+		vehicle.NewVehicleVINChecked(vehicle.NewVehicleDefault(rp, feed.VehiclePublishers{ix, kd, fd, whSv}), dc), nm), vl)
+	// - audit log of the merges of duplicate vehicles
+	mergeLog, err := dedup.NewVehicleMergeLog(a.mergeLogPath)
+	if err != nil {
+		return
+	}
+	// - hot reload
+	if a.reloadInterval > 0 {
+		go loader.NewFileWatcher(a.loaderFilePath, a.reloadInterval).Watch(done, func() { a.reload(sv) })
+	}
 	// - handler
 	hd := handler.NewVehicleDefault(sv)
+	hdV2 := handler.NewVehicleV2(sv)
+	hdWebhook := handler.NewWebhookDefault(whSv)
+	im := vehicle.NewVehicleImporter(sv, job.NewJobMap(), 0)
+	hdImport := handler.NewVehicleImportDefault(im)
+	hdFeed := handler.NewVehicleFeedDefault(fd)
+	hdSubscription := handler.NewVehicleSubscriptionDefault(sv, fd)
+	hdBackup := handler.NewBackupDefault(a.backupService(sv))
+	hdSearch := handler.NewVehicleSearchDefault(vehicle.NewVehicleSearchDefault(sv, ix))
+	hdSimilarity := handler.NewVehicleSimilarityDefault(vehicle.NewVehicleSimilarityDefault(sv, kd, a.similarity))
+	hdDuplicate := handler.NewVehicleDuplicateDefault(dedup.NewVehicleDuplicateDefault(sv, mergeLog))
+	hdVIN := handler.NewVINDefault(dc)
+	hdNormalization := handler.NewNormalizationDefault(vehicle.NewVehicleNormalizationDefault(sv, nm))
 	// router
 	rt := chi.NewRouter()
 	// - middlewares
 	rt.Use(middleware.Logger)
 	rt.Use(middleware.Recoverer)
 	// - endpoints
-	rt.Route("/vehicles", func(rt chi.Router) {
-		rt.Get("/", hd.GetAll())
-		rt.Post("/", hd.PostCreate())
-		rt.Get("/color/{color}/year/{year}", hd.GetByColorAndYear())
-		rt.Delete("/{id}", hd.DeleteById())
-		rt.Put("/{id}/update_speed", hd.PutUpdateSpeed())
-		rt.Put("/{id}/update_fuel", hd.UpdateFuelType())
-		rt.Get("/fuel_type/{type}", hd.GetByFuelType())
-		rt.Get("/transmission/{type}", hd.GetByTransmissionType())
-		rt.Post("/batch", hd.PostCreateBatch())
-		rt.Get("/brand/{brand}/between/{start_year}/{end_year}", hd.GetByBrandAndBetweenYear())
-		rt.Get("/id/{id}", hd.GetById())
-		rt.Get("/avarage_speed/brand/{brand}", hd.GetByBrandAverageSpeed())
-		rt.Get("/avarage_capacity/brand/{brand}", hd.GetByBrandAverageCapacity())
-		rt.Get("/dimensions", hd.GetByDimensions())
-		rt.Get("/weight", hd.GetByWeightRange())
-		rt.Get("/color/{color}", hd.GetByColor())
+	// - v1, deprecated, mounted both unversioned and under /v1
+	vehiclesV1 := func(rt chi.Router) {
+		rt.Use(handler.Deprecated(v1Deprecation, a.v1Sunset, "/v2/vehicles"))
+
+		// - endpoints with their own media types
+		rt.Get("/export", hd.GetExport())
+		rt.Get("/reports/composition.html", hd.GetCompositionHTML())
+		rt.Get("/events", hdFeed.GetEvents())
+		rt.Get("/subscriptions", hdSubscription.GetSubscribe())
+		rt.Post("/import", hdImport.PostImport())
 
+		// - endpoints negotiating JSON, XML, YAML or MessagePack
+		rt.Group(func(rt chi.Router) {
+			rt.Use(handler.Negotiate)
+			rt.Get("/", hd.GetAll())
+			rt.Post("/", hd.PostCreate())
+			rt.Get("/stats", hd.GetStats())
+			rt.Get("/distributions/{field}", hd.GetDistribution())
+			rt.Get("/reports/composition", hd.GetComposition())
+			rt.Get("/search", hdSearch.GetSearch())
+			rt.Get("/duplicates", hdDuplicate.GetDuplicates())
+			rt.Post("/duplicates/merge", hdDuplicate.PostMerge())
+			rt.Get("/duplicates/merges", hdDuplicate.GetMerges())
+			rt.Get("/color/{color}/year/{year}", hd.GetByColorAndYear())
+			rt.Delete("/{id}", hd.DeleteById())
+			rt.Put("/{id}/update_speed", hd.PutUpdateSpeed())
+			rt.Put("/{id}/update_fuel", hd.UpdateFuelType())
+			rt.Get("/fuel_type/{type}", hd.GetByFuelType())
+			rt.Get("/transmission/{type}", hd.GetByTransmissionType())
+			rt.Post("/batch", hd.PostCreateBatch())
+			rt.Get("/brand/{brand}/between/{start_year}/{end_year}", hd.GetByBrandAndBetweenYear())
+			rt.Get("/id/{id}", hd.GetById())
+			rt.Get("/registration/{plate}", hd.GetByRegistration())
+			rt.Get("/vin/{vin}", hd.GetByVIN())
+			rt.Get("/{id}/similar", hdSimilarity.GetSimilar())
+			rt.Get("/avarage_speed/brand/{brand}", hd.GetByBrandAverageSpeed())
+			rt.Get("/avarage_capacity/brand/{brand}", hd.GetByBrandAverageCapacity())
+			rt.Get("/dimensions", hd.GetByDimensions())
+			rt.Get("/weight", hd.GetByWeightRange())
+			rt.Get("/color/{color}", hd.GetByColor())
+		})
+	}
+	rt.Route("/vehicles", vehiclesV1)
+	rt.Route("/v1/vehicles", vehiclesV1)
+	// - v2
+	rt.Route("/v2", func(rt chi.Router) {
+		rt.Route("/vehicles", func(rt chi.Router) {
+			// - endpoints with their own media types
+			rt.Get("/export", hd.GetExport())
+			rt.Get("/reports/composition.html", hd.GetCompositionHTML())
+			rt.Get("/events", hdFeed.GetEvents())
+			rt.Get("/subscriptions", hdSubscription.GetSubscribe())
+			rt.Post("/import", hdImport.PostImport())
+
+			// - endpoints negotiating JSON, XML, YAML or MessagePack
+			rt.Group(func(rt chi.Router) {
+				rt.Use(handler.Negotiate)
+				rt.Get("/", hdV2.GetAll())
+				rt.Post("/", hdV2.PostCreate())
+				rt.Post("/batch", hd.PostCreateBatch())
+				rt.Get("/stats", hd.GetStats())
+				rt.Get("/distributions/{field}", hd.GetDistribution())
+				rt.Get("/reports/composition", hd.GetComposition())
+				rt.Get("/search", hdSearch.GetSearch())
+				rt.Get("/duplicates", hdDuplicate.GetDuplicates())
+				rt.Post("/duplicates/merge", hdDuplicate.PostMerge())
+				rt.Get("/duplicates/merges", hdDuplicate.GetMerges())
+				rt.Get("/registration/{plate}", hd.GetByRegistration())
+				rt.Get("/vin/{vin}", hd.GetByVIN())
+				rt.Get("/{id}", hdV2.GetById())
+				rt.Get("/{id}/similar", hdSimilarity.GetSimilar())
+				rt.Put("/{id}", hdV2.PutReplace())
+				rt.Patch("/{id}", hdV2.PatchUpdate())
+				rt.Delete("/{id}", hdV2.DeleteById())
+			})
+		})
+		rt.With(handler.Negotiate).Get("/brands/{brand}/averages", hdV2.GetBrandAverages())
+		rt.With(handler.Negotiate).Get("/vins/{vin}", hdVIN.GetDecode())
+	})
+	rt.With(handler.Negotiate).Get("/jobs/{id}", hdImport.GetJob())
+	rt.Route("/webhooks", func(rt chi.Router) {
+		rt.Use(handler.Negotiate)
+		rt.Get("/", hdWebhook.GetAll())
+		rt.Post("/", hdWebhook.PostCreate())
+		rt.Get("/dead_letters", hdWebhook.GetDeadLetters())
+		rt.Post("/deliveries/{id}/redeliver", hdWebhook.PostRedeliver())
+		rt.Get("/{id}", hdWebhook.GetById())
+		rt.Delete("/{id}", hdWebhook.DeleteById())
+		rt.Get("/{id}/deliveries", hdWebhook.GetDeliveries())
 	})
+	rt.Route("/admin", func(rt chi.Router) {
+		rt.Use(handler.Negotiate)
+		rt.Get("/snapshots", hdBackup.GetAll())
+		rt.Post("/snapshots", hdBackup.PostCreate())
+		rt.Post("/snapshots/{id}/restore", hdBackup.PostRestore())
+		rt.Get("/normalization", hdNormalization.GetAll())
+		rt.Post("/normalization/apply", hdNormalization.PostApply())
+		rt.Get("/normalization/{field}", hdNormalization.GetByField())
+		rt.Put("/normalization/{field}/{canonical}", hdNormalization.PutEntry())
+		rt.Delete("/normalization/{field}/{canonical}", hdNormalization.DeleteEntry())
+	})
+	return rt, nil
+}
 
-	// run server
-	err = http.ListenAndServe(a.serverAddress, rt)
+// repository is a method that builds the vehicle repository for the configured mode
+// - the vehicles of the loader file are normalized with nm
+func (a *ServerChi) repository(nm internal.VehicleNormalizer) (rp internal.VehicleRepository, err error) {
+	if a.eventLogPath == "" {
+		var db map[int]internal.Vehicle
+		db, err = a.load(nm)
+		if err != nil {
+			return
+		}
+		rp = vehicle.NewVehicleMap(db)
+		return
+	}
+
+	// event sourcing mode
+	es := a.EventSourced()
+	if err = es.Replay(); err != nil {
+		return
+	}
+	// - an empty stream is seeded with the vehicles of the loader file, in id order
+	// - as a replace, so the registrations the file shares are kept as they are
+	if es.Sequence() == 0 && a.loaderFilePath != "" {
+		var db map[int]internal.Vehicle
+		db, err = a.load(nm)
+		if err != nil {
+			return
+		}
+		if _, err = es.Replace(db); err != nil {
+			return
+		}
+	}
+	rp = es
+	return
+}
+
+// vehicleLoader is a method that returns the loader matching the extension of the vehicles file
+func (a *ServerChi) vehicleLoader() internal.VehicleLoader {
+	switch strings.ToLower(filepath.Ext(a.loaderFilePath)) {
+	case ".csv":
+		return loader.NewVehicleCSVFile(a.loaderFilePath, a.loaderCSV)
+	default:
+		return loader.NewVehicleJSONFile(a.loaderFilePath, &loader.ConfigVehicleJSON{Strict: a.loaderStrict})
+	}
+}
+
+// load is a method that loads the vehicles file and normalizes its vehicles with nm
+// - invalid rows of a CSV file are logged and skipped
+func (a *ServerChi) load(nm internal.VehicleNormalizer) (db map[int]internal.Vehicle, err error) {
+	ld := a.vehicleLoader()
+	db, err = ld.Load()
+	a.reportQuality(ld)
+	var rowErrs loader.RowErrors
+	if errors.As(err, &rowErrs) {
+		for _, re := range rowErrs {
+			log.Printf("loader: %s: skipped %s", a.loaderFilePath, re)
+		}
+		err = nil
+	}
+	if err != nil {
+		return
+	}
+	db = vehicle.NormalizeVehicles(nm, db)
+	return
+}
+
+// reportQuality is a method that logs the summary of the data quality report of a load
+// and writes the whole report when a path is configured
+func (a *ServerChi) reportQuality(ld internal.VehicleLoader) {
+	qr, ok := ld.(loader.QualityReporter)
+	if !ok {
+		return
+	}
+	report := qr.Report()
+	log.Printf("loader: %s: %s", a.loaderFilePath, report.Summary())
+	if a.qualityReport == "" {
+		return
+	}
+
+	data, err := json.MarshalIndent(report, "", "  ")
+	if err == nil {
+		err = os.WriteFile(a.qualityReport, append(data, '\n'), 0o644)
+	}
+	if err != nil {
+		log.Printf("loader: quality report: %s", err)
+	}
+}
+
+// reload is a method that loads the vehicles file again and swaps the fleet of the service.
+// A file that fails to parse or holds an invalid vehicle is rejected as a whole and the
+// current fleet keeps serving requests.
+func (a *ServerChi) reload(sv internal.VehicleService) {
+	start := time.Now()
+	ld := a.vehicleLoader()
+	db, err := ld.Load()
+	a.reportQuality(ld)
+	if err == nil {
+		err = validateFleet(db)
+	}
+	if err != nil {
+		log.Printf("reload: %s: rejected: %s", a.loaderFilePath, err)
+		return
+	}
+
+	ops, err := sv.Replace(db)
+	if err != nil {
+		log.Printf("reload: %s: %s", a.loaderFilePath, err)
+		return
+	}
+	count := make(map[internal.VehicleOperationType]int)
+	for _, op := range ops {
+		count[op.Type]++
+	}
+	log.Printf("reload: %s: %d vehicles, %d created, %d updated, %d deleted in %s", a.loaderFilePath, len(db),
+		count[internal.VehicleOperationCreate], count[internal.VehicleOperationUpdate], count[internal.VehicleOperationDelete],
+		time.Since(start).Round(time.Millisecond))
+}
+
+// validateFleet is a function that checks every vehicle of a reloaded file
+func validateFleet(db map[int]internal.Vehicle) error {
+	if len(db) == 0 {
+		return errors.New("no vehicles")
+	}
+	ids := make([]int, 0, len(db))
+	for id := range db {
+		ids = append(ids, id)
+	}
+	sort.Ints(ids)
+	for _, id := range ids {
+		if err := vehicle.ValidateVehicle(db[id]); err != nil {
+			return fmt.Errorf("vehicle %d: %w", id, err)
+		}
+	}
+	return nil
+}
+
+// EventSourced is a method that returns the event sourced repository for the configured paths
+// without replaying it
+func (a *ServerChi) EventSourced() *vehicle.VehicleEventSourced {
+	var ss internal.VehicleSnapshotStore
+	if a.snapshotPath != "" {
+		ss = eventstore.NewVehicleSnapshotJSONFile(a.snapshotPath)
+	}
+	return vehicle.NewVehicleEventSourced(eventstore.NewVehicleEventJSONFile(a.eventLogPath), ss, a.snapshotEvery)
+}
+
+// Backup is a method that takes a backup of the configured repository without running the server
+func (a *ServerChi) Backup() (b internal.VehicleBackup, err error) {
+	nm, err := normalization.NewVehicleDictionary(a.normalization)
+	if err != nil {
+		return
+	}
+	rp, err := a.repository(nm)
+	if err != nil {
+		return
+	}
+	return a.backupService(vehicle.NewVehicleDefault(rp, nil)).Backup()
+}
+
+// Backups is a method that returns the backups of the configured directory
+func (a *ServerChi) Backups() ([]internal.VehicleBackup, error) {
+	return backup.NewVehicleBackupDir(a.backupDir).List()
+}
+
+// Restore is a method that restores a backup into the configured repository without running the server.
+// In event sourcing mode the changes are recorded as events, otherwise the vehicles file is rewritten.
+func (a *ServerChi) Restore(id string) (b internal.VehicleBackup, ops []internal.VehicleOperation, err error) {
+	nm, err := normalization.NewVehicleDictionary(a.normalization)
+	if err != nil {
+		return
+	}
+	rp, err := a.repository(nm)
+	if err != nil {
+		return
+	}
+	b, ops, err = a.backupService(vehicle.NewVehicleNormalized(vehicle.NewVehicleDefault(rp, nil), nm)).Restore(id)
+	if err != nil || a.eventLogPath != "" {
+		return
+	}
+	db, err := rp.FindAll()
+	if err != nil {
+		return
+	}
+	err = a.writeVehicles(db)
 	return
 }
+
+// backupService is a method that returns the backup service of the fleet of sv
+func (a *ServerChi) backupService(sv internal.VehicleService) internal.VehicleBackupService {
+	return vehicle.NewVehicleBackupDefault(sv, backup.NewVehicleBackupDir(a.backupDir))
+}
+
+// writeVehicles is a method that replaces the vehicles file with db in the format matching its extension
+func (a *ServerChi) writeVehicles(db map[int]internal.Vehicle) (err error) {
+	tmp, err := os.CreateTemp(filepath.Dir(a.loaderFilePath), filepath.Base(a.loaderFilePath)+".tmp-*")
+	if err != nil {
+		return
+	}
+	defer os.Remove(tmp.Name())
+	defer tmp.Close()
+
+	switch strings.ToLower(filepath.Ext(a.loaderFilePath)) {
+	case ".csv":
+		ids := make([]int, 0, len(db))
+		for id := range db {
+			ids = append(ids, id)
+		}
+		sort.Ints(ids)
+		// - in the format of the loader, with the raw brand and color so a restore keeps them
+		cfg := &export.ConfigVehicleCSV{Raw: true}
+		if a.loaderCSV != nil {
+			cfg.Delimiter, cfg.DecimalSeparator = a.loaderCSV.Delimiter, a.loaderCSV.DecimalSeparator
+		}
+		vw := export.NewVehicleCSV(tmp, cfg)
+		for _, id := range ids {
+			if err = vw.Write(db[id]); err != nil {
+				return
+			}
+		}
+		err = vw.Close()
+	default:
+		err = backup.EncodeVehicles(tmp, db)
+	}
+	if err != nil {
+		return
+	}
+	if err = tmp.Chmod(0o644); err != nil {
+		return
+	}
+	if err = tmp.Close(); err != nil {
+		return
+	}
+	return os.Rename(tmp.Name(), a.loaderFilePath)
+}
@@ -0,0 +1,58 @@
+package application
+
+import (
+	"app/internal/loader"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestServerChi_RestoreCSV(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "vehicles.csv")
+	data := "id;brand;model;registration;color;max_speed;height;brand_raw;color_raw\n" +
+		"1;Toyota;Corolla;ABC1234;Red;180,5;1,5;TOYOTA;RED\n" +
+		"2;Ford;Ka;DEF5678;Blue;150;1,4;;\n"
+	if err := os.WriteFile(path, []byte(data), 0o644); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+	csvCfg := &loader.ConfigVehicleCSV{Delimiter: ';', DecimalSeparator: ','}
+	want, err := loader.NewVehicleCSVFile(path, csvCfg).Load()
+	if err != nil {
+		t.Fatalf("load: %v", err)
+	}
+
+	app := NewServerChi(&ConfigServerChi{LoaderFilePath: path, LoaderCSV: csvCfg, BackupDir: filepath.Join(dir, "backups")})
+	b, err := app.Backup()
+	if err != nil {
+		t.Fatalf("backup: %v", err)
+	}
+	if err = os.WriteFile(path, []byte("id;brand;model\n3;Fiat;Uno\n"), 0o644); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+	if _, _, err = app.Restore(b.Id); err != nil {
+		t.Fatalf("restore: %v", err)
+	}
+
+	// the file is rewritten in the format of the loader, raw values included
+	written, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("read: %v", err)
+	}
+	header, _, _ := strings.Cut(string(written), "\n")
+	if !strings.HasPrefix(header, "id;brand;") || !strings.HasSuffix(header, ";brand_raw;color_raw") {
+		t.Errorf("header = %q, want ';' separated columns with the raw values", header)
+	}
+	if !strings.Contains(string(written), "180,5") {
+		t.Errorf("file = %s, want decimals written with ','", written)
+	}
+	got, err := loader.NewVehicleCSVFile(path, csvCfg).Load()
+	if err != nil {
+		t.Fatalf("load restored file: %v", err)
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("restored vehicles = %+v, want %+v", got, want)
+	}
+}
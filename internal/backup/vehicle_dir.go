@@ -0,0 +1,281 @@
+package backup
+
+import (
+	"app/internal"
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// idLayout is the time layout of a backup id
+	idLayout = "20060102T150405.000000Z"
+	// filePrefix and fileSuffix surround the id in the name of a backup file
+	filePrefix, fileSuffix = "vehicles-", ".json.gz"
+	// checksumSuffix is appended to the name of a backup file to name its checksum file
+	checksumSuffix = ".sha256"
+	// fileVersion is the version of the envelope written in the backups, the one read by the JSON loader
+	fileVersion = 1
+)
+
+// idPattern matches the ids of backups, anything else can't name a file of the directory
+var idPattern = regexp.MustCompile(`^[0-9]{8}T[0-9]{6}\.[0-9]{6}Z$`)
+
+// VehicleFileJSON is a struct that represents the versioned envelope of a backup.
+// A decompressed backup is a valid vehicles file for the JSON loader.
+type VehicleFileJSON struct {
+	Version  int                    `json:"version"`
+	Vehicles []internal.VehicleJSON `json:"vehicles"`
+}
+
+// NewVehicleBackupDir is a function that returns a new instance of VehicleBackupDir
+func NewVehicleBackupDir(dir string) *VehicleBackupDir {
+	return &VehicleBackupDir{
+		dir: dir,
+	}
+}
+
+// VehicleBackupDir is a struct that implements the VehicleBackupStore interface with one gzip
+// compressed JSON file per backup, next to a checksum file in the format of sha256sum.
+// - a backup is complete once its checksum file exists, a crash before leaves no visible backup
+type VehicleBackupDir struct {
+	// dir is the directory of the backups
+	dir string
+}
+
+// Save is a method that writes a backup of db
+func (s *VehicleBackupDir) Save(db map[int]internal.Vehicle) (b internal.VehicleBackup, err error) {
+	if err = os.MkdirAll(s.dir, 0o755); err != nil {
+		return
+	}
+	now := time.Now().UTC()
+	b = internal.VehicleBackup{Id: now.Format(idLayout), CreatedAt: now, Vehicles: len(db)}
+	name := filePrefix + b.Id + fileSuffix
+
+	// data file
+	// - written to a temporary file and renamed so a backup is never read half written
+	tmp, err := os.CreateTemp(s.dir, name+".tmp-*")
+	if err != nil {
+		return
+	}
+	defer os.Remove(tmp.Name())
+	defer tmp.Close()
+
+	h := sha256.New()
+	cw := &countingWriter{w: io.MultiWriter(tmp, h)}
+	zw, err := gzip.NewWriterLevel(cw, gzip.BestCompression)
+	if err != nil {
+		return
+	}
+	zw.Header = gzip.Header{Name: "vehicles.json", ModTime: now, Comment: fmt.Sprintf("vehicles=%d", len(db))}
+	if err = encodeVehicles(zw, db); err != nil {
+		return
+	}
+	if err = zw.Close(); err != nil {
+		return
+	}
+	if err = tmp.Sync(); err != nil {
+		return
+	}
+	if err = tmp.Close(); err != nil {
+		return
+	}
+	if err = os.Rename(tmp.Name(), filepath.Join(s.dir, name)); err != nil {
+		return
+	}
+	b.Size = cw.n
+	b.Checksum = hex.EncodeToString(h.Sum(nil))
+
+	// checksum file
+	err = writeFileAtomic(filepath.Join(s.dir, name+checksumSuffix), []byte(b.Checksum+"  "+name+"\n"))
+	return
+}
+
+// Load is a method that verifies the checksum of a backup and decodes its vehicles
+func (s *VehicleBackupDir) Load(id string) (db map[int]internal.Vehicle, b internal.VehicleBackup, err error) {
+	b, err = s.find(id)
+	if err != nil {
+		return
+	}
+	data, err := os.ReadFile(filepath.Join(s.dir, filePrefix+id+fileSuffix))
+	if err != nil {
+		return
+	}
+	sum := sha256.Sum256(data)
+	if hex.EncodeToString(sum[:]) != b.Checksum {
+		return nil, b, fmt.Errorf("%w: %s: checksum mismatch", internal.ErrBackupCorrupt, id)
+	}
+
+	zr, err := gzip.NewReader(bytes.NewReader(data))
+	if err != nil {
+		return nil, b, fmt.Errorf("%w: %s: %s", internal.ErrBackupCorrupt, id, err)
+	}
+	db, err = DecodeVehicles(zr)
+	if err != nil {
+		return nil, b, fmt.Errorf("%w: %s: %s", internal.ErrBackupCorrupt, id, err)
+	}
+	b.Vehicles = len(db)
+	return
+}
+
+// List is a method that returns the complete backups of the directory in creation order
+func (s *VehicleBackupDir) List() (b []internal.VehicleBackup, err error) {
+	names, err := filepath.Glob(filepath.Join(s.dir, filePrefix+"*"+fileSuffix))
+	if err != nil {
+		return
+	}
+	b = make([]internal.VehicleBackup, 0, len(names))
+	for _, name := range names {
+		id := strings.TrimSuffix(strings.TrimPrefix(filepath.Base(name), filePrefix), fileSuffix)
+		bk, err := s.find(id)
+		if errors.Is(err, internal.ErrBackupNotFound) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		b = append(b, bk)
+	}
+	sort.Slice(b, func(i, j int) bool { return b[i].Id < b[j].Id })
+	return
+}
+
+// find is a method that returns the metadata of a complete backup without verifying it
+func (s *VehicleBackupDir) find(id string) (b internal.VehicleBackup, err error) {
+	if !idPattern.MatchString(id) {
+		return b, fmt.Errorf("%w: %s", internal.ErrBackupNotFound, id)
+	}
+	name := filePrefix + id + fileSuffix
+	b = internal.VehicleBackup{Id: id}
+	b.CreatedAt, _ = time.Parse(idLayout, id)
+
+	// checksum
+	data, err := os.ReadFile(filepath.Join(s.dir, name+checksumSuffix))
+	if errors.Is(err, os.ErrNotExist) {
+		return b, fmt.Errorf("%w: %s", internal.ErrBackupNotFound, id)
+	}
+	if err != nil {
+		return
+	}
+	fields := strings.Fields(string(data))
+	if len(fields) != 2 || fields[1] != name {
+		return b, fmt.Errorf("%w: %s: invalid checksum file", internal.ErrBackupCorrupt, id)
+	}
+	b.Checksum = fields[0]
+
+	// data file
+	f, err := os.Open(filepath.Join(s.dir, name))
+	if errors.Is(err, os.ErrNotExist) {
+		return b, fmt.Errorf("%w: %s", internal.ErrBackupNotFound, id)
+	}
+	if err != nil {
+		return
+	}
+	defer f.Close()
+	info, err := f.Stat()
+	if err != nil {
+		return
+	}
+	b.Size = info.Size()
+	// - the number of vehicles is kept in the gzip header to list without decompressing
+	if zr, err := gzip.NewReader(f); err == nil {
+		if n, ok := strings.CutPrefix(zr.Comment, "vehicles="); ok {
+			b.Vehicles, _ = strconv.Atoi(n)
+		}
+	}
+	return b, nil
+}
+
+// EncodeVehicles is a function that writes db in id order as a versioned vehicles file
+func EncodeVehicles(w io.Writer, db map[int]internal.Vehicle) (err error) {
+	bw := bufio.NewWriter(w)
+	if err = encodeVehicles(bw, db); err != nil {
+		return
+	}
+	return bw.Flush()
+}
+
+// encodeVehicles is a function that writes db in id order as a versioned vehicles file
+func encodeVehicles(w io.Writer, db map[int]internal.Vehicle) (err error) {
+	ids := make([]int, 0, len(db))
+	for id := range db {
+		ids = append(ids, id)
+	}
+	sort.Ints(ids)
+
+	file := VehicleFileJSON{Version: fileVersion, Vehicles: make([]internal.VehicleJSON, 0, len(ids))}
+	for _, id := range ids {
+		file.Vehicles = append(file.Vehicles, internal.NewVehicleJSON(db[id]))
+	}
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", " ")
+	return enc.Encode(file)
+}
+
+// DecodeVehicles is a function that reads a versioned vehicles file, rejecting unknown fields and duplicate ids
+func DecodeVehicles(r io.Reader) (db map[int]internal.Vehicle, err error) {
+	dec := json.NewDecoder(r)
+	dec.DisallowUnknownFields()
+	var file VehicleFileJSON
+	if err = dec.Decode(&file); err != nil {
+		return
+	}
+	if file.Version != fileVersion {
+		return nil, fmt.Errorf("unsupported version %d", file.Version)
+	}
+
+	db = make(map[int]internal.Vehicle, len(file.Vehicles))
+	for _, vh := range file.Vehicles {
+		if _, ok := db[vh.Id]; ok {
+			return nil, fmt.Errorf("duplicate id %d", vh.Id)
+		}
+		db[vh.Id] = vh.Vehicle()
+	}
+	return
+}
+
+// writeFileAtomic is a function that replaces the content of a file through a temporary file
+func writeFileAtomic(path string, data []byte) (err error) {
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".tmp-*")
+	if err != nil {
+		return
+	}
+	defer os.Remove(tmp.Name())
+	defer tmp.Close()
+	if _, err = tmp.Write(data); err != nil {
+		return
+	}
+	if err = tmp.Sync(); err != nil {
+		return
+	}
+	if err = tmp.Close(); err != nil {
+		return
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+// countingWriter is a struct that counts the bytes written through it
+type countingWriter struct {
+	w io.Writer
+	n int64
+}
+
+// Write is a method that writes to the underlying writer
+func (c *countingWriter) Write(p []byte) (n int, err error) {
+	n, err = c.w.Write(p)
+	c.n += int64(n)
+	return
+}
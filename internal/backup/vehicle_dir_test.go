@@ -0,0 +1,97 @@
+package backup
+
+import (
+	"app/internal"
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+// fleet is a function that returns a small fleet with raw and unit values
+func fleet() map[int]internal.Vehicle {
+	v1 := internal.Vehicle{Id: 1}
+	v1.Brand, v1.Model, v1.Registration, v1.MaxSpeed, v1.Weight, v1.Height = "Toyota", "Corolla", "ABC1234", 180.5, 1200, 1.5
+	v1.VIN, v1.BrandRaw, v1.ColorRaw = "1HGCM82633A004352", "toyota ", "RED"
+	v2 := internal.Vehicle{Id: 2}
+	v2.Brand, v2.Model = "Ford", "Ka"
+	return map[int]internal.Vehicle{1: v1, 2: v2}
+}
+
+func TestVehicleBackupDir_SaveLoad(t *testing.T) {
+	st := NewVehicleBackupDir(filepath.Join(t.TempDir(), "backups"))
+	b, err := st.Save(fleet())
+	if err != nil {
+		t.Fatalf("save: %v", err)
+	}
+	if b.Vehicles != 2 || b.Size == 0 || len(b.Checksum) != 64 {
+		t.Errorf("backup = %+v, want 2 vehicles with a size and a sha256", b)
+	}
+
+	list, err := st.List()
+	if err != nil || len(list) != 1 || list[0].Id != b.Id || list[0].Checksum != b.Checksum || list[0].Vehicles != 2 {
+		t.Fatalf("list = %+v, %v, want the backup", list, err)
+	}
+	db, loaded, err := st.Load(b.Id)
+	if err != nil {
+		t.Fatalf("load: %v", err)
+	}
+	if !reflect.DeepEqual(db, fleet()) {
+		t.Errorf("loaded fleet = %+v, want %+v", db, fleet())
+	}
+	if loaded.Checksum != b.Checksum {
+		t.Errorf("checksum = %s, want %s", loaded.Checksum, b.Checksum)
+	}
+}
+
+func TestVehicleBackupDir_Checksum(t *testing.T) {
+	dir := t.TempDir()
+	st := NewVehicleBackupDir(dir)
+	b, err := st.Save(fleet())
+	if err != nil {
+		t.Fatalf("save: %v", err)
+	}
+	path := filepath.Join(dir, filePrefix+b.Id+fileSuffix)
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("read: %v", err)
+	}
+
+	// a flipped byte
+	data[len(data)/2] ^= 0xff
+	if err = os.WriteFile(path, data, 0o644); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+	if _, _, err = st.Load(b.Id); !errors.Is(err, internal.ErrBackupCorrupt) {
+		t.Errorf("load of a changed backup: %v, want ErrBackupCorrupt", err)
+	}
+
+	// a checksum file naming another file
+	if err = os.WriteFile(path+checksumSuffix, []byte(b.Checksum+"  other.json.gz\n"), 0o644); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+	if _, _, err = st.Load(b.Id); !errors.Is(err, internal.ErrBackupCorrupt) {
+		t.Errorf("load with an invalid checksum file: %v, want ErrBackupCorrupt", err)
+	}
+
+	// a backup without its checksum file is not complete
+	if err = os.Remove(path + checksumSuffix); err != nil {
+		t.Fatalf("remove: %v", err)
+	}
+	if list, err := st.List(); err != nil || len(list) != 0 {
+		t.Errorf("list = %+v, %v, want no backup", list, err)
+	}
+	if _, _, err = st.Load(b.Id); !errors.Is(err, internal.ErrBackupNotFound) {
+		t.Errorf("load without checksum: %v, want ErrBackupNotFound", err)
+	}
+}
+
+func TestVehicleBackupDir_InvalidId(t *testing.T) {
+	st := NewVehicleBackupDir(t.TempDir())
+	for _, id := range []string{"", "../vehicles", "20240101T000000.000000Z/.."} {
+		if _, _, err := st.Load(id); !errors.Is(err, internal.ErrBackupNotFound) {
+			t.Errorf("load %q: %v, want ErrBackupNotFound", id, err)
+		}
+	}
+}
@@ -0,0 +1,201 @@
+package dedup
+
+import (
+	"app/internal"
+	"app/internal/search"
+	"math"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// DefaultThreshold is the smallest similarity of a near duplicate when a query has none
+const DefaultThreshold = 0.85
+
+// field is a struct that represents an attribute compared by the similarity of two vehicles
+// - similarity returns the similarity of the values of the attribute from 0 to 1, ok false when a value is missing
+type field struct {
+	name       string
+	weight     float64
+	similarity func(a, b internal.Vehicle) (s float64, ok bool)
+}
+
+// fields are the attributes compared by the similarity of two vehicles, the registration is left out
+// since duplicates entered by hand often differ in it
+var fields = []field{
+	{"brand", 2, func(a, b internal.Vehicle) (float64, bool) { return text(a.Brand, b.Brand) }},
+	{"model", 3, func(a, b internal.Vehicle) (float64, bool) { return text(a.Model, b.Model) }},
+	{"year", 1, func(a, b internal.Vehicle) (float64, bool) { return year(a.FabricationYear, b.FabricationYear) }},
+	{"color", 1, func(a, b internal.Vehicle) (float64, bool) { return same(a.Color, b.Color) }},
+	{"fuel_type", 1, func(a, b internal.Vehicle) (float64, bool) { return same(a.FuelType, b.FuelType) }},
+	{"transmission", 1, func(a, b internal.Vehicle) (float64, bool) { return same(a.Transmission, b.Transmission) }},
+	{"passengers", 1, func(a, b internal.Vehicle) (float64, bool) { return number(float64(a.Capacity), float64(b.Capacity)) }},
+	{"max_speed", 1, func(a, b internal.Vehicle) (float64, bool) { return number(a.MaxSpeed, b.MaxSpeed) }},
+	{"weight", 1, func(a, b internal.Vehicle) (float64, bool) { return number(a.Weight, b.Weight) }},
+	{"height", 0.5, func(a, b internal.Vehicle) (float64, bool) { return number(a.Height, b.Height) }},
+	{"length", 0.5, func(a, b internal.Vehicle) (float64, bool) { return number(a.Length, b.Length) }},
+	{"width", 0.5, func(a, b internal.Vehicle) (float64, bool) { return number(a.Width, b.Width) }},
+}
+
+// Detect is a function that returns the registration clashes of the vehicles, in registration order,
+// then the pairs of vehicles with a similarity of at least threshold, from the most similar.
+// Only the vehicles sharing their brand or their model are compared, so the cost grows with the size
+// of the largest brand rather than with the square of the fleet.
+func Detect(vehicles []internal.Vehicle, threshold float64) (duplicates []internal.VehicleDuplicate) {
+	sort.Slice(vehicles, func(i, j int) bool { return vehicles[i].Id < vehicles[j].Id })
+
+	// registration clashes
+	registrations := make(map[string][]int)
+	for _, v := range vehicles {
+		if k := key(v.Registration); k != "" {
+			registrations[k] = append(registrations[k], v.Id)
+		}
+	}
+	keys := make([]string, 0, len(registrations))
+	for k, ids := range registrations {
+		if len(ids) > 1 {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		duplicates = append(duplicates, internal.VehicleDuplicate{
+			Kind: internal.VehicleDuplicateRegistration, Ids: registrations[k], Key: k, Score: 1,
+		})
+	}
+
+	// near duplicates
+	blocks := make(map[string][]int)
+	for i, v := range vehicles {
+		if k := key(v.Brand); k != "" {
+			blocks["brand "+k] = append(blocks["brand "+k], i)
+		}
+		if k := key(v.Model); k != "" {
+			blocks["model "+k] = append(blocks["model "+k], i)
+		}
+	}
+	seen := make(map[[2]int]bool)
+	var near []internal.VehicleDuplicate
+	for _, block := range blocks {
+		for x := 0; x < len(block); x++ {
+			for y := x + 1; y < len(block); y++ {
+				a, b := vehicles[block[x]], vehicles[block[y]]
+				pair := [2]int{a.Id, b.Id}
+				if seen[pair] {
+					continue
+				}
+				seen[pair] = true
+				if score, matched := Similarity(a, b); score >= threshold {
+					near = append(near, internal.VehicleDuplicate{
+						Kind: internal.VehicleDuplicateNear, Ids: pair[:], Score: score, Matched: matched,
+					})
+				}
+			}
+		}
+	}
+	sort.Slice(near, func(i, j int) bool {
+		if near[i].Score != near[j].Score {
+			return near[i].Score > near[j].Score
+		}
+		if near[i].Ids[0] != near[j].Ids[0] {
+			return near[i].Ids[0] < near[j].Ids[0]
+		}
+		return near[i].Ids[1] < near[j].Ids[1]
+	})
+	return append(duplicates, near...)
+}
+
+// Similarity is a function that returns the weighted similarity of two vehicles from 0 to 1
+// and the JSON names of the fields with the same value.
+// A field missing in either vehicle, e.g. a zero length, is left out of the weighted average.
+func Similarity(a, b internal.Vehicle) (score float64, matched []string) {
+	var total float64
+	for _, f := range fields {
+		s, ok := f.similarity(a, b)
+		if !ok {
+			continue
+		}
+		score += f.weight * s
+		total += f.weight
+		if s == 1 {
+			matched = append(matched, f.name)
+		}
+	}
+	if total == 0 {
+		return 0, nil
+	}
+	return score / total, matched
+}
+
+// text is a function that returns the similarity of two texts regardless of case, diacritics and punctuation,
+// one minus their edit distance over the length of the longest
+func text(a, b string) (float64, bool) {
+	ka, kb := []rune(key(a)), []rune(key(b))
+	if len(ka) == 0 || len(kb) == 0 {
+		return 0, false
+	}
+	return 1 - float64(levenshtein(ka, kb))/float64(max(len(ka), len(kb))), true
+}
+
+// same is a function that returns 1 when two categories are the same regardless of case, 0 otherwise
+func same(a, b string) (float64, bool) {
+	ka, kb := key(a), key(b)
+	if ka == "" || kb == "" {
+		return 0, false
+	}
+	if ka == kb {
+		return 1, true
+	}
+	return 0, true
+}
+
+// year is a function that returns the similarity of two fabrication years, half for consecutive years
+func year(a, b int) (float64, bool) {
+	if a == 0 || b == 0 {
+		return 0, false
+	}
+	switch d := a - b; {
+	case d == 0:
+		return 1, true
+	case d == 1 || d == -1:
+		return 0.5, true
+	}
+	return 0, true
+}
+
+// number is a function that returns the similarity of two measures, decreasing from 1 when they are equal
+// to 0 when they differ by a fifth of the largest
+func number(a, b float64) (float64, bool) {
+	if a == 0 || b == 0 {
+		return 0, false
+	}
+	rel := math.Abs(a-b) / math.Max(math.Abs(a), math.Abs(b))
+	return math.Max(0, 1-rel/0.2), true
+}
+
+// levenshtein is a function that returns the number of insertions, deletions and substitutions of runes
+// turning a into b
+func levenshtein(a, b []rune) int {
+	prev, curr := make([]int, len(b)+1), make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		curr[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			curr[j] = min(prev[j]+1, curr[j-1]+1, prev[j-1]+cost)
+		}
+		prev, curr = curr, prev
+	}
+	return prev[len(b)]
+}
+
+// key is a function that returns the form a value is compared in, folded without diacritics and
+// with its words separated by a single space
+func key(value string) string {
+	return strings.Join(strings.FieldsFunc(search.Fold(value), func(r rune) bool { return !unicode.IsLetter(r) && !unicode.IsDigit(r) }), " ")
+}
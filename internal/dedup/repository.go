@@ -0,0 +1,149 @@
+package dedup
+
+import (
+	"app/internal"
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+)
+
+// VehicleMergeJSON is a struct that represents a line of the merge log
+type VehicleMergeJSON struct {
+	Id       int                  `json:"id"`
+	KeptId   int                  `json:"kept_id"`
+	MergedId int                  `json:"merged_id"`
+	Merged   internal.VehicleJSON `json:"merged"`
+	Filled   []string             `json:"filled"`
+	At       time.Time            `json:"at"`
+}
+
+// NewVehicleMergeLog is a function that returns a new instance of VehicleMergeLog
+// - path is the file the merges are appended to, one JSON merge per line, it is read back on creation
+// - an empty path keeps the merges in memory only
+func NewVehicleMergeLog(path string) (l *VehicleMergeLog, err error) {
+	l = &VehicleMergeLog{path: path}
+	if path == "" {
+		return
+	}
+
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return l, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	sc := bufio.NewScanner(bytes.NewReader(data))
+	for line := 1; sc.Scan(); line++ {
+		if len(bytes.TrimSpace(sc.Bytes())) == 0 {
+			continue
+		}
+		var mj VehicleMergeJSON
+		if err = json.Unmarshal(sc.Bytes(), &mj); err != nil {
+			return nil, fmt.Errorf("merge log: %s:%d: %w", path, line, err)
+		}
+		l.merges = append(l.merges, mergeFromJSON(mj))
+	}
+	if err = sc.Err(); err != nil {
+		return nil, err
+	}
+	if n := len(l.merges); n > 0 {
+		l.lastId = l.merges[n-1].Id
+	}
+	return
+}
+
+// VehicleMergeLog is a struct that implements the VehicleMergeRepository interface in memory,
+// optionally backed by an append-only file
+type VehicleMergeLog struct {
+	// mu guards the fields below and serializes appends
+	mu sync.RWMutex
+	// path is the file the merges are appended to, empty when they are not
+	path string
+	// merges are the recorded merges in id order
+	merges []internal.VehicleMerge
+	// lastId is the id of the last recorded merge
+	lastId int
+}
+
+// Save is a method that records a merge and sets its id
+func (l *VehicleMergeLog) Save(m *internal.VehicleMerge) (err error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	saved := *m
+	saved.Id = l.lastId + 1
+	if l.path != "" {
+		var line []byte
+		if line, err = json.Marshal(mergeToJSON(saved)); err != nil {
+			return
+		}
+		var file *os.File
+		if file, err = os.OpenFile(l.path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o644); err != nil {
+			return
+		}
+		defer file.Close()
+		if _, err = file.Write(append(line, '\n')); err != nil {
+			return
+		}
+		if err = file.Sync(); err != nil {
+			return
+		}
+	}
+
+	l.lastId = saved.Id
+	l.merges = append(l.merges, saved)
+	*m = saved
+	return
+}
+
+// FindAll is a method that returns the merges in id order
+func (l *VehicleMergeLog) FindAll() ([]internal.VehicleMerge, error) {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+
+	return append([]internal.VehicleMerge{}, l.merges...), nil
+}
+
+// FindByVehicle is a method that returns the merges that kept or merged a vehicle, in id order
+func (l *VehicleMergeLog) FindByVehicle(id int) (merges []internal.VehicleMerge, err error) {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+
+	merges = []internal.VehicleMerge{}
+	for _, m := range l.merges {
+		if m.KeptId == id || m.MergedId == id {
+			merges = append(merges, m)
+		}
+	}
+	return
+}
+
+// mergeToJSON is a function that converts a merge to its log representation
+func mergeToJSON(m internal.VehicleMerge) VehicleMergeJSON {
+	return VehicleMergeJSON{
+		Id:       m.Id,
+		KeptId:   m.KeptId,
+		MergedId: m.MergedId,
+		Merged:   internal.NewVehicleJSON(m.Merged),
+		Filled:   m.Filled,
+		At:       m.At,
+	}
+}
+
+// mergeFromJSON is a function that converts a line of the merge log to a merge
+func mergeFromJSON(mj VehicleMergeJSON) internal.VehicleMerge {
+	return internal.VehicleMerge{
+		Id:       mj.Id,
+		KeptId:   mj.KeptId,
+		MergedId: mj.MergedId,
+		Merged:   mj.Merged.Vehicle(),
+		Filled:   mj.Filled,
+		At:       mj.At,
+	}
+}
@@ -0,0 +1,142 @@
+package dedup
+
+import (
+	"app/internal"
+	"fmt"
+	"time"
+)
+
+// fillable are the attributes of a kept vehicle filled from the duplicate when they are empty, by JSON name
+var fillable = []struct {
+	name string
+	fill func(kept *internal.Vehicle, merged internal.Vehicle) bool
+}{
+	{"brand", func(k *internal.Vehicle, m internal.Vehicle) bool { return fillString(&k.Brand, m.Brand) }},
+	{"model", func(k *internal.Vehicle, m internal.Vehicle) bool { return fillString(&k.Model, m.Model) }},
+	{"registration", func(k *internal.Vehicle, m internal.Vehicle) bool { return fillString(&k.Registration, m.Registration) }},
+	{"vin", func(k *internal.Vehicle, m internal.Vehicle) bool { return fillString(&k.VIN, m.VIN) }},
+	{"color", func(k *internal.Vehicle, m internal.Vehicle) bool { return fillString(&k.Color, m.Color) }},
+	{"year", func(k *internal.Vehicle, m internal.Vehicle) bool {
+		return fillNumber(&k.FabricationYear, m.FabricationYear)
+	}},
+	{"passengers", func(k *internal.Vehicle, m internal.Vehicle) bool { return fillNumber(&k.Capacity, m.Capacity) }},
+	{"max_speed", func(k *internal.Vehicle, m internal.Vehicle) bool { return fillNumber(&k.MaxSpeed, m.MaxSpeed) }},
+	{"fuel_type", func(k *internal.Vehicle, m internal.Vehicle) bool { return fillString(&k.FuelType, m.FuelType) }},
+	{"transmission", func(k *internal.Vehicle, m internal.Vehicle) bool { return fillString(&k.Transmission, m.Transmission) }},
+	{"weight", func(k *internal.Vehicle, m internal.Vehicle) bool { return fillNumber(&k.Weight, m.Weight) }},
+	{"height", func(k *internal.Vehicle, m internal.Vehicle) bool { return fillNumber(&k.Height, m.Height) }},
+	{"length", func(k *internal.Vehicle, m internal.Vehicle) bool { return fillNumber(&k.Length, m.Length) }},
+	{"width", func(k *internal.Vehicle, m internal.Vehicle) bool { return fillNumber(&k.Width, m.Width) }},
+}
+
+// NewVehicleDuplicateDefault is a function that returns a new instance of VehicleDuplicateDefault
+func NewVehicleDuplicateDefault(sv internal.VehicleService, rp internal.VehicleMergeRepository) *VehicleDuplicateDefault {
+	return &VehicleDuplicateDefault{sv: sv, rp: rp}
+}
+
+// VehicleDuplicateDefault is a struct that implements the VehicleDuplicateService interface
+type VehicleDuplicateDefault struct {
+	// sv is the vehicle service holding the fleet
+	sv internal.VehicleService
+	// rp is the audit log of the merges
+	rp internal.VehicleMergeRepository
+}
+
+// Duplicates is a method that returns the registration clashes and the near duplicates of the vehicles matching the filter
+func (s *VehicleDuplicateDefault) Duplicates(q internal.VehicleDuplicateQuery) ([]internal.VehicleDuplicate, error) {
+	if q.Threshold == 0 {
+		q.Threshold = DefaultThreshold
+	}
+	if q.Threshold <= 0 || q.Threshold > 1 {
+		return nil, fmt.Errorf("%w: threshold must be greater than 0 and at most 1", internal.ErrVehicleDuplicateInvalid)
+	}
+	vehicles, err := s.sv.FindByFilter(q.Filter)
+	if err != nil {
+		return nil, err
+	}
+	return Detect(vehicles, q.Threshold), nil
+}
+
+// Merge is a method that consolidates a duplicate into the vehicle kept and records the merge.
+// The delete of the duplicate and the update of the kept vehicle are applied in a single atomic batch.
+// - the duplicate is deleted first so its registration and VIN are free to move to the kept vehicle
+func (s *VehicleDuplicateDefault) Merge(keptId, mergedId int) (m internal.VehicleMerge, kept internal.Vehicle, err error) {
+	if keptId == mergedId {
+		err = fmt.Errorf("%w: a vehicle can't be merged into itself", internal.ErrVehicleMergeInvalid)
+		return
+	}
+	if kept, err = s.find(keptId); err != nil {
+		return
+	}
+	merged, err := s.find(mergedId)
+	if err != nil {
+		return
+	}
+
+	m = internal.VehicleMerge{KeptId: keptId, MergedId: mergedId, Merged: merged, Filled: []string{}}
+	for _, f := range fillable {
+		if f.fill(&kept, merged) {
+			m.Filled = append(m.Filled, f.name)
+		}
+	}
+	ops := []internal.VehicleOperation{{Type: internal.VehicleOperationDelete, Vehicle: merged}}
+	if len(m.Filled) > 0 {
+		ops = append(ops, internal.VehicleOperation{Type: internal.VehicleOperationUpdate, Vehicle: kept})
+	}
+	results, err := s.sv.ApplyBatch(ops, true)
+	if err != nil {
+		// - the reason of the operation that aborted the batch, e.g. a vehicle deleted in the meantime
+		for _, res := range results {
+			if res.Err != nil {
+				err = res.Err
+				break
+			}
+		}
+		return
+	}
+	if len(m.Filled) > 0 {
+		kept = results[1].Vehicle
+	}
+
+	m.At = time.Now().UTC()
+	err = s.rp.Save(&m)
+	return
+}
+
+// Merges is a method that returns the recorded merges, the ones involving a vehicle when id is not 0
+func (s *VehicleDuplicateDefault) Merges(id int) ([]internal.VehicleMerge, error) {
+	if id == 0 {
+		return s.rp.FindAll()
+	}
+	return s.rp.FindByVehicle(id)
+}
+
+// find is a method that returns the vehicle with the given id
+func (s *VehicleDuplicateDefault) find(id int) (v internal.Vehicle, err error) {
+	vehicles, err := s.sv.FindById(id)
+	if err != nil {
+		return
+	}
+	if len(vehicles) == 0 {
+		return v, internal.ErrVehicleNotFound
+	}
+	return vehicles[0], nil
+}
+
+// fillString is a function that sets an empty text to value and reports whether it did
+func fillString(dst *string, value string) bool {
+	if *dst != "" || value == "" {
+		return false
+	}
+	*dst = value
+	return true
+}
+
+// fillNumber is a function that sets a zero number to value and reports whether it did
+func fillNumber[T int | float64](dst *T, value T) bool {
+	if *dst != 0 || value == 0 {
+		return false
+	}
+	*dst = value
+	return true
+}
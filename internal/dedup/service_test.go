@@ -0,0 +1,40 @@
+package dedup
+
+import (
+	"app/internal"
+	"app/internal/vehicle"
+	"testing"
+)
+
+func TestVehicleDuplicateDefault_MergeMovesVIN(t *testing.T) {
+	kept := internal.Vehicle{Id: 1}
+	kept.Brand, kept.Model, kept.Registration = "Toyota", "Corolla", "ABC1234"
+	merged := internal.Vehicle{Id: 2}
+	merged.Brand, merged.Model, merged.VIN, merged.Color = "Toyota", "Corolla", "JTDBR32E720123456", "Red"
+	sv := vehicle.NewVehicleDefault(vehicle.NewVehicleMap(map[int]internal.Vehicle{1: kept, 2: merged}), nil)
+	rp, err := NewVehicleMergeLog("")
+	if err != nil {
+		t.Fatalf("merge log: %v", err)
+	}
+
+	// the VIN of the duplicate moves to the kept vehicle in the batch that deletes the duplicate
+	m, got, err := NewVehicleDuplicateDefault(sv, rp).Merge(1, 2)
+	if err != nil {
+		t.Fatalf("merge: %v", err)
+	}
+	if got.VIN != merged.VIN || got.Color != "Red" || got.Registration != "ABC1234" {
+		t.Errorf("kept vehicle = %+v, want the VIN and the color of the duplicate", got)
+	}
+	if len(m.Filled) != 2 || m.Filled[0] != "vin" || m.Filled[1] != "color" {
+		t.Errorf("filled = %v, want [vin color]", m.Filled)
+	}
+	if found, _ := sv.FindById(2); len(found) != 0 {
+		t.Errorf("duplicate = %+v, want it deleted", found)
+	}
+	if found, _ := sv.FindById(1); len(found) != 1 || found[0] != got {
+		t.Errorf("stored kept vehicle = %+v, want %+v", found, got)
+	}
+	if merges, _ := rp.FindAll(); len(merges) != 1 {
+		t.Errorf("merges = %+v, want the merge recorded", merges)
+	}
+}
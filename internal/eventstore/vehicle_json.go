@@ -0,0 +1,303 @@
+package eventstore
+
+import (
+	"app/internal"
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// VehicleEventJSON is a struct that represents a line of the event log
+type VehicleEventJSON struct {
+	Sequence   uint64                `json:"sequence"`
+	Type       string                `json:"type"`
+	VehicleId  int                   `json:"vehicle_id"`
+	OccurredAt time.Time             `json:"occurred_at"`
+	Vehicle    *internal.VehicleJSON `json:"vehicle,omitempty"`
+	MaxSpeed   float64               `json:"max_speed,omitempty"`
+	FuelType   string                `json:"fuel_type,omitempty"`
+}
+
+// VehicleSnapshotJSON is a struct that represents a snapshot file
+type VehicleSnapshotJSON struct {
+	Sequence uint64                 `json:"sequence"`
+	TakenAt  time.Time              `json:"taken_at"`
+	Vehicles []internal.VehicleJSON `json:"vehicles"`
+}
+
+// NewVehicleEventJSONFile is a function that returns a new instance of VehicleEventJSONFile
+func NewVehicleEventJSONFile(path string) *VehicleEventJSONFile {
+	return &VehicleEventJSONFile{
+		path: path,
+	}
+}
+
+// VehicleEventJSONFile is a struct that implements the VehicleEventStore interface
+// storing one JSON event per line in an append-only file.
+// A last line torn by a crash during an append is ignored, and cut off before the next append.
+type VehicleEventJSONFile struct {
+	// path is the path to the event log
+	path string
+	// mu serializes appends
+	mu sync.Mutex
+	// scanned reports whether lastSequence was read from the file
+	scanned bool
+	// lastSequence is the sequence of the last event in the file
+	lastSequence uint64
+}
+
+// Append is a method that appends the events to the log in a single write
+func (s *VehicleEventJSONFile) Append(e ...internal.VehicleEvent) (stored []internal.VehicleEvent, err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if !s.scanned {
+		var events []internal.VehicleEvent
+		var valid int64
+		events, valid, err = s.read(0)
+		if err != nil {
+			return
+		}
+		if len(events) > 0 {
+			s.lastSequence = events[len(events)-1].Sequence
+		}
+		// - the events are appended on a line of their own after the last decoded one
+		if err = repairTail(s.path, valid); err != nil {
+			return
+		}
+		s.scanned = true
+	}
+
+	// encode events
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	seq := s.lastSequence
+	for _, ev := range e {
+		seq++
+		ev.Sequence = seq
+		if ev.OccurredAt.IsZero() {
+			ev.OccurredAt = time.Now().UTC()
+		}
+		if err = enc.Encode(eventToJSON(ev)); err != nil {
+			return nil, err
+		}
+		stored = append(stored, ev)
+	}
+
+	// write events
+	file, err := os.OpenFile(s.path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o644)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	if _, err = file.Write(buf.Bytes()); err != nil {
+		// a partial write is cut off by the next append
+		s.scanned = false
+		return nil, err
+	}
+	if err = file.Sync(); err != nil {
+		s.scanned = false
+		return nil, err
+	}
+
+	s.lastSequence = seq
+	return
+}
+
+// Load is a method that returns the events with a sequence greater than after
+func (s *VehicleEventJSONFile) Load(after uint64) (e []internal.VehicleEvent, err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	e, _, err = s.read(after)
+	return
+}
+
+// read is a method that decodes the log, a missing file is an empty stream.
+// valid is the length of the log up to its last complete line: a last line without newline
+// that does not decode is an append torn by a crash, it is left out of the stream.
+func (s *VehicleEventJSONFile) read(after uint64) (e []internal.VehicleEvent, valid int64, err error) {
+	file, err := os.Open(s.path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			err = nil
+		}
+		return
+	}
+	defer file.Close()
+
+	rd := bufio.NewReader(file)
+	line := 0
+	for {
+		data, rerr := rd.ReadBytes('\n')
+		if rerr != nil && rerr != io.EOF {
+			return nil, 0, rerr
+		}
+		if len(data) == 0 {
+			break
+		}
+		line++
+		complete := data[len(data)-1] == '\n'
+		if len(bytes.TrimSpace(data)) > 0 {
+			var ev VehicleEventJSON
+			if err = json.Unmarshal(data, &ev); err != nil {
+				if !complete {
+					// torn last line
+					return e, valid, nil
+				}
+				return nil, 0, fmt.Errorf("event log %s line %d: %w", s.path, line, err)
+			}
+			if ev.Sequence > after {
+				e = append(e, eventFromJSON(ev))
+			}
+		}
+		valid += int64(len(data))
+		if !complete {
+			break
+		}
+	}
+	return
+}
+
+// repairTail is a function that cuts a log down to its valid length and ends it with a newline,
+// a missing or empty log is left as it is
+func repairTail(path string, valid int64) (err error) {
+	if valid == 0 {
+		if err = os.Truncate(path, 0); errors.Is(err, os.ErrNotExist) {
+			err = nil
+		}
+		return
+	}
+	file, err := os.OpenFile(path, os.O_RDWR, 0o644)
+	if err != nil {
+		return
+	}
+	defer file.Close()
+	if err = file.Truncate(valid); err != nil {
+		return
+	}
+	last := make([]byte, 1)
+	if _, err = file.ReadAt(last, valid-1); err != nil {
+		return
+	}
+	if last[0] != '\n' {
+		_, err = file.WriteAt([]byte("\n"), valid)
+	}
+	return
+}
+
+// NewVehicleSnapshotJSONFile is a function that returns a new instance of VehicleSnapshotJSONFile
+func NewVehicleSnapshotJSONFile(path string) *VehicleSnapshotJSONFile {
+	return &VehicleSnapshotJSONFile{
+		path: path,
+	}
+}
+
+// VehicleSnapshotJSONFile is a struct that implements the VehicleSnapshotStore interface
+type VehicleSnapshotJSONFile struct {
+	// path is the path to the snapshot file
+	path string
+}
+
+// Save is a method that writes the snapshot to a temporary file and renames it over the previous one
+func (s *VehicleSnapshotJSONFile) Save(sn internal.VehicleSnapshot) (err error) {
+	data := VehicleSnapshotJSON{
+		Sequence: sn.Sequence,
+		TakenAt:  sn.TakenAt,
+		Vehicles: make([]internal.VehicleJSON, 0, len(sn.Vehicles)),
+	}
+	for _, v := range sn.Vehicles {
+		data.Vehicles = append(data.Vehicles, internal.NewVehicleJSON(v))
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(s.path), filepath.Base(s.path)+".*.tmp")
+	if err != nil {
+		return
+	}
+	defer os.Remove(tmp.Name())
+
+	if err = json.NewEncoder(tmp).Encode(data); err != nil {
+		tmp.Close()
+		return
+	}
+	if err = tmp.Sync(); err != nil {
+		tmp.Close()
+		return
+	}
+	if err = tmp.Close(); err != nil {
+		return
+	}
+	err = os.Rename(tmp.Name(), s.path)
+	return
+}
+
+// Latest is a method that returns the snapshot stored in the file
+func (s *VehicleSnapshotJSONFile) Latest() (sn internal.VehicleSnapshot, ok bool, err error) {
+	file, err := os.Open(s.path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			err = nil
+		}
+		return
+	}
+	defer file.Close()
+
+	var data VehicleSnapshotJSON
+	if err = json.NewDecoder(file).Decode(&data); err != nil {
+		return
+	}
+
+	sn = internal.VehicleSnapshot{
+		Sequence: data.Sequence,
+		TakenAt:  data.TakenAt,
+		Vehicles: make(map[int]internal.Vehicle, len(data.Vehicles)),
+	}
+	for _, v := range data.Vehicles {
+		sn.Vehicles[v.Id] = v.Vehicle()
+	}
+	ok = true
+	return
+}
+
+// eventToJSON is a function that converts a domain event to its log representation
+func eventToJSON(e internal.VehicleEvent) (ev VehicleEventJSON) {
+	ev = VehicleEventJSON{
+		Sequence:   e.Sequence,
+		Type:       string(e.Type),
+		VehicleId:  e.VehicleId,
+		OccurredAt: e.OccurredAt,
+	}
+	switch e.Type {
+	case internal.VehicleRegistered, internal.AttributesChanged:
+		vh := internal.NewVehicleJSON(e.Vehicle)
+		ev.Vehicle = &vh
+	case internal.SpeedChanged:
+		ev.MaxSpeed = e.MaxSpeed
+	case internal.FuelTypeChanged:
+		ev.FuelType = e.FuelType
+	}
+	return
+}
+
+// eventFromJSON is a function that converts a log line to a domain event
+func eventFromJSON(ev VehicleEventJSON) (e internal.VehicleEvent) {
+	e = internal.VehicleEvent{
+		Sequence:   ev.Sequence,
+		Type:       internal.VehicleEventType(ev.Type),
+		VehicleId:  ev.VehicleId,
+		OccurredAt: ev.OccurredAt,
+		MaxSpeed:   ev.MaxSpeed,
+		FuelType:   ev.FuelType,
+	}
+	if ev.Vehicle != nil {
+		e.Vehicle = ev.Vehicle.Vehicle()
+	}
+	return
+}
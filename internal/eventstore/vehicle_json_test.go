@@ -0,0 +1,101 @@
+package eventstore
+
+import (
+	"app/internal"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// registered is a function that returns the registration event of a vehicle
+func registered(id int, brand string) internal.VehicleEvent {
+	v := internal.Vehicle{Id: id}
+	v.Brand = brand
+	return internal.VehicleEvent{Type: internal.VehicleRegistered, VehicleId: id, Vehicle: v}
+}
+
+func TestVehicleEventJSONFile_TornLastLine(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "events.jsonl")
+	st := NewVehicleEventJSONFile(path)
+	if _, err := st.Append(registered(1, "Toyota"), registered(2, "Ford")); err != nil {
+		t.Fatalf("append: %v", err)
+	}
+
+	// a crash in the middle of the third append
+	file, err := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0o644)
+	if err != nil {
+		t.Fatalf("open: %v", err)
+	}
+	if _, err = file.WriteString(`{"sequence":3,"type":"vehicle_regis`); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+	file.Close()
+
+	// a fresh store, as after a restart
+	st = NewVehicleEventJSONFile(path)
+	events, err := st.Load(0)
+	if err != nil {
+		t.Fatalf("load: %v", err)
+	}
+	if len(events) != 2 || events[1].Vehicle.Brand != "Ford" {
+		t.Fatalf("events = %+v, want the 2 complete events", events)
+	}
+
+	stored, err := st.Append(registered(3, "Fiat"))
+	if err != nil {
+		t.Fatalf("append after the torn line: %v", err)
+	}
+	if stored[0].Sequence != 3 {
+		t.Errorf("sequence = %d, want 3", stored[0].Sequence)
+	}
+	events, err = NewVehicleEventJSONFile(path).Load(0)
+	if err != nil {
+		t.Fatalf("load after append: %v", err)
+	}
+	if len(events) != 3 || events[2].Vehicle.Brand != "Fiat" {
+		t.Errorf("events = %+v, want 3 events ending with Fiat", events)
+	}
+}
+
+func TestVehicleEventJSONFile_CorruptLine(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "events.jsonl")
+	st := NewVehicleEventJSONFile(path)
+	if _, err := st.Append(registered(1, "Toyota")); err != nil {
+		t.Fatalf("append: %v", err)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("read: %v", err)
+	}
+	// - a complete line that does not decode is not a torn write
+	data = append([]byte("{not json}\n"), data...)
+	if err = os.WriteFile(path, data, 0o644); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+
+	_, err = NewVehicleEventJSONFile(path).Load(0)
+	if err == nil || !strings.Contains(err.Error(), "line 1") {
+		t.Errorf("error = %v, want the corrupt line 1 reported", err)
+	}
+}
+
+func TestVehicleEventJSONFile_LastLineWithoutNewline(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "events.jsonl")
+	line := `{"sequence":1,"type":"vehicle_registered","vehicle_id":1,"occurred_at":"2024-01-01T00:00:00Z","vehicle":{"id":1,"brand":"Toyota"}}`
+	if err := os.WriteFile(path, []byte(line), 0o644); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+
+	st := NewVehicleEventJSONFile(path)
+	if _, err := st.Append(registered(2, "Ford")); err != nil {
+		t.Fatalf("append: %v", err)
+	}
+	events, err := NewVehicleEventJSONFile(path).Load(0)
+	if err != nil {
+		t.Fatalf("load: %v", err)
+	}
+	if len(events) != 2 || events[0].Vehicle.Brand != "Toyota" || events[1].Sequence != 2 {
+		t.Errorf("events = %+v, want Toyota then Ford", events)
+	}
+}
@@ -0,0 +1,359 @@
+package export
+
+import (
+	"app/internal"
+	"archive/zip"
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// VehicleColumns is the list of exported columns, named as in the JSON representation
+var VehicleColumns = []string{
+	"id", "brand", "model", "registration", "color", "year", "passengers", "max_speed",
+	"fuel_type", "transmission", "weight", "height", "length", "width", "vin",
+}
+
+// Format is a struct that represents an export format
+type Format struct {
+	// ContentType is the media type of the format
+	ContentType string
+	// Extension is the file extension of the format, including the dot
+	Extension string
+	// New returns a writer of the format
+	New func(w io.Writer) internal.VehicleWriter
+}
+
+// Formats is the list of export formats by name
+var Formats = map[string]Format{
+	"csv": {
+		ContentType: "text/csv; charset=utf-8",
+		Extension:   ".csv",
+		New:         func(w io.Writer) internal.VehicleWriter { return NewVehicleCSV(w, nil) },
+	},
+	"ndjson": {
+		ContentType: "application/x-ndjson",
+		Extension:   ".ndjson",
+		New:         func(w io.Writer) internal.VehicleWriter { return NewVehicleNDJSON(w) },
+	},
+	"json": {
+		ContentType: "application/json",
+		Extension:   ".json",
+		New:         func(w io.Writer) internal.VehicleWriter { return NewVehicleJSONArray(w) },
+	},
+	"xlsx": {
+		ContentType: "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet",
+		Extension:   ".xlsx",
+		New:         func(w io.Writer) internal.VehicleWriter { return NewVehicleXLSX(w) },
+	},
+}
+
+// ConfigVehicleCSV is a struct that represents the format of a CSV export
+type ConfigVehicleCSV struct {
+	// Delimiter is the column separator, default ','
+	Delimiter rune
+	// DecimalSeparator is the decimal separator of numbers, default '.'
+	DecimalSeparator rune
+	// Raw adds the brand_raw and color_raw columns, the brand and color before their normalization
+	Raw bool
+}
+
+// NewVehicleCSV is a function that returns a new instance of VehicleCSV
+func NewVehicleCSV(w io.Writer, cfg *ConfigVehicleCSV) *VehicleCSV {
+	// default config
+	defaultConfig := &ConfigVehicleCSV{
+		Delimiter:        ',',
+		DecimalSeparator: '.',
+	}
+	if cfg != nil {
+		if cfg.Delimiter != 0 {
+			defaultConfig.Delimiter = cfg.Delimiter
+		}
+		if cfg.DecimalSeparator != 0 {
+			defaultConfig.DecimalSeparator = cfg.DecimalSeparator
+		}
+		defaultConfig.Raw = cfg.Raw
+	}
+
+	cw := csv.NewWriter(w)
+	cw.Comma = defaultConfig.Delimiter
+	header := VehicleColumns
+	if defaultConfig.Raw {
+		header = append(append([]string{}, VehicleColumns...), "brand_raw", "color_raw")
+	}
+	return &VehicleCSV{
+		w:       cw,
+		decimal: defaultConfig.DecimalSeparator,
+		raw:     defaultConfig.Raw,
+		header:  header,
+	}
+}
+
+// VehicleCSV is a struct that implements the VehicleWriter interface in CSV format.
+// The header uses the field names understood by the CSV loader, so an export can be loaded back
+// with the same delimiter and decimal separator.
+type VehicleCSV struct {
+	// w is the CSV writer
+	w *csv.Writer
+	// decimal is the decimal separator of numbers
+	decimal rune
+	// raw reports whether the raw brand and color are written
+	raw bool
+	// header is the list of columns
+	header []string
+	// started reports whether the header was written
+	started bool
+}
+
+// Write is a method that writes a vehicle as a CSV record, preceded by the header on the first call
+func (e *VehicleCSV) Write(v internal.Vehicle) (err error) {
+	if !e.started {
+		e.started = true
+		if err = e.w.Write(e.header); err != nil {
+			return
+		}
+	}
+	record := vehicleRecord(v)
+	if e.decimal != '.' {
+		for i, col := range VehicleColumns {
+			if numericColumns[col] {
+				record[i] = strings.Replace(record[i], ".", string(e.decimal), 1)
+			}
+		}
+	}
+	if e.raw {
+		record = append(record, textCell(v.BrandRaw), textCell(v.ColorRaw))
+	}
+	return e.w.Write(record)
+}
+
+// Close is a method that flushes the output, writing the header if there were no vehicles
+func (e *VehicleCSV) Close() (err error) {
+	if !e.started {
+		e.started = true
+		if err = e.w.Write(e.header); err != nil {
+			return
+		}
+	}
+	e.w.Flush()
+	return e.w.Error()
+}
+
+// NewVehicleNDJSON is a function that returns a new instance of VehicleNDJSON
+func NewVehicleNDJSON(w io.Writer) *VehicleNDJSON {
+	bw := bufio.NewWriter(w)
+	return &VehicleNDJSON{w: bw, enc: json.NewEncoder(bw)}
+}
+
+// VehicleNDJSON is a struct that implements the VehicleWriter interface with one JSON object per line
+type VehicleNDJSON struct {
+	// w buffers the output
+	w *bufio.Writer
+	// enc is the JSON encoder
+	enc *json.Encoder
+}
+
+// Write is a method that writes a vehicle as a JSON line
+func (e *VehicleNDJSON) Write(v internal.Vehicle) (err error) {
+	return e.enc.Encode(internal.NewVehicleJSON(v))
+}
+
+// Close is a method that flushes the output
+func (e *VehicleNDJSON) Close() (err error) {
+	return e.w.Flush()
+}
+
+// NewVehicleJSONArray is a function that returns a new instance of VehicleJSONArray
+func NewVehicleJSONArray(w io.Writer) *VehicleJSONArray {
+	return &VehicleJSONArray{w: bufio.NewWriter(w)}
+}
+
+// VehicleJSONArray is a struct that implements the VehicleWriter interface as a JSON array
+// written element by element
+type VehicleJSONArray struct {
+	// w buffers the output
+	w *bufio.Writer
+	// count is the number of vehicles written
+	count int
+}
+
+// Write is a method that writes a vehicle as an element of the array
+func (e *VehicleJSONArray) Write(v internal.Vehicle) (err error) {
+	sep := ","
+	if e.count == 0 {
+		sep = "["
+	}
+	e.count++
+	data, err := json.Marshal(internal.NewVehicleJSON(v))
+	if err != nil {
+		return
+	}
+	if _, err = e.w.WriteString(sep); err != nil {
+		return
+	}
+	_, err = e.w.Write(data)
+	return
+}
+
+// Close is a method that closes the array and flushes the output
+func (e *VehicleJSONArray) Close() (err error) {
+	end := "]\n"
+	if e.count == 0 {
+		end = "[]\n"
+	}
+	if _, err = e.w.WriteString(end); err != nil {
+		return
+	}
+	return e.w.Flush()
+}
+
+// NewVehicleXLSX is a function that returns a new instance of VehicleXLSX
+func NewVehicleXLSX(w io.Writer) *VehicleXLSX {
+	return &VehicleXLSX{zw: zip.NewWriter(w)}
+}
+
+// VehicleXLSX is a struct that implements the VehicleWriter interface as an Office Open XML workbook
+// with a single sheet. The parts are written to the zip in order so the sheet is streamed row by row.
+type VehicleXLSX struct {
+	// zw is the zip container
+	zw *zip.Writer
+	// sheet is the writer of the sheet part, nil until the first row
+	sheet *bufio.Writer
+	// row is the number of rows written, including the header
+	row int
+}
+
+// xlsxParts is the list of parts written before the sheet
+var xlsxParts = []struct{ name, body string }{
+	{"[Content_Types].xml", `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>` +
+		`<Types xmlns="http://schemas.openxmlformats.org/package/2006/content-types">` +
+		`<Default Extension="rels" ContentType="application/vnd.openxmlformats-package.relationships+xml"/>` +
+		`<Default Extension="xml" ContentType="application/xml"/>` +
+		`<Override PartName="/xl/workbook.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.sheet.main+xml"/>` +
+		`<Override PartName="/xl/worksheets/sheet1.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.worksheet+xml"/>` +
+		`</Types>`},
+	{"_rels/.rels", `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>` +
+		`<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">` +
+		`<Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/officeDocument" Target="xl/workbook.xml"/>` +
+		`</Relationships>`},
+	{"xl/workbook.xml", `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>` +
+		`<workbook xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main" xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships">` +
+		`<sheets><sheet name="Vehicles" sheetId="1" r:id="rId1"/></sheets>` +
+		`</workbook>`},
+	{"xl/_rels/workbook.xml.rels", `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>` +
+		`<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">` +
+		`<Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/worksheet" Target="worksheets/sheet1.xml"/>` +
+		`</Relationships>`},
+}
+
+// Write is a method that writes a vehicle as a row of the sheet, preceded by the header on the first call
+func (e *VehicleXLSX) Write(v internal.Vehicle) (err error) {
+	if err = e.start(); err != nil {
+		return
+	}
+	return e.writeRow(vehicleRecord(v), true)
+}
+
+// Close is a method that closes the sheet and the zip container
+func (e *VehicleXLSX) Close() (err error) {
+	if err = e.start(); err != nil {
+		return
+	}
+	if _, err = e.sheet.WriteString(`</sheetData></worksheet>`); err != nil {
+		return
+	}
+	if err = e.sheet.Flush(); err != nil {
+		return
+	}
+	return e.zw.Close()
+}
+
+// start is a method that writes the fixed parts and opens the sheet with its header row
+func (e *VehicleXLSX) start() (err error) {
+	if e.sheet != nil {
+		return
+	}
+	for _, p := range xlsxParts {
+		var fw io.Writer
+		if fw, err = e.zw.Create(p.name); err != nil {
+			return
+		}
+		if _, err = io.WriteString(fw, p.body); err != nil {
+			return
+		}
+	}
+	fw, err := e.zw.Create("xl/worksheets/sheet1.xml")
+	if err != nil {
+		return
+	}
+	e.sheet = bufio.NewWriter(fw)
+	_, err = e.sheet.WriteString(`<?xml version="1.0" encoding="UTF-8" standalone="yes"?>` +
+		`<worksheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main"><sheetData>`)
+	if err != nil {
+		return
+	}
+	return e.writeRow(VehicleColumns, false)
+}
+
+// writeRow is a method that writes a row, numeric cells are typed as numbers when numeric is true
+func (e *VehicleXLSX) writeRow(values []string, numeric bool) (err error) {
+	e.row++
+	fmt.Fprintf(e.sheet, `<row r="%d">`, e.row)
+	for i, value := range values {
+		ref := string(rune('A'+i)) + strconv.Itoa(e.row)
+		if numeric && numericColumns[VehicleColumns[i]] {
+			fmt.Fprintf(e.sheet, `<c r="%s"><v>%s</v></c>`, ref, value)
+			continue
+		}
+		fmt.Fprintf(e.sheet, `<c r="%s" t="inlineStr"><is><t>`, ref)
+		if err = xml.EscapeText(e.sheet, []byte(value)); err != nil {
+			return
+		}
+		e.sheet.WriteString(`</t></is></c>`)
+	}
+	_, err = e.sheet.WriteString(`</row>`)
+	return
+}
+
+// numericColumns is the set of columns written as numbers
+var numericColumns = map[string]bool{
+	"id": true, "year": true, "passengers": true, "max_speed": true,
+	"weight": true, "height": true, "length": true, "width": true,
+}
+
+// vehicleRecord is a function that returns the values of a vehicle in VehicleColumns order,
+// with the text values neutralized so a spreadsheet does not read them as formulas
+func vehicleRecord(v internal.Vehicle) []string {
+	return []string{
+		strconv.Itoa(v.Id),
+		textCell(v.Brand),
+		textCell(v.Model),
+		textCell(v.Registration),
+		textCell(v.Color),
+		strconv.Itoa(v.FabricationYear),
+		strconv.Itoa(v.Capacity),
+		strconv.FormatFloat(v.MaxSpeed, 'f', -1, 64),
+		textCell(v.FuelType),
+		textCell(v.Transmission),
+		strconv.FormatFloat(v.Weight, 'f', -1, 64),
+		strconv.FormatFloat(v.Height, 'f', -1, 64),
+		strconv.FormatFloat(v.Length, 'f', -1, 64),
+		strconv.FormatFloat(v.Width, 'f', -1, 64),
+		textCell(v.VIN),
+	}
+}
+
+// textCell is a function that prefixes with a quote a text value a spreadsheet would read as a formula,
+// i.e. starting with '=', '+', '-', '@', a tab or a carriage return
+// - the CSV loader drops the quote, so an export is loaded back unchanged
+func textCell(value string) string {
+	if value != "" && strings.ContainsRune("=+-@\t\r", rune(value[0])) {
+		return "'" + value
+	}
+	return value
+}
@@ -0,0 +1,110 @@
+package export
+
+import (
+	"app/internal"
+	"app/internal/loader"
+	"archive/zip"
+	"bytes"
+	"encoding/csv"
+	"io"
+	"strings"
+	"testing"
+)
+
+// formulas is a function that returns a vehicle whose text values a spreadsheet would read as formulas
+func formulas() internal.Vehicle {
+	v := internal.Vehicle{Id: 1}
+	v.Brand, v.Model, v.Registration, v.Color = "=HYPERLINK(\"http://evil\")", "+1", "-2", "@SUM(A1)"
+	v.FuelType, v.Transmission, v.VIN = "\tgas", "manual", "1HGCM82633A004352"
+	v.MaxSpeed = -5
+	return v
+}
+
+func TestVehicleCSV_Formulas(t *testing.T) {
+	var buf bytes.Buffer
+	e := NewVehicleCSV(&buf, nil)
+	if err := e.Write(formulas()); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+	if err := e.Close(); err != nil {
+		t.Fatalf("close: %v", err)
+	}
+
+	records, err := csv.NewReader(&buf).ReadAll()
+	if err != nil || len(records) != 2 {
+		t.Fatalf("records = %v, %v, want a header and a row", records, err)
+	}
+	row := records[1]
+	want := map[string]string{
+		"brand":        "'=HYPERLINK(\"http://evil\")",
+		"model":        "'+1",
+		"registration": "'-2",
+		"color":        "'@SUM(A1)",
+		"fuel_type":    "'\tgas",
+		"transmission": "manual",
+		"vin":          "1HGCM82633A004352",
+		// - numbers are not text, a negative one is kept as is
+		"max_speed": "-5",
+	}
+	for i, col := range VehicleColumns {
+		if w, ok := want[col]; ok && row[i] != w {
+			t.Errorf("%s = %q, want %q", col, row[i], w)
+		}
+	}
+}
+
+func TestVehicleXLSX_Formulas(t *testing.T) {
+	var buf bytes.Buffer
+	e := NewVehicleXLSX(&buf)
+	if err := e.Write(formulas()); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+	if err := e.Close(); err != nil {
+		t.Fatalf("close: %v", err)
+	}
+
+	zr, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	if err != nil {
+		t.Fatalf("zip: %v", err)
+	}
+	var sheet string
+	for _, f := range zr.File {
+		if f.Name == "xl/worksheets/sheet1.xml" {
+			rc, _ := f.Open()
+			data, _ := io.ReadAll(rc)
+			rc.Close()
+			sheet = string(data)
+		}
+	}
+	for _, want := range []string{"<t>&#39;=HYPERLINK", "<t>&#39;+1</t>", "<t>&#39;-2</t>", "<t>&#39;@SUM(A1)</t>", "<v>-5</v>"} {
+		if !strings.Contains(sheet, want) {
+			t.Errorf("sheet does not contain %q: %s", want, sheet)
+		}
+	}
+}
+
+func TestVehicleNDJSON_RoundTrip(t *testing.T) {
+	v := internal.Vehicle{Id: 7}
+	v.Brand, v.Model, v.Registration, v.Color, v.VIN = "Toyota", "Corolla", "ABC1234", "Red", "1HGCM82633A004352"
+	v.BrandRaw, v.ColorRaw = "TOYOTA", "RED"
+	v.FabricationYear, v.Capacity, v.MaxSpeed, v.FuelType, v.Transmission = 2015, 5, 180.5, "gasoline", "manual"
+	v.Weight, v.Height, v.Length, v.Width = 1200, 1.5, 4.6, 1.8
+
+	var buf bytes.Buffer
+	e := NewVehicleNDJSON(&buf)
+	if err := e.Write(v); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+	if err := e.Close(); err != nil {
+		t.Fatalf("close: %v", err)
+	}
+
+	// an export is read back by the loader with the raw values it was normalized from
+	got, _, err := loader.NewVehicleNDJSONDecoder(&buf).Next()
+	if err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if got != v {
+		t.Errorf("vehicle = %+v, want %+v", got, v)
+	}
+}
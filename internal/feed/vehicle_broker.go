@@ -0,0 +1,106 @@
+package feed
+
+import (
+	"app/internal"
+	"sync"
+	"time"
+)
+
+// NewVehicleBroker is a function that returns a new instance of VehicleBroker
+// - capacity is the number of changes kept for Last-Event-ID resume
+// - subscriberBuffer is the number of changes a subscriber may lag behind before being dropped
+func NewVehicleBroker(capacity, subscriberBuffer int) *VehicleBroker {
+	// default values
+	if capacity <= 0 {
+		capacity = 1024
+	}
+	if subscriberBuffer <= 0 {
+		subscriberBuffer = 64
+	}
+	return &VehicleBroker{
+		ring:             make([]internal.VehicleChange, capacity),
+		subscriberBuffer: subscriberBuffer,
+		subscribers:      make(map[chan internal.VehicleChange]struct{}),
+	}
+}
+
+// VehicleBroker is a struct that implements the VehicleFeed interface
+// keeping the last changes in a bounded ring buffer
+type VehicleBroker struct {
+	// mu guards the fields below
+	mu sync.Mutex
+	// ring is the buffer of the last changes
+	ring []internal.VehicleChange
+	// size is the number of changes stored in ring
+	size int
+	// lastId is the id of the last published change
+	lastId uint64
+	// subscriberBuffer is the capacity of each subscriber channel
+	subscriberBuffer int
+	// subscribers is the set of live subscriber channels
+	subscribers map[chan internal.VehicleChange]struct{}
+}
+
+// Publish is a method that stores the change in the ring and fans it out to the subscribers
+// - a subscriber whose buffer is full is dropped, it may resume with its last received id
+func (b *VehicleBroker) Publish(c internal.VehicleChange) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.lastId++
+	c.Id = b.lastId
+	if c.OccurredAt.IsZero() {
+		c.OccurredAt = time.Now().UTC()
+	}
+	b.ring[int((c.Id-1)%uint64(len(b.ring)))] = c
+	if b.size < len(b.ring) {
+		b.size++
+	}
+
+	for ch := range b.subscribers {
+		select {
+		case ch <- c:
+		default:
+			delete(b.subscribers, ch)
+			close(ch)
+		}
+	}
+}
+
+// Subscribe is a method that registers a new subscriber
+func (b *VehicleBroker) Subscribe(lastId uint64, resume bool) (s internal.VehicleSubscription) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	// backlog
+	if resume {
+		oldest := b.lastId - uint64(b.size) + 1
+		switch {
+		case lastId > b.lastId:
+			// unknown id, e.g. issued before a restart
+			s.Missed = true
+			lastId = oldest - 1
+		case lastId+1 < oldest:
+			s.Missed = true
+			lastId = oldest - 1
+		}
+		for id := lastId + 1; id <= b.lastId; id++ {
+			s.Backlog = append(s.Backlog, b.ring[int((id-1)%uint64(len(b.ring)))])
+		}
+	}
+
+	// live
+	ch := make(chan internal.VehicleChange, b.subscriberBuffer)
+	b.subscribers[ch] = struct{}{}
+	s.Changes = ch
+	s.Cancel = func() {
+		b.mu.Lock()
+		defer b.mu.Unlock()
+
+		if _, ok := b.subscribers[ch]; ok {
+			delete(b.subscribers, ch)
+			close(ch)
+		}
+	}
+	return
+}
@@ -0,0 +1,14 @@
+package feed
+
+import "app/internal"
+
+// VehiclePublishers is a list of publishers that implements the VehiclePublisher interface
+// forwarding every change to each of them in order
+type VehiclePublishers []internal.VehiclePublisher
+
+// Publish is a method that publishes the change on every publisher of the list
+func (p VehiclePublishers) Publish(c internal.VehicleChange) {
+	for _, pb := range p {
+		pb.Publish(c)
+	}
+}
@@ -0,0 +1,52 @@
+package feed
+
+import "app/internal"
+
+// VehicleViewOp is the kind of diff produced by a VehicleView
+type VehicleViewOp string
+
+const (
+	// VehicleViewAdd is produced when a vehicle enters the view
+	VehicleViewAdd VehicleViewOp = "add"
+	// VehicleViewChange is produced when a vehicle of the view is updated and still matches
+	VehicleViewChange VehicleViewOp = "change"
+	// VehicleViewRemove is produced when a vehicle leaves the view
+	VehicleViewRemove VehicleViewOp = "remove"
+)
+
+// NewVehicleView is a function that returns a new instance of VehicleView
+// - initial is the current list of vehicles matching the filter
+func NewVehicleView(f internal.VehicleFilter, initial []internal.Vehicle) *VehicleView {
+	members := make(map[int]struct{}, len(initial))
+	for _, v := range initial {
+		members[v.Id] = struct{}{}
+	}
+	return &VehicleView{filter: f, members: members}
+}
+
+// VehicleView is a struct that tracks the vehicles matching a filter as changes arrive
+type VehicleView struct {
+	// filter is the criteria of the view
+	filter internal.VehicleFilter
+	// members is the set of vehicle ids currently in the view
+	members map[int]struct{}
+}
+
+// Apply is a method that updates the view with a change and returns the resulting diff
+// - ok is false when the change does not affect the view
+func (vw *VehicleView) Apply(c internal.VehicleChange) (op VehicleViewOp, ok bool) {
+	_, was := vw.members[c.Vehicle.Id]
+	is := c.Type != internal.VehicleDeleted && vw.filter.Match(c.Vehicle)
+
+	switch {
+	case !was && is:
+		vw.members[c.Vehicle.Id] = struct{}{}
+		return VehicleViewAdd, true
+	case was && is:
+		return VehicleViewChange, true
+	case was && !is:
+		delete(vw.members, c.Vehicle.Id)
+		return VehicleViewRemove, true
+	}
+	return
+}
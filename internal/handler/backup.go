@@ -0,0 +1,132 @@
+package handler
+
+import (
+	"app/internal"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// BackupJSON is a struct that represents a backup in JSON format
+type BackupJSON struct {
+	ID        string    `json:"id"`
+	CreatedAt time.Time `json:"created_at"`
+	Vehicles  int       `json:"vehicles"`
+	Size      int64     `json:"size"`
+	Checksum  string    `json:"checksum"`
+}
+
+// RestoreJSON is a struct that represents the outcome of a restore in JSON format
+type RestoreJSON struct {
+	Backup  BackupJSON `json:"backup"`
+	Created int        `json:"created"`
+	Updated int        `json:"updated"`
+	Deleted int        `json:"deleted"`
+}
+
+// NewBackupDefault is a function that returns a new instance of BackupDefault
+func NewBackupDefault(sv internal.VehicleBackupService) *BackupDefault {
+	return &BackupDefault{sv: sv}
+}
+
+// BackupDefault is a struct that represents the handler of backups of the fleet
+type BackupDefault struct {
+	// sv is the backup service
+	sv internal.VehicleBackupService
+}
+
+// GetAll is a method that returns the stored backups
+func (h *BackupDefault) GetAll() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		b, err := h.sv.List()
+		if err != nil {
+			writeBackupError(w, r, err)
+			return
+		}
+
+		data := make([]BackupJSON, 0, len(b))
+		for _, bk := range b {
+			data = append(data, backupToJSON(bk))
+		}
+		render(w, r, http.StatusOK, map[string]any{
+			"message": "success",
+			"data":    data,
+		})
+	}
+}
+
+// PostCreate is a method that takes a backup of the fleet
+func (h *BackupDefault) PostCreate() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		b, err := h.sv.Backup()
+		if err != nil {
+			writeBackupError(w, r, err)
+			return
+		}
+		render(w, r, http.StatusCreated, map[string]any{
+			"message": "backup created successfully",
+			"data":    backupToJSON(b),
+		})
+	}
+}
+
+// PostRestore is a method that replaces the fleet with the vehicles of a backup
+func (h *BackupDefault) PostRestore() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		b, ops, err := h.sv.Restore(chi.URLParam(r, "id"))
+		if err != nil {
+			writeBackupError(w, r, err)
+			return
+		}
+
+		data := RestoreJSON{Backup: backupToJSON(b)}
+		for _, op := range ops {
+			switch op.Type {
+			case internal.VehicleOperationCreate:
+				data.Created++
+			case internal.VehicleOperationUpdate:
+				data.Updated++
+			case internal.VehicleOperationDelete:
+				data.Deleted++
+			}
+		}
+		render(w, r, http.StatusOK, map[string]any{
+			"message": "backup restored successfully",
+			"data":    data,
+		})
+	}
+}
+
+// writeBackupError is a function that writes the response of a backup error
+func writeBackupError(w http.ResponseWriter, r *http.Request, err error) {
+	switch {
+	case errors.Is(err, internal.ErrBackupNotFound):
+		render(w, r, http.StatusNotFound, map[string]string{"error": err.Error()})
+	case errors.Is(err, internal.ErrBackupCorrupt),
+		errors.Is(err, internal.ErrVehicleOperationInvalid),
+		errors.Is(err, internal.ErrVehicleRegistrationInvalid),
+		errors.Is(err, internal.ErrVehicleVINInvalid),
+		errors.Is(err, internal.ErrVehicleVINMismatch):
+		render(w, r, http.StatusUnprocessableEntity, map[string]string{"error": err.Error()})
+	// - the vehicles of a restore are written through the service, which may reject them
+	case errors.Is(err, internal.ErrVehicleExists),
+		errors.Is(err, internal.ErrVehicleRegistrationExists),
+		errors.Is(err, internal.ErrVehicleVINExists):
+		render(w, r, http.StatusConflict, map[string]string{"error": err.Error()})
+	default:
+		render(w, r, http.StatusInternalServerError, map[string]string{"error": "internal server error"})
+	}
+}
+
+// backupToJSON is a function that converts a backup to its JSON representation
+func backupToJSON(b internal.VehicleBackup) BackupJSON {
+	return BackupJSON{
+		ID:        b.Id,
+		CreatedAt: b.CreatedAt,
+		Vehicles:  b.Vehicles,
+		Size:      b.Size,
+		Checksum:  b.Checksum,
+	}
+}
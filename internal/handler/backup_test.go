@@ -0,0 +1,55 @@
+package handler
+
+import (
+	"app/internal"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// backupServiceStub is a struct that implements the VehicleBackupService interface failing with err
+type backupServiceStub struct {
+	err error
+}
+
+func (s backupServiceStub) Backup() (internal.VehicleBackup, error) {
+	return internal.VehicleBackup{}, s.err
+}
+
+func (s backupServiceStub) Restore(id string) (internal.VehicleBackup, []internal.VehicleOperation, error) {
+	return internal.VehicleBackup{}, nil, s.err
+}
+
+func (s backupServiceStub) List() ([]internal.VehicleBackup, error) {
+	return nil, s.err
+}
+
+func TestBackupDefault_PostRestoreErrors(t *testing.T) {
+	cases := []struct {
+		err  error
+		code int
+	}{
+		{internal.ErrBackupNotFound, http.StatusNotFound},
+		{internal.ErrBackupCorrupt, http.StatusUnprocessableEntity},
+		{internal.ErrVehicleOperationInvalid, http.StatusUnprocessableEntity},
+		{internal.ErrVehicleRegistrationInvalid, http.StatusUnprocessableEntity},
+		{internal.ErrVehicleVINMismatch, http.StatusUnprocessableEntity},
+		{internal.ErrVehicleExists, http.StatusConflict},
+		{internal.ErrVehicleRegistrationExists, http.StatusConflict},
+		{internal.ErrVehicleVINExists, http.StatusConflict},
+		{errors.New("disk full"), http.StatusInternalServerError},
+	}
+
+	for _, c := range cases {
+		t.Run(c.err.Error(), func(t *testing.T) {
+			hd := NewBackupDefault(backupServiceStub{err: fmt.Errorf("%w: vehicle 1", c.err)})
+			res := httptest.NewRecorder()
+			hd.PostRestore()(res, httptest.NewRequest(http.MethodPost, "/backups/x/restore", nil))
+			if res.Code != c.code {
+				t.Errorf("code = %d, want %d", res.Code, c.code)
+			}
+		})
+	}
+}
@@ -0,0 +1,25 @@
+package handler
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// Deprecated is a function that returns a middleware announcing that the routes are deprecated.
+// It sets the Deprecation header to the date the deprecation took effect (RFC 9745), the Sunset
+// header to the date the routes stop working (RFC 8594) and links the successor version.
+func Deprecated(since, sunset time.Time, successor string) func(http.Handler) http.Handler {
+	deprecation := fmt.Sprintf("@%d", since.Unix())
+	link := fmt.Sprintf("<%s>; rel=\"successor-version\"", successor)
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Deprecation", deprecation)
+			if !sunset.IsZero() {
+				w.Header().Set("Sunset", sunset.UTC().Format(http.TimeFormat))
+			}
+			w.Header().Add("Link", link)
+			next.ServeHTTP(w, r)
+		})
+	}
+}
@@ -0,0 +1,160 @@
+package handler
+
+import (
+	"app/internal"
+	"errors"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// NormalizationEntryJSON is a struct that represents an entry of a normalization dictionary in JSON format
+type NormalizationEntryJSON struct {
+	Canonical string   `json:"canonical"`
+	Aliases   []string `json:"aliases"`
+}
+
+// NewNormalizationDefault is a function that returns a new instance of NormalizationDefault
+func NewNormalizationDefault(sv internal.VehicleNormalizationService) *NormalizationDefault {
+	return &NormalizationDefault{sv: sv}
+}
+
+// NormalizationDefault is a struct that represents the handler of the brand and color normalization dictionaries
+type NormalizationDefault struct {
+	// sv is the normalization service
+	sv internal.VehicleNormalizationService
+}
+
+// GetAll is a method that returns the entries of every dictionary keyed by field
+func (h *NormalizationDefault) GetAll() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		data := make(map[string][]NormalizationEntryJSON, len(internal.VehicleNormalizationFields))
+		for _, field := range internal.VehicleNormalizationFields {
+			entries, err := h.sv.Entries(field)
+			if err != nil {
+				writeNormalizationError(w, r, err)
+				return
+			}
+			data[field] = normalizationEntriesToJSON(entries)
+		}
+		render(w, r, http.StatusOK, map[string]any{
+			"message": "success",
+			"data":    data,
+		})
+	}
+}
+
+// GetByField is a method that returns the entries of the dictionary of a field
+func (h *NormalizationDefault) GetByField() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		entries, err := h.sv.Entries(chi.URLParam(r, "field"))
+		if err != nil {
+			writeNormalizationError(w, r, err)
+			return
+		}
+		render(w, r, http.StatusOK, map[string]any{
+			"message": "success",
+			"data":    normalizationEntriesToJSON(entries),
+		})
+	}
+}
+
+// PutEntry is a method that creates or replaces the entry of the canonical value of the path with the aliases of the body.
+// The stored vehicles keep their values until they are normalized again with PostApply.
+func (h *NormalizationDefault) PutEntry() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var req struct {
+			Aliases []string `json:"aliases"`
+		}
+		if err := decode(r, &req); err != nil {
+			render(w, r, http.StatusBadRequest, map[string]string{"error": "invalid body"})
+			return
+		}
+
+		field, canonical := chi.URLParam(r, "field"), strings.TrimSpace(pathParam(r, "canonical"))
+		if err := h.sv.SetEntry(field, internal.VehicleNormalizationEntry{Canonical: canonical, Aliases: req.Aliases}); err != nil {
+			writeNormalizationError(w, r, err)
+			return
+		}
+		// - the stored entry, with its aliases cleaned up
+		entries, err := h.sv.Entries(field)
+		if err != nil {
+			writeNormalizationError(w, r, err)
+			return
+		}
+		data := NormalizationEntryJSON{Canonical: canonical, Aliases: req.Aliases}
+		for _, e := range entries {
+			if e.Canonical == canonical {
+				data = normalizationEntriesToJSON([]internal.VehicleNormalizationEntry{e})[0]
+			}
+		}
+		render(w, r, http.StatusOK, map[string]any{
+			"message": "normalization entry saved",
+			"data":    data,
+		})
+	}
+}
+
+// DeleteEntry is a method that deletes the entry of the canonical value of the path
+func (h *NormalizationDefault) DeleteEntry() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if err := h.sv.DeleteEntry(chi.URLParam(r, "field"), pathParam(r, "canonical")); err != nil {
+			writeNormalizationError(w, r, err)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+// PostApply is a method that normalizes the stored vehicles again with the current dictionaries
+func (h *NormalizationDefault) PostApply() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		n, err := h.sv.Renormalize()
+		if err != nil {
+			writeNormalizationError(w, r, err)
+			return
+		}
+		render(w, r, http.StatusOK, map[string]any{
+			"message": "vehicles normalized",
+			"data":    map[string]int{"updated": n},
+		})
+	}
+}
+
+// writeNormalizationError is a function that writes the response of a normalization error
+func writeNormalizationError(w http.ResponseWriter, r *http.Request, err error) {
+	switch {
+	case errors.Is(err, internal.ErrVehicleNormalizationNotFound):
+		render(w, r, http.StatusNotFound, map[string]string{"error": err.Error()})
+	case errors.Is(err, internal.ErrVehicleNormalizationConflict):
+		render(w, r, http.StatusConflict, map[string]string{"error": err.Error()})
+	case errors.Is(err, internal.ErrVehicleNormalizationInvalid):
+		render(w, r, http.StatusUnprocessableEntity, map[string]string{"error": err.Error()})
+	default:
+		render(w, r, http.StatusInternalServerError, map[string]string{"error": "internal server error"})
+	}
+}
+
+// normalizationEntriesToJSON is a function that converts dictionary entries to their JSON representation
+func normalizationEntriesToJSON(entries []internal.VehicleNormalizationEntry) []NormalizationEntryJSON {
+	data := make([]NormalizationEntryJSON, 0, len(entries))
+	for _, e := range entries {
+		aliases := e.Aliases
+		if aliases == nil {
+			aliases = []string{}
+		}
+		data = append(data, NormalizationEntryJSON{Canonical: e.Canonical, Aliases: aliases})
+	}
+	return data
+}
+
+// pathParam is a function that returns a decoded URL parameter, so values holding an escaped slash are supported
+func pathParam(r *http.Request, name string) string {
+	value := chi.URLParam(r, name)
+	if decoded, err := url.PathUnescape(value); err == nil {
+		return decoded
+	}
+	return value
+}
@@ -0,0 +1,685 @@
+package handler
+
+import (
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"github.com/bootcamp-go/web/response"
+	"github.com/vmihailenco/msgpack/v5"
+	"gopkg.in/yaml.v3"
+)
+
+var (
+	// ErrNotAcceptable is returned when no codec matches the Accept header
+	ErrNotAcceptable = errors.New("handler: not acceptable")
+	// ErrUnsupportedMediaType is returned when no codec matches the Content-Type header
+	ErrUnsupportedMediaType = errors.New("handler: unsupported media type")
+)
+
+// Codec is a struct that represents an encoding of request and response bodies.
+// Every codec works on the JSON representation of a body, so the json tags of the
+// handler structs name the fields in every encoding.
+type Codec struct {
+	// MediaType is the media type written in the Content-Type header
+	MediaType string
+	// Aliases is the list of other media types accepted for the codec
+	Aliases []string
+	// Encode writes a body
+	Encode func(w io.Writer, body any) error
+	// Decode reads a body into ptr
+	Decode func(r io.Reader, ptr any) error
+}
+
+// Codecs is the list of codecs in order of preference, the first one is the default
+var Codecs = []Codec{
+	{
+		MediaType: "application/json",
+		Encode: func(w io.Writer, body any) (err error) {
+			data, err := json.Marshal(body)
+			if err != nil {
+				return
+			}
+			_, err = w.Write(data)
+			return
+		},
+		Decode: func(r io.Reader, ptr any) error { return json.NewDecoder(r).Decode(ptr) },
+	},
+	{
+		MediaType: "application/xml",
+		Aliases:   []string{"text/xml"},
+		Encode:    encodeXML,
+		Decode:    decodeXML,
+	},
+	{
+		MediaType: "application/yaml",
+		Aliases:   []string{"application/x-yaml", "text/yaml", "text/x-yaml"},
+		Encode:    encodeYAML,
+		Decode:    decodeYAML,
+	},
+	{
+		MediaType: "application/msgpack",
+		Aliases:   []string{"application/x-msgpack", "application/vnd.msgpack"},
+		Encode:    encodeMsgpack,
+		Decode:    decodeMsgpack,
+	},
+}
+
+// Negotiate is a middleware that rejects requests whose Accept header matches no codec with 406,
+// requests whose body Content-Type matches no codec with 415 and requests for an unknown unit system with 400
+func Negotiate(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if _, err := responseCodec(r); err != nil {
+			types := make([]string, 0, len(Codecs))
+			for _, c := range Codecs {
+				types = append(types, c.MediaType)
+			}
+			response.JSON(w, http.StatusNotAcceptable, map[string]any{"error": "not acceptable", "supported": types})
+			return
+		}
+		if _, err := unitsOf(r); err != nil {
+			response.JSON(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+			return
+		}
+		if r.ContentLength != 0 && r.Method != http.MethodGet {
+			if _, err := requestCodec(r); err != nil {
+				render(w, r, http.StatusUnsupportedMediaType, map[string]string{"error": err.Error()})
+				return
+			}
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// render is a function that writes the body in the encoding negotiated from the Accept header.
+// It falls back to JSON, the Negotiate middleware rejects the requests it could not serve.
+func render(w http.ResponseWriter, r *http.Request, code int, body any) {
+	if body == nil {
+		w.WriteHeader(code)
+		return
+	}
+	c, err := responseCodec(r)
+	if err != nil {
+		c = Codecs[0]
+	}
+
+	// encode before writing the header so a failure can still change the status code
+	var buf bytes.Buffer
+	if err := c.Encode(&buf, body); err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", c.MediaType)
+	w.Header().Add("Vary", "Accept")
+	w.WriteHeader(code)
+	w.Write(buf.Bytes())
+}
+
+// decode is a function that reads the request body in the encoding of its Content-Type header.
+// A body without Content-Type is read as JSON.
+func decode(r *http.Request, ptr any) (err error) {
+	c, err := requestCodec(r)
+	if err != nil {
+		return
+	}
+	return c.Decode(r.Body, ptr)
+}
+
+// responseCodec is a function that returns the preferred codec of the Accept header
+func responseCodec(r *http.Request) (c Codec, err error) {
+	header := r.Header.Values("Accept")
+	if len(header) == 0 {
+		return Codecs[0], nil
+	}
+
+	// media ranges
+	type mediaRange struct {
+		typ string
+		q   float64
+	}
+	var ranges []mediaRange
+	for _, value := range header {
+		for _, part := range strings.Split(value, ",") {
+			mt, params, err := mime.ParseMediaType(strings.TrimSpace(part))
+			if err != nil {
+				continue
+			}
+			q := 1.0
+			if s, ok := params["q"]; ok {
+				if q, err = strconv.ParseFloat(s, 64); err != nil {
+					continue
+				}
+			}
+			ranges = append(ranges, mediaRange{typ: mt, q: q})
+		}
+	}
+
+	// the codec with the highest quality wins, ties go to the order of Codecs
+	// - a specific media range overrides a wildcard one for the same codec
+	best, bestQ := -1, 0.0
+	for i, codec := range Codecs {
+		q, specificity := 0.0, -1
+		for _, mr := range ranges {
+			if s := matchMediaRange(mr.typ, codec); s > specificity {
+				q, specificity = mr.q, s
+			}
+		}
+		if q > bestQ {
+			best, bestQ = i, q
+		}
+	}
+	if best < 0 {
+		return Codec{}, ErrNotAcceptable
+	}
+	return Codecs[best], nil
+}
+
+// matchMediaRange is a function that returns how specifically a media range matches a codec, -1 for no match
+func matchMediaRange(mr string, c Codec) int {
+	types := append([]string{c.MediaType}, c.Aliases...)
+	for _, t := range types {
+		if mr == t {
+			return 2
+		}
+	}
+	if mr == "*/*" {
+		return 0
+	}
+	for _, t := range types {
+		if prefix, ok := strings.CutSuffix(mr, "/*"); ok && strings.HasPrefix(t, prefix+"/") {
+			return 1
+		}
+	}
+	return -1
+}
+
+// requestCodec is a function that returns the codec of the Content-Type header
+func requestCodec(r *http.Request) (c Codec, err error) {
+	header := r.Header.Get("Content-Type")
+	if header == "" {
+		return Codecs[0], nil
+	}
+	mt, _, err := mime.ParseMediaType(header)
+	if err != nil {
+		return Codec{}, fmt.Errorf("%w: %s", ErrUnsupportedMediaType, header)
+	}
+	for _, codec := range Codecs {
+		if matchMediaRange(mt, codec) == 2 {
+			return codec, nil
+		}
+	}
+	return Codec{}, fmt.Errorf("%w: %s", ErrUnsupportedMediaType, mt)
+}
+
+// object is a JSON object that keeps the order of its members
+type object []member
+
+// member is a member of an object
+type member struct {
+	key   string
+	value any
+}
+
+// toGeneric is a function that returns the JSON representation of a body as objects, slices and scalars
+func toGeneric(body any) (v any, err error) {
+	data, err := json.Marshal(body)
+	if err != nil {
+		return
+	}
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	return readGeneric(dec)
+}
+
+// readGeneric is a function that reads the next JSON value of the decoder
+func readGeneric(dec *json.Decoder) (v any, err error) {
+	tok, err := dec.Token()
+	if err != nil {
+		return
+	}
+	switch t := tok.(type) {
+	case json.Delim:
+		if t == '{' {
+			obj := object{}
+			for dec.More() {
+				var key json.Token
+				if key, err = dec.Token(); err != nil {
+					return
+				}
+				var value any
+				if value, err = readGeneric(dec); err != nil {
+					return
+				}
+				obj = append(obj, member{key: key.(string), value: value})
+			}
+			_, err = dec.Token()
+			return obj, err
+		}
+		list := []any{}
+		for dec.More() {
+			var value any
+			if value, err = readGeneric(dec); err != nil {
+				return
+			}
+			list = append(list, value)
+		}
+		_, err = dec.Token()
+		return list, err
+	case json.Number:
+		if i, err := t.Int64(); err == nil {
+			return i, nil
+		}
+		return t.Float64()
+	default:
+		return t, nil
+	}
+}
+
+// transcode is a function that decodes a body read by another codec into ptr through its JSON encoding
+func transcode(v any, ptr any) (err error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return
+	}
+	return json.Unmarshal(data, ptr)
+}
+
+// encodeYAML is a function that writes a body as a YAML document
+func encodeYAML(w io.Writer, body any) (err error) {
+	v, err := toGeneric(body)
+	if err != nil {
+		return
+	}
+	node, err := yamlNode(v)
+	if err != nil {
+		return
+	}
+	enc := yaml.NewEncoder(w)
+	enc.SetIndent(2)
+	if err = enc.Encode(node); err != nil {
+		return
+	}
+	return enc.Close()
+}
+
+// yamlNode is a function that converts a generic value to a YAML node
+func yamlNode(v any) (n *yaml.Node, err error) {
+	switch t := v.(type) {
+	case object:
+		n = &yaml.Node{Kind: yaml.MappingNode}
+		for _, m := range t {
+			var value *yaml.Node
+			if value, err = yamlNode(m.value); err != nil {
+				return
+			}
+			n.Content = append(n.Content, &yaml.Node{Kind: yaml.ScalarNode, Value: m.key}, value)
+		}
+	case []any:
+		n = &yaml.Node{Kind: yaml.SequenceNode}
+		for _, item := range t {
+			var value *yaml.Node
+			if value, err = yamlNode(item); err != nil {
+				return
+			}
+			n.Content = append(n.Content, value)
+		}
+	default:
+		n = &yaml.Node{}
+		err = n.Encode(t)
+	}
+	return
+}
+
+// decodeYAML is a function that reads a YAML document into ptr
+func decodeYAML(r io.Reader, ptr any) (err error) {
+	var v any
+	if err = yaml.NewDecoder(r).Decode(&v); err != nil {
+		return
+	}
+	return transcode(v, ptr)
+}
+
+// encodeMsgpack is a function that writes a body as MessagePack
+func encodeMsgpack(w io.Writer, body any) (err error) {
+	v, err := toGeneric(body)
+	if err != nil {
+		return
+	}
+	enc := msgpack.NewEncoder(w)
+	enc.UseCompactInts(true)
+	return writeMsgpack(enc, v)
+}
+
+// writeMsgpack is a function that writes a generic value with the MessagePack encoder
+func writeMsgpack(enc *msgpack.Encoder, v any) (err error) {
+	switch t := v.(type) {
+	case object:
+		if err = enc.EncodeMapLen(len(t)); err != nil {
+			return
+		}
+		for _, m := range t {
+			if err = enc.EncodeString(m.key); err != nil {
+				return
+			}
+			if err = writeMsgpack(enc, m.value); err != nil {
+				return
+			}
+		}
+	case []any:
+		if err = enc.EncodeArrayLen(len(t)); err != nil {
+			return
+		}
+		for _, item := range t {
+			if err = writeMsgpack(enc, item); err != nil {
+				return
+			}
+		}
+	default:
+		err = enc.Encode(t)
+	}
+	return
+}
+
+// decodeMsgpack is a function that reads a MessagePack body into ptr
+func decodeMsgpack(r io.Reader, ptr any) (err error) {
+	dec := msgpack.NewDecoder(r)
+	dec.SetMapDecoder(func(d *msgpack.Decoder) (any, error) {
+		return d.DecodeUntypedMap()
+	})
+	v, err := dec.DecodeInterface()
+	if err != nil {
+		return
+	}
+	v, err = stringKeys(v)
+	if err != nil {
+		return
+	}
+	return transcode(v, ptr)
+}
+
+// stringKeys is a function that converts the maps of a decoded MessagePack value to maps with string keys
+func stringKeys(v any) (any, error) {
+	switch t := v.(type) {
+	case map[any]any:
+		m := make(map[string]any, len(t))
+		for key, value := range t {
+			k, ok := key.(string)
+			if !ok {
+				return nil, fmt.Errorf("msgpack: map key %v is not a string", key)
+			}
+			var err error
+			if m[k], err = stringKeys(value); err != nil {
+				return nil, err
+			}
+		}
+		return m, nil
+	case []any:
+		for i, item := range t {
+			var err error
+			if t[i], err = stringKeys(item); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return v, nil
+}
+
+// xmlRoot is the name of the root element of XML bodies
+const xmlRoot = "response"
+
+// xmlItem is the name of the elements of an XML list
+const xmlItem = "item"
+
+// encodeXML is a function that writes a body as an XML document.
+// Object members are elements named after their key, or entry elements with a key attribute
+// when the key is not a valid name, and list elements are named item.
+func encodeXML(w io.Writer, body any) (err error) {
+	v, err := toGeneric(body)
+	if err != nil {
+		return
+	}
+	if _, err = io.WriteString(w, xml.Header); err != nil {
+		return
+	}
+	enc := xml.NewEncoder(w)
+	if err = writeXML(enc, xml.StartElement{Name: xml.Name{Local: xmlRoot}}, v); err != nil {
+		return
+	}
+	return enc.Flush()
+}
+
+// writeXML is a function that writes a generic value as the content of the start element
+func writeXML(enc *xml.Encoder, start xml.StartElement, v any) (err error) {
+	if err = enc.EncodeToken(start); err != nil {
+		return
+	}
+	switch t := v.(type) {
+	case object:
+		for _, m := range t {
+			el := xml.StartElement{Name: xml.Name{Local: m.key}}
+			if !isXMLName(m.key) {
+				el = xml.StartElement{
+					Name: xml.Name{Local: "entry"},
+					Attr: []xml.Attr{{Name: xml.Name{Local: "key"}, Value: m.key}},
+				}
+			}
+			if err = writeXML(enc, el, m.value); err != nil {
+				return
+			}
+		}
+	case []any:
+		for _, item := range t {
+			if err = writeXML(enc, xml.StartElement{Name: xml.Name{Local: xmlItem}}, item); err != nil {
+				return
+			}
+		}
+	case nil:
+	default:
+		if err = enc.EncodeToken(xml.CharData(fmt.Sprint(t))); err != nil {
+			return
+		}
+	}
+	return enc.EncodeToken(start.End())
+}
+
+// isXMLName is a function that reports whether a key can be used as an element name
+func isXMLName(key string) bool {
+	if key == "" || strings.HasPrefix(strings.ToLower(key), "xml") {
+		return false
+	}
+	for i, r := range key {
+		letter := r == '_' || (r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z')
+		if !letter && (i == 0 || !(r == '-' || r == '.' || (r >= '0' && r <= '9'))) {
+			return false
+		}
+	}
+	return true
+}
+
+// xmlElement is a struct that represents a parsed XML element
+type xmlElement struct {
+	name     string
+	key      string
+	text     string
+	children []*xmlElement
+}
+
+// decodeXML is a function that reads an XML document into ptr.
+// The element tree is converted to JSON guided by the type of ptr, so text is read as a number
+// only where the target field is a number. Untyped targets infer numbers and booleans from the text.
+func decodeXML(r io.Reader, ptr any) (err error) {
+	root, err := parseXML(xml.NewDecoder(r))
+	if err != nil {
+		return
+	}
+	// - a body read in a unit system is shaped as its destination
+	t := reflect.TypeOf(ptr)
+	if b, ok := ptr.(*unitsBody); ok {
+		t = reflect.TypeOf(b.ptr)
+	}
+	return transcode(xmlValue(root, t), ptr)
+}
+
+// parseXML is a function that reads the root element of a document
+func parseXML(dec *xml.Decoder) (root *xmlElement, err error) {
+	var stack []*xmlElement
+	for {
+		var tok xml.Token
+		if tok, err = dec.Token(); err != nil {
+			if err == io.EOF && root != nil {
+				err = nil
+			}
+			return
+		}
+		switch t := tok.(type) {
+		case xml.StartElement:
+			el := &xmlElement{name: t.Name.Local}
+			for _, a := range t.Attr {
+				if a.Name.Local == "key" {
+					el.key = a.Value
+				}
+			}
+			if len(stack) > 0 {
+				parent := stack[len(stack)-1]
+				parent.children = append(parent.children, el)
+			} else if root != nil {
+				return nil, errors.New("xml: multiple root elements")
+			} else {
+				root = el
+			}
+			stack = append(stack, el)
+		case xml.EndElement:
+			stack = stack[:len(stack)-1]
+		case xml.CharData:
+			if len(stack) > 0 {
+				stack[len(stack)-1].text += string(t)
+			}
+		}
+	}
+}
+
+// jsonUnmarshaler is the type of the json.Unmarshaler interface
+var jsonUnmarshaler = reflect.TypeOf((*json.Unmarshaler)(nil)).Elem()
+
+// xmlValue is a function that converts an element to a generic JSON value of type t
+func xmlValue(el *xmlElement, t reflect.Type) any {
+	for t != nil && t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	if t == nil || t.Kind() == reflect.Interface || reflect.PointerTo(t).Implements(jsonUnmarshaler) {
+		return xmlInfer(el)
+	}
+	text := strings.TrimSpace(el.text)
+
+	switch t.Kind() {
+	case reflect.Struct:
+		fields := jsonFields(t)
+		m := make(map[string]any)
+		for _, c := range el.children {
+			if ft, ok := fields[c.name]; ok {
+				m[c.name] = xmlValue(c, ft)
+			}
+		}
+		return m
+	case reflect.Slice, reflect.Array:
+		list := make([]any, 0, len(el.children))
+		for _, c := range el.children {
+			list = append(list, xmlValue(c, t.Elem()))
+		}
+		return list
+	case reflect.Map:
+		m := make(map[string]any)
+		for _, c := range el.children {
+			key := c.name
+			if c.key != "" {
+				key = c.key
+			}
+			m[key] = xmlValue(c, t.Elem())
+		}
+		return m
+	case reflect.String:
+		return el.text
+	case reflect.Bool:
+		if text == "" {
+			return nil
+		}
+		if b, err := strconv.ParseBool(text); err == nil {
+			return b
+		}
+		return text
+	default:
+		// numbers
+		if text == "" {
+			return nil
+		}
+		return json.Number(text)
+	}
+}
+
+// xmlInfer is a function that converts an element to a generic JSON value without a target type
+func xmlInfer(el *xmlElement) any {
+	if len(el.children) == 0 {
+		text := strings.TrimSpace(el.text)
+		if f, err := strconv.ParseFloat(text, 64); err == nil && json.Valid([]byte(text)) {
+			return f
+		}
+		if b, err := strconv.ParseBool(text); err == nil && (text == "true" || text == "false") {
+			return b
+		}
+		return el.text
+	}
+	list := true
+	for _, c := range el.children {
+		list = list && c.name == xmlItem
+	}
+	if list {
+		items := make([]any, 0, len(el.children))
+		for _, c := range el.children {
+			items = append(items, xmlInfer(c))
+		}
+		return items
+	}
+	m := make(map[string]any)
+	for _, c := range el.children {
+		key := c.name
+		if c.key != "" {
+			key = c.key
+		}
+		m[key] = xmlInfer(c)
+	}
+	return m
+}
+
+// jsonFields is a function that returns the type of each field of a struct by its JSON name
+func jsonFields(t reflect.Type) map[string]reflect.Type {
+	fields := make(map[string]reflect.Type)
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if !f.IsExported() {
+			continue
+		}
+		name, _, _ := strings.Cut(f.Tag.Get("json"), ",")
+		if name == "-" {
+			continue
+		}
+		if name == "" {
+			if f.Anonymous && f.Type.Kind() == reflect.Struct {
+				for k, v := range jsonFields(f.Type) {
+					fields[k] = v
+				}
+				continue
+			}
+			name = f.Name
+		}
+		fields[name] = f.Type
+	}
+	return fields
+}
@@ -0,0 +1,159 @@
+package handler
+
+import (
+	"app/internal"
+	"bytes"
+	"encoding/json"
+	"mime"
+	"net/http"
+	"reflect"
+	"strings"
+)
+
+// unitsParam is the name of the query parameter, and of the Accept media type parameter,
+// selecting the unit system of a response, e.g. ?units=imperial or Accept: application/json; units=imperial
+const unitsParam = "units"
+
+// unitsOf is a function that returns the unit system of the response to a request,
+// from the units query parameter or else from the units parameter of the Accept header, metric by default.
+// An unknown unit system returns metric along with the error.
+func unitsOf(r *http.Request) (internal.VehicleUnitSystem, error) {
+	if value := r.URL.Query().Get(unitsParam); value != "" {
+		return parseUnits(value)
+	}
+	for _, value := range r.Header.Values("Accept") {
+		for _, part := range strings.Split(value, ",") {
+			if _, params, err := mime.ParseMediaType(strings.TrimSpace(part)); err == nil && params[unitsParam] != "" {
+				return parseUnits(params[unitsParam])
+			}
+		}
+	}
+	return internal.VehicleUnitsMetric, nil
+}
+
+// parseUnits is a function that parses a unit system, falling back to metric on error
+func parseUnits(value string) (s internal.VehicleUnitSystem, err error) {
+	if s, err = internal.ParseVehicleUnitSystem(value); err != nil {
+		s = internal.VehicleUnitsMetric
+	}
+	return
+}
+
+// decodeIn is a function that reads the request body like decode, with the numbers of the fields with a unit
+// in the unit system s instead of the stored unit, e.g. {"max_speed":62} is 62 mph in imperial.
+// A value with a unit keeps its unit, e.g. {"max_speed":"100 km/h"}.
+func decodeIn(r *http.Request, ptr any, s internal.VehicleUnitSystem) error {
+	if s == internal.VehicleUnitsMetric {
+		return decode(r, ptr)
+	}
+	return decode(r, &unitsBody{ptr: ptr, s: s})
+}
+
+// unitsBody is a struct that implements json.Unmarshaler reading a body into ptr in the unit system s.
+// Every codec reads the JSON representation of a body, so it converts the bodies of every encoding.
+type unitsBody struct {
+	// ptr is the destination of the body
+	ptr any
+	// s is the unit system of the numbers of the fields with a unit
+	s internal.VehicleUnitSystem
+}
+
+// UnmarshalJSON is a method that reads data into the destination, see unmarshalIn
+func (b *unitsBody) UnmarshalJSON(data []byte) error {
+	return unmarshalIn(data, b.ptr, b.s)
+}
+
+// unmarshalIn is a function that reads a JSON document into ptr, with the numbers of the fields with a unit,
+// the ones of type VehicleSpeed, VehicleMass or VehicleLength, in the unit system s
+func unmarshalIn(data []byte, ptr any, s internal.VehicleUnitSystem) (err error) {
+	if s == internal.VehicleUnitsMetric {
+		return json.Unmarshal(data, ptr)
+	}
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	var v any
+	if err = dec.Decode(&v); err != nil {
+		return
+	}
+	if data, err = json.Marshal(storedUnits(v, reflect.TypeOf(ptr), s)); err != nil {
+		return
+	}
+	return json.Unmarshal(data, ptr)
+}
+
+// quantityTypes are the types of the fields with a unit and their quantity
+var quantityTypes = map[reflect.Type]internal.VehicleQuantity{
+	reflect.TypeOf(internal.VehicleSpeed(0)):  internal.VehicleQuantitySpeed,
+	reflect.TypeOf(internal.VehicleMass(0)):   internal.VehicleQuantityMass,
+	reflect.TypeOf(internal.VehicleLength(0)): internal.VehicleQuantityLength,
+}
+
+// storedUnits is a function that converts the numbers of the generic JSON value v of type t held by a field
+// with a unit from the unit system s to the stored unit. The other values are returned as they are.
+func storedUnits(v any, t reflect.Type, s internal.VehicleUnitSystem) any {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	if q, ok := quantityTypes[t]; ok {
+		n, ok := v.(json.Number)
+		if !ok {
+			return v
+		}
+		f, err := n.Float64()
+		if err != nil {
+			return v
+		}
+		return s.Stored(f, q)
+	}
+
+	switch t.Kind() {
+	case reflect.Struct:
+		m, ok := v.(map[string]any)
+		if !ok {
+			return v
+		}
+		fields := jsonFields(t)
+		for key, value := range m {
+			if ft, ok := fields[key]; ok {
+				m[key] = storedUnits(value, ft, s)
+			}
+		}
+	case reflect.Slice, reflect.Array:
+		list, ok := v.([]any)
+		if !ok {
+			return v
+		}
+		for i, value := range list {
+			list[i] = storedUnits(value, t.Elem(), s)
+		}
+	case reflect.Map:
+		m, ok := v.(map[string]any)
+		if !ok {
+			return v
+		}
+		for key, value := range m {
+			m[key] = storedUnits(value, t.Elem(), s)
+		}
+	}
+	return v
+}
+
+// VehicleUnitsJSON is a struct that represents the unit of each field with a unit of a vehicle in JSON format
+type VehicleUnitsJSON struct {
+	MaxSpeed string `json:"max_speed"`
+	Weight   string `json:"weight"`
+	Height   string `json:"height"`
+	Length   string `json:"length"`
+	Width    string `json:"width"`
+}
+
+// vehicleUnitsToJSON is a function that returns the units of the fields of a vehicle in the unit system
+func vehicleUnitsToJSON(s internal.VehicleUnitSystem) VehicleUnitsJSON {
+	return VehicleUnitsJSON{
+		MaxSpeed: s.Unit(internal.VehicleQuantitySpeed).Symbol,
+		Weight:   s.Unit(internal.VehicleQuantityMass).Symbol,
+		Height:   s.Unit(internal.VehicleQuantityLength).Symbol,
+		Length:   s.Unit(internal.VehicleQuantityLength).Symbol,
+		Width:    s.Unit(internal.VehicleQuantityLength).Symbol,
+	}
+}
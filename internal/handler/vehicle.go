@@ -3,29 +3,51 @@ package handler
 import (
 	"app/internal"
 	"encoding/json"
+	"errors"
+	"fmt"
 	"net/http"
 	"strconv"
 	"strings"
 
-	"github.com/bootcamp-go/web/response"
 	"github.com/go-chi/chi/v5"
 )
 
-type VehicleJSON struct {
-	ID              int     `json:"id"`
-	Brand           string  `json:"brand"`
-	Model           string  `json:"model"`
-	Registration    string  `json:"registration"`
-	Color           string  `json:"color"`
-	FabricationYear int     `json:"year"`
-	Capacity        int     `json:"passengers"`
-	MaxSpeed        float64 `json:"max_speed"`
-	FuelType        string  `json:"fuel_type"`
-	Transmission    string  `json:"transmission"`
-	Weight          float64 `json:"weight"`
-	Height          float64 `json:"height"`
-	Length          float64 `json:"length"`
-	Width           float64 `json:"width"`
+const (
+	// batchModeAtomic applies every operation of a batch or none
+	batchModeAtomic = "atomic"
+	// batchModeBestEffort applies the valid operations of a batch and reports the others
+	batchModeBestEffort = "best_effort"
+)
+
+// batchStatus is the status reported for each applied operation type
+var batchStatus = map[internal.VehicleOperationType]string{
+	internal.VehicleOperationCreate: "created",
+	internal.VehicleOperationUpdate: "updated",
+	internal.VehicleOperationDelete: "deleted",
+}
+
+// BatchOperationJSON is a struct that represents an operation of a batch in JSON format
+// - id defaults to vehicle.id, delete only needs the id, a create or an update without a vehicle fails
+type BatchOperationJSON struct {
+	Op      string                `json:"op"`
+	ID      int                   `json:"id"`
+	Vehicle *internal.VehicleJSON `json:"vehicle"`
+}
+
+// BatchRequestJSON is a struct that represents a batch of mixed operations in JSON format
+type BatchRequestJSON struct {
+	Mode       string               `json:"mode"`
+	Operations []BatchOperationJSON `json:"operations"`
+}
+
+// BatchResultJSON is a struct that represents the outcome of an operation of a batch in JSON format
+// - status is created, updated, deleted, failed or skipped (valid but not applied in an aborted batch)
+type BatchResultJSON struct {
+	Index  int    `json:"index"`
+	Op     string `json:"op"`
+	ID     int    `json:"id"`
+	Status string `json:"status"`
+	Error  string `json:"error,omitempty"`
 }
 
 func NewVehicleDefault(sv internal.VehicleService) *VehicleDefault {
@@ -36,84 +58,85 @@ type VehicleDefault struct {
 	sv internal.VehicleService
 }
 
+// GetAll is a method that returns the vehicles matching the filter query parameters, keyed by id
 func (h *VehicleDefault) GetAll() http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
-		v, err := h.sv.FindAll()
+		us, _ := unitsOf(r)
+		f, err := vehicleFilterFromQuery(r.URL.Query(), us)
 		if err != nil {
-			response.JSON(w, http.StatusInternalServerError, nil)
+			render(w, r, http.StatusBadRequest, map[string]string{"error": err.Error()})
+			return
+		}
+		v, err := h.sv.FindByFilter(f)
+		if err != nil {
+			render(w, r, http.StatusInternalServerError, nil)
 			return
 		}
 
 		// response
-		data := make(map[int]VehicleJSON)
-		for key, value := range v {
-			data[key] = VehicleJSON{
-				ID:              value.Id,
-				Brand:           value.Brand,
-				Model:           value.Model,
-				Registration:    value.Registration,
-				Color:           value.Color,
-				FabricationYear: value.FabricationYear,
-				Capacity:        value.Capacity,
-				MaxSpeed:        value.MaxSpeed,
-				FuelType:        value.FuelType,
-				Transmission:    value.Transmission,
-				Weight:          value.Weight,
-				Height:          value.Height,
-				Length:          value.Length,
-				Width:           value.Width,
-			}
+		data := make(map[int]internal.VehicleJSON)
+		for _, value := range v {
+			data[value.Id] = vehicleToJSON(value, us)
 		}
-		response.JSON(w, http.StatusOK, map[string]any{
+		render(w, r, http.StatusOK, map[string]any{
 			"message": "success",
 			"data":    data,
+			"units":   vehicleUnitsToJSON(us),
 		})
 	}
 }
 
 func (h *VehicleDefault) PostCreate() http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
-		var req VehicleJSON
+		us, _ := unitsOf(r)
+		var req internal.VehicleJSON
 
-		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-			response.JSON(w, http.StatusBadRequest, map[string]string{"error": "invalid JSON"})
+		if err := decodeIn(r, &req, us); err != nil {
+			render(w, r, http.StatusBadRequest, map[string]string{"error": "invalid body"})
 			return
 		}
 		v := internal.Vehicle{
-			Id: req.ID,
+			Id: req.Id,
 			VehicleAttributes: internal.VehicleAttributes{
 				Brand:           req.Brand,
 				Model:           req.Model,
 				Registration:    req.Registration,
+				VIN:             req.VIN,
 				Color:           req.Color,
 				FabricationYear: req.FabricationYear,
 				Capacity:        req.Capacity,
-				MaxSpeed:        req.MaxSpeed,
+				MaxSpeed:        float64(req.MaxSpeed),
 				FuelType:        req.FuelType,
 				Transmission:    req.Transmission,
-				Weight:          req.Weight,
+				Weight:          float64(req.Weight),
 				Dimensions: internal.Dimensions{
-					Height: req.Height,
-					Length: req.Length,
-					Width:  req.Width,
+					Height: float64(req.Height),
+					Length: float64(req.Length),
+					Width:  float64(req.Width),
 				},
 			},
 		}
 
 		err := h.sv.Create(v)
 		if err != nil {
-			if strings.Contains(err.Error(), "identifier of the existing vehicle") {
-				response.JSON(w, http.StatusConflict, map[string]string{
+			if errors.Is(err, internal.ErrVehicleRegistrationInvalid) || errors.Is(err, internal.ErrVehicleVINInvalid) || errors.Is(err, internal.ErrVehicleVINMismatch) {
+				render(w, r, http.StatusUnprocessableEntity, map[string]string{
+					"error": err.Error(),
+				})
+				return
+			}
+			if errors.Is(err, internal.ErrVehicleExists) || errors.Is(err, internal.ErrVehicleRegistrationExists) || errors.Is(err, internal.ErrVehicleVINExists) {
+				render(w, r, http.StatusConflict, map[string]string{
 					"error": err.Error(),
 				})
 				return
 			}
-			response.JSON(w, http.StatusBadRequest, map[string]string{
+			render(w, r, http.StatusBadRequest, map[string]string{
 				"error": err.Error(),
 			})
 			return
 		}
-		response.JSON(w, http.StatusCreated, map[string]string{
+		render(w, r, http.StatusCreated, map[string]string{
 			"message": "vehicle created successfully",
 		})
 	}
@@ -126,38 +149,25 @@ func (h *VehicleDefault) GetByColorAndYear() http.HandlerFunc {
 
 		year, err := strconv.Atoi(yearStr)
 		if err != nil {
-			response.JSON(w, http.StatusBadRequest, map[string]string{"error": "invalid formated year"})
+			render(w, r, http.StatusBadRequest, map[string]string{"error": "invalid formated year"})
 			return
 		}
 
 		vehicles, err := h.sv.FindByColorAndYear(color, year)
 		if err != nil {
-			response.JSON(w, http.StatusNotFound, map[string]string{"error": "vehicle not found"})
+			render(w, r, http.StatusNotFound, map[string]string{"error": "vehicle not found"})
 		}
 
-		var data []VehicleJSON
+		us, _ := unitsOf(r)
+		var data []internal.VehicleJSON
 		for _, value := range vehicles {
-			data = append(data, VehicleJSON{
-				ID:              value.Id,
-				Brand:           value.Brand,
-				Model:           value.Model,
-				Registration:    value.Registration,
-				Color:           value.Color,
-				FabricationYear: value.FabricationYear,
-				Capacity:        value.Capacity,
-				MaxSpeed:        value.MaxSpeed,
-				FuelType:        value.FuelType,
-				Transmission:    value.Transmission,
-				Weight:          value.Weight,
-				Height:          value.Height,
-				Length:          value.Length,
-				Width:           value.Width,
-			})
+			data = append(data, vehicleToJSON(value, us))
 		}
 
-		response.JSON(w, http.StatusOK, map[string]any{
+		render(w, r, http.StatusOK, map[string]any{
 			"message": "success",
 			"data":    data,
+			"units":   vehicleUnitsToJSON(us),
 		})
 	}
 }
@@ -168,14 +178,14 @@ func (h *VehicleDefault) DeleteById() http.HandlerFunc {
 
 		id, err := strconv.Atoi(idStr)
 		if err != nil {
-			response.JSON(w, http.StatusBadRequest, map[string]any{
+			render(w, r, http.StatusBadRequest, map[string]any{
 				"error": "invalid ID",
 			})
 			return
 		}
 		err = h.sv.Delete(id)
 		if err != nil {
-			response.JSON(w, http.StatusNotFound, map[string]any{
+			render(w, r, http.StatusNotFound, map[string]any{
 				"error": err.Error(),
 			})
 			return
@@ -191,30 +201,31 @@ func (h *VehicleDefault) PutUpdateSpeed() http.HandlerFunc {
 		id, err := strconv.Atoi(idStr)
 
 		if err != nil {
-			response.JSON(w, http.StatusBadRequest, map[string]any{
+			render(w, r, http.StatusBadRequest, map[string]any{
 				"error": "invalid ID",
 			})
 			return
 		}
 
+		us, _ := unitsOf(r)
 		var body struct {
-			MaxSpeed float64 `json:"max_speed"`
+			MaxSpeed internal.VehicleSpeed `json:"max_speed"`
 		}
 
-		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
-			response.JSON(w, http.StatusBadRequest, map[string]string{"error": "invalid JSON"})
+		if err := decodeIn(r, &body, us); err != nil {
+			render(w, r, http.StatusBadRequest, map[string]string{"error": "invalid body"})
 			return
 		}
 
-		err = h.sv.UpdateSpeed(id, body.MaxSpeed)
+		err = h.sv.UpdateSpeed(id, float64(body.MaxSpeed))
 		if err != nil {
-			response.JSON(w, http.StatusNotFound, map[string]string{
+			render(w, r, http.StatusNotFound, map[string]string{
 				"error": err.Error(),
 			})
 			return
 		}
 
-		response.JSON(w, http.StatusOK, map[string]any{
+		render(w, r, http.StatusOK, map[string]any{
 			"message": "max speed update sucessfully",
 		})
 
@@ -228,7 +239,7 @@ func (h *VehicleDefault) UpdateFuelType() http.HandlerFunc {
 		id, err := strconv.Atoi(idStr)
 
 		if err != nil {
-			response.JSON(w, http.StatusBadRequest, map[string]any{
+			render(w, r, http.StatusBadRequest, map[string]any{
 				"error": "invalid ID",
 			})
 			return
@@ -238,19 +249,19 @@ func (h *VehicleDefault) UpdateFuelType() http.HandlerFunc {
 			FuelType string `json:"fuel_type"`
 		}
 
-		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
-			response.JSON(w, http.StatusBadRequest, map[string]string{"error": "invalid JSON"})
+		if err := decode(r, &body); err != nil {
+			render(w, r, http.StatusBadRequest, map[string]string{"error": "invalid body"})
 			return
 		}
 
 		err = h.sv.UpdateFuelType(id, body.FuelType)
 		if err != nil {
-			response.JSON(w, http.StatusNotFound, map[string]string{
+			render(w, r, http.StatusNotFound, map[string]string{
 				"error": err.Error(),
 			})
 			return
 		}
-		response.JSON(w, http.StatusOK, map[string]string{
+		render(w, r, http.StatusOK, map[string]string{
 			"message": "sucess update fueltype",
 		})
 	}
@@ -262,33 +273,20 @@ func (h *VehicleDefault) GetByFuelType() http.HandlerFunc {
 
 		vehicles, err := h.sv.FindByFuelType(fuelType)
 		if err != nil {
-			response.JSON(w, http.StatusNotFound, map[string]string{"error": "vehicle not found"})
+			render(w, r, http.StatusNotFound, map[string]string{"error": "vehicle not found"})
 			return
 		}
 
-		var data []VehicleJSON
+		us, _ := unitsOf(r)
+		var data []internal.VehicleJSON
 		for _, value := range vehicles {
-			data = append(data, VehicleJSON{
-				ID:              value.Id,
-				Brand:           value.Brand,
-				Model:           value.Model,
-				Registration:    value.Registration,
-				Color:           value.Color,
-				FabricationYear: value.FabricationYear,
-				Capacity:        value.Capacity,
-				MaxSpeed:        value.MaxSpeed,
-				FuelType:        value.FuelType,
-				Transmission:    value.Transmission,
-				Weight:          value.Weight,
-				Height:          value.Height,
-				Length:          value.Length,
-				Width:           value.Width,
-			})
+			data = append(data, vehicleToJSON(value, us))
 		}
 
-		response.JSON(w, http.StatusOK, map[string]any{
+		render(w, r, http.StatusOK, map[string]any{
 			"message": "success",
 			"data":    data,
+			"units":   vehicleUnitsToJSON(us),
 		})
 	}
 }
@@ -299,86 +297,133 @@ func (h *VehicleDefault) GetByTransmissionType() http.HandlerFunc {
 
 		vehicles, err := h.sv.FindByTransmissionType(transmission)
 		if err != nil {
-			response.JSON(w, http.StatusNotFound, map[string]string{"error": "vehicle not found"})
+			render(w, r, http.StatusNotFound, map[string]string{"error": "vehicle not found"})
 			return
 		}
 
-		var data []VehicleJSON
+		us, _ := unitsOf(r)
+		var data []internal.VehicleJSON
 		for _, value := range vehicles {
-			data = append(data, VehicleJSON{
-				ID:              value.Id,
-				Brand:           value.Brand,
-				Model:           value.Model,
-				Registration:    value.Registration,
-				Color:           value.Color,
-				FabricationYear: value.FabricationYear,
-				Capacity:        value.Capacity,
-				MaxSpeed:        value.MaxSpeed,
-				FuelType:        value.FuelType,
-				Transmission:    value.Transmission,
-				Weight:          value.Weight,
-				Height:          value.Height,
-				Length:          value.Length,
-				Width:           value.Width,
-			})
+			data = append(data, vehicleToJSON(value, us))
 		}
 
-		response.JSON(w, http.StatusOK, map[string]any{
+		render(w, r, http.StatusOK, map[string]any{
 			"message": "success",
 			"data":    data,
+			"units":   vehicleUnitsToJSON(us),
 		})
 	}
 }
 
+// PostCreateBatch is a method that applies a batch of operations.
+// The body is either a list of vehicles to create or an envelope of mixed operations:
+// {"mode":"atomic|best_effort","operations":[{"op":"create|update|delete","id":1,"vehicle":{...}}]}
+// The mode query parameter overrides the envelope mode, the default is atomic.
 func (h *VehicleDefault) PostCreateBatch() http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
-		var req []VehicleJSON
-		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-			response.JSON(w, http.StatusBadRequest, map[string]string{
-				"error": "invalid JSON",
+		us, _ := unitsOf(r)
+		var raw json.RawMessage
+		if err := decode(r, &raw); err != nil {
+			render(w, r, http.StatusBadRequest, map[string]string{
+				"error": "invalid body",
 			})
 			return
 		}
-		var vehicles []internal.Vehicle
-		for _, item := range req {
-			vehicles = append(vehicles, internal.Vehicle{
-				Id: item.ID,
-				VehicleAttributes: internal.VehicleAttributes{
-					Brand:           item.Brand,
-					Model:           item.Model,
-					Registration:    item.Registration,
-					Color:           item.Color,
-					FabricationYear: item.FabricationYear,
-					Capacity:        item.Capacity,
-					MaxSpeed:        item.MaxSpeed,
-					FuelType:        item.FuelType,
-					Transmission:    item.Transmission,
-					Weight:          item.Weight,
-					Dimensions: internal.Dimensions{
-						Height: item.Height,
-						Length: item.Length,
-						Width:  item.Width,
-					},
-				},
-			})
-		}
-		err := h.sv.CreateBatch(vehicles)
-		if err != nil {
-			if strings.Contains(err.Error(), "already exists") {
-				response.JSON(w, http.StatusConflict, map[string]string{
-					"error": err.Error(),
-				})
-			} else {
-				response.JSON(w, http.StatusBadRequest, map[string]string{
-					"error": err.Error(),
-				})
+
+		// request
+		var req BatchRequestJSON
+		legacy := len(raw) > 0 && raw[0] == '['
+		if legacy {
+			var items []internal.VehicleJSON
+			if err := unmarshalIn(raw, &items, us); err != nil {
+				render(w, r, http.StatusBadRequest, map[string]string{"error": "invalid body"})
+				return
+			}
+			for _, item := range items {
+				vh := item
+				req.Operations = append(req.Operations, BatchOperationJSON{Op: string(internal.VehicleOperationCreate), Vehicle: &vh})
 			}
+		} else if err := unmarshalIn(raw, &req, us); err != nil {
+			render(w, r, http.StatusBadRequest, map[string]string{"error": "invalid body"})
+			return
+		}
+		if mode := r.URL.Query().Get("mode"); mode != "" {
+			req.Mode = mode
+		}
+		if req.Mode == "" {
+			req.Mode = batchModeAtomic
+		}
+		if req.Mode != batchModeAtomic && req.Mode != batchModeBestEffort {
+			render(w, r, http.StatusBadRequest, map[string]string{"error": "invalid mode"})
 			return
 		}
 
-		response.JSON(w, http.StatusCreated, map[string]string{
-			"message": "vehicles created sucessfuly",
+		// - only a delete may be given by id alone, a create or an update without a vehicle fails
+		// instead of writing a zero valued vehicle
+		ops := make([]internal.VehicleOperation, 0, len(req.Operations))
+		invalid := make([]error, len(req.Operations))
+		for i, item := range req.Operations {
+			var vh internal.VehicleJSON
+			if item.Vehicle != nil {
+				vh = *item.Vehicle
+			}
+			if item.ID != 0 {
+				vh.Id = item.ID
+			}
+			op := internal.VehicleOperationType(item.Op)
+			if item.Vehicle == nil && (op == internal.VehicleOperationCreate || op == internal.VehicleOperationUpdate) {
+				invalid[i] = fmt.Errorf("%w: %s needs a vehicle", internal.ErrVehicleOperationInvalid, op)
+			}
+			ops = append(ops, internal.VehicleOperation{Type: op, Vehicle: vehicleFromJSON(vh)})
+		}
+
+		// process
+		results, err := internal.ApplyCheckedVehicleBatch(h.sv, ops, req.Mode == batchModeAtomic, func(i int, _ *internal.VehicleOperation) error {
+			return invalid[i]
 		})
+		if err != nil && !errors.Is(err, internal.ErrVehicleBatchAborted) {
+			render(w, r, http.StatusInternalServerError, map[string]string{
+				"error": err.Error(),
+			})
+			return
+		}
+
+		// response
+		data := make([]BatchResultJSON, 0, len(results))
+		var applied, failed int
+		for i, res := range results {
+			item := BatchResultJSON{Index: i, Op: string(res.Type), ID: res.Vehicle.Id}
+			switch {
+			case res.Applied:
+				applied++
+				item.Status = batchStatus[res.Type]
+			case res.Err != nil:
+				failed++
+				item.Status = "failed"
+				item.Error = res.Err.Error()
+			default:
+				item.Status = "skipped"
+			}
+			data = append(data, item)
+		}
+		body := map[string]any{
+			"mode":    req.Mode,
+			"applied": applied,
+			"failed":  failed,
+			"data":    data,
+		}
+		code := http.StatusOK
+		switch {
+		case errors.Is(err, internal.ErrVehicleBatchAborted):
+			code, body["message"] = http.StatusConflict, "batch aborted, no operation applied"
+		case failed > 0:
+			code, body["message"] = http.StatusMultiStatus, "batch partially applied"
+		case legacy:
+			code, body["message"] = http.StatusCreated, "vehicles created sucessfuly"
+		default:
+			body["message"] = "batch applied successfully"
+		}
+		render(w, r, code, body)
 	}
 
 }
@@ -391,49 +436,36 @@ func (h *VehicleDefault) GetByBrandAndBetweenYear() http.HandlerFunc {
 
 		start, err := strconv.Atoi(startStr)
 		if err != nil {
-			response.JSON(w, http.StatusBadRequest, map[string]string{
+			render(w, r, http.StatusBadRequest, map[string]string{
 				"error": "invalid start year",
 			})
 		}
 
 		end, err := strconv.Atoi(endStr)
 		if err != nil {
-			response.JSON(w, http.StatusBadRequest, map[string]string{
+			render(w, r, http.StatusBadRequest, map[string]string{
 				"error": "invalid end year",
 			})
 		}
 
 		vehicles, err := h.sv.FindByBrandAndBetweenYear(brand, start, end)
 		if err != nil {
-			response.JSON(w, http.StatusNotFound, map[string]string{
+			render(w, r, http.StatusNotFound, map[string]string{
 				"error": err.Error(),
 			})
 			return
 		}
 
-		var data []VehicleJSON
+		us, _ := unitsOf(r)
+		var data []internal.VehicleJSON
 		for _, v := range vehicles {
-			data = append(data, VehicleJSON{
-				ID:              v.Id,
-				Brand:           v.Brand,
-				Model:           v.Model,
-				Registration:    v.Registration,
-				Color:           v.Color,
-				FabricationYear: v.FabricationYear,
-				Capacity:        v.Capacity,
-				MaxSpeed:        v.MaxSpeed,
-				FuelType:        v.FuelType,
-				Transmission:    v.Transmission,
-				Weight:          v.Weight,
-				Height:          v.Dimensions.Height,
-				Length:          v.Dimensions.Length,
-				Width:           v.Dimensions.Width,
-			})
+			data = append(data, vehicleToJSON(v, us))
 		}
 
-		response.JSON(w, http.StatusOK, map[string]any{
+		render(w, r, http.StatusOK, map[string]any{
 			"message": "sucess",
 			"data":    data,
+			"units":   vehicleUnitsToJSON(us),
 		})
 
 	}
@@ -445,39 +477,26 @@ func (h *VehicleDefault) GetById() http.HandlerFunc {
 
 		id, err := strconv.Atoi(idStr)
 		if err != nil {
-			response.JSON(w, http.StatusBadRequest, map[string]string{
+			render(w, r, http.StatusBadRequest, map[string]string{
 				"error": "invalid ID",
 			})
 		}
 		vehicles, err := h.sv.FindById(id)
 		if err != nil {
-			response.JSON(w, http.StatusBadRequest, map[string]string{
+			render(w, r, http.StatusBadRequest, map[string]string{
 				"error": err.Error(),
 			})
 			return
 		}
-		var data []VehicleJSON
+		us, _ := unitsOf(r)
+		var data []internal.VehicleJSON
 		for _, v := range vehicles {
-			data = append(data, VehicleJSON{
-				ID:              v.Id,
-				Brand:           v.Brand,
-				Model:           v.Model,
-				Registration:    v.Registration,
-				Color:           v.Color,
-				FabricationYear: v.FabricationYear,
-				Capacity:        v.Capacity,
-				MaxSpeed:        v.MaxSpeed,
-				FuelType:        v.FuelType,
-				Transmission:    v.Transmission,
-				Weight:          v.Weight,
-				Height:          v.Dimensions.Height,
-				Length:          v.Dimensions.Length,
-				Width:           v.Dimensions.Width,
-			})
+			data = append(data, vehicleToJSON(v, us))
 		}
-		response.JSON(w, http.StatusOK, map[string]any{
+		render(w, r, http.StatusOK, map[string]any{
 			"message": "sucess",
 			"data":    data,
+			"units":   vehicleUnitsToJSON(us),
 		})
 
 	}
@@ -489,14 +508,16 @@ func (h *VehicleDefault) GetByBrandAverageSpeed() http.HandlerFunc {
 
 		avg, err := h.sv.FindByBrandAverageSpeed(brand)
 		if err != nil {
-			response.JSON(w, http.StatusNotFound, map[string]string{
+			render(w, r, http.StatusNotFound, map[string]string{
 				"error": err.Error(),
 			})
 			return
 		}
-		response.JSON(w, http.StatusOK, map[string]any{
+		us, _ := unitsOf(r)
+		render(w, r, http.StatusOK, map[string]any{
 			"message":           "sucess",
-			"avarage_max_speed": avg,
+			"avarage_max_speed": us.Convert(avg, internal.VehicleQuantitySpeed),
+			"units":             map[string]string{"avarage_max_speed": us.Unit(internal.VehicleQuantitySpeed).Symbol},
 		})
 	}
 }
@@ -507,14 +528,14 @@ func (h *VehicleDefault) GetByBrandAverageCapacity() http.HandlerFunc {
 
 		avg, err := h.sv.FindByBrandAverageCapacity(brand)
 		if err != nil {
-			response.JSON(w, http.StatusNotFound, map[string]string{
+			render(w, r, http.StatusNotFound, map[string]string{
 				"error": err.Error(),
 			})
 			return
 		}
-		response.JSON(w, http.StatusOK, map[string]any{
+		render(w, r, http.StatusOK, map[string]any{
 			"message":              "sucess",
-			"avarage_max_capacity": avg,
+			"avarage_max_capacity": int(avg),
 		})
 	}
 }
@@ -526,7 +547,7 @@ func (h *VehicleDefault) GetByDimensions() http.HandlerFunc {
 		width := strings.Split(r.URL.Query().Get("width"), "-")
 
 		if len(length) != 2 || len(width) != 2 {
-			response.JSON(w, http.StatusBadRequest, map[string]string{"error": "invalid format"})
+			render(w, r, http.StatusBadRequest, map[string]string{"error": "invalid format"})
 			return
 		}
 
@@ -535,33 +556,23 @@ func (h *VehicleDefault) GetByDimensions() http.HandlerFunc {
 		widthMin, _ := strconv.ParseFloat(width[0], 64)
 		widthMax, _ := strconv.ParseFloat(width[1], 64)
 
+		// - the bounds are in the unit system of the request
+		us, _ := unitsOf(r)
+		lengthMin, lengthMax = us.Stored(lengthMin, internal.VehicleQuantityLength), us.Stored(lengthMax, internal.VehicleQuantityLength)
+		widthMin, widthMax = us.Stored(widthMin, internal.VehicleQuantityLength), us.Stored(widthMax, internal.VehicleQuantityLength)
+
 		vehicles, err := h.sv.FindByDimensions(lengthMin, lengthMax, widthMin, widthMax)
 		if err != nil {
-			response.JSON(w, http.StatusNotFound, map[string]string{"error": err.Error()})
+			render(w, r, http.StatusNotFound, map[string]string{"error": err.Error()})
 			return
 		}
 
-		var data []VehicleJSON
+		var data []internal.VehicleJSON
 		for _, v := range vehicles {
-			data = append(data, VehicleJSON{
-				ID:              v.Id,
-				Brand:           v.Brand,
-				Model:           v.Model,
-				Registration:    v.Registration,
-				Color:           v.Color,
-				FabricationYear: v.FabricationYear,
-				Capacity:        v.Capacity,
-				MaxSpeed:        v.MaxSpeed,
-				FuelType:        v.FuelType,
-				Transmission:    v.Transmission,
-				Weight:          v.Weight,
-				Height:          v.Height,
-				Length:          v.Length,
-				Width:           v.Width,
-			})
+			data = append(data, vehicleToJSON(v, us))
 		}
 
-		response.JSON(w, http.StatusOK, map[string]any{"message": "success", "data": data})
+		render(w, r, http.StatusOK, map[string]any{"message": "success", "data": data, "units": vehicleUnitsToJSON(us)})
 	}
 }
 
@@ -573,33 +584,22 @@ func (h *VehicleDefault) GetByWeightRange() http.HandlerFunc {
 		min, _ := strconv.ParseFloat(minStr, 64)
 		max, _ := strconv.ParseFloat(maxStr, 64)
 
+		// - the bounds are in the unit system of the request
+		us, _ := unitsOf(r)
+		min, max = us.Stored(min, internal.VehicleQuantityMass), us.Stored(max, internal.VehicleQuantityMass)
+
 		vehicles, err := h.sv.FindByWeight(min, max)
 		if err != nil {
-			response.JSON(w, http.StatusNotFound, map[string]string{"error": err.Error()})
+			render(w, r, http.StatusNotFound, map[string]string{"error": err.Error()})
 			return
 		}
 
-		var data []VehicleJSON
+		var data []internal.VehicleJSON
 		for _, v := range vehicles {
-			data = append(data, VehicleJSON{
-				ID:              v.Id,
-				Brand:           v.Brand,
-				Model:           v.Model,
-				Registration:    v.Registration,
-				Color:           v.Color,
-				FabricationYear: v.FabricationYear,
-				Capacity:        v.Capacity,
-				MaxSpeed:        v.MaxSpeed,
-				FuelType:        v.FuelType,
-				Transmission:    v.Transmission,
-				Weight:          v.Weight,
-				Height:          v.Height,
-				Length:          v.Length,
-				Width:           v.Width,
-			})
+			data = append(data, vehicleToJSON(v, us))
 		}
 
-		response.JSON(w, http.StatusOK, map[string]any{"message": "success", "data": data})
+		render(w, r, http.StatusOK, map[string]any{"message": "success", "data": data, "units": vehicleUnitsToJSON(us)})
 	}
 }
 
@@ -609,33 +609,31 @@ func (h *VehicleDefault) GetByColor() http.HandlerFunc {
 
 		vehicles, err := h.sv.FindByColor(color)
 		if err != nil {
-			response.JSON(w, http.StatusNotFound, map[string]string{"error": "vehicle not found"})
+			render(w, r, http.StatusNotFound, map[string]string{"error": "vehicle not found"})
 		}
 
-		var data []VehicleJSON
+		us, _ := unitsOf(r)
+		var data []internal.VehicleJSON
 		for _, value := range vehicles {
-			data = append(data, VehicleJSON{
-				ID:              value.Id,
-				Brand:           value.Brand,
-				Model:           value.Model,
-				Registration:    value.Registration,
-				Color:           value.Color,
-				FabricationYear: value.FabricationYear,
-				Capacity:        value.Capacity,
-				MaxSpeed:        value.MaxSpeed,
-				FuelType:        value.FuelType,
-				Transmission:    value.Transmission,
-				Weight:          value.Weight,
-				Height:          value.Height,
-				Length:          value.Length,
-				Width:           value.Width,
-			})
+			data = append(data, vehicleToJSON(value, us))
 		}
 
-		response.JSON(w, http.StatusOK, map[string]any{
+		render(w, r, http.StatusOK, map[string]any{
 			"message": "success",
 			"data":    data,
+			"units":   vehicleUnitsToJSON(us),
 		})
 
 	}
 }
+
+// vehicleToJSON is a function that converts a vehicle to its JSON representation,
+// with the values of its fields with a unit in the unit system
+func vehicleToJSON(v internal.Vehicle, s internal.VehicleUnitSystem) internal.VehicleJSON {
+	return internal.NewVehicleJSON(s.Vehicle(v))
+}
+
+// vehicleFromJSON is a function that converts a JSON vehicle to a vehicle
+func vehicleFromJSON(vh internal.VehicleJSON) internal.Vehicle {
+	return vh.Vehicle()
+}
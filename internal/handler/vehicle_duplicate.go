@@ -0,0 +1,181 @@
+package handler
+
+import (
+	"app/internal"
+	"errors"
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// VehicleDuplicateJSON is a struct that represents vehicles that are likely the same vehicle in JSON format
+// - registration is the shared registration of a registration clash
+// - matched are the fields with the same value of a near duplicate
+type VehicleDuplicateJSON struct {
+	Kind         string   `json:"kind"`
+	Ids          []int    `json:"ids"`
+	Registration string   `json:"registration,omitempty"`
+	Score        float64  `json:"score"`
+	Matched      []string `json:"matched,omitempty"`
+}
+
+// VehicleMergeJSON is a struct that represents the merge of a duplicate into the vehicle kept in JSON format
+// - merged is the duplicate as it was before it was deleted
+// - filled are the fields of the kept vehicle filled from the duplicate
+type VehicleMergeJSON struct {
+	ID       int                  `json:"id"`
+	KeptID   int                  `json:"kept_id"`
+	MergedID int                  `json:"merged_id"`
+	Merged   internal.VehicleJSON `json:"merged"`
+	Filled   []string             `json:"filled"`
+	At       time.Time            `json:"at"`
+}
+
+// NewVehicleDuplicateDefault is a function that returns a new instance of VehicleDuplicateDefault
+func NewVehicleDuplicateDefault(sv internal.VehicleDuplicateService) *VehicleDuplicateDefault {
+	return &VehicleDuplicateDefault{sv: sv}
+}
+
+// VehicleDuplicateDefault is a struct that represents the handler of the duplicate vehicles
+type VehicleDuplicateDefault struct {
+	// sv is the vehicle duplicate service
+	sv internal.VehicleDuplicateService
+}
+
+// GetDuplicates is a method that returns the registration clashes and the near duplicates of the vehicles
+// matching the filter query parameters. threshold is the smallest similarity of a near duplicate, default 0.85.
+func (h *VehicleDuplicateDefault) GetDuplicates() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		us, _ := unitsOf(r)
+		f, err := vehicleFilterFromQuery(r.URL.Query(), us)
+		if err != nil {
+			render(w, r, http.StatusBadRequest, map[string]string{"error": err.Error()})
+			return
+		}
+		q := internal.VehicleDuplicateQuery{Filter: f}
+		if value := r.URL.Query().Get("threshold"); value != "" {
+			if q.Threshold, err = strconv.ParseFloat(value, 64); err != nil || q.Threshold <= 0 || q.Threshold > 1 {
+				render(w, r, http.StatusBadRequest, map[string]string{"error": "invalid threshold, expected a number greater than 0 and at most 1"})
+				return
+			}
+		}
+
+		duplicates, err := h.sv.Duplicates(q)
+		if err != nil {
+			if errors.Is(err, internal.ErrVehicleDuplicateInvalid) {
+				render(w, r, http.StatusBadRequest, map[string]string{"error": err.Error()})
+				return
+			}
+			render(w, r, http.StatusInternalServerError, map[string]string{"error": "internal server error"})
+			return
+		}
+
+		data := make([]VehicleDuplicateJSON, 0, len(duplicates))
+		for _, d := range duplicates {
+			data = append(data, VehicleDuplicateJSON{
+				Kind:         string(d.Kind),
+				Ids:          d.Ids,
+				Registration: d.Key,
+				Score:        math.Round(d.Score*1000) / 1000,
+				Matched:      d.Matched,
+			})
+		}
+		render(w, r, http.StatusOK, map[string]any{
+			"message": "success",
+			"data":    data,
+		})
+	}
+}
+
+// PostMerge is a method that merges the duplicate of the body into the vehicle kept, filling the empty fields
+// of the kept vehicle, deleting the duplicate and recording the merge
+func (h *VehicleDuplicateDefault) PostMerge() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var req struct {
+			Keep  int `json:"keep"`
+			Merge int `json:"merge"`
+		}
+		if err := decode(r, &req); err != nil {
+			render(w, r, http.StatusBadRequest, map[string]string{"error": "invalid body"})
+			return
+		}
+
+		m, kept, err := h.sv.Merge(req.Keep, req.Merge)
+		if err != nil {
+			switch {
+			case errors.Is(err, internal.ErrVehicleMergeInvalid):
+				render(w, r, http.StatusUnprocessableEntity, map[string]string{"error": err.Error()})
+			case errors.Is(err, internal.ErrVehicleNotFound):
+				render(w, r, http.StatusNotFound, map[string]string{"error": err.Error()})
+			// - the kept vehicle, with the fields filled from the duplicate, clashes with another vehicle
+			case errors.Is(err, internal.ErrVehicleExists), errors.Is(err, internal.ErrVehicleRegistrationExists),
+				errors.Is(err, internal.ErrVehicleVINExists):
+				render(w, r, http.StatusConflict, map[string]string{"error": err.Error()})
+			case errors.Is(err, internal.ErrVehicleRegistrationInvalid), errors.Is(err, internal.ErrVehicleVINInvalid),
+				errors.Is(err, internal.ErrVehicleVINMismatch):
+				render(w, r, http.StatusUnprocessableEntity, map[string]string{"error": err.Error()})
+			default:
+				render(w, r, http.StatusInternalServerError, map[string]string{"error": "internal server error"})
+			}
+			return
+		}
+		us, _ := unitsOf(r)
+		render(w, r, http.StatusOK, map[string]any{
+			"message": "vehicles merged",
+			"data": map[string]any{
+				"merge":   vehicleMergeToJSON(m, us),
+				"vehicle": vehicleToJSON(kept, us),
+			},
+			"units": vehicleUnitsToJSON(us),
+		})
+	}
+}
+
+// GetMerges is a method that returns the audit log of the merges, the ones that kept or merged
+// the vehicle_id query parameter when it is set
+func (h *VehicleDuplicateDefault) GetMerges() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id := 0
+		if value := r.URL.Query().Get("vehicle_id"); value != "" {
+			var err error
+			if id, err = strconv.Atoi(value); err != nil || id <= 0 {
+				render(w, r, http.StatusBadRequest, map[string]string{"error": "invalid vehicle_id"})
+				return
+			}
+		}
+
+		merges, err := h.sv.Merges(id)
+		if err != nil {
+			render(w, r, http.StatusInternalServerError, map[string]string{"error": "internal server error"})
+			return
+		}
+		us, _ := unitsOf(r)
+		data := make([]VehicleMergeJSON, 0, len(merges))
+		for _, m := range merges {
+			data = append(data, vehicleMergeToJSON(m, us))
+		}
+		render(w, r, http.StatusOK, map[string]any{
+			"message": "success",
+			"data":    data,
+			"units":   vehicleUnitsToJSON(us),
+		})
+	}
+}
+
+// vehicleMergeToJSON is a function that converts a merge to its JSON representation,
+// with the merged vehicle in the unit system
+func vehicleMergeToJSON(m internal.VehicleMerge, s internal.VehicleUnitSystem) VehicleMergeJSON {
+	filled := m.Filled
+	if filled == nil {
+		filled = []string{}
+	}
+	return VehicleMergeJSON{
+		ID:       m.Id,
+		KeptID:   m.KeptId,
+		MergedID: m.MergedId,
+		Merged:   vehicleToJSON(m.Merged, s),
+		Filled:   filled,
+		At:       m.At,
+	}
+}
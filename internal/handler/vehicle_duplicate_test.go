@@ -0,0 +1,54 @@
+package handler
+
+import (
+	"app/internal"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// duplicateServiceStub is a struct that implements the VehicleDuplicateService interface failing with err
+type duplicateServiceStub struct {
+	err error
+}
+
+func (s duplicateServiceStub) Duplicates(q internal.VehicleDuplicateQuery) ([]internal.VehicleDuplicate, error) {
+	return nil, s.err
+}
+
+func (s duplicateServiceStub) Merge(keptId, mergedId int) (internal.VehicleMerge, internal.Vehicle, error) {
+	return internal.VehicleMerge{}, internal.Vehicle{}, s.err
+}
+
+func (s duplicateServiceStub) Merges(id int) ([]internal.VehicleMerge, error) {
+	return nil, s.err
+}
+
+func TestVehicleDuplicateDefault_PostMergeErrors(t *testing.T) {
+	cases := []struct {
+		err  error
+		code int
+	}{
+		{internal.ErrVehicleMergeInvalid, http.StatusUnprocessableEntity},
+		{internal.ErrVehicleNotFound, http.StatusNotFound},
+		{internal.ErrVehicleExists, http.StatusConflict},
+		{internal.ErrVehicleRegistrationExists, http.StatusConflict},
+		{internal.ErrVehicleVINExists, http.StatusConflict},
+		{internal.ErrVehicleVINInvalid, http.StatusUnprocessableEntity},
+		{errors.New("disk full"), http.StatusInternalServerError},
+	}
+
+	for _, c := range cases {
+		t.Run(c.err.Error(), func(t *testing.T) {
+			hd := NewVehicleDuplicateDefault(duplicateServiceStub{err: fmt.Errorf("%w: vehicle 1", c.err)})
+			res := httptest.NewRecorder()
+			hd.PostMerge()(res, httptest.NewRequest(http.MethodPost, "/v2/vehicles/duplicates/merge", strings.NewReader(`{"keep":1,"merge":2}`)))
+			if res.Code != c.code {
+				t.Errorf("code = %d, want %d", res.Code, c.code)
+			}
+		})
+	}
+}
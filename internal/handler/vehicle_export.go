@@ -0,0 +1,52 @@
+package handler
+
+import (
+	"app/internal"
+	"app/internal/export"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/bootcamp-go/web/response"
+)
+
+// GetExport is a method that streams the vehicles matching the filter query parameters
+// as a downloadable file, format is one of csv, ndjson, json or xlsx (default csv).
+// The fields with a unit are always in km/h, kg and m so a file reads back the same, a units query parameter
+// other than metric is rejected.
+func (h *VehicleDefault) GetExport() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		name := r.URL.Query().Get("format")
+		if name == "" {
+			name = "csv"
+		}
+		format, ok := export.Formats[name]
+		if !ok {
+			response.JSON(w, http.StatusBadRequest, map[string]string{"error": "invalid format"})
+			return
+		}
+		f, err := vehicleFilterFromQuery(r.URL.Query(), internal.VehicleUnitsMetric)
+		if err != nil {
+			response.JSON(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+			return
+		}
+		if us, err := unitsOf(r); err != nil || us != internal.VehicleUnitsMetric {
+			response.JSON(w, http.StatusBadRequest, map[string]string{"error": "export is only available in metric units"})
+			return
+		}
+
+		// response
+		// - once streaming started a failure can only cut the download short
+		filename := fmt.Sprintf("vehicles-%s%s", time.Now().UTC().Format("20060102-150405"), format.Extension)
+		w.Header().Set("Content-Type", format.ContentType)
+		w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filename))
+		w.Header().Set("Cache-Control", "no-store")
+		w.WriteHeader(http.StatusOK)
+
+		vw := format.New(w)
+		if err := h.sv.ForEach(f, vw.Write); err != nil {
+			return
+		}
+		_ = vw.Close()
+	}
+}
@@ -0,0 +1,148 @@
+package handler
+
+import (
+	"app/internal"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/bootcamp-go/web/response"
+)
+
+// heartbeatInterval is the interval between keep-alive comments on idle streams
+const heartbeatInterval = 15 * time.Second
+
+// VehicleChangeJSON is a struct that represents a vehicle change in JSON format
+type VehicleChangeJSON struct {
+	ID         uint64               `json:"id"`
+	Type       string               `json:"type"`
+	OccurredAt time.Time            `json:"occurred_at"`
+	Vehicle    internal.VehicleJSON `json:"vehicle"`
+	Units      VehicleUnitsJSON     `json:"units"`
+}
+
+// NewVehicleFeedDefault is a function that returns a new instance of VehicleFeedDefault
+func NewVehicleFeedDefault(fd internal.VehicleFeed) *VehicleFeedDefault {
+	return &VehicleFeedDefault{fd: fd}
+}
+
+// VehicleFeedDefault is a struct that streams the vehicle feed as Server-Sent Events
+type VehicleFeedDefault struct {
+	// fd is the feed of vehicle changes
+	fd internal.VehicleFeed
+}
+
+// GetEvents is a method that streams the vehicle changes
+// - Last-Event-ID header (or last_event_id query) resumes after the given change
+// - brand and type query parameters filter the changes, type is a comma separated list
+// - units query parameter selects the unit system of the vehicles, see unitsOf
+func (h *VehicleFeedDefault) GetEvents() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		flusher, ok := w.(http.Flusher)
+		if !ok {
+			response.JSON(w, http.StatusInternalServerError, map[string]string{"error": "streaming unsupported"})
+			return
+		}
+
+		// request
+		lastIdStr := r.Header.Get("Last-Event-ID")
+		if lastIdStr == "" {
+			lastIdStr = r.URL.Query().Get("last_event_id")
+		}
+		var lastId uint64
+		resume := lastIdStr != ""
+		if resume {
+			var err error
+			lastId, err = strconv.ParseUint(lastIdStr, 10, 64)
+			if err != nil {
+				response.JSON(w, http.StatusBadRequest, map[string]string{"error": "invalid Last-Event-ID"})
+				return
+			}
+		}
+		us, err := unitsOf(r)
+		if err != nil {
+			response.JSON(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+			return
+		}
+		brand := r.URL.Query().Get("brand")
+		types := make(map[internal.VehicleChangeType]bool)
+		if t := r.URL.Query().Get("type"); t != "" {
+			for _, item := range strings.Split(t, ",") {
+				ct := internal.VehicleChangeType(strings.TrimSpace(item))
+				switch ct {
+				case internal.VehicleCreated, internal.VehicleUpdated, internal.VehicleDeleted:
+					types[ct] = true
+				default:
+					response.JSON(w, http.StatusBadRequest, map[string]string{"error": "invalid event type"})
+					return
+				}
+			}
+		}
+		match := func(c internal.VehicleChange) bool {
+			if brand != "" && !strings.EqualFold(c.Vehicle.Brand, brand) {
+				return false
+			}
+			return len(types) == 0 || types[c.Type]
+		}
+
+		// subscribe
+		sub := h.fd.Subscribe(lastId, resume)
+		defer sub.Cancel()
+
+		// response
+		w.Header().Set("Content-Type", "text/event-stream")
+		w.Header().Set("Cache-Control", "no-cache")
+		w.Header().Set("Connection", "keep-alive")
+		w.WriteHeader(http.StatusOK)
+		if sub.Missed {
+			// the client has to reload the fleet, the gap can't be replayed
+			fmt.Fprint(w, "event: reset\ndata: {}\n\n")
+		}
+		for _, c := range sub.Backlog {
+			if match(c) {
+				writeVehicleChange(w, c, us)
+			}
+		}
+		flusher.Flush()
+
+		heartbeat := time.NewTicker(heartbeatInterval)
+		defer heartbeat.Stop()
+		for {
+			select {
+			case <-r.Context().Done():
+				return
+			case c, ok := <-sub.Changes:
+				if !ok {
+					// dropped for falling behind, the client reconnects with its Last-Event-ID
+					return
+				}
+				if !match(c) {
+					continue
+				}
+				writeVehicleChange(w, c, us)
+				flusher.Flush()
+			case <-heartbeat.C:
+				fmt.Fprint(w, ": heartbeat\n\n")
+				flusher.Flush()
+			}
+		}
+	}
+}
+
+// writeVehicleChange is a function that writes a change as a Server-Sent Event, with the vehicle in the unit system
+func writeVehicleChange(w http.ResponseWriter, c internal.VehicleChange, s internal.VehicleUnitSystem) {
+	data, err := json.Marshal(VehicleChangeJSON{
+		ID:         c.Id,
+		Type:       string(c.Type),
+		OccurredAt: c.OccurredAt,
+		Vehicle:    vehicleToJSON(c.Vehicle, s),
+		Units:      vehicleUnitsToJSON(s),
+	})
+	if err != nil {
+		return
+	}
+	fmt.Fprintf(w, "id: %d\nevent: %s\ndata: %s\n\n", c.Id, c.Type, data)
+}
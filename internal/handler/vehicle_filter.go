@@ -0,0 +1,102 @@
+package handler
+
+import (
+	"app/internal"
+	"fmt"
+	"net/url"
+	"strconv"
+)
+
+// VehicleFilterJSON is a struct that represents a vehicle filter in JSON format
+// - the bounds with a unit are a number in the unit system of the request, or a string with a unit, e.g. "3000 lb"
+type VehicleFilterJSON struct {
+	Brand        string                 `json:"brand"`
+	Model        string                 `json:"model"`
+	Color        string                 `json:"color"`
+	FuelType     string                 `json:"fuel_type"`
+	Transmission string                 `json:"transmission"`
+	YearMin      int                    `json:"year_min"`
+	YearMax      int                    `json:"year_max"`
+	CapacityMin  int                    `json:"passengers_min"`
+	CapacityMax  int                    `json:"passengers_max"`
+	SpeedMin     internal.VehicleSpeed  `json:"max_speed_min"`
+	SpeedMax     internal.VehicleSpeed  `json:"max_speed_max"`
+	WeightMin    internal.VehicleMass   `json:"weight_min"`
+	WeightMax    internal.VehicleMass   `json:"weight_max"`
+	LengthMin    internal.VehicleLength `json:"length_min"`
+	LengthMax    internal.VehicleLength `json:"length_max"`
+	WidthMin     internal.VehicleLength `json:"width_min"`
+	WidthMax     internal.VehicleLength `json:"width_max"`
+}
+
+// vehicleFilterFromQuery is a function that reads a vehicle filter from the query parameters,
+// named as the fields of VehicleFilterJSON. The bounds without unit are in the unit system s.
+func vehicleFilterFromQuery(q url.Values, s internal.VehicleUnitSystem) (f internal.VehicleFilter, err error) {
+	f = internal.VehicleFilter{
+		Brand:        q.Get("brand"),
+		Model:        q.Get("model"),
+		Color:        q.Get("color"),
+		FuelType:     q.Get("fuel_type"),
+		Transmission: q.Get("transmission"),
+	}
+	ints := []struct {
+		name string
+		dst  *int
+	}{
+		{"year_min", &f.YearMin}, {"year_max", &f.YearMax},
+		{"passengers_min", &f.CapacityMin}, {"passengers_max", &f.CapacityMax},
+	}
+	for _, p := range ints {
+		if value := q.Get(p.name); value != "" {
+			if *p.dst, err = strconv.Atoi(value); err != nil {
+				return f, fmt.Errorf("invalid %s", p.name)
+			}
+		}
+	}
+	floats := []struct {
+		name string
+		q    internal.VehicleQuantity
+		dst  *float64
+	}{
+		{"max_speed_min", internal.VehicleQuantitySpeed, &f.SpeedMin},
+		{"max_speed_max", internal.VehicleQuantitySpeed, &f.SpeedMax},
+		{"weight_min", internal.VehicleQuantityMass, &f.WeightMin},
+		{"weight_max", internal.VehicleQuantityMass, &f.WeightMax},
+		{"length_min", internal.VehicleQuantityLength, &f.LengthMin},
+		{"length_max", internal.VehicleQuantityLength, &f.LengthMax},
+		{"width_min", internal.VehicleQuantityLength, &f.WidthMin},
+		{"width_max", internal.VehicleQuantityLength, &f.WidthMax},
+	}
+	// - the bounds may have a unit, e.g. weight_max=3000lb
+	for _, p := range floats {
+		if value := q.Get(p.name); value != "" {
+			if *p.dst, err = s.Parse(value, p.q); err != nil {
+				return f, fmt.Errorf("invalid %s", p.name)
+			}
+		}
+	}
+	return
+}
+
+// vehicleFilterFromJSON is a function that converts a JSON filter to a vehicle filter
+func vehicleFilterFromJSON(f VehicleFilterJSON) internal.VehicleFilter {
+	return internal.VehicleFilter{
+		Brand:        f.Brand,
+		Model:        f.Model,
+		Color:        f.Color,
+		FuelType:     f.FuelType,
+		Transmission: f.Transmission,
+		YearMin:      f.YearMin,
+		YearMax:      f.YearMax,
+		CapacityMin:  f.CapacityMin,
+		CapacityMax:  f.CapacityMax,
+		SpeedMin:     float64(f.SpeedMin),
+		SpeedMax:     float64(f.SpeedMax),
+		WeightMin:    float64(f.WeightMin),
+		WeightMax:    float64(f.WeightMax),
+		LengthMin:    float64(f.LengthMin),
+		LengthMax:    float64(f.LengthMax),
+		WidthMin:     float64(f.WidthMin),
+		WidthMax:     float64(f.WidthMax),
+	}
+}
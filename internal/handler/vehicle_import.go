@@ -0,0 +1,182 @@
+package handler
+
+import (
+	"app/internal"
+	"errors"
+	"fmt"
+	"mime"
+	"net/http"
+	"strconv"
+	"time"
+	"unicode/utf8"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// maxImportSize is the maximum size of an import upload, a larger upload fails its job
+const maxImportSize = 1 << 30
+
+// JobRowErrorJSON is a struct that represents a row rejected by a job in JSON format
+type JobRowErrorJSON struct {
+	Line    int    `json:"line"`
+	Message string `json:"message"`
+}
+
+// JobJSON is a struct that represents a job in JSON format
+type JobJSON struct {
+	ID              int               `json:"id"`
+	Kind            string            `json:"kind"`
+	Status          string            `json:"status"`
+	Progress        float64           `json:"progress"`
+	BytesTotal      int64             `json:"bytes_total"`
+	BytesRead       int64             `json:"bytes_read"`
+	Processed       int               `json:"processed"`
+	Succeeded       int               `json:"succeeded"`
+	Failed          int               `json:"failed"`
+	Errors          []JobRowErrorJSON `json:"errors"`
+	ErrorsTruncated bool              `json:"errors_truncated"`
+	Error           string            `json:"error,omitempty"`
+	CreatedAt       time.Time         `json:"created_at"`
+	FinishedAt      *time.Time        `json:"finished_at,omitempty"`
+}
+
+// NewVehicleImportDefault is a function that returns a new instance of VehicleImportDefault
+func NewVehicleImportDefault(im internal.VehicleImporter) *VehicleImportDefault {
+	return &VehicleImportDefault{im: im}
+}
+
+// VehicleImportDefault is a struct that represents the handler of vehicle imports and their jobs
+type VehicleImportDefault struct {
+	// im is the importer
+	im internal.VehicleImporter
+}
+
+// PostImport is a method that imports an NDJSON or CSV upload while it is received.
+// The format comes from the format query parameter or the Content-Type header,
+// delimiter and decimal query parameters describe a CSV upload.
+// - the import is not a background job, it is tied to the request: the upload is never spooled,
+// the job reads the rest of the request body as it goes and the handler only returns once it is done
+// - the job is answered with 202 before the upload is read so its progress can be followed on /jobs/{id},
+// on HTTP/1.1 a client that waits for the end of its upload before reading the response gets it at that point
+// - a client that disconnects before the end of its upload fails the job, the rows inserted so far are kept
+func (h *VehicleImportDefault) PostImport() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		// request
+		opts := internal.VehicleImportOptions{Format: r.URL.Query().Get("format")}
+		if opts.Format == "" {
+			mt, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+			switch mt {
+			case "application/x-ndjson", "application/ndjson", "application/jsonl":
+				opts.Format = "ndjson"
+			case "text/csv":
+				opts.Format = "csv"
+			}
+		}
+		if opts.Format != "ndjson" && opts.Format != "csv" {
+			render(w, r, http.StatusUnsupportedMediaType, map[string]string{"error": "format must be ndjson or csv"})
+			return
+		}
+		for _, p := range []struct {
+			name string
+			dst  *rune
+		}{{"delimiter", &opts.CSVDelimiter}, {"decimal", &opts.CSVDecimalSeparator}} {
+			value := r.URL.Query().Get(p.name)
+			if value == "" {
+				continue
+			}
+			if value == "tab" {
+				value = "\t"
+			}
+			if utf8.RuneCountInString(value) != 1 {
+				render(w, r, http.StatusBadRequest, map[string]string{"error": "invalid " + p.name})
+				return
+			}
+			*p.dst, _ = utf8.DecodeRuneInString(value)
+		}
+
+		// process
+		// - the body must stay readable after the response is written, HTTP/2 always allows it
+		rc := http.NewResponseController(w)
+		_ = rc.EnableFullDuplex()
+		if r.ContentLength > 0 {
+			opts.Size = r.ContentLength
+		}
+		body := http.MaxBytesReader(w, r.Body, maxImportSize)
+		j, done, err := h.im.Import(body, opts)
+		if err != nil {
+			var mbe *http.MaxBytesError
+			if errors.As(err, &mbe) {
+				render(w, r, http.StatusRequestEntityTooLarge, map[string]string{"error": "upload too large"})
+				return
+			}
+			render(w, r, http.StatusBadRequest, map[string]string{"error": err.Error()})
+			return
+		}
+
+		// response
+		w.Header().Set("Location", fmt.Sprintf("/jobs/%d", j.Id))
+		render(w, r, http.StatusAccepted, map[string]any{
+			"message": "import started",
+			"data":    jobToJSON(j),
+		})
+		_ = rc.Flush()
+		<-done
+	}
+}
+
+// GetJob is a method that returns the progress of a job
+func (h *VehicleImportDefault) GetJob() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id, err := strconv.Atoi(chi.URLParam(r, "id"))
+		if err != nil {
+			render(w, r, http.StatusBadRequest, map[string]string{"error": "invalid ID"})
+			return
+		}
+
+		j, err := h.im.FindJob(id)
+		if err != nil {
+			if errors.Is(err, internal.ErrJobNotFound) {
+				render(w, r, http.StatusNotFound, map[string]string{"error": err.Error()})
+				return
+			}
+			render(w, r, http.StatusInternalServerError, nil)
+			return
+		}
+		render(w, r, http.StatusOK, map[string]any{
+			"message": "success",
+			"data":    jobToJSON(j),
+		})
+	}
+}
+
+// jobToJSON is a function that converts a job to its JSON representation
+func jobToJSON(j internal.Job) (data JobJSON) {
+	data = JobJSON{
+		ID:              j.Id,
+		Kind:            j.Kind,
+		Status:          string(j.Status),
+		BytesTotal:      j.BytesTotal,
+		BytesRead:       j.BytesRead,
+		Processed:       j.Processed,
+		Succeeded:       j.Succeeded,
+		Failed:          j.Failed,
+		Errors:          make([]JobRowErrorJSON, 0, len(j.Errors)),
+		ErrorsTruncated: j.ErrorsTruncated,
+		Error:           j.Error,
+		CreatedAt:       j.CreatedAt,
+	}
+	switch {
+	case j.Status != internal.JobRunning:
+		data.Progress = 1
+	case j.BytesTotal > 0:
+		data.Progress = float64(j.BytesRead) / float64(j.BytesTotal)
+	}
+	if !j.FinishedAt.IsZero() {
+		finished := j.FinishedAt
+		data.FinishedAt = &finished
+	}
+	for _, e := range j.Errors {
+		data.Errors = append(data.Errors, JobRowErrorJSON{Line: e.Line, Message: e.Message})
+	}
+	return
+}
@@ -0,0 +1,53 @@
+package handler
+
+import (
+	"app/internal"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// GetByRegistration is a method that returns the vehicle holding the registration of the path,
+// regardless of case and separators, e.g. abc-1d23 finds ABC1D23.
+// A registration shared by the vehicles of a loaded fleet is answered with a conflict listing them.
+func (h *VehicleDefault) GetByRegistration() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		plate := pathParam(r, "plate")
+		if strings.TrimSpace(plate) == "" {
+			render(w, r, http.StatusBadRequest, map[string]string{"error": "invalid registration"})
+			return
+		}
+
+		vehicles, err := h.sv.FindByRegistration(plate)
+		if err != nil {
+			render(w, r, http.StatusInternalServerError, map[string]string{"error": "internal server error"})
+			return
+		}
+		renderHeldBy(w, r, "registration", plate, vehicles)
+	}
+}
+
+// renderHeldBy is a function that renders the vehicle holding the value of a unique field,
+// a not found when none does and a conflict listing the vehicles of a loaded fleet sharing it
+func renderHeldBy(w http.ResponseWriter, r *http.Request, name, value string, vehicles []internal.Vehicle) {
+	switch len(vehicles) {
+	case 0:
+		render(w, r, http.StatusNotFound, map[string]string{"error": fmt.Sprintf("vehicle with %s: %v, not found", name, value)})
+	case 1:
+		us, _ := unitsOf(r)
+		render(w, r, http.StatusOK, map[string]any{
+			"message": "success",
+			"data":    vehicleToJSON(vehicles[0], us),
+			"units":   vehicleUnitsToJSON(us),
+		})
+	default:
+		ids := make([]int, len(vehicles))
+		for i, v := range vehicles {
+			ids[i] = v.Id
+		}
+		render(w, r, http.StatusConflict, map[string]any{
+			"error": fmt.Sprintf("%s: %v, is shared by %d vehicles", name, value, len(vehicles)),
+			"ids":   ids,
+		})
+	}
+}
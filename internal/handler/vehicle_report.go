@@ -0,0 +1,175 @@
+package handler
+
+import (
+	"app/internal"
+	"bytes"
+	"fmt"
+	"net/http"
+	"sort"
+	"time"
+)
+
+// CategoryShareJSON is a struct that represents the number and share of the vehicles of a category in JSON format
+// - share is a fraction of the vehicles of the period, or of the fleet, between 0 and 1
+type CategoryShareJSON struct {
+	Name  string  `json:"name"`
+	Count int     `json:"count"`
+	Share float64 `json:"share"`
+}
+
+// CompositionPeriodJSON is a struct that represents the vehicles of a fabrication period in JSON format
+// - average_age is null for a period without vehicles
+// - every category of the fleet is listed, in name order, even when the period has none
+type CompositionPeriodJSON struct {
+	Label         string              `json:"label"`
+	From          int                 `json:"from"`
+	To            int                 `json:"to"`
+	Count         int                 `json:"count"`
+	Share         float64             `json:"share"`
+	AverageAge    *float64            `json:"average_age"`
+	FuelTypes     []CategoryShareJSON `json:"fuel_types"`
+	Transmissions []CategoryShareJSON `json:"transmissions"`
+}
+
+// CompositionJSON is a struct that represents the composition of the fleet in JSON format
+type CompositionJSON struct {
+	Reference     string                  `json:"reference"`
+	Period        string                  `json:"period"`
+	Count         int                     `json:"count"`
+	AverageAge    *float64                `json:"average_age"`
+	FuelTypes     []CategoryShareJSON     `json:"fuel_types"`
+	Transmissions []CategoryShareJSON     `json:"transmissions"`
+	Periods       []CompositionPeriodJSON `json:"periods"`
+}
+
+// GetComposition is a method that returns the composition of the fleet matching the filter query parameters
+// per fabrication period.
+// period is year (default) or decade, reference the date of the average ages as 2006-01-02 (default today).
+func (h *VehicleDefault) GetComposition() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		data, err := h.composition(r)
+		if err != nil {
+			render(w, r, http.StatusBadRequest, map[string]string{"error": err.Error()})
+			return
+		}
+		render(w, r, http.StatusOK, map[string]any{
+			"message": "success",
+			"data":    data,
+		})
+	}
+}
+
+// GetCompositionHTML is a method that returns the composition report as an HTML page with inline SVG charts.
+// It takes the same query parameters as GetComposition.
+func (h *VehicleDefault) GetCompositionHTML() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		data, err := h.composition(r)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		var buf bytes.Buffer
+		if err := compositionTemplate.Execute(&buf, compositionPage(data)); err != nil {
+			http.Error(w, "internal server error", http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		w.WriteHeader(http.StatusOK)
+		w.Write(buf.Bytes())
+	}
+}
+
+// composition is a method that computes the composition report requested by the query parameters
+func (h *VehicleDefault) composition(r *http.Request) (data CompositionJSON, err error) {
+	// request
+	query := r.URL.Query()
+	us, _ := unitsOf(r)
+	f, err := vehicleFilterFromQuery(query, us)
+	if err != nil {
+		return
+	}
+	q := internal.VehicleCompositionQuery{Filter: f, Reference: time.Now().UTC()}
+	switch query.Get("period") {
+	case "", "year":
+	case "decade":
+		q.Decade = true
+	default:
+		return data, fmt.Errorf("invalid period %q, expected year or decade", query.Get("period"))
+	}
+	if value := query.Get("reference"); value != "" {
+		if q.Reference, err = time.Parse(time.DateOnly, value); err != nil {
+			return data, fmt.Errorf("invalid reference %q, expected a date as 2006-01-02", value)
+		}
+	}
+
+	// process
+	c, err := h.sv.Composition(q)
+	if err != nil {
+		return
+	}
+
+	// response
+	fuelTypes, transmissions := sortedNames(c.FuelTypes), sortedNames(c.Transmissions)
+	data = CompositionJSON{
+		Reference:     c.Reference.Format(time.DateOnly),
+		Period:        "year",
+		Count:         c.Count,
+		FuelTypes:     categoryShares(fuelTypes, c.FuelTypes, c.Count),
+		Transmissions: categoryShares(transmissions, c.Transmissions, c.Count),
+		Periods:       make([]CompositionPeriodJSON, 0, len(c.Periods)),
+	}
+	if c.Decade {
+		data.Period = "decade"
+	}
+	if c.Count > 0 {
+		data.AverageAge = &c.AverageAge
+	}
+	for _, p := range c.Periods {
+		item := CompositionPeriodJSON{
+			Label:         fmt.Sprint(p.From),
+			From:          p.From,
+			To:            p.To,
+			Count:         p.Count,
+			Share:         share(p.Count, c.Count),
+			FuelTypes:     categoryShares(fuelTypes, p.FuelTypes, p.Count),
+			Transmissions: categoryShares(transmissions, p.Transmissions, p.Count),
+		}
+		if c.Decade {
+			item.Label = fmt.Sprintf("%ds", p.From)
+		}
+		if p.Count > 0 {
+			age := p.AverageAge
+			item.AverageAge = &age
+		}
+		data.Periods = append(data.Periods, item)
+	}
+	return
+}
+
+// sortedNames is a function that returns the keys of counts in order
+func sortedNames(counts map[string]int) []string {
+	names := make([]string, 0, len(counts))
+	for name := range counts {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// categoryShares is a function that returns the count and share of each category out of total
+func categoryShares(names []string, counts map[string]int, total int) []CategoryShareJSON {
+	shares := make([]CategoryShareJSON, 0, len(names))
+	for _, name := range names {
+		shares = append(shares, CategoryShareJSON{Name: name, Count: counts[name], Share: share(counts[name], total)})
+	}
+	return shares
+}
+
+// share is a function that returns n as a fraction of total, 0 when total is 0
+func share(n, total int) float64 {
+	if total == 0 {
+		return 0
+	}
+	return float64(n) / float64(total)
+}
@@ -0,0 +1,232 @@
+package handler
+
+import (
+	"fmt"
+	"html/template"
+	"math"
+	"strings"
+)
+
+// chart geometry of the composition report, in SVG user units
+const (
+	chartWidth  = 760.0
+	chartHeight = 260.0
+	chartLeft   = 48.0
+	chartRight  = 12.0
+	chartTop    = 12.0
+	chartBottom = 36.0
+	// chartLabels is the maximum number of period labels under a chart
+	chartLabels = 12
+)
+
+// chartPalette are the colors of the categories of a chart, reused in order
+var chartPalette = []string{"#4e79a7", "#f28e2b", "#e15759", "#76b7b2", "#59a14f", "#edc948", "#b07aa1", "#ff9da7", "#9c755f", "#bab0ac"}
+
+// svgRect is a struct that represents a segment of a stacked bar
+type svgRect struct {
+	Y, H  float64
+	Color string
+	Title string
+}
+
+// svgBar is a struct that represents the bar of a period
+// - Labeled is false for the periods skipped to keep the labels readable
+type svgBar struct {
+	X, W    float64
+	Label   string
+	LabelX  float64
+	Labeled bool
+	Rects   []svgRect
+}
+
+// svgTick is a struct that represents a graduation of the vertical axis
+type svgTick struct {
+	Y     float64
+	Label string
+}
+
+// svgDot is a struct that represents a point of a line chart
+type svgDot struct {
+	X, Y  float64
+	Title string
+}
+
+// svgLegend is a struct that represents an entry of the legend of a chart
+type svgLegend struct {
+	Color string
+	Name  string
+}
+
+// svgChart is a struct that represents a chart of the composition report
+// - Points is the polyline of a line chart, empty for a bar chart
+type svgChart struct {
+	Title  string
+	Width  float64
+	Height float64
+	Left   float64
+	Bottom float64
+	Right  float64
+	Bars   []svgBar
+	Ticks  []svgTick
+	Legend []svgLegend
+	Points string
+	Dots   []svgDot
+}
+
+// compositionView is a struct that represents the data of the composition report page
+type compositionView struct {
+	CompositionJSON
+	AverageAgeText string
+	Charts         []svgChart
+}
+
+// compositionPage is a function that lays out the charts of the composition report
+func compositionPage(data CompositionJSON) compositionView {
+	view := compositionView{CompositionJSON: data, AverageAgeText: "-"}
+	if data.AverageAge != nil {
+		view.AverageAgeText = fmt.Sprintf("%.1f years", *data.AverageAge)
+	}
+	view.Charts = []svgChart{
+		stackedChart("Vehicles per fuel type", data.Periods, func(p CompositionPeriodJSON) []CategoryShareJSON { return p.FuelTypes }),
+		stackedChart("Vehicles per transmission", data.Periods, func(p CompositionPeriodJSON) []CategoryShareJSON { return p.Transmissions }),
+		ageChart(data.Periods),
+	}
+	return view
+}
+
+// newChart is a function that returns an empty chart with a bar slot per period
+func newChart(title string, periods []CompositionPeriodJSON, top float64) (c svgChart, slot float64) {
+	c = svgChart{Title: title, Width: chartWidth, Height: chartHeight, Left: chartLeft, Bottom: chartHeight - chartBottom, Right: chartWidth - chartRight}
+	if len(periods) == 0 {
+		return
+	}
+	slot = (c.Right - c.Left) / float64(len(periods))
+	every := int(math.Ceil(float64(len(periods)) / chartLabels))
+	for i, p := range periods {
+		x := c.Left + float64(i)*slot
+		c.Bars = append(c.Bars, svgBar{X: x + slot*0.1, W: slot * 0.8, Label: p.Label, LabelX: x + slot/2, Labeled: i%every == 0})
+	}
+	// - four graduations from 0 to top
+	for i := 0; i <= 4; i++ {
+		value := top * float64(i) / 4
+		c.Ticks = append(c.Ticks, svgTick{Y: c.y(value, top), Label: strings.TrimSuffix(fmt.Sprintf("%.1f", value), ".0")})
+	}
+	return
+}
+
+// y is a method that returns the vertical position of a value on a scale from 0 to top
+func (c svgChart) y(value, top float64) float64 {
+	if top == 0 {
+		return c.Bottom
+	}
+	return c.Bottom - value/top*(c.Bottom-chartTop)
+}
+
+// stackedChart is a function that returns a bar chart of the number of vehicles per category and period
+func stackedChart(title string, periods []CompositionPeriodJSON, categories func(p CompositionPeriodJSON) []CategoryShareJSON) svgChart {
+	top := 0
+	for _, p := range periods {
+		top = max(top, p.Count)
+	}
+	// - a multiple of the four graduations keeps them whole numbers
+	top = (top + 3) / 4 * 4
+	c, _ := newChart(title, periods, float64(top))
+	for i, p := range periods {
+		base := 0
+		for j, cat := range categories(p) {
+			if cat.Count == 0 {
+				continue
+			}
+			y0, y1 := c.y(float64(base), float64(top)), c.y(float64(base+cat.Count), float64(top))
+			c.Bars[i].Rects = append(c.Bars[i].Rects, svgRect{
+				Y: y1, H: y0 - y1, Color: chartPalette[j%len(chartPalette)],
+				Title: fmt.Sprintf("%s %s: %d (%.0f%%)", p.Label, cat.Name, cat.Count, cat.Share*100),
+			})
+			base += cat.Count
+		}
+	}
+	if len(periods) > 0 {
+		for j, cat := range categories(periods[0]) {
+			c.Legend = append(c.Legend, svgLegend{Color: chartPalette[j%len(chartPalette)], Name: cat.Name})
+		}
+	}
+	return c
+}
+
+// ageChart is a function that returns a line chart of the average age of the vehicles of each period
+func ageChart(periods []CompositionPeriodJSON) svgChart {
+	top := 0.0
+	for _, p := range periods {
+		if p.AverageAge != nil {
+			top = max(top, *p.AverageAge)
+		}
+	}
+	top = math.Ceil(top/10) * 10
+	c, slot := newChart("Average age per period", periods, top)
+	var points []string
+	for i, p := range periods {
+		if p.AverageAge == nil {
+			continue
+		}
+		x, y := c.Left+(float64(i)+0.5)*slot, c.y(*p.AverageAge, top)
+		points = append(points, fmt.Sprintf("%.1f,%.1f", x, y))
+		c.Dots = append(c.Dots, svgDot{X: x, Y: y, Title: fmt.Sprintf("%s: %.1f years", p.Label, *p.AverageAge)})
+	}
+	c.Points = strings.Join(points, " ")
+	return c
+}
+
+// compositionTemplate is the template of the composition report page
+var compositionTemplate = template.Must(template.New("composition").Funcs(template.FuncMap{
+	"percent": func(f float64) string { return fmt.Sprintf("%.1f%%", f*100) },
+	"num":     func(f float64) string { return fmt.Sprintf("%.1f", f) },
+}).Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<title>Fleet composition report</title>
+<style>
+body { font-family: sans-serif; margin: 2em; color: #222; }
+table { border-collapse: collapse; margin: 1em 0; }
+th, td { border: 1px solid #ccc; padding: 0.3em 0.6em; text-align: right; }
+th:first-child, td:first-child { text-align: left; }
+svg text { font-size: 11px; fill: #444; }
+.legend span { display: inline-block; margin-right: 1em; }
+.legend i { display: inline-block; width: 0.8em; height: 0.8em; margin-right: 0.3em; }
+</style>
+</head>
+<body>
+<h1>Fleet composition report</h1>
+<p>Reference date {{.Reference}}, {{.Count}} vehicles, average age {{.AverageAgeText}}, per {{.Period}}.</p>
+
+<table>
+<tr><th>Fuel type</th><th>Vehicles</th><th>Share</th></tr>
+{{range .FuelTypes}}<tr><td>{{.Name}}</td><td>{{.Count}}</td><td>{{percent .Share}}</td></tr>
+{{end}}</table>
+<table>
+<tr><th>Transmission</th><th>Vehicles</th><th>Share</th></tr>
+{{range .Transmissions}}<tr><td>{{.Name}}</td><td>{{.Count}}</td><td>{{percent .Share}}</td></tr>
+{{end}}</table>
+
+{{range .Charts}}
+<h2>{{.Title}}</h2>
+<svg xmlns="http://www.w3.org/2000/svg" width="{{num .Width}}" height="{{num .Height}}" viewBox="0 0 {{num .Width}} {{num .Height}}" role="img" aria-label="{{.Title}}">
+{{$c := .}}{{range .Ticks}}<line x1="{{num $c.Left}}" x2="{{num $c.Right}}" y1="{{num .Y}}" y2="{{num .Y}}" stroke="#eee"/>
+<text x="{{num $c.Left}}" y="{{num .Y}}" dx="-6" dy="4" text-anchor="end">{{.Label}}</text>
+{{end}}{{range .Bars}}{{$b := .}}{{range .Rects}}<rect x="{{num $b.X}}" y="{{num .Y}}" width="{{num $b.W}}" height="{{num .H}}" fill="{{.Color}}"><title>{{.Title}}</title></rect>
+{{end}}{{if .Labeled}}<text x="{{num .LabelX}}" y="{{num $c.Bottom}}" dy="16" text-anchor="middle">{{.Label}}</text>
+{{end}}{{end}}{{if .Points}}<polyline points="{{.Points}}" fill="none" stroke="#4e79a7" stroke-width="2"/>
+{{range .Dots}}<circle cx="{{num .X}}" cy="{{num .Y}}" r="3" fill="#4e79a7"><title>{{.Title}}</title></circle>
+{{end}}{{end}}<line x1="{{num .Left}}" x2="{{num .Right}}" y1="{{num .Bottom}}" y2="{{num .Bottom}}" stroke="#888"/>
+</svg>
+{{if .Legend}}<p class="legend">{{range .Legend}}<span><i style="background: {{.Color}}"></i>{{.Name}}</span>{{end}}</p>{{end}}
+{{end}}
+
+<h2>Periods</h2>
+<table>
+<tr><th>Period</th><th>Vehicles</th><th>Share</th><th>Average age</th>{{range .FuelTypes}}<th>{{.Name}}</th>{{end}}{{range .Transmissions}}<th>{{.Name}}</th>{{end}}</tr>
+{{range .Periods}}<tr><td>{{.Label}}</td><td>{{.Count}}</td><td>{{percent .Share}}</td><td>{{with .AverageAge}}{{num .}}{{else}}-{{end}}</td>{{range .FuelTypes}}<td>{{.Count}}</td>{{end}}{{range .Transmissions}}<td>{{.Count}}</td>{{end}}</tr>
+{{end}}</table>
+</body>
+</html>
+`))
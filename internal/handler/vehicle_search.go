@@ -0,0 +1,75 @@
+package handler
+
+import (
+	"app/internal"
+	"errors"
+	"math"
+	"net/http"
+	"strconv"
+)
+
+// defaultSearchLimit and maxSearchLimit are the default and the maximum number of results of a search
+const (
+	defaultSearchLimit = 20
+	maxSearchLimit     = 100
+)
+
+// VehicleSearchResultJSON is a struct that represents a vehicle found by a search in JSON format
+// - matched are the fields holding the terms of the query
+type VehicleSearchResultJSON struct {
+	internal.VehicleJSON
+	Score   float64  `json:"score"`
+	Matched []string `json:"matched"`
+}
+
+// NewVehicleSearchDefault is a function that returns a new instance of VehicleSearchDefault
+func NewVehicleSearchDefault(sv internal.VehicleSearchService) *VehicleSearchDefault {
+	return &VehicleSearchDefault{sv: sv}
+}
+
+// VehicleSearchDefault is a struct that represents the handler of the full-text search of vehicles
+type VehicleSearchDefault struct {
+	// sv is the vehicle search service
+	sv internal.VehicleSearchService
+}
+
+// GetSearch is a method that returns the vehicles whose brand, model, color or registration match the q query
+// parameter, the most relevant first. limit caps the number of results.
+func (h *VehicleSearchDefault) GetSearch() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		limit := defaultSearchLimit
+		if value := r.URL.Query().Get("limit"); value != "" {
+			n, err := strconv.Atoi(value)
+			if err != nil || n < 1 || n > maxSearchLimit {
+				render(w, r, http.StatusBadRequest, map[string]string{"error": "invalid limit, expected 1 to 100"})
+				return
+			}
+			limit = n
+		}
+
+		results, err := h.sv.Search(r.URL.Query().Get("q"), limit)
+		if err != nil {
+			if errors.Is(err, internal.ErrVehicleSearchInvalid) {
+				render(w, r, http.StatusBadRequest, map[string]string{"error": "missing search terms in q"})
+				return
+			}
+			render(w, r, http.StatusInternalServerError, map[string]string{"error": "internal server error"})
+			return
+		}
+
+		us, _ := unitsOf(r)
+		data := make([]VehicleSearchResultJSON, 0, len(results))
+		for _, res := range results {
+			data = append(data, VehicleSearchResultJSON{
+				VehicleJSON: vehicleToJSON(res.Vehicle, us),
+				Score:       math.Round(res.Score*1000) / 1000,
+				Matched:     res.Fields,
+			})
+		}
+		render(w, r, http.StatusOK, map[string]any{
+			"message": "success",
+			"data":    data,
+			"units":   vehicleUnitsToJSON(us),
+		})
+	}
+}
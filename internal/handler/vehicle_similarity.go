@@ -0,0 +1,88 @@
+package handler
+
+import (
+	"app/internal"
+	"errors"
+	"math"
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// defaultSimilarK and maxSimilarK are the default and the maximum number of similar vehicles
+const (
+	defaultSimilarK = 5
+	maxSimilarK     = 100
+)
+
+// VehicleSimilarJSON is a struct that represents a vehicle similar to another one in JSON format
+// - distance is the weighted distance between the vehicles, 0 for identical vehicles
+type VehicleSimilarJSON struct {
+	internal.VehicleJSON
+	Distance float64 `json:"distance"`
+}
+
+// NewVehicleSimilarityDefault is a function that returns a new instance of VehicleSimilarityDefault
+func NewVehicleSimilarityDefault(sv internal.VehicleSimilarityService) *VehicleSimilarityDefault {
+	return &VehicleSimilarityDefault{sv: sv}
+}
+
+// VehicleSimilarityDefault is a struct that represents the handler of the similar vehicle recommendations
+type VehicleSimilarityDefault struct {
+	// sv is the vehicle similarity service
+	sv internal.VehicleSimilarityService
+}
+
+// GetSimilar is a method that returns the k vehicles most similar to the vehicle of the path, the closest first.
+// weights overrides the configured weights of some fields as field:weight separated by commas, e.g. brand:0,year:2
+func (h *VehicleSimilarityDefault) GetSimilar() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id, err := strconv.Atoi(chi.URLParam(r, "id"))
+		if err != nil {
+			render(w, r, http.StatusBadRequest, map[string]string{"error": "invalid ID"})
+			return
+		}
+		k := defaultSimilarK
+		if value := r.URL.Query().Get("k"); value != "" {
+			n, err := strconv.Atoi(value)
+			if err != nil || n < 1 || n > maxSimilarK {
+				render(w, r, http.StatusBadRequest, map[string]string{"error": "invalid k, expected 1 to 100"})
+				return
+			}
+			k = n
+		}
+		weights, err := internal.ParseVehicleSimilarityWeights(r.URL.Query().Get("weights"))
+		if err != nil {
+			render(w, r, http.StatusBadRequest, map[string]string{"error": err.Error()})
+			return
+		}
+
+		results, err := h.sv.Similar(id, k, weights)
+		if err != nil {
+			switch {
+			case errors.Is(err, internal.ErrVehicleNotFound):
+				render(w, r, http.StatusNotFound, map[string]string{"error": "vehicle not found"})
+			case errors.Is(err, internal.ErrVehicleSimilarityInvalid):
+				render(w, r, http.StatusBadRequest, map[string]string{"error": err.Error()})
+			default:
+				render(w, r, http.StatusInternalServerError, map[string]string{"error": "internal server error"})
+			}
+			return
+		}
+
+		us, _ := unitsOf(r)
+		data := make([]VehicleSimilarJSON, 0, len(results))
+		for _, res := range results {
+			data = append(data, VehicleSimilarJSON{
+				VehicleJSON: vehicleToJSON(res.Vehicle, us),
+				Distance:    math.Round(res.Distance*1000) / 1000,
+			})
+		}
+		render(w, r, http.StatusOK, map[string]any{
+			"message": "success",
+			"data":    data,
+			"units":   vehicleUnitsToJSON(us),
+		})
+	}
+}
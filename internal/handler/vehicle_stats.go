@@ -0,0 +1,237 @@
+package handler
+
+import (
+	"app/internal"
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// VehicleStatsJSON is a struct that represents the aggregates of a group of vehicles in JSON format
+// - group holds the value of each group_by field, it is empty without group_by
+// - a metric without any value to aggregate, e.g. the average of an empty fleet, is null
+type VehicleStatsJSON struct {
+	Group   map[string]string   `json:"group"`
+	Count   int                 `json:"count"`
+	Metrics map[string]*float64 `json:"metrics"`
+}
+
+// HistogramBucketJSON is a struct that represents a bucket of a histogram in JSON format
+// - a bucket holds the values within [min, max), the last one includes max
+type HistogramBucketJSON struct {
+	Min   float64 `json:"min"`
+	Max   float64 `json:"max"`
+	Count int     `json:"count"`
+}
+
+// QuantileJSON is a struct that represents a quantile in JSON format
+type QuantileJSON struct {
+	P     float64  `json:"p"`
+	Value *float64 `json:"value"`
+}
+
+// VehicleDistributionJSON is a struct that represents the distribution of a numeric field in JSON format
+// - segment is the value of the segment_by field, empty without segment_by
+// - the statistics are null when there are no values
+type VehicleDistributionJSON struct {
+	Segment   string                `json:"segment,omitempty"`
+	Count     int                   `json:"count"`
+	Mean      *float64              `json:"mean"`
+	Stddev    *float64              `json:"stddev"`
+	Min       *float64              `json:"min"`
+	Max       *float64              `json:"max"`
+	Median    *float64              `json:"median"`
+	Q1        *float64              `json:"q1"`
+	Q3        *float64              `json:"q3"`
+	IQR       *float64              `json:"iqr"`
+	Quantiles []QuantileJSON        `json:"quantiles"`
+	Histogram []HistogramBucketJSON `json:"histogram"`
+}
+
+// GetStats is a method that returns aggregates of the vehicles matching the filter query parameters.
+// group_by is a comma separated list of fields, metrics a comma separated list of func:field or count.
+// The metrics over a field with a unit are in the unit system of the request, listed in units by metric.
+func (h *VehicleDefault) GetStats() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		// request
+		us, _ := unitsOf(r)
+		f, err := vehicleFilterFromQuery(r.URL.Query(), us)
+		if err != nil {
+			render(w, r, http.StatusBadRequest, map[string]string{"error": err.Error()})
+			return
+		}
+		q := internal.VehicleStatsQuery{Filter: f, GroupBy: splitList(r.URL.Query().Get("group_by"))}
+		metrics := splitList(r.URL.Query().Get("metrics"))
+		if len(metrics) == 0 {
+			metrics = []string{"count"}
+		}
+		for _, value := range metrics {
+			m, err := internal.ParseVehicleMetric(value)
+			if err != nil {
+				render(w, r, http.StatusBadRequest, map[string]string{"error": err.Error()})
+				return
+			}
+			q.Metrics = append(q.Metrics, m)
+		}
+
+		// process
+		groups, err := h.sv.Stats(q)
+		if err != nil {
+			if errors.Is(err, internal.ErrVehicleStatsInvalid) {
+				render(w, r, http.StatusBadRequest, map[string]string{"error": err.Error()})
+				return
+			}
+			render(w, r, http.StatusInternalServerError, map[string]string{"error": "internal server error"})
+			return
+		}
+
+		// response
+		// - every metric is linear in its values, so it converts as they do
+		quantities := make(map[string]internal.VehicleQuantity)
+		units := make(map[string]string)
+		for _, m := range q.Metrics {
+			if qt, ok := internal.VehicleQuantityFields[m.Field]; ok {
+				quantities[m.String()] = qt
+				units[m.String()] = us.Unit(qt).Symbol
+			}
+		}
+		data := make([]VehicleStatsJSON, 0, len(groups))
+		for _, g := range groups {
+			item := VehicleStatsJSON{Group: make(map[string]string, len(g.Key)), Count: g.Count, Metrics: make(map[string]*float64, len(g.Values))}
+			for i, field := range q.GroupBy {
+				item.Group[field] = g.Key[i]
+			}
+			for name, value := range g.Values {
+				if qt, ok := quantities[name]; ok {
+					value = us.Convert(value, qt)
+				}
+				item.Metrics[name] = nullable(value)
+			}
+			data = append(data, item)
+		}
+		render(w, r, http.StatusOK, map[string]any{
+			"message": "success",
+			"data":    data,
+			"units":   units,
+		})
+	}
+}
+
+// GetDistribution is a method that returns the distribution of a numeric field over the vehicles matching
+// the filter query parameters, optionally segmented by a categorical field.
+// buckets sets the number of histogram buckets, bucket_width their width, quantiles is a comma separated list.
+// The values of a field with a unit are in the unit system of the request, as is bucket_width unless it has a unit.
+func (h *VehicleDefault) GetDistribution() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		// request
+		us, _ := unitsOf(r)
+		f, err := vehicleFilterFromQuery(r.URL.Query(), us)
+		if err != nil {
+			render(w, r, http.StatusBadRequest, map[string]string{"error": err.Error()})
+			return
+		}
+		q := internal.VehicleDistributionQuery{
+			Filter:    f,
+			Field:     chi.URLParam(r, "field"),
+			SegmentBy: r.URL.Query().Get("segment_by"),
+		}
+		if value := r.URL.Query().Get("buckets"); value != "" {
+			if q.Buckets, err = strconv.Atoi(value); err != nil || q.Buckets < 1 {
+				render(w, r, http.StatusBadRequest, map[string]string{"error": "invalid buckets"})
+				return
+			}
+		}
+		qt, hasUnit := internal.VehicleQuantityFields[q.Field]
+		if value := r.URL.Query().Get("bucket_width"); value != "" {
+			if hasUnit {
+				q.BucketWidth, err = us.Parse(value, qt)
+			} else {
+				q.BucketWidth, err = strconv.ParseFloat(value, 64)
+			}
+			if err != nil || !(q.BucketWidth > 0) {
+				render(w, r, http.StatusBadRequest, map[string]string{"error": "invalid bucket_width"})
+				return
+			}
+		}
+		for _, value := range splitList(r.URL.Query().Get("quantiles")) {
+			p, err := strconv.ParseFloat(value, 64)
+			if err != nil {
+				render(w, r, http.StatusBadRequest, map[string]string{"error": fmt.Sprintf("invalid quantile %q", value)})
+				return
+			}
+			q.Quantiles = append(q.Quantiles, p)
+		}
+
+		// process
+		distributions, err := h.sv.Distribution(q)
+		if err != nil {
+			if errors.Is(err, internal.ErrVehicleStatsInvalid) {
+				render(w, r, http.StatusBadRequest, map[string]string{"error": err.Error()})
+				return
+			}
+			render(w, r, http.StatusInternalServerError, map[string]string{"error": "internal server error"})
+			return
+		}
+
+		// response
+		scale := func(value float64) float64 { return value }
+		units := map[string]string{}
+		if hasUnit {
+			scale = func(value float64) float64 { return us.Convert(value, qt) }
+			units[q.Field] = us.Unit(qt).Symbol
+		}
+		data := make([]VehicleDistributionJSON, 0, len(distributions))
+		for _, d := range distributions {
+			item := VehicleDistributionJSON{
+				Segment:   d.Segment,
+				Count:     d.Count,
+				Mean:      nullable(scale(d.Mean)),
+				Stddev:    nullable(scale(d.Stddev)),
+				Min:       nullable(scale(d.Min)),
+				Max:       nullable(scale(d.Max)),
+				Median:    nullable(scale(d.Median)),
+				Q1:        nullable(scale(d.Q1)),
+				Q3:        nullable(scale(d.Q3)),
+				IQR:       nullable(scale(d.IQR)),
+				Quantiles: make([]QuantileJSON, 0, len(d.Quantiles)),
+				Histogram: make([]HistogramBucketJSON, 0, len(d.Histogram)),
+			}
+			for _, p := range d.Quantiles {
+				item.Quantiles = append(item.Quantiles, QuantileJSON{P: p.P, Value: nullable(scale(p.Value))})
+			}
+			for _, b := range d.Histogram {
+				item.Histogram = append(item.Histogram, HistogramBucketJSON{Min: scale(b.Min), Max: scale(b.Max), Count: b.Count})
+			}
+			data = append(data, item)
+		}
+		render(w, r, http.StatusOK, map[string]any{
+			"message": "success",
+			"field":   q.Field,
+			"data":    data,
+			"units":   units,
+		})
+	}
+}
+
+// nullable is a function that returns a pointer to f, nil when f is NaN
+func nullable(f float64) *float64 {
+	if math.IsNaN(f) {
+		return nil
+	}
+	return &f
+}
+
+// splitList is a function that splits a comma separated query parameter, dropping blank items
+func splitList(s string) (items []string) {
+	for _, item := range strings.Split(s, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return
+}
@@ -0,0 +1,194 @@
+package handler
+
+import (
+	"app/internal"
+	"app/internal/feed"
+	"net/http"
+	"time"
+
+	"github.com/bootcamp-go/web/response"
+	"github.com/gorilla/websocket"
+)
+
+const (
+	// wsWriteWait is the time allowed to write a message to the peer
+	wsWriteWait = 10 * time.Second
+	// wsPongWait is the time allowed to read the next pong from the peer
+	wsPongWait = 60 * time.Second
+	// wsPingPeriod is the interval between pings, it must be less than wsPongWait
+	wsPingPeriod = 50 * time.Second
+)
+
+// SubscriptionRequestJSON is a struct that represents a message sent by a subscription client
+// - action is either "subscribe" or "unsubscribe"
+type SubscriptionRequestJSON struct {
+	Action       string            `json:"action"`
+	Subscription string            `json:"subscription"`
+	Filter       VehicleFilterJSON `json:"filter"`
+}
+
+// SubscriptionMessageJSON is a struct that represents a message sent to a subscription client
+// - type is one of "snapshot", "add", "change", "remove" or "error"
+// - units is set along with the vehicles
+type SubscriptionMessageJSON struct {
+	Type         string                 `json:"type"`
+	Subscription string                 `json:"subscription,omitempty"`
+	ChangeID     uint64                 `json:"change_id,omitempty"`
+	Vehicle      *internal.VehicleJSON  `json:"vehicle,omitempty"`
+	Vehicles     []internal.VehicleJSON `json:"vehicles,omitempty"`
+	Units        *VehicleUnitsJSON      `json:"units,omitempty"`
+	Error        string                 `json:"error,omitempty"`
+}
+
+// NewVehicleSubscriptionDefault is a function that returns a new instance of VehicleSubscriptionDefault
+func NewVehicleSubscriptionDefault(sv internal.VehicleService, fd internal.VehicleFeed) *VehicleSubscriptionDefault {
+	return &VehicleSubscriptionDefault{
+		sv: sv,
+		fd: fd,
+		upgrader: websocket.Upgrader{
+			ReadBufferSize:  1024,
+			WriteBufferSize: 1024,
+		},
+	}
+}
+
+// VehicleSubscriptionDefault is a struct that serves live vehicle subscriptions over WebSocket
+type VehicleSubscriptionDefault struct {
+	// sv is the service used to load the initial state of a subscription
+	sv internal.VehicleService
+	// fd is the feed of vehicle changes
+	fd internal.VehicleFeed
+	// upgrader upgrades the http connection to the WebSocket protocol
+	upgrader websocket.Upgrader
+}
+
+// GetSubscribe is a method that upgrades the connection and serves subscriptions on it.
+// A client sends {"action":"subscribe","subscription":"<name>","filter":{...}} and receives
+// a snapshot of the matching vehicles followed by add, change and remove diffs.
+// The units query parameter selects the unit system of the vehicles, see unitsOf.
+func (h *VehicleSubscriptionDefault) GetSubscribe() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		us, err := unitsOf(r)
+		if err != nil {
+			response.JSON(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+			return
+		}
+		units := vehicleUnitsToJSON(us)
+
+		conn, err := h.upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			// the upgrader already replied with an http error
+			return
+		}
+		defer conn.Close()
+
+		// subscribe to the feed before loading any snapshot so no change is lost in between
+		sub := h.fd.Subscribe(0, false)
+		defer sub.Cancel()
+
+		// reader
+		// - the connection supports one concurrent reader and one concurrent writer
+		requests := make(chan SubscriptionRequestJSON)
+		done := make(chan struct{})
+		go func() {
+			defer close(done)
+			conn.SetReadDeadline(time.Now().Add(wsPongWait))
+			conn.SetPongHandler(func(string) error {
+				return conn.SetReadDeadline(time.Now().Add(wsPongWait))
+			})
+			for {
+				var req SubscriptionRequestJSON
+				// - the bounds of the filter are in the unit system of the connection
+				if err := conn.ReadJSON(&unitsBody{ptr: &req, s: us}); err != nil {
+					return
+				}
+				select {
+				case requests <- req:
+				case <-r.Context().Done():
+					return
+				}
+			}
+		}()
+
+		// writer
+		write := func(msg SubscriptionMessageJSON) error {
+			conn.SetWriteDeadline(time.Now().Add(wsWriteWait))
+			return conn.WriteJSON(msg)
+		}
+		views := make(map[string]*feed.VehicleView)
+		ping := time.NewTicker(wsPingPeriod)
+		defer ping.Stop()
+		for {
+			var msgs []SubscriptionMessageJSON
+			select {
+			case <-done:
+				return
+			case req := <-requests:
+				msgs = h.handle(views, req, us)
+			case c, ok := <-sub.Changes:
+				if !ok {
+					// fell behind the feed, the client has to subscribe again
+					conn.SetWriteDeadline(time.Now().Add(wsWriteWait))
+					conn.WriteMessage(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.CloseTryAgainLater, "subscriber fell behind"))
+					return
+				}
+				for name, vw := range views {
+					op, ok := vw.Apply(c)
+					if !ok {
+						continue
+					}
+					data := vehicleToJSON(c.Vehicle, us)
+					msgs = append(msgs, SubscriptionMessageJSON{
+						Type:         string(op),
+						Subscription: name,
+						ChangeID:     c.Id,
+						Vehicle:      &data,
+						Units:        &units,
+					})
+				}
+			case <-ping.C:
+				if err := conn.WriteControl(websocket.PingMessage, nil, time.Now().Add(wsWriteWait)); err != nil {
+					return
+				}
+			}
+			for _, msg := range msgs {
+				if err := write(msg); err != nil {
+					return
+				}
+			}
+		}
+	}
+}
+
+// handle is a method that applies a client request to the views and returns the replies,
+// with the vehicles in the unit system
+func (h *VehicleSubscriptionDefault) handle(views map[string]*feed.VehicleView, req SubscriptionRequestJSON, s internal.VehicleUnitSystem) []SubscriptionMessageJSON {
+	fail := func(msg string) []SubscriptionMessageJSON {
+		return []SubscriptionMessageJSON{{Type: "error", Subscription: req.Subscription, Error: msg}}
+	}
+	if req.Subscription == "" {
+		return fail("subscription name is required")
+	}
+
+	switch req.Action {
+	case "subscribe":
+		f := vehicleFilterFromJSON(req.Filter)
+		vehicles, err := h.sv.FindByFilter(f)
+		if err != nil {
+			return fail(err.Error())
+		}
+		views[req.Subscription] = feed.NewVehicleView(f, vehicles)
+
+		data := make([]internal.VehicleJSON, 0, len(vehicles))
+		for _, v := range vehicles {
+			data = append(data, vehicleToJSON(v, s))
+		}
+		units := vehicleUnitsToJSON(s)
+		return []SubscriptionMessageJSON{{Type: "snapshot", Subscription: req.Subscription, Vehicles: data, Units: &units}}
+	case "unsubscribe":
+		delete(views, req.Subscription)
+		return nil
+	default:
+		return fail("invalid action")
+	}
+}
@@ -0,0 +1,263 @@
+package handler
+
+import (
+	"app/internal"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// VehiclePatchJSON is a struct that represents a partial update of a vehicle in JSON format
+// - only the fields present in the body are changed
+type VehiclePatchJSON struct {
+	MaxSpeed *internal.VehicleSpeed `json:"max_speed"`
+	FuelType *string                `json:"fuel_type"`
+}
+
+// BrandAveragesJSON is a struct that represents the averages of the vehicles of a brand in JSON format
+type BrandAveragesJSON struct {
+	Brand    string  `json:"brand"`
+	MaxSpeed float64 `json:"max_speed"`
+	Capacity float64 `json:"passengers"`
+}
+
+// NewVehicleV2 is a function that returns a new instance of VehicleV2
+func NewVehicleV2(sv internal.VehicleService) *VehicleV2 {
+	return &VehicleV2{sv: sv}
+}
+
+// VehicleV2 is a struct that represents the handler of the resource oriented vehicle routes of /v2.
+// A single vehicle is returned as an object, a collection as a list in id order.
+type VehicleV2 struct {
+	// sv is the vehicle service, shared with the v1 handler
+	sv internal.VehicleService
+}
+
+// GetAll is a method that returns the vehicles matching the filter query parameters
+func (h *VehicleV2) GetAll() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		us, _ := unitsOf(r)
+		f, err := vehicleFilterFromQuery(r.URL.Query(), us)
+		if err != nil {
+			render(w, r, http.StatusBadRequest, map[string]string{"error": err.Error()})
+			return
+		}
+		vehicles, err := h.sv.FindByFilter(f)
+		if err != nil {
+			writeVehicleError(w, r, err)
+			return
+		}
+		data := make([]internal.VehicleJSON, 0, len(vehicles))
+		for _, v := range vehicles {
+			data = append(data, vehicleToJSON(v, us))
+		}
+		render(w, r, http.StatusOK, map[string]any{
+			"message": "success",
+			"data":    data,
+			"units":   vehicleUnitsToJSON(us),
+		})
+	}
+}
+
+// GetById is a method that returns a vehicle
+func (h *VehicleV2) GetById() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id, err := strconv.Atoi(chi.URLParam(r, "id"))
+		if err != nil {
+			render(w, r, http.StatusBadRequest, map[string]string{"error": "invalid ID"})
+			return
+		}
+
+		v, err := h.find(id)
+		if err != nil {
+			writeVehicleError(w, r, err)
+			return
+		}
+		us, _ := unitsOf(r)
+		render(w, r, http.StatusOK, map[string]any{
+			"message": "success",
+			"data":    vehicleToJSON(v, us),
+			"units":   vehicleUnitsToJSON(us),
+		})
+	}
+}
+
+// PostCreate is a method that adds a vehicle and returns it along with its location
+func (h *VehicleV2) PostCreate() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		us, _ := unitsOf(r)
+		var req internal.VehicleJSON
+		if err := decodeIn(r, &req, us); err != nil {
+			render(w, r, http.StatusBadRequest, map[string]string{"error": "invalid body"})
+			return
+		}
+		// - the id names the resource, /v2/vehicles/0 could not be read back
+		if req.Id <= 0 {
+			render(w, r, http.StatusBadRequest, map[string]string{"error": "invalid ID, expected a positive id"})
+			return
+		}
+
+		v := vehicleFromJSON(req)
+		if err := h.sv.Create(v); err != nil {
+			writeVehicleError(w, r, err)
+			return
+		}
+		// - the stored vehicle, as normalized by the service
+		if stored, err := h.find(v.Id); err == nil {
+			v = stored
+		}
+		w.Header().Set("Location", fmt.Sprintf("/v2/vehicles/%d", v.Id))
+		render(w, r, http.StatusCreated, map[string]any{
+			"message": "vehicle created",
+			"data":    vehicleToJSON(v, us),
+			"units":   vehicleUnitsToJSON(us),
+		})
+	}
+}
+
+// PutReplace is a method that replaces every attribute of an existing vehicle
+// - the id of the path wins over the one of the body
+func (h *VehicleV2) PutReplace() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id, err := strconv.Atoi(chi.URLParam(r, "id"))
+		if err != nil {
+			render(w, r, http.StatusBadRequest, map[string]string{"error": "invalid ID"})
+			return
+		}
+		us, _ := unitsOf(r)
+		var req internal.VehicleJSON
+		if err := decodeIn(r, &req, us); err != nil {
+			render(w, r, http.StatusBadRequest, map[string]string{"error": "invalid body"})
+			return
+		}
+		req.Id = id
+
+		v := vehicleFromJSON(req)
+		results, err := h.sv.ApplyBatch([]internal.VehicleOperation{{Type: internal.VehicleOperationUpdate, Vehicle: v}}, true)
+		if err != nil && len(results) == 1 && results[0].Err != nil {
+			err = results[0].Err
+		}
+		if err != nil {
+			writeVehicleError(w, r, err)
+			return
+		}
+		render(w, r, http.StatusOK, map[string]any{
+			"message": "vehicle updated",
+			"data":    vehicleToJSON(results[0].Vehicle, us),
+			"units":   vehicleUnitsToJSON(us),
+		})
+	}
+}
+
+// PatchUpdate is a method that changes the max speed and/or the fuel type of a vehicle and returns it
+func (h *VehicleV2) PatchUpdate() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id, err := strconv.Atoi(chi.URLParam(r, "id"))
+		if err != nil {
+			render(w, r, http.StatusBadRequest, map[string]string{"error": "invalid ID"})
+			return
+		}
+		us, _ := unitsOf(r)
+		var req VehiclePatchJSON
+		if err := decodeIn(r, &req, us); err != nil {
+			render(w, r, http.StatusBadRequest, map[string]string{"error": "invalid body"})
+			return
+		}
+		if req.MaxSpeed == nil && req.FuelType == nil {
+			render(w, r, http.StatusBadRequest, map[string]string{"error": "nothing to update, expected max_speed or fuel_type"})
+			return
+		}
+
+		// both fields are changed by a single patch, read and written under the lock of the repository
+		// so a concurrent change of the vehicle is not overwritten
+		p := internal.VehiclePatch{FuelType: req.FuelType}
+		if req.MaxSpeed != nil {
+			speed := float64(*req.MaxSpeed)
+			p.MaxSpeed = &speed
+		}
+		op := internal.VehicleOperation{Type: internal.VehicleOperationPatch, Vehicle: internal.Vehicle{Id: id}, Patch: &p}
+		results, err := h.sv.ApplyBatch([]internal.VehicleOperation{op}, true)
+		if err != nil && len(results) == 1 && results[0].Err != nil {
+			err = results[0].Err
+		}
+		if err != nil {
+			writeVehicleError(w, r, err)
+			return
+		}
+		render(w, r, http.StatusOK, map[string]any{
+			"message": "vehicle updated",
+			"data":    vehicleToJSON(results[0].Vehicle, us),
+			"units":   vehicleUnitsToJSON(us),
+		})
+	}
+}
+
+// DeleteById is a method that removes a vehicle
+func (h *VehicleV2) DeleteById() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id, err := strconv.Atoi(chi.URLParam(r, "id"))
+		if err != nil {
+			render(w, r, http.StatusBadRequest, map[string]string{"error": "invalid ID"})
+			return
+		}
+
+		if err = h.sv.Delete(id); err != nil {
+			writeVehicleError(w, r, err)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+// GetBrandAverages is a method that returns the average max speed and capacity of the vehicles of a brand
+func (h *VehicleV2) GetBrandAverages() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		brand := chi.URLParam(r, "brand")
+
+		speed, err := h.sv.FindByBrandAverageSpeed(brand)
+		if err != nil {
+			render(w, r, http.StatusNotFound, map[string]string{"error": err.Error()})
+			return
+		}
+		capacity, err := h.sv.FindByBrandAverageCapacity(brand)
+		if err != nil {
+			render(w, r, http.StatusNotFound, map[string]string{"error": err.Error()})
+			return
+		}
+		us, _ := unitsOf(r)
+		render(w, r, http.StatusOK, map[string]any{
+			"message": "success",
+			"data":    BrandAveragesJSON{Brand: brand, MaxSpeed: us.Convert(speed, internal.VehicleQuantitySpeed), Capacity: capacity},
+			"units":   map[string]string{"max_speed": us.Unit(internal.VehicleQuantitySpeed).Symbol},
+		})
+	}
+}
+
+// find is a method that returns the vehicle with the given id
+func (h *VehicleV2) find(id int) (v internal.Vehicle, err error) {
+	vehicles, err := h.sv.FindById(id)
+	if err != nil {
+		return
+	}
+	if len(vehicles) == 0 {
+		return v, internal.ErrVehicleNotFound
+	}
+	return vehicles[0], nil
+}
+
+// writeVehicleError is a function that maps a vehicle service error to its response
+func writeVehicleError(w http.ResponseWriter, r *http.Request, err error) {
+	switch {
+	case errors.Is(err, internal.ErrVehicleNotFound):
+		render(w, r, http.StatusNotFound, map[string]string{"error": err.Error()})
+	case errors.Is(err, internal.ErrVehicleExists), errors.Is(err, internal.ErrVehicleRegistrationExists), errors.Is(err, internal.ErrVehicleVINExists):
+		render(w, r, http.StatusConflict, map[string]string{"error": err.Error()})
+	case errors.Is(err, internal.ErrVehicleRegistrationInvalid), errors.Is(err, internal.ErrVehicleVINInvalid), errors.Is(err, internal.ErrVehicleVINMismatch):
+		render(w, r, http.StatusUnprocessableEntity, map[string]string{"error": err.Error()})
+	default:
+		render(w, r, http.StatusInternalServerError, map[string]string{"error": "internal server error"})
+	}
+}
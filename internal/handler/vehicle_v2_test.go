@@ -0,0 +1,153 @@
+package handler
+
+import (
+	"app/internal"
+	"app/internal/vehicle"
+	"encoding/json"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// newVehicleV2Router is a function that returns the /v2 vehicle routes over a fleet of one vehicle
+func newVehicleV2Router() (chi.Router, internal.VehicleService) {
+	v := internal.Vehicle{Id: 1}
+	v.Brand, v.Registration, v.MaxSpeed, v.FuelType, v.Weight = "Toyota", "ABC1234", 150, "gasoline", 1000
+	sv := vehicle.NewVehicleDefault(vehicle.NewVehicleMap(map[int]internal.Vehicle{1: v}), nil)
+	h := NewVehicleV2(sv)
+
+	rt := chi.NewRouter()
+	rt.Get("/v2/vehicles/", h.GetAll())
+	rt.Post("/v2/vehicles/", h.PostCreate())
+	rt.Patch("/v2/vehicles/{id}", h.PatchUpdate())
+	return rt, sv
+}
+
+func TestVehicleV2_PostCreateId(t *testing.T) {
+	cases := []struct {
+		body string
+		code int
+	}{
+		{`{"id":0,"brand":"Ford","registration":"DEF5678"}`, http.StatusBadRequest},
+		{`{"id":-3,"brand":"Ford","registration":"DEF5678"}`, http.StatusBadRequest},
+		{`{"brand":"Ford","registration":"DEF5678"}`, http.StatusBadRequest},
+		{`{"id":2,"brand":"Ford","registration":"DEF5678"}`, http.StatusCreated},
+	}
+
+	for _, c := range cases {
+		t.Run(c.body, func(t *testing.T) {
+			rt, _ := newVehicleV2Router()
+			req := httptest.NewRequest(http.MethodPost, "/v2/vehicles/", strings.NewReader(c.body))
+			res := httptest.NewRecorder()
+			rt.ServeHTTP(res, req)
+			if res.Code != c.code {
+				t.Errorf("code = %d, want %d: %s", res.Code, c.code, res.Body)
+			}
+		})
+	}
+}
+
+func TestVehicleV2_PatchUpdate(t *testing.T) {
+	rt, sv := newVehicleV2Router()
+
+	patch := func(id, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPatch, "/v2/vehicles/"+id, strings.NewReader(body))
+		res := httptest.NewRecorder()
+		rt.ServeHTTP(res, req)
+		return res
+	}
+
+	if res := patch("1", `{"max_speed":180}`); res.Code != http.StatusOK {
+		t.Fatalf("patch speed: code = %d, want 200: %s", res.Code, res.Body)
+	}
+	if res := patch("1", `{"fuel_type":"diesel"}`); res.Code != http.StatusOK {
+		t.Fatalf("patch fuel type: code = %d, want 200: %s", res.Code, res.Body)
+	}
+	vehicles, _ := sv.FindById(1)
+	if len(vehicles) != 1 || vehicles[0].MaxSpeed != 180 || vehicles[0].FuelType != "diesel" || vehicles[0].Registration != "ABC1234" {
+		t.Errorf("vehicle = %+v, want both patches applied and the rest kept", vehicles)
+	}
+
+	if res := patch("2", `{"max_speed":180}`); res.Code != http.StatusNotFound {
+		t.Errorf("patch missing vehicle: code = %d, want 404", res.Code)
+	}
+	if res := patch("1", `{}`); res.Code != http.StatusBadRequest {
+		t.Errorf("empty patch: code = %d, want 400", res.Code)
+	}
+}
+
+func TestVehicleV2_ImperialRoundTrip(t *testing.T) {
+	rt, sv := newVehicleV2Router()
+
+	serve := func(method, target, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, target, strings.NewReader(body))
+		res := httptest.NewRecorder()
+		rt.ServeHTTP(res, req)
+		return res
+	}
+	near := func(got, want float64) bool { return math.Abs(got-want) < 1e-3 }
+
+	// a bare number is in the unit system of the request and is read back unchanged
+	res := serve(http.MethodPatch, "/v2/vehicles/1?units=imperial", `{"max_speed":62}`)
+	if res.Code != http.StatusOK {
+		t.Fatalf("patch: code = %d, want 200: %s", res.Code, res.Body)
+	}
+	var body struct {
+		Data internal.VehicleJSON `json:"data"`
+	}
+	if err := json.Unmarshal(res.Body.Bytes(), &body); err != nil {
+		t.Fatalf("patch: %v", err)
+	}
+	if !near(float64(body.Data.MaxSpeed), 62) {
+		t.Errorf("max_speed = %v, want 62", body.Data.MaxSpeed)
+	}
+	vehicles, _ := sv.FindById(1)
+	if len(vehicles) != 1 || !near(vehicles[0].MaxSpeed, 62*1.609344) {
+		t.Errorf("stored max speed = %+v, want %v km/h", vehicles, 62*1.609344)
+	}
+
+	// a string with a unit keeps its own unit
+	res = serve(http.MethodPost, "/v2/vehicles/?units=imperial", `{"id":2,"brand":"Ford","registration":"DEF5678","max_speed":"100 km/h","weight":"2000 kg"}`)
+	if res.Code != http.StatusCreated {
+		t.Fatalf("post: code = %d, want 201: %s", res.Code, res.Body)
+	}
+	vehicles, _ = sv.FindById(2)
+	if len(vehicles) != 1 || !near(vehicles[0].MaxSpeed, 100) || !near(vehicles[0].Weight, 2000) {
+		t.Errorf("stored vehicle = %+v, want 100 km/h and 2000 kg", vehicles)
+	}
+
+	// the bounds of a filter are in the unit system of the request: 2000 lb is about 907 kg
+	cases := []struct {
+		query string
+		ids   []int
+	}{
+		{"?units=imperial&weight_min=2000", []int{1, 2}},
+		{"?units=imperial&weight_min=2300", []int{2}},
+		{"?weight_min=2000", []int{2}},
+	}
+	for _, c := range cases {
+		t.Run(c.query, func(t *testing.T) {
+			res := serve(http.MethodGet, "/v2/vehicles/"+c.query, "")
+			if res.Code != http.StatusOK {
+				t.Fatalf("code = %d, want 200: %s", res.Code, res.Body)
+			}
+			var body struct {
+				Data []internal.VehicleJSON `json:"data"`
+			}
+			if err := json.Unmarshal(res.Body.Bytes(), &body); err != nil {
+				t.Fatal(err)
+			}
+			ids := make([]int, 0, len(body.Data))
+			for _, v := range body.Data {
+				ids = append(ids, v.Id)
+			}
+			if len(ids) != len(c.ids) || (len(ids) > 0 && ids[0] != c.ids[0]) {
+				t.Errorf("ids = %v, want %v", ids, c.ids)
+			}
+		})
+	}
+}
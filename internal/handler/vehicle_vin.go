@@ -0,0 +1,80 @@
+package handler
+
+import (
+	"app/internal"
+	"errors"
+	"net/http"
+	"strings"
+)
+
+// VehicleVINJSON is a struct that represents the data decoded from a VIN in JSON format
+// - model_years are the model years the VIN may stand for, the most likely first
+type VehicleVINJSON struct {
+	VIN          string   `json:"vin"`
+	WMI          string   `json:"wmi"`
+	Manufacturer string   `json:"manufacturer,omitempty"`
+	Brands       []string `json:"brands,omitempty"`
+	Country      string   `json:"country,omitempty"`
+	Region       string   `json:"region,omitempty"`
+	ModelYears   []int    `json:"model_years"`
+}
+
+// GetByVIN is a method that returns the vehicle holding the VIN of the path, regardless of case, spaces and hyphens
+func (h *VehicleDefault) GetByVIN() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		vin := pathParam(r, "vin")
+		if strings.TrimSpace(vin) == "" {
+			render(w, r, http.StatusBadRequest, map[string]string{"error": "invalid vin"})
+			return
+		}
+
+		vehicles, err := h.sv.FindByVIN(vin)
+		if err != nil {
+			render(w, r, http.StatusInternalServerError, map[string]string{"error": "internal server error"})
+			return
+		}
+		renderHeldBy(w, r, "vin", vin, vehicles)
+	}
+}
+
+// NewVINDefault is a function that returns a new instance of VINDefault
+func NewVINDefault(dc internal.VehicleVINDecoder) *VINDefault {
+	return &VINDefault{dc: dc}
+}
+
+// VINDefault is a struct that represents the handler of the decoding of VINs
+type VINDefault struct {
+	// dc is the decoder of the VINs
+	dc internal.VehicleVINDecoder
+}
+
+// GetDecode is a method that checks the VIN of the path and returns its manufacturer, country and model years
+func (h *VINDefault) GetDecode() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		d, err := h.dc.Decode(pathParam(r, "vin"))
+		if err != nil {
+			if errors.Is(err, internal.ErrVehicleVINInvalid) {
+				render(w, r, http.StatusUnprocessableEntity, map[string]string{"error": err.Error()})
+				return
+			}
+			render(w, r, http.StatusInternalServerError, map[string]string{"error": "internal server error"})
+			return
+		}
+		years := d.ModelYears
+		if years == nil {
+			years = []int{}
+		}
+		render(w, r, http.StatusOK, map[string]any{
+			"message": "success",
+			"data": VehicleVINJSON{
+				VIN:          d.VIN,
+				WMI:          d.WMI,
+				Manufacturer: d.Manufacturer,
+				Brands:       d.Brands,
+				Country:      d.Country,
+				Region:       d.Region,
+				ModelYears:   years,
+			},
+		})
+	}
+}
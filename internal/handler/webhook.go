@@ -0,0 +1,249 @@
+package handler
+
+import (
+	"app/internal"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// WebhookJSON is a struct that represents a webhook in JSON format
+// - the secret is only returned on creation
+type WebhookJSON struct {
+	ID        int       `json:"id"`
+	URL       string    `json:"url"`
+	Secret    string    `json:"secret,omitempty"`
+	Events    []string  `json:"events"`
+	Units     string    `json:"units"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// WebhookDeliveryJSON is a struct that represents a webhook delivery in JSON format
+type WebhookDeliveryJSON struct {
+	ID             int             `json:"id"`
+	WebhookID      int             `json:"webhook_id"`
+	Event          string          `json:"event"`
+	VehicleID      int             `json:"vehicle_id"`
+	Status         string          `json:"status"`
+	Attempts       int             `json:"attempts"`
+	LastStatusCode int             `json:"last_status_code,omitempty"`
+	LastError      string          `json:"last_error,omitempty"`
+	NextAttemptAt  *time.Time      `json:"next_attempt_at,omitempty"`
+	Payload        json.RawMessage `json:"payload"`
+	CreatedAt      time.Time       `json:"created_at"`
+	UpdatedAt      time.Time       `json:"updated_at"`
+}
+
+// NewWebhookDefault is a function that returns a new instance of WebhookDefault
+func NewWebhookDefault(sv internal.WebhookService) *WebhookDefault {
+	return &WebhookDefault{sv: sv}
+}
+
+// WebhookDefault is a struct that represents the handler of webhook subscriptions
+type WebhookDefault struct {
+	// sv is the webhook service
+	sv internal.WebhookService
+}
+
+// GetAll is a method that returns all the webhooks
+func (h *WebhookDefault) GetAll() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		webhooks, err := h.sv.FindAll()
+		if err != nil {
+			render(w, r, http.StatusInternalServerError, nil)
+			return
+		}
+
+		data := make([]WebhookJSON, 0, len(webhooks))
+		for _, value := range webhooks {
+			data = append(data, webhookToJSON(value, false))
+		}
+		render(w, r, http.StatusOK, map[string]any{
+			"message": "success",
+			"data":    data,
+		})
+	}
+}
+
+// GetById is a method that returns a webhook
+func (h *WebhookDefault) GetById() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id, err := strconv.Atoi(chi.URLParam(r, "id"))
+		if err != nil {
+			render(w, r, http.StatusBadRequest, map[string]string{"error": "invalid ID"})
+			return
+		}
+
+		wh, err := h.sv.FindById(id)
+		if err != nil {
+			writeWebhookError(w, r, err)
+			return
+		}
+		render(w, r, http.StatusOK, map[string]any{
+			"message": "success",
+			"data":    webhookToJSON(wh, false),
+		})
+	}
+}
+
+// PostCreate is a method that creates a webhook subscription.
+// units is the unit system of the vehicles of its deliveries, metric by default.
+func (h *WebhookDefault) PostCreate() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var req struct {
+			URL    string   `json:"url"`
+			Secret string   `json:"secret"`
+			Events []string `json:"events"`
+			Units  string   `json:"units"`
+		}
+		if err := decode(r, &req); err != nil {
+			render(w, r, http.StatusBadRequest, map[string]string{"error": "invalid body"})
+			return
+		}
+
+		wh := internal.Webhook{URL: req.URL, Secret: req.Secret, Units: internal.VehicleUnitSystem(req.Units)}
+		for _, e := range req.Events {
+			wh.Events = append(wh.Events, internal.VehicleChangeType(e))
+		}
+		if err := h.sv.Create(&wh); err != nil {
+			writeWebhookError(w, r, err)
+			return
+		}
+		render(w, r, http.StatusCreated, map[string]any{
+			"message": "webhook created successfully",
+			"data":    webhookToJSON(wh, true),
+		})
+	}
+}
+
+// DeleteById is a method that removes a webhook subscription
+func (h *WebhookDefault) DeleteById() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id, err := strconv.Atoi(chi.URLParam(r, "id"))
+		if err != nil {
+			render(w, r, http.StatusBadRequest, map[string]string{"error": "invalid ID"})
+			return
+		}
+
+		if err := h.sv.Delete(id); err != nil {
+			writeWebhookError(w, r, err)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+// GetDeliveries is a method that returns the delivery log of a webhook
+func (h *WebhookDefault) GetDeliveries() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id, err := strconv.Atoi(chi.URLParam(r, "id"))
+		if err != nil {
+			render(w, r, http.StatusBadRequest, map[string]string{"error": "invalid ID"})
+			return
+		}
+
+		deliveries, err := h.sv.FindDeliveries(id)
+		if err != nil {
+			writeWebhookError(w, r, err)
+			return
+		}
+		render(w, r, http.StatusOK, map[string]any{
+			"message": "success",
+			"data":    webhookDeliveriesToJSON(deliveries),
+		})
+	}
+}
+
+// GetDeadLetters is a method that returns the deliveries that exhausted their attempts
+func (h *WebhookDefault) GetDeadLetters() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		deliveries, err := h.sv.FindDeadLetters()
+		if err != nil {
+			render(w, r, http.StatusInternalServerError, nil)
+			return
+		}
+		render(w, r, http.StatusOK, map[string]any{
+			"message": "success",
+			"data":    webhookDeliveriesToJSON(deliveries),
+		})
+	}
+}
+
+// PostRedeliver is a method that queues a dead delivery again
+func (h *WebhookDefault) PostRedeliver() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id, err := strconv.Atoi(chi.URLParam(r, "id"))
+		if err != nil {
+			render(w, r, http.StatusBadRequest, map[string]string{"error": "invalid ID"})
+			return
+		}
+
+		if err := h.sv.Redeliver(id); err != nil {
+			writeWebhookError(w, r, err)
+			return
+		}
+		render(w, r, http.StatusAccepted, map[string]string{
+			"message": "delivery queued",
+		})
+	}
+}
+
+// writeWebhookError is a function that maps a webhook service error to its response
+func writeWebhookError(w http.ResponseWriter, r *http.Request, err error) {
+	switch {
+	case errors.Is(err, internal.ErrWebhookNotFound):
+		render(w, r, http.StatusNotFound, map[string]string{"error": err.Error()})
+	case errors.Is(err, internal.ErrWebhookInvalid):
+		render(w, r, http.StatusUnprocessableEntity, map[string]string{"error": err.Error()})
+	default:
+		render(w, r, http.StatusInternalServerError, map[string]string{"error": "internal server error"})
+	}
+}
+
+// webhookToJSON is a function that converts a webhook to its JSON representation
+func webhookToJSON(wh internal.Webhook, withSecret bool) (data WebhookJSON) {
+	data = WebhookJSON{
+		ID:        wh.Id,
+		URL:       wh.URL,
+		Events:    make([]string, 0, len(wh.Events)),
+		Units:     string(wh.Units),
+		CreatedAt: wh.CreatedAt,
+	}
+	if withSecret {
+		data.Secret = wh.Secret
+	}
+	for _, e := range wh.Events {
+		data.Events = append(data.Events, string(e))
+	}
+	return
+}
+
+// webhookDeliveriesToJSON is a function that converts deliveries to their JSON representation
+func webhookDeliveriesToJSON(deliveries []internal.WebhookDelivery) []WebhookDeliveryJSON {
+	data := make([]WebhookDeliveryJSON, 0, len(deliveries))
+	for _, d := range deliveries {
+		item := WebhookDeliveryJSON{
+			ID:             d.Id,
+			WebhookID:      d.WebhookId,
+			Event:          string(d.Event),
+			VehicleID:      d.VehicleId,
+			Status:         string(d.Status),
+			Attempts:       d.Attempts,
+			LastStatusCode: d.LastStatusCode,
+			LastError:      d.LastError,
+			Payload:        json.RawMessage(d.Payload),
+			CreatedAt:      d.CreatedAt,
+			UpdatedAt:      d.UpdatedAt,
+		}
+		if d.Status == internal.WebhookDeliveryPending {
+			next := d.NextAttemptAt
+			item.NextAttemptAt = &next
+		}
+		data = append(data, item)
+	}
+	return data
+}
@@ -0,0 +1,90 @@
+package internal
+
+import (
+	"errors"
+	"io"
+	"time"
+)
+
+// JobStatus is the state of a job
+type JobStatus string
+
+const (
+	// JobRunning is a job in progress
+	JobRunning JobStatus = "running"
+	// JobSucceeded is a job that went through its whole input, some rows may still have failed
+	JobSucceeded JobStatus = "succeeded"
+	// JobFailed is a job stopped by an error
+	JobFailed JobStatus = "failed"
+)
+
+var (
+	// ErrJobNotFound is returned when a job does not exist
+	ErrJobNotFound = errors.New("job not found")
+)
+
+// JobRowError is a struct that represents a row rejected by a job
+type JobRowError struct {
+	// Line is the line of the row in the input
+	Line int
+	// Message is the reason
+	Message string
+}
+
+// Job is a struct that represents a job over an input, its progress can be followed while it runs
+type Job struct {
+	Id int
+	// Kind is the kind of job, e.g. "vehicles_import"
+	Kind   string
+	Status JobStatus
+	// BytesTotal is the size of the input, 0 when it is not known up front
+	BytesTotal int64
+	// BytesRead is the amount of the input processed so far
+	BytesRead int64
+	// Processed is the number of rows read
+	Processed int
+	// Succeeded is the number of rows applied
+	Succeeded int
+	// Failed is the number of rows rejected
+	Failed int
+	// Errors is the list of rejected rows, limited to the first ones
+	Errors []JobRowError
+	// ErrorsTruncated reports whether some rejected rows are missing from Errors
+	ErrorsTruncated bool
+	// Error is the reason a failed job stopped
+	Error      string
+	CreatedAt  time.Time
+	FinishedAt time.Time
+}
+
+// JobRepository is an interface that represents the storage of jobs
+type JobRepository interface {
+	// Create is a method that stores a job, assigning its id
+	Create(j *Job) (err error)
+	// Update is a method that replaces a stored job
+	Update(j Job) (err error)
+	// FindById is a method that returns a job
+	FindById(id int) (j Job, err error)
+}
+
+// VehicleImportOptions is a struct that represents the format of a vehicle import
+type VehicleImportOptions struct {
+	// Format is either "ndjson" or "csv"
+	Format string
+	// CSVDelimiter is the column separator of a CSV input, 0 for the default
+	CSVDelimiter rune
+	// CSVDecimalSeparator is the decimal separator of a CSV input, 0 for the default
+	CSVDecimalSeparator rune
+	// Size is the size of the input when known, e.g. from Content-Length, 0 otherwise
+	Size int64
+}
+
+// VehicleImporter is an interface that represents an importer of vehicle files
+type VehicleImporter interface {
+	// Import is a method that starts a job importing the input as it is read and returns it without
+	// waiting for the input. The job reads r as it goes, r must stay readable until done is closed,
+	// so the job lasts as long as whoever provides r, e.g. the request of an upload.
+	Import(r io.Reader, opts VehicleImportOptions) (j Job, done <-chan struct{}, err error)
+	// FindJob is a method that returns the state of an import job
+	FindJob(id int) (j Job, err error)
+}
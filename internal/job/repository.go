@@ -0,0 +1,65 @@
+package job
+
+import (
+	"app/internal"
+	"fmt"
+	"sync"
+)
+
+// NewJobMap is a function that returns a new instance of JobMap
+func NewJobMap() *JobMap {
+	return &JobMap{db: make(map[int]internal.Job)}
+}
+
+// JobMap is a struct that implements the JobRepository interface in memory
+type JobMap struct {
+	// mu guards the fields below
+	mu sync.RWMutex
+	// db is a map of jobs
+	db map[int]internal.Job
+	// lastId is the id of the last created job
+	lastId int
+}
+
+// Create is a method that stores a job, assigning its id
+func (r *JobMap) Create(j *internal.Job) (err error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.lastId++
+	j.Id = r.lastId
+	r.db[j.Id] = copyJob(*j)
+	return
+}
+
+// Update is a method that replaces a stored job
+func (r *JobMap) Update(j internal.Job) (err error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.db[j.Id]; !ok {
+		return fmt.Errorf("%w: id %d", internal.ErrJobNotFound, j.Id)
+	}
+	r.db[j.Id] = copyJob(j)
+	return
+}
+
+// FindById is a method that returns a job
+func (r *JobMap) FindById(id int) (j internal.Job, err error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	j, ok := r.db[id]
+	if !ok {
+		err = fmt.Errorf("%w: id %d", internal.ErrJobNotFound, id)
+		return
+	}
+	j = copyJob(j)
+	return
+}
+
+// copyJob is a function that copies the errors of a job so stored jobs are not shared with callers
+func copyJob(j internal.Job) internal.Job {
+	j.Errors = append([]internal.JobRowError(nil), j.Errors...)
+	return j
+}
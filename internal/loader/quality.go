@@ -0,0 +1,106 @@
+package loader
+
+import (
+	"fmt"
+	"strings"
+)
+
+// QualityIssueKind is the kind of a data quality issue
+type QualityIssueKind string
+
+const (
+	// IssueUnknownField is a field that is not part of the vehicle schema
+	IssueUnknownField QualityIssueKind = "unknown_field"
+	// IssueMissingField is a required field that is absent or null
+	IssueMissingField QualityIssueKind = "missing_field"
+	// IssueDuplicateId is an id already used by an earlier row
+	IssueDuplicateId QualityIssueKind = "duplicate_id"
+	// IssueInvalidValue is a value of the wrong type, the row can't be loaded
+	IssueInvalidValue QualityIssueKind = "invalid_value"
+)
+
+// QualityIssue is a struct that represents an issue found in a row of a data file
+type QualityIssue struct {
+	// Line is the line where the row starts
+	Line int `json:"line"`
+	// Row is the position of the row in the file, starting at 1
+	Row int `json:"row"`
+	// Id is the id of the vehicle, 0 when unknown
+	Id int `json:"id,omitempty"`
+	// Field is the field of the issue, empty when the whole row is concerned
+	Field string `json:"field,omitempty"`
+	// Kind is the kind of issue
+	Kind QualityIssueKind `json:"kind"`
+	// Message describes the issue
+	Message string `json:"message"`
+}
+
+// String is a method that returns the issue as a log line
+func (i QualityIssue) String() string {
+	if i.Field == "" {
+		return fmt.Sprintf("line %d: %s: %s", i.Line, i.Kind, i.Message)
+	}
+	return fmt.Sprintf("line %d: %s %s: %s", i.Line, i.Kind, i.Field, i.Message)
+}
+
+// QualityReport is a struct that represents the data quality of a loaded file
+type QualityReport struct {
+	// Path is the path to the file
+	Path string `json:"path"`
+	// Version is the version of the envelope, 0 for a bare list of vehicles
+	Version int `json:"version"`
+	// Strict reports whether the file was loaded in strict mode
+	Strict bool `json:"strict"`
+	// Rows is the number of rows in the file
+	Rows int `json:"rows"`
+	// Loaded is the number of vehicles loaded, 0 when a strict load was rejected
+	Loaded int `json:"loaded"`
+	// Counts is the number of issues of each kind
+	Counts map[QualityIssueKind]int `json:"counts"`
+	// Issues is the list of issues in file order
+	Issues []QualityIssue `json:"issues"`
+}
+
+// add is a method that records an issue
+func (r *QualityReport) add(i QualityIssue) {
+	if r.Counts == nil {
+		r.Counts = make(map[QualityIssueKind]int)
+	}
+	r.Counts[i.Kind]++
+	r.Issues = append(r.Issues, i)
+}
+
+// Summary is a method that returns a one line description of the report
+func (r QualityReport) Summary() string {
+	kinds := make([]string, 0, len(r.Counts))
+	for _, k := range []QualityIssueKind{IssueUnknownField, IssueMissingField, IssueDuplicateId, IssueInvalidValue} {
+		if n := r.Counts[k]; n > 0 {
+			kinds = append(kinds, fmt.Sprintf("%d %s", n, k))
+		}
+	}
+	summary := fmt.Sprintf("version %d, %d rows, %d loaded, %d issues", r.Version, r.Rows, r.Loaded, len(r.Issues))
+	if len(kinds) > 0 {
+		summary += " (" + strings.Join(kinds, ", ") + ")"
+	}
+	return summary
+}
+
+// QualityReporter is an interface implemented by the loaders that produce a data quality report
+type QualityReporter interface {
+	// Report is a method that returns the report of the last load
+	Report() QualityReport
+}
+
+// QualityError is a struct that represents a file rejected by a strict load
+type QualityError struct {
+	Report QualityReport
+}
+
+// Error is a method that returns the error message
+func (e *QualityError) Error() string {
+	msg := fmt.Sprintf("%s: rejected by strict mode: %s", e.Report.Path, e.Report.Summary())
+	if len(e.Report.Issues) > 0 {
+		msg += ", first: " + e.Report.Issues[0].String()
+	}
+	return msg
+}
@@ -0,0 +1,359 @@
+package loader
+
+import (
+	"app/internal"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// VehicleCSVFields is the list of fields a CSV column can be mapped to, named as in internal.VehicleJSON.
+// The values of the fields with a unit may have a unit, e.g. "2645 lb", a bare number is in km/h, kg or m.
+// The raw brand and color are written by the normalization of the vehicles.
+var VehicleCSVFields = []string{
+	"id", "brand", "model", "registration", "color", "year", "passengers", "max_speed",
+	"fuel_type", "transmission", "weight", "height", "length", "width", "vin", "brand_raw", "color_raw",
+}
+
+// defaultCSVHeader is the mapping of common column names to fields, applied before the configured one
+var defaultCSVHeader = map[string]string{
+	"capacity":         "passengers",
+	"fabrication_year": "year",
+	"speed":            "max_speed",
+	"fuel":             "fuel_type",
+	"plate":            "registration",
+}
+
+// RowError is a struct that represents an invalid row of a CSV file
+type RowError struct {
+	// Line is the line of the row in the file, starting at 1 for the header
+	Line int
+	// Column is the header of the invalid column, empty when the whole row is invalid
+	Column string
+	// Err is the reason
+	Err error
+}
+
+// Error is a method that returns the error message
+func (e RowError) Error() string {
+	if e.Column == "" {
+		return fmt.Sprintf("line %d: %s", e.Line, e.Err)
+	}
+	return fmt.Sprintf("line %d: column %s: %s", e.Line, e.Column, e.Err)
+}
+
+// Unwrap is a method that returns the reason
+func (e RowError) Unwrap() error {
+	return e.Err
+}
+
+// RowErrors is a list of row errors returned along with the valid rows
+type RowErrors []RowError
+
+// Error is a method that returns the error message
+func (e RowErrors) Error() string {
+	msgs := make([]string, 0, len(e))
+	for _, re := range e {
+		msgs = append(msgs, re.Error())
+	}
+	return fmt.Sprintf("%d invalid rows: %s", len(e), strings.Join(msgs, "; "))
+}
+
+// ConfigVehicleCSV is a struct that represents the format of a vehicle CSV file
+type ConfigVehicleCSV struct {
+	// Delimiter is the column separator, default ','
+	Delimiter rune
+	// DecimalSeparator is the decimal separator of numbers, default '.'
+	DecimalSeparator rune
+	// Header maps column names to fields of VehicleCSVFields, e.g. "capacity" to "passengers".
+	// Column names are compared case-insensitively with spaces and dashes read as underscores.
+	Header map[string]string
+}
+
+// NewVehicleCSVFile is a function that returns a new instance of VehicleCSVFile
+func NewVehicleCSVFile(path string, cfg *ConfigVehicleCSV) *VehicleCSVFile {
+	return &VehicleCSVFile{
+		path: path,
+		cfg:  cfg,
+	}
+}
+
+// VehicleCSVFile is a struct that implements the LoaderVehicle interface for CSV files
+type VehicleCSVFile struct {
+	// path is the path to the file that contains the vehicles in CSV format
+	path string
+	// cfg is the format of the file
+	cfg *ConfigVehicleCSV
+}
+
+// Load is a method that loads the vehicles of the valid rows.
+// When some rows are invalid the error is a RowErrors listing them, and v holds the valid ones.
+func (l *VehicleCSVFile) Load() (v map[int]internal.Vehicle, err error) {
+	// open file
+	file, err := os.Open(l.path)
+	if err != nil {
+		return
+	}
+	defer file.Close()
+
+	// decode file
+	dec, err := NewVehicleCSVDecoder(file, l.cfg)
+	if err != nil {
+		return
+	}
+	v = make(map[int]internal.Vehicle)
+	lines := make(map[int]int)
+	var rowErrs RowErrors
+	for {
+		vh, line, err := dec.Next()
+		if err == io.EOF {
+			break
+		}
+		var re RowError
+		if errors.As(err, &re) {
+			rowErrs = append(rowErrs, re)
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		if first, ok := lines[vh.Id]; ok {
+			rowErrs = append(rowErrs, RowError{Line: line, Column: "id", Err: fmt.Errorf("duplicate id %d, first seen on line %d", vh.Id, first)})
+			continue
+		}
+		lines[vh.Id] = line
+		v[vh.Id] = vh
+	}
+	if len(rowErrs) > 0 {
+		err = rowErrs
+	}
+	return
+}
+
+// NewVehicleCSVDecoder is a function that reads the header of a CSV stream and returns a decoder of its rows
+func NewVehicleCSVDecoder(r io.Reader, cfg *ConfigVehicleCSV) (d *VehicleCSVDecoder, err error) {
+	// default config
+	defaultConfig := &ConfigVehicleCSV{
+		Delimiter:        ',',
+		DecimalSeparator: '.',
+	}
+	if cfg != nil {
+		if cfg.Delimiter != 0 {
+			defaultConfig.Delimiter = cfg.Delimiter
+		}
+		if cfg.DecimalSeparator != 0 {
+			defaultConfig.DecimalSeparator = cfg.DecimalSeparator
+		}
+		defaultConfig.Header = cfg.Header
+	}
+	if defaultConfig.Delimiter == defaultConfig.DecimalSeparator {
+		return nil, errors.New("csv: delimiter and decimal separator must differ")
+	}
+
+	// mapping
+	mapping := make(map[string]string)
+	for _, f := range VehicleCSVFields {
+		mapping[f] = f
+	}
+	for col, f := range defaultCSVHeader {
+		mapping[col] = f
+	}
+	for col, f := range defaultConfig.Header {
+		if mapping[f] != f {
+			return nil, fmt.Errorf("csv: header %q mapped to unknown field %q", col, f)
+		}
+		mapping[normalizeColumn(col)] = f
+	}
+
+	// header
+	rd := csv.NewReader(r)
+	rd.Comma = defaultConfig.Delimiter
+	rd.FieldsPerRecord = -1
+	rd.TrimLeadingSpace = true
+	header, err := rd.Read()
+	if err != nil {
+		if err == io.EOF {
+			err = errors.New("csv: missing header")
+		}
+		return
+	}
+	d = &VehicleCSVDecoder{
+		rd:      rd,
+		decimal: defaultConfig.DecimalSeparator,
+		header:  header,
+		fields:  make([]string, len(header)),
+	}
+	seen := make(map[string]string)
+	for i, col := range header {
+		if i == 0 {
+			col = strings.TrimPrefix(col, "\ufeff")
+		}
+		f, ok := mapping[normalizeColumn(col)]
+		if !ok {
+			// unknown columns are ignored
+			continue
+		}
+		if prev, ok := seen[f]; ok {
+			return nil, fmt.Errorf("csv: columns %q and %q both map to %q", prev, col, f)
+		}
+		seen[f] = col
+		d.fields[i] = f
+	}
+	if _, ok := seen["id"]; !ok {
+		return nil, errors.New("csv: missing id column")
+	}
+	return
+}
+
+// VehicleCSVDecoder is a struct that decodes the rows of a CSV stream one at a time
+type VehicleCSVDecoder struct {
+	// rd is the CSV reader
+	rd *csv.Reader
+	// decimal is the decimal separator
+	decimal rune
+	// header is the list of column names
+	header []string
+	// fields is the field of each column, empty for ignored columns
+	fields []string
+}
+
+// Next is a method that decodes the next row.
+// An invalid row returns a RowError and decoding may go on, io.EOF ends the stream.
+func (d *VehicleCSVDecoder) Next() (v internal.Vehicle, line int, err error) {
+	record, err := d.rd.Read()
+	if err != nil {
+		var pe *csv.ParseError
+		if errors.As(err, &pe) {
+			line = pe.StartLine
+			err = RowError{Line: line, Err: pe.Err}
+		}
+		return
+	}
+	line, _ = d.rd.FieldPos(0)
+
+	var hasId bool
+	for i, value := range record {
+		if i >= len(d.fields) || d.fields[i] == "" {
+			continue
+		}
+		value = strings.TrimSpace(value)
+		if value == "" {
+			continue
+		}
+		if err = d.set(&v, d.fields[i], value); err != nil {
+			err = RowError{Line: line, Column: d.header[i], Err: err}
+			return
+		}
+		hasId = hasId || d.fields[i] == "id"
+	}
+	if !hasId {
+		err = RowError{Line: line, Column: "id", Err: errors.New("missing id")}
+	}
+	return
+}
+
+// set is a method that coerces the value and assigns it to the field of the vehicle
+func (d *VehicleCSVDecoder) set(v *internal.Vehicle, field, value string) (err error) {
+	switch field {
+	case "id":
+		v.Id, err = d.parseInt(value)
+	case "brand":
+		v.Brand = textValue(value)
+	case "model":
+		v.Model = textValue(value)
+	case "registration":
+		v.Registration = textValue(value)
+	case "vin":
+		v.VIN = textValue(value)
+	case "brand_raw":
+		v.BrandRaw = textValue(value)
+	case "color_raw":
+		v.ColorRaw = textValue(value)
+	case "color":
+		v.Color = textValue(value)
+	case "year":
+		v.FabricationYear, err = d.parseInt(value)
+	case "passengers":
+		v.Capacity, err = d.parseInt(value)
+	case "max_speed":
+		v.MaxSpeed, err = d.parseQuantity(value, internal.VehicleQuantitySpeed)
+	case "fuel_type":
+		v.FuelType = textValue(value)
+	case "transmission":
+		v.Transmission = textValue(value)
+	case "weight":
+		v.Weight, err = d.parseQuantity(value, internal.VehicleQuantityMass)
+	case "height":
+		v.Height, err = d.parseQuantity(value, internal.VehicleQuantityLength)
+	case "length":
+		v.Length, err = d.parseQuantity(value, internal.VehicleQuantityLength)
+	case "width":
+		v.Width, err = d.parseQuantity(value, internal.VehicleQuantityLength)
+	}
+	return
+}
+
+// parseFloat is a method that parses a number written with the configured decimal separator
+func (d *VehicleCSVDecoder) parseFloat(value string) (f float64, err error) {
+	if d.decimal != '.' {
+		if strings.ContainsRune(value, '.') {
+			return 0, fmt.Errorf("invalid number %q", value)
+		}
+		value = strings.ReplaceAll(value, string(d.decimal), ".")
+	}
+	f, err = strconv.ParseFloat(value, 64)
+	if err != nil || math.IsNaN(f) || math.IsInf(f, 0) {
+		return 0, fmt.Errorf("invalid number %q", value)
+	}
+	return
+}
+
+// parseQuantity is a method that parses a value of a quantity written with the configured decimal separator
+// and an optional unit, e.g. "1200 kg" or "120mph", and returns it in the stored unit of the quantity
+func (d *VehicleCSVDecoder) parseQuantity(value string, q internal.VehicleQuantity) (f float64, err error) {
+	if d.decimal != '.' {
+		if strings.ContainsRune(value, '.') {
+			return 0, fmt.Errorf("invalid number %q", value)
+		}
+		value = strings.ReplaceAll(value, string(d.decimal), ".")
+	}
+	if f, err = internal.ParseVehicleQuantity(value, q); err != nil {
+		return
+	}
+	if math.IsNaN(f) || math.IsInf(f, 0) {
+		return 0, fmt.Errorf("invalid number %q", value)
+	}
+	return
+}
+
+// parseInt is a method that parses an integer, accepting numbers with a zero fraction such as "2008.0"
+func (d *VehicleCSVDecoder) parseInt(value string) (i int, err error) {
+	if i, err = strconv.Atoi(value); err == nil {
+		return
+	}
+	f, err := d.parseFloat(value)
+	if err != nil || f != math.Trunc(f) || math.Abs(f) > math.MaxInt32 {
+		return 0, fmt.Errorf("invalid integer %q", value)
+	}
+	return int(f), nil
+}
+
+// textValue is a function that drops the quote an export puts before a text value a spreadsheet
+// would read as a formula, e.g. "'=x" is read as "=x"
+func textValue(value string) string {
+	if len(value) > 1 && value[0] == '\'' && strings.ContainsRune("=+-@\t\r", rune(value[1])) {
+		return value[1:]
+	}
+	return value
+}
+
+// normalizeColumn is a function that returns the lookup key of a column name
+func normalizeColumn(col string) string {
+	col = strings.ToLower(strings.TrimSpace(col))
+	return strings.NewReplacer(" ", "_", "-", "_").Replace(col)
+}
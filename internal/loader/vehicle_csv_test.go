@@ -0,0 +1,156 @@
+package loader
+
+import (
+	"app/internal"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// decodeAll is a function that decodes every row of a CSV stream, keeping the row errors apart
+func decodeAll(t *testing.T, data string, cfg *ConfigVehicleCSV) (v []internal.Vehicle, rowErrs []RowError) {
+	t.Helper()
+	dec, err := NewVehicleCSVDecoder(strings.NewReader(data), cfg)
+	if err != nil {
+		t.Fatalf("new decoder: %v", err)
+	}
+	for {
+		vh, _, err := dec.Next()
+		var re RowError
+		switch {
+		case err == nil:
+			v = append(v, vh)
+			continue
+		case errors.As(err, &re):
+			rowErrs = append(rowErrs, re)
+			continue
+		case err == io.EOF:
+			return
+		default:
+			t.Fatalf("next: %v", err)
+		}
+	}
+}
+
+func TestVehicleCSVDecoder_Header(t *testing.T) {
+	cases := []struct {
+		name   string
+		data   string
+		header map[string]string
+		want   internal.Vehicle
+	}{
+		{
+			name: "field names",
+			data: "id,brand,passengers,year\n1,Toyota,5,2010\n",
+			want: internal.Vehicle{Id: 1, VehicleAttributes: internal.VehicleAttributes{Brand: "Toyota", Capacity: 5, FabricationYear: 2010}},
+		},
+		{
+			name: "default aliases with case, spaces and dashes",
+			data: "\ufeffID,Capacity,Fabrication Year,fuel-type,Plate,Notes\n2,7,2015,diesel,ABC1234,ignored\n",
+			want: internal.Vehicle{Id: 2, VehicleAttributes: internal.VehicleAttributes{Capacity: 7, FabricationYear: 2015, FuelType: "diesel", Registration: "ABC1234"}},
+		},
+		{
+			name:   "configured mapping",
+			data:   "codigo,marca,ano\n3,Fiat,2001\n",
+			header: map[string]string{"Codigo": "id", "marca": "brand", "ano": "year"},
+			want:   internal.Vehicle{Id: 3, VehicleAttributes: internal.VehicleAttributes{Brand: "Fiat", FabricationYear: 2001}},
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			v, rowErrs := decodeAll(t, c.data, &ConfigVehicleCSV{Header: c.header})
+			if len(rowErrs) != 0 || len(v) != 1 || v[0] != c.want {
+				t.Errorf("vehicles = %+v, row errors %v, want %+v", v, rowErrs, c.want)
+			}
+		})
+	}
+}
+
+func TestVehicleCSVDecoder_HeaderInvalid(t *testing.T) {
+	cases := []struct {
+		name   string
+		data   string
+		header map[string]string
+		want   string
+	}{
+		{name: "missing id", data: "brand\nToyota\n", want: "missing id column"},
+		{name: "two columns for a field", data: "id,capacity,passengers\n1,5,5\n", want: "both map to"},
+		{name: "unknown field", data: "id\n1\n", header: map[string]string{"x": "wings"}, want: "unknown field"},
+		{name: "empty", data: "", want: "missing header"},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			_, err := NewVehicleCSVDecoder(strings.NewReader(c.data), &ConfigVehicleCSV{Header: c.header})
+			if err == nil || !strings.Contains(err.Error(), c.want) {
+				t.Errorf("error = %v, want %q", err, c.want)
+			}
+		})
+	}
+}
+
+func TestVehicleCSVDecoder_DecimalSeparator(t *testing.T) {
+	data := "id;max_speed;weight;height;year\n1;180,5;1200,25;1,5;2008,0\n2;120 mph;2645 lb;1.5;2010\n"
+	v, rowErrs := decodeAll(t, data, &ConfigVehicleCSV{Delimiter: ';', DecimalSeparator: ','})
+
+	if len(v) != 1 || v[0].MaxSpeed != 180.5 || v[0].Weight != 1200.25 || v[0].Height != 1.5 || v[0].FabricationYear != 2008 {
+		t.Errorf("vehicles = %+v, want vehicle 1 with the decimals read", v)
+	}
+	// - a '.' is not a decimal separator when ',' is, so 2645 lb is fine but 1.5 is rejected
+	if len(rowErrs) != 1 || rowErrs[0].Line != 3 || rowErrs[0].Column != "height" {
+		t.Errorf("row errors = %v, want line 3 column height", rowErrs)
+	}
+
+	if _, err := NewVehicleCSVDecoder(strings.NewReader(data), &ConfigVehicleCSV{Delimiter: ',', DecimalSeparator: ','}); err == nil {
+		t.Error("the same delimiter and decimal separator were accepted")
+	}
+}
+
+func TestVehicleCSVFile_RowErrors(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "vehicles.csv")
+	data := strings.Join([]string{
+		"id,brand,passengers,max_speed",
+		"1,Toyota,5,180",
+		"2,Ford,five,150",
+		",Fiat,4,120",
+		"3,Hon\"da,4,130",
+		"1,VW,4,140",
+		"4,Kia,5,160",
+	}, "\n")
+	if err := os.WriteFile(path, []byte(data), 0o644); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+
+	v, err := NewVehicleCSVFile(path, nil).Load()
+	var rowErrs RowErrors
+	if !errors.As(err, &rowErrs) {
+		t.Fatalf("error = %v, want RowErrors", err)
+	}
+	if len(v) != 2 || v[1].Brand != "Toyota" || v[4].Brand != "Kia" {
+		t.Errorf("vehicles = %+v, want the valid rows 1 and 4", v)
+	}
+	want := []struct {
+		line   int
+		column string
+	}{{3, "passengers"}, {4, "id"}, {5, ""}, {6, "id"}}
+	if len(rowErrs) != len(want) {
+		t.Fatalf("row errors = %v, want %d", rowErrs, len(want))
+	}
+	for i, w := range want {
+		if rowErrs[i].Line != w.line || rowErrs[i].Column != w.column {
+			t.Errorf("row error %d = %v, want line %d column %q", i, rowErrs[i], w.line, w.column)
+		}
+	}
+}
+
+func TestVehicleCSVDecoder_ExportedFormulas(t *testing.T) {
+	// - the quote the export puts before a formula character is dropped, any other is kept
+	v, rowErrs := decodeAll(t, "id,brand,model,color\n1,'=SUM(A1),'-2,'quoted\n", nil)
+	if len(rowErrs) != 0 || len(v) != 1 || v[0].Brand != "=SUM(A1)" || v[0].Model != "-2" || v[0].Color != "'quoted" {
+		t.Errorf("vehicles = %+v, row errors %v, want the values without the export quote", v, rowErrs)
+	}
+}
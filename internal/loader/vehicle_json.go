@@ -2,14 +2,51 @@ package loader
 
 import (
 	"app/internal"
+	"bytes"
 	"encoding/json"
+	"errors"
+	"fmt"
 	"os"
+	"sort"
 )
 
+// VehicleFileVersion is the latest version of the envelope of a vehicles file:
+// {"version":1,"vehicles":[...]}. A bare list of vehicles is read as version 0.
+const VehicleFileVersion = 1
+
+// VehicleRequiredFields is the default list of fields a row must have in strict mode
+var VehicleRequiredFields = []string{
+	"id", "brand", "model", "registration", "color", "year", "passengers", "max_speed",
+	"fuel_type", "transmission", "weight", "height", "length", "width",
+}
+
+// ConfigVehicleJSON is a struct that represents how a vehicle JSON file is read
+type ConfigVehicleJSON struct {
+	// Strict rejects the whole file when it has unknown fields, duplicate ids, missing required
+	// fields or invalid values. Otherwise the issues are only reported, a duplicate id overwrites
+	// the earlier row and a row with an invalid value is skipped.
+	Strict bool
+	// Required is the list of fields a row must have, default VehicleRequiredFields
+	Required []string
+}
+
 // NewVehicleJSONFile is a function that returns a new instance of VehicleJSONFile
-func NewVehicleJSONFile(path string) *VehicleJSONFile {
+func NewVehicleJSONFile(path string, cfg *ConfigVehicleJSON) *VehicleJSONFile {
+	// default config
+	defaultConfig := &ConfigVehicleJSON{
+		Required: VehicleRequiredFields,
+	}
+	if cfg != nil {
+		defaultConfig.Strict = cfg.Strict
+		if cfg.Required != nil {
+			defaultConfig.Required = cfg.Required
+		}
+	}
+
 	return &VehicleJSONFile{
-		path: path,
+		path:     path,
+		strict:   defaultConfig.Strict,
+		required: defaultConfig.Required,
 	}
 }
 
@@ -17,66 +54,211 @@ func NewVehicleJSONFile(path string) *VehicleJSONFile {
 type VehicleJSONFile struct {
 	// path is the path to the file that contains the vehicles in JSON format
 	path string
+	// strict reports whether any issue rejects the file
+	strict bool
+	// required is the list of fields a row must have
+	required []string
+	// report is the data quality report of the last load
+	report QualityReport
 }
 
-// VehicleJSON is a struct that represents a vehicle in JSON format
-type VehicleJSON struct {
-	Id              int     `json:"id"`
-	Brand           string  `json:"brand"`
-	Model           string  `json:"model"`
-	Registration    string  `json:"registration"`
-	Color           string  `json:"color"`
-	FabricationYear int     `json:"year"`
-	Capacity        int     `json:"passengers"`
-	MaxSpeed        float64 `json:"max_speed"`
-	FuelType        string  `json:"fuel_type"`
-	Transmission    string  `json:"transmission"`
-	Weight          float64 `json:"weight"`
-	Height          float64 `json:"height"`
-	Length          float64 `json:"length"`
-	Width           float64 `json:"width"`
-}
-
-// Load is a method that loads the vehicles
+// Load is a method that loads the vehicles and builds the data quality report of the file.
+// In strict mode a file with issues returns a *QualityError and no vehicles.
 func (l *VehicleJSONFile) Load() (v map[int]internal.Vehicle, err error) {
-	// open file
-	file, err := os.Open(l.path)
+	l.report = QualityReport{Path: l.path, Strict: l.strict, Issues: []QualityIssue{}}
+
+	// read file
+	// - the whole file is kept to report the line of each row
+	data, err := os.ReadFile(l.path)
 	if err != nil {
 		return
 	}
-	defer file.Close()
+	d := &vehicleJSONDecoder{
+		dec:  json.NewDecoder(bytes.NewReader(data)),
+		data: data,
+		l:    l,
+		v:    make(map[int]internal.Vehicle),
+		ids:  make(map[int]int),
+	}
 
 	// decode file
-	var vehiclesJSON []VehicleJSON
-	err = json.NewDecoder(file).Decode(&vehiclesJSON)
+	switch firstByte(data) {
+	case '[':
+		err = d.rows()
+	case '{':
+		err = d.envelope()
+	default:
+		err = errors.New("json: expected a list of vehicles or a versioned envelope")
+	}
 	if err != nil {
-		return
+		return nil, fmt.Errorf("%s: %w", l.path, err)
 	}
 
-	// serialize vehicles
-	v = make(map[int]internal.Vehicle)
-	for _, vh := range vehiclesJSON {
-		v[vh.Id] = internal.Vehicle{
-			Id: vh.Id,
-			VehicleAttributes: internal.VehicleAttributes{
-				Brand:           vh.Brand,
-				Model:           vh.Model,
-				Registration:    vh.Registration,
-				Color:           vh.Color,
-				FabricationYear: vh.FabricationYear,
-				Capacity:        vh.Capacity,
-				MaxSpeed:        vh.MaxSpeed,
-				FuelType:        vh.FuelType,
-				Transmission:    vh.Transmission,
-				Weight:          vh.Weight,
-				Dimensions: internal.Dimensions{
-					Height: vh.Height,
-					Length: vh.Length,
-					Width:  vh.Width,
-				},
-			},
+	l.report.Loaded = len(d.v)
+	if l.strict && len(l.report.Issues) > 0 {
+		l.report.Loaded = 0
+		return nil, &QualityError{Report: l.report}
+	}
+	v = d.v
+	return
+}
+
+// Report is a method that returns the data quality report of the last load
+func (l *VehicleJSONFile) Report() QualityReport {
+	return l.report
+}
+
+// vehicleJSONDecoder is a struct that holds the state of a load
+type vehicleJSONDecoder struct {
+	// dec is the token decoder of the file
+	dec *json.Decoder
+	// data is the content of the file
+	data []byte
+	// l is the loader, its report collects the issues
+	l *VehicleJSONFile
+	// v is the list of loaded vehicles
+	v map[int]internal.Vehicle
+	// ids is the line of the first row of each id
+	ids map[int]int
+}
+
+// envelope is a method that decodes a versioned envelope
+func (d *vehicleJSONDecoder) envelope() (err error) {
+	if _, err = d.dec.Token(); err != nil {
+		return
+	}
+	var version, vehicles bool
+	for d.dec.More() {
+		line := d.line()
+		tok, err := d.dec.Token()
+		if err != nil {
+			return err
 		}
+		switch key := tok.(string); key {
+		case "version":
+			if err = d.dec.Decode(&d.l.report.Version); err != nil {
+				return fmt.Errorf("json: invalid version: %w", err)
+			}
+			if d.l.report.Version < 1 || d.l.report.Version > VehicleFileVersion {
+				return fmt.Errorf("json: unsupported version %d, latest is %d", d.l.report.Version, VehicleFileVersion)
+			}
+			version = true
+		case "vehicles":
+			if !version {
+				return errors.New("json: version must come before vehicles")
+			}
+			if err = d.rows(); err != nil {
+				return err
+			}
+			vehicles = true
+		default:
+			var skip json.RawMessage
+			if err = d.dec.Decode(&skip); err != nil {
+				return err
+			}
+			d.l.report.add(QualityIssue{Line: line, Field: key, Kind: IssueUnknownField, Message: "unknown envelope field"})
+		}
+	}
+	if !version || !vehicles {
+		return errors.New("json: envelope needs version and vehicles")
 	}
+	_, err = d.dec.Token()
+	return
+}
 
+// rows is a method that decodes the list of vehicles
+func (d *vehicleJSONDecoder) rows() (err error) {
+	tok, err := d.dec.Token()
+	if err != nil {
+		return
+	}
+	if delim, ok := tok.(json.Delim); !ok || delim != '[' {
+		return errors.New("json: vehicles must be a list")
+	}
+	for d.dec.More() {
+		line := d.line()
+		var raw json.RawMessage
+		if err = d.dec.Decode(&raw); err != nil {
+			return
+		}
+		d.l.report.Rows++
+		d.row(d.l.report.Rows, line, raw)
+	}
+	_, err = d.dec.Token()
 	return
 }
+
+// row is a method that checks a row against the schema and loads it
+func (d *vehicleJSONDecoder) row(row, line int, raw json.RawMessage) {
+	report := func(id int, field string, kind QualityIssueKind, msg string) {
+		d.l.report.add(QualityIssue{Line: line, Row: row, Id: id, Field: field, Kind: kind, Message: msg})
+	}
+
+	// fields
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(raw, &fields); err != nil {
+		report(0, "", IssueInvalidValue, "row is not an object")
+		return
+	}
+	var vh internal.VehicleJSON
+	if err := json.Unmarshal(raw, &vh); err != nil {
+		var te *json.UnmarshalTypeError
+		if errors.As(err, &te) {
+			report(vh.Id, te.Field, IssueInvalidValue, fmt.Sprintf("expected %s, got %s", te.Type, te.Value))
+		} else {
+			report(vh.Id, "", IssueInvalidValue, err.Error())
+		}
+		return
+	}
+	names := make([]string, 0, len(fields))
+	for name := range fields {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		if !knownVehicleField[name] {
+			report(vh.Id, name, IssueUnknownField, "not a vehicle field")
+		}
+	}
+	for _, name := range d.l.required {
+		if value, ok := fields[name]; !ok || string(value) == "null" {
+			report(vh.Id, name, IssueMissingField, "required field is missing")
+		}
+	}
+
+	// id
+	if first, ok := d.ids[vh.Id]; ok {
+		report(vh.Id, "id", IssueDuplicateId, fmt.Sprintf("duplicate id %d, first seen on line %d", vh.Id, first))
+	} else {
+		d.ids[vh.Id] = line
+	}
+	d.v[vh.Id] = vh.Vehicle()
+}
+
+// line is a method that returns the line of the next value of the decoder
+func (d *vehicleJSONDecoder) line() int {
+	offset := int(d.dec.InputOffset())
+	// skip the separators before the value
+	for offset < len(d.data) && bytes.IndexByte([]byte(" \t\r\n,:"), d.data[offset]) >= 0 {
+		offset++
+	}
+	return bytes.Count(d.data[:offset], []byte("\n")) + 1
+}
+
+// knownVehicleField is the set of fields of internal.VehicleJSON
+var knownVehicleField = func() map[string]bool {
+	m := make(map[string]bool, len(VehicleCSVFields))
+	for _, f := range VehicleCSVFields {
+		m[f] = true
+	}
+	return m
+}()
+
+// firstByte is a function that returns the first non-space byte of data, 0 when there is none
+func firstByte(data []byte) byte {
+	data = bytes.TrimLeft(data, " \t\r\n")
+	if len(data) == 0 {
+		return 0
+	}
+	return data[0]
+}
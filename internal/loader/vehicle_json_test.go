@@ -0,0 +1,110 @@
+package loader
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// row is a function that returns a complete vehicle row with extra appended to its fields
+func row(id, extra string) string {
+	return `{"id":` + id + `,"brand":"Toyota","model":"Corolla","registration":"ABC1234","color":"red","year":2010,` +
+		`"passengers":5,"max_speed":180,"fuel_type":"gasoline","transmission":"manual","weight":1200,` +
+		`"height":1.5,"length":4.5,"width":1.8` + extra + `}`
+}
+
+// writeRows is a function that writes the rows as a JSON list, one per line, and returns the path
+func writeRows(t *testing.T, rows ...string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "vehicles.json")
+	if err := os.WriteFile(path, []byte("[\n"+strings.Join(rows, ",\n")+"\n]\n"), 0o644); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+	return path
+}
+
+func TestVehicleJSONFile_Strict(t *testing.T) {
+	cases := []struct {
+		name  string
+		rows  []string
+		kind  QualityIssueKind
+		field string
+		line  int
+	}{
+		{name: "unknown field", rows: []string{row("1", ""), row("2", `,"wings":2`)}, kind: IssueUnknownField, field: "wings", line: 3},
+		{name: "missing field", rows: []string{row("1", ""), `{"id":2,"brand":"Ford"}`}, kind: IssueMissingField, field: "model", line: 3},
+		{name: "null field", rows: []string{strings.Replace(row("1", ""), `"color":"red"`, `"color":null`, 1)}, kind: IssueMissingField, field: "color", line: 2},
+		{name: "duplicate id", rows: []string{row("1", ""), row("1", "")}, kind: IssueDuplicateId, field: "id", line: 3},
+		{name: "invalid value", rows: []string{row("1", ""), strings.Replace(row("2", ""), `"year":2010`, `"year":"2010"`, 1)}, kind: IssueInvalidValue, field: "year", line: 3},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			path := writeRows(t, c.rows...)
+
+			// strict mode rejects the whole file
+			l := NewVehicleJSONFile(path, &ConfigVehicleJSON{Strict: true})
+			v, err := l.Load()
+			var qe *QualityError
+			if !errors.As(err, &qe) || v != nil {
+				t.Fatalf("strict load = %v, %v, want a QualityError and no vehicles", v, err)
+			}
+			if qe.Report.Loaded != 0 || !qe.Report.Strict || qe.Report.Counts[c.kind] == 0 {
+				t.Errorf("report = %+v, want a strict report with a %s issue and nothing loaded", qe.Report, c.kind)
+			}
+			found := false
+			for _, i := range qe.Report.Issues {
+				found = found || i.Kind == c.kind && i.Field == c.field && i.Line == c.line
+			}
+			if !found {
+				t.Errorf("issues = %+v, want %s %s on line %d", qe.Report.Issues, c.kind, c.field, c.line)
+			}
+
+			// otherwise the issues are only reported
+			l = NewVehicleJSONFile(path, nil)
+			if v, err = l.Load(); err != nil || len(v) == 0 {
+				t.Fatalf("lenient load = %v, %v, want the vehicles", v, err)
+			}
+			if r := l.Report(); r.Strict || r.Loaded != len(v) || r.Counts[c.kind] == 0 {
+				t.Errorf("report = %+v, want the %s issue reported and %d loaded", r, c.kind, len(v))
+			}
+		})
+	}
+}
+
+func TestVehicleJSONFile_StrictClean(t *testing.T) {
+	path := writeRows(t, row("1", `,"vin":"1HGCM82633A004352","brand_raw":"toyota","color_raw":"RED"`), row("2", ""))
+	l := NewVehicleJSONFile(path, &ConfigVehicleJSON{Strict: true})
+	v, err := l.Load()
+	if err != nil || len(v) != 2 {
+		t.Fatalf("load = %v, %v, want 2 vehicles", v, err)
+	}
+	if v[1].BrandRaw != "toyota" || v[1].ColorRaw != "RED" || v[1].VIN == "" {
+		t.Errorf("vehicle 1 = %+v, want the vin and raw values", v[1])
+	}
+	if r := l.Report(); r.Rows != 2 || r.Loaded != 2 || len(r.Issues) != 0 {
+		t.Errorf("report = %+v, want 2 rows loaded without issues", r)
+	}
+}
+
+func TestVehicleJSONFile_StrictEnvelope(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "vehicles.json")
+	data := `{"version":1,"source":"test","vehicles":[` + row("1", "") + `]}`
+	if err := os.WriteFile(path, []byte(data), 0o644); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+
+	_, err := NewVehicleJSONFile(path, &ConfigVehicleJSON{Strict: true}).Load()
+	var qe *QualityError
+	if !errors.As(err, &qe) || qe.Report.Version != 1 || qe.Report.Counts[IssueUnknownField] != 1 {
+		t.Errorf("error = %v, want the unknown envelope field rejected", err)
+	}
+
+	// - a relaxed list of required fields is honored
+	path = writeRows(t, `{"id":1,"brand":"Ford"}`)
+	if v, err := NewVehicleJSONFile(path, &ConfigVehicleJSON{Strict: true, Required: []string{"id", "brand"}}).Load(); err != nil || len(v) != 1 {
+		t.Errorf("load = %v, %v, want the row accepted", v, err)
+	}
+}
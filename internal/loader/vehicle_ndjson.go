@@ -0,0 +1,52 @@
+package loader
+
+import (
+	"app/internal"
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"io"
+)
+
+// maxNDJSONLine is the maximum length of a line of an NDJSON stream
+const maxNDJSONLine = 1024 * 1024
+
+// NewVehicleNDJSONDecoder is a function that returns a new instance of VehicleNDJSONDecoder
+func NewVehicleNDJSONDecoder(r io.Reader) *VehicleNDJSONDecoder {
+	sc := bufio.NewScanner(r)
+	sc.Buffer(make([]byte, 64*1024), maxNDJSONLine)
+	return &VehicleNDJSONDecoder{sc: sc}
+}
+
+// VehicleNDJSONDecoder is a struct that decodes a stream with one internal.VehicleJSON object per line
+type VehicleNDJSONDecoder struct {
+	// sc splits the stream in lines
+	sc *bufio.Scanner
+	// line is the number of the last line read
+	line int
+}
+
+// Next is a method that decodes the next non-blank line.
+// An invalid line returns a RowError and decoding may go on, io.EOF ends the stream.
+func (d *VehicleNDJSONDecoder) Next() (v internal.Vehicle, line int, err error) {
+	for d.sc.Scan() {
+		d.line++
+		data := bytes.TrimSpace(d.sc.Bytes())
+		if len(data) == 0 {
+			continue
+		}
+		line = d.line
+
+		var vh internal.VehicleJSON
+		if err = json.Unmarshal(data, &vh); err != nil {
+			err = RowError{Line: line, Err: err}
+			return
+		}
+		v = vh.Vehicle()
+		return
+	}
+	if err = d.sc.Err(); err == nil {
+		err = io.EOF
+	}
+	return
+}
@@ -0,0 +1,72 @@
+package loader
+
+import (
+	"os"
+	"time"
+)
+
+// NewFileWatcher is a function that returns a new instance of FileWatcher
+func NewFileWatcher(path string, interval time.Duration) *FileWatcher {
+	// default interval
+	if interval <= 0 {
+		interval = 2 * time.Second
+	}
+
+	return &FileWatcher{
+		path:     path,
+		interval: interval,
+	}
+}
+
+// FileWatcher is a struct that detects changes to a file by polling its size and modification time.
+// Polling also sees a file replaced by a rename, the usual way of dropping a new version in place.
+type FileWatcher struct {
+	// path is the path to the watched file
+	path string
+	// interval is the time between polls
+	interval time.Duration
+}
+
+// fileState is a struct that represents what is compared between polls
+type fileState struct {
+	size    int64
+	modTime time.Time
+	exists  bool
+}
+
+// Watch is a method that calls fn each time the file changes, until done is closed.
+// - fn runs once the file stayed unchanged for a whole interval, so a file still being
+// written is not read halfway
+// - fn runs on the watcher goroutine, a change made while it runs is seen by the next poll
+func (w *FileWatcher) Watch(done <-chan struct{}, fn func()) {
+	last := w.stat()
+	pending, changed := false, last
+	ticker := time.NewTicker(w.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-done:
+			return
+		case <-ticker.C:
+		}
+
+		st := w.stat()
+		switch {
+		case st != changed:
+			// still changing
+			pending, changed = st != last, st
+		case pending && st.exists:
+			pending, last = false, st
+			fn()
+		}
+	}
+}
+
+// stat is a method that returns the current state of the file
+func (w *FileWatcher) stat() fileState {
+	info, err := os.Stat(w.path)
+	if err != nil {
+		return fileState{}
+	}
+	return fileState{size: info.Size(), modTime: info.ModTime(), exists: true}
+}
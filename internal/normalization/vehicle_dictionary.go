@@ -0,0 +1,332 @@
+package normalization
+
+import (
+	"app/internal"
+	"app/internal/search"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"unicode"
+)
+
+// defaultEntries are the dictionaries used when there is no dictionary file yet
+var defaultEntries = map[string][]internal.VehicleNormalizationEntry{
+	internal.VehicleNormalizationBrand: {
+		{Canonical: "Acura"}, {Canonical: "Aston Martin", Aliases: []string{"Aston"}}, {Canonical: "Audi"},
+		{Canonical: "BMW", Aliases: []string{"Beemer", "Bimmer"}}, {Canonical: "Bentley"}, {Canonical: "Buick"},
+		{Canonical: "Cadillac", Aliases: []string{"Caddy"}}, {Canonical: "Chevrolet", Aliases: []string{"Chevy"}},
+		{Canonical: "Dodge"}, {Canonical: "Ferrari"}, {Canonical: "Ford"}, {Canonical: "GMC"}, {Canonical: "Honda"},
+		{Canonical: "Hyundai"}, {Canonical: "Jeep"}, {Canonical: "Kia"}, {Canonical: "Lamborghini", Aliases: []string{"Lambo"}},
+		{Canonical: "Land Rover", Aliases: []string{"Landrover"}}, {Canonical: "Lexus"}, {Canonical: "Mazda"},
+		{Canonical: "Mercedes-Benz", Aliases: []string{"Mercedes", "Merc", "Benz"}}, {Canonical: "Mitsubishi"},
+		{Canonical: "Nissan"}, {Canonical: "Oldsmobile", Aliases: []string{"Olds"}}, {Canonical: "Porsche"},
+		{Canonical: "Rolls-Royce", Aliases: []string{"Rolls"}}, {Canonical: "Subaru"}, {Canonical: "Suzuki"},
+		{Canonical: "Toyota"}, {Canonical: "Volkswagen", Aliases: []string{"VW", "Volkswagon"}}, {Canonical: "Volvo"},
+	},
+	internal.VehicleNormalizationColor: {
+		{Canonical: "Aquamarine"}, {Canonical: "Black"}, {Canonical: "Blue"}, {Canonical: "Crimson"},
+		{Canonical: "Fuchsia", Aliases: []string{"Fuscia", "Fuschia"}}, {Canonical: "Goldenrod"},
+		{Canonical: "Gray", Aliases: []string{"Grey"}}, {Canonical: "Green"}, {Canonical: "Indigo"}, {Canonical: "Khaki"},
+		{Canonical: "Maroon"}, {Canonical: "Mauve", Aliases: []string{"Mauv"}}, {Canonical: "Orange"}, {Canonical: "Pink"},
+		{Canonical: "Puce"}, {Canonical: "Purple"}, {Canonical: "Red"}, {Canonical: "Teal"}, {Canonical: "Turquoise"},
+		{Canonical: "Violet"}, {Canonical: "White"},
+	},
+}
+
+// NewVehicleDictionary is a function that returns a new instance of VehicleDictionary
+// - path is the JSON file the dictionaries are stored in, the default dictionaries are used while it does not exist
+// - an empty path keeps the dictionaries in memory only
+func NewVehicleDictionary(path string) (d *VehicleDictionary, err error) {
+	d = &VehicleDictionary{path: path}
+	entries := defaultEntries
+	if path != "" {
+		var data []byte
+		data, err = os.ReadFile(path)
+		switch {
+		case errors.Is(err, os.ErrNotExist):
+			err = nil
+		case err != nil:
+			return nil, err
+		default:
+			if entries, err = decode(data); err != nil {
+				return nil, fmt.Errorf("normalization: %s: %w", path, err)
+			}
+		}
+	}
+
+	d.fields = make(map[string]map[string]internal.VehicleNormalizationEntry, len(internal.VehicleNormalizationFields))
+	for _, field := range internal.VehicleNormalizationFields {
+		d.fields[field] = make(map[string]internal.VehicleNormalizationEntry)
+		for _, e := range entries[field] {
+			if d.fields[field], err = set(d.fields[field], e); err != nil {
+				return nil, fmt.Errorf("normalization: %s: %w", field, err)
+			}
+		}
+	}
+	d.index()
+	return
+}
+
+// VehicleDictionary is a struct that implements the VehicleNormalizer interface with in-memory dictionaries,
+// optionally stored in a JSON file rewritten on every change
+type VehicleDictionary struct {
+	// mu guards the dictionaries
+	mu sync.RWMutex
+	// path is the file the dictionaries are stored in, empty when they are not
+	path string
+	// fields are the entries of each dictionary keyed by the key of their canonical value
+	fields map[string]map[string]internal.VehicleNormalizationEntry
+	// lookup maps the keys of the canonical values and aliases of each dictionary to their canonical value
+	lookup map[string]map[string]string
+}
+
+// Normalize is a method that returns the vehicle with its brand and color replaced by their canonical values
+func (d *VehicleDictionary) Normalize(v internal.Vehicle) internal.Vehicle {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+
+	v.Brand, v.BrandRaw = d.normalize(internal.VehicleNormalizationBrand, v.Brand, v.BrandRaw)
+	v.Color, v.ColorRaw = d.normalize(internal.VehicleNormalizationColor, v.Color, v.ColorRaw)
+	return v
+}
+
+// normalize is a method that returns the canonical value of a field and the raw value to keep along
+func (d *VehicleDictionary) normalize(field, value, raw string) (string, string) {
+	canonical := d.canonical(field, value)
+	if canonical != value {
+		return canonical, value
+	}
+	// - a raw value is kept only while it is an alias of the value, not after the value was changed
+	if raw != "" && raw != canonical && d.canonical(field, raw) == canonical {
+		return canonical, raw
+	}
+	return canonical, ""
+}
+
+// canonical is a method that returns the canonical value of a value, the value itself when it has no entry
+func (d *VehicleDictionary) canonical(field, value string) string {
+	if canonical, ok := d.lookup[field][key(value)]; ok {
+		return canonical
+	}
+	return value
+}
+
+// Entries is a method that returns the entries of the dictionary of a field in canonical value order
+func (d *VehicleDictionary) Entries(field string) ([]internal.VehicleNormalizationEntry, error) {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+
+	entries, ok := d.fields[field]
+	if !ok {
+		return nil, unknownField(field)
+	}
+	return sorted(entries), nil
+}
+
+// SetEntry is a method that creates an entry or replaces the entry with the same canonical value
+func (d *VehicleDictionary) SetEntry(field string, e internal.VehicleNormalizationEntry) (err error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	entries, ok := d.fields[field]
+	if !ok {
+		return unknownField(field)
+	}
+	if entries, err = set(entries, e); err != nil {
+		return
+	}
+	return d.commit(field, entries)
+}
+
+// DeleteEntry is a method that deletes the entry of a canonical value
+func (d *VehicleDictionary) DeleteEntry(field, canonical string) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	entries, ok := d.fields[field]
+	if !ok {
+		return unknownField(field)
+	}
+	k := key(canonical)
+	if _, ok := entries[k]; !ok {
+		return fmt.Errorf("%w: %s %q has no entry", internal.ErrVehicleNormalizationNotFound, field, canonical)
+	}
+	entries = clone(entries)
+	delete(entries, k)
+	return d.commit(field, entries)
+}
+
+// commit is a method that stores the new entries of a dictionary, the dictionaries are left unchanged
+// when they can't be written to their file
+func (d *VehicleDictionary) commit(field string, entries map[string]internal.VehicleNormalizationEntry) (err error) {
+	previous := d.fields[field]
+	d.fields[field] = entries
+	if err = d.save(); err != nil {
+		d.fields[field] = previous
+		return
+	}
+	d.index()
+	return
+}
+
+// index is a method that builds the lookup of the dictionaries
+func (d *VehicleDictionary) index() {
+	d.lookup = make(map[string]map[string]string, len(d.fields))
+	for field, entries := range d.fields {
+		lookup := make(map[string]string)
+		for k, e := range entries {
+			lookup[k] = e.Canonical
+			for _, alias := range e.Aliases {
+				lookup[key(alias)] = e.Canonical
+			}
+		}
+		d.lookup[field] = lookup
+	}
+}
+
+// save is a method that writes the dictionaries to their file, replacing it in one step
+func (d *VehicleDictionary) save() (err error) {
+	if d.path == "" {
+		return
+	}
+	data := make(map[string]map[string][]string, len(d.fields))
+	for field, entries := range d.fields {
+		data[field] = make(map[string][]string, len(entries))
+		for _, e := range entries {
+			data[field][e.Canonical] = append([]string{}, e.Aliases...)
+		}
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(d.path), filepath.Base(d.path)+".*.tmp")
+	if err != nil {
+		return
+	}
+	defer os.Remove(tmp.Name())
+
+	enc := json.NewEncoder(tmp)
+	enc.SetIndent("", "  ")
+	if err = enc.Encode(data); err != nil {
+		tmp.Close()
+		return
+	}
+	if err = tmp.Sync(); err != nil {
+		tmp.Close()
+		return
+	}
+	if err = tmp.Chmod(0o644); err != nil {
+		tmp.Close()
+		return
+	}
+	if err = tmp.Close(); err != nil {
+		return
+	}
+	err = os.Rename(tmp.Name(), d.path)
+	return
+}
+
+// decode is a function that parses a dictionary file, an object of canonical values and their aliases per field
+func decode(data []byte) (entries map[string][]internal.VehicleNormalizationEntry, err error) {
+	var file map[string]map[string][]string
+	if err = json.Unmarshal(data, &file); err != nil {
+		return
+	}
+	entries = make(map[string][]internal.VehicleNormalizationEntry, len(file))
+	for field, canonicals := range file {
+		if !slices.Contains(internal.VehicleNormalizationFields, field) {
+			return nil, unknownField(field)
+		}
+		for canonical, aliases := range canonicals {
+			entries[field] = append(entries[field], internal.VehicleNormalizationEntry{Canonical: canonical, Aliases: aliases})
+		}
+		// - a stable order reports the same conflict on every load
+		sort.Slice(entries[field], func(i, j int) bool { return entries[field][i].Canonical < entries[field][j].Canonical })
+	}
+	return
+}
+
+// set is a function that returns a copy of the entries of a dictionary with e added, replacing the entry
+// with the same canonical value. Aliases repeating the canonical value or another alias are dropped.
+func set(entries map[string]internal.VehicleNormalizationEntry, e internal.VehicleNormalizationEntry) (map[string]internal.VehicleNormalizationEntry, error) {
+	e.Canonical = strings.TrimSpace(e.Canonical)
+	k := key(e.Canonical)
+	if k == "" {
+		return nil, fmt.Errorf("%w: canonical value %q has no letter or digit", internal.ErrVehicleNormalizationInvalid, e.Canonical)
+	}
+
+	// other entries holding a key
+	owners := make(map[string]string)
+	for ek, other := range entries {
+		if ek == k {
+			continue
+		}
+		owners[ek] = other.Canonical
+		for _, alias := range other.Aliases {
+			owners[key(alias)] = other.Canonical
+		}
+	}
+	if owner, ok := owners[k]; ok {
+		return nil, fmt.Errorf("%w: %q is already an alias of %q", internal.ErrVehicleNormalizationConflict, e.Canonical, owner)
+	}
+
+	seen := map[string]bool{k: true}
+	aliases := make([]string, 0, len(e.Aliases))
+	for _, alias := range e.Aliases {
+		alias = strings.TrimSpace(alias)
+		ak := key(alias)
+		if ak == "" {
+			return nil, fmt.Errorf("%w: alias %q has no letter or digit", internal.ErrVehicleNormalizationInvalid, alias)
+		}
+		if owner, ok := owners[ak]; ok {
+			return nil, fmt.Errorf("%w: %q is already an alias of %q", internal.ErrVehicleNormalizationConflict, alias, owner)
+		}
+		if seen[ak] {
+			continue
+		}
+		seen[ak] = true
+		aliases = append(aliases, alias)
+	}
+	sort.Strings(aliases)
+	e.Aliases = aliases
+
+	entries = clone(entries)
+	entries[k] = e
+	return entries, nil
+}
+
+// clone is a function that returns a copy of the entries of a dictionary
+func clone(entries map[string]internal.VehicleNormalizationEntry) map[string]internal.VehicleNormalizationEntry {
+	c := make(map[string]internal.VehicleNormalizationEntry, len(entries)+1)
+	for k, e := range entries {
+		c[k] = e
+	}
+	return c
+}
+
+// sorted is a function that returns the entries of a dictionary in canonical value order
+func sorted(entries map[string]internal.VehicleNormalizationEntry) []internal.VehicleNormalizationEntry {
+	list := make([]internal.VehicleNormalizationEntry, 0, len(entries))
+	for _, e := range entries {
+		e.Aliases = append([]string{}, e.Aliases...)
+		list = append(list, e)
+	}
+	sort.Slice(list, func(i, j int) bool { return key(list[i].Canonical) < key(list[j].Canonical) })
+	return list
+}
+
+// unknownField is a function that returns the error for a field without dictionary
+func unknownField(field string) error {
+	return fmt.Errorf("%w: unknown dictionary %q, expected %s", internal.ErrVehicleNormalizationInvalid, field,
+		strings.Join(internal.VehicleNormalizationFields, " or "))
+}
+
+// key is a function that returns the form a value is compared in, folded without diacritics and
+// with its words separated by a single space, e.g. Mercedes-Benz becomes mercedes benz
+func key(value string) string {
+	return strings.Join(strings.FieldsFunc(search.Fold(value), func(r rune) bool { return !unicode.IsLetter(r) && !unicode.IsDigit(r) }), " ")
+}
@@ -0,0 +1,120 @@
+package registration
+
+import (
+	"app/internal"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// FormatAny is the name of the format accepting any registration, the default
+const FormatAny = "any"
+
+// Format is a struct that represents a plate format of a country or region
+// - Match reports whether a registration key, as returned by internal.VehicleRegistrationKey, has the format
+type Format struct {
+	Name        string
+	Description string
+	Match       func(key string) bool
+}
+
+// formats are the registered plate formats by name
+var formats = map[string]Format{}
+
+// Register is a function that adds a plate format, or replaces the one with the same name,
+// so it can be selected by configuration
+func Register(f Format) {
+	formats[f.Name] = f
+}
+
+// Formats is a function that returns the registered plate formats in name order
+func Formats() []Format {
+	list := make([]Format, 0, len(formats))
+	for _, f := range formats {
+		list = append(list, f)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].Name < list[j].Name })
+	return list
+}
+
+// pattern is a function that returns a matcher of the keys matching any of the regular expressions
+func pattern(exprs ...string) func(key string) bool {
+	res := make([]*regexp.Regexp, len(exprs))
+	for i, expr := range exprs {
+		res[i] = regexp.MustCompile("^" + expr + "$")
+	}
+	return func(key string) bool {
+		for _, re := range res {
+			if re.MatchString(key) {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+func init() {
+	Register(Format{Name: FormatAny, Description: "any registration", Match: func(key string) bool { return key != "" }})
+	Register(Format{Name: "br-old", Description: "Brazil before Mercosul, e.g. ABC-1234", Match: pattern(`[A-Z]{3}[0-9]{4}`)})
+	Register(Format{Name: "br-mercosul", Description: "Brazil Mercosul, e.g. ABC1D23", Match: pattern(`[A-Z]{3}[0-9][A-Z][0-9]{2}`)})
+	Register(Format{Name: "br", Description: "Brazil, old or Mercosul", Match: pattern(`[A-Z]{3}[0-9]{4}`, `[A-Z]{3}[0-9][A-Z][0-9]{2}`)})
+	Register(Format{Name: "ar", Description: "Argentina, old or Mercosul, e.g. ABC 123 or AB 123 CD", Match: pattern(`[A-Z]{3}[0-9]{3}`, `[A-Z]{2}[0-9]{3}[A-Z]{2}`)})
+	Register(Format{Name: "uk", Description: "United Kingdom since 2001, e.g. AB51 ABC", Match: pattern(`[A-Z]{2}[0-9]{2}[A-Z]{3}`)})
+	Register(Format{Name: "us", Description: "United States, 1 to 8 letters or digits", Match: pattern(`[A-Z0-9]{1,8}`)})
+}
+
+// NewVehicleRegistrationValidator is a function that returns a new instance of VehicleRegistrationValidator
+// - names are the accepted formats, a registration is valid when it matches any of them, none means any
+func NewVehicleRegistrationValidator(names ...string) (*VehicleRegistrationValidator, error) {
+	v := &VehicleRegistrationValidator{}
+	for _, name := range names {
+		name = strings.ToLower(strings.TrimSpace(name))
+		if name == "" {
+			continue
+		}
+		f, ok := formats[name]
+		if !ok {
+			known := make([]string, 0, len(formats))
+			for _, f := range Formats() {
+				known = append(known, f.Name)
+			}
+			return nil, fmt.Errorf("unknown registration format %q, expected one of %s", name, strings.Join(known, ", "))
+		}
+		v.formats = append(v.formats, f)
+	}
+	if len(v.formats) == 0 {
+		v.formats = []Format{formats[FormatAny]}
+	}
+	return v, nil
+}
+
+// VehicleRegistrationValidator is a struct that implements the VehicleRegistrationValidator interface
+// with the plate formats selected by configuration
+type VehicleRegistrationValidator struct {
+	// formats are the accepted formats
+	formats []Format
+}
+
+// Validate is a method that checks a registration against the accepted formats, regardless of case and separators
+func (v *VehicleRegistrationValidator) Validate(registration string) error {
+	if registration == "" {
+		return nil
+	}
+	key := internal.VehicleRegistrationKey(registration)
+	for _, f := range v.formats {
+		if f.Match(key) {
+			return nil
+		}
+	}
+	return fmt.Errorf("%w: %q matches none of the formats %s", internal.ErrVehicleRegistrationInvalid, registration, strings.Join(v.Formats(), ", "))
+}
+
+// Formats is a method that returns the names of the accepted formats
+func (v *VehicleRegistrationValidator) Formats() []string {
+	names := make([]string, len(v.formats))
+	for i, f := range v.formats {
+		names[i] = f.Name
+	}
+	return names
+}
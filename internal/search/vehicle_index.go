@@ -0,0 +1,291 @@
+package search
+
+import (
+	"app/internal"
+	"math"
+	"sort"
+	"strings"
+	"sync"
+	"unicode"
+
+	"golang.org/x/text/unicode/norm"
+)
+
+// field is a bit of the mask of the fields of a vehicle holding a term
+type field uint8
+
+const (
+	fieldBrand field = 1 << iota
+	fieldModel
+	fieldColor
+	fieldRegistration
+	fieldVIN
+)
+
+// fields are the indexed fields of a vehicle with their JSON name and their weight in the ranking
+// - the raw brand and color are indexed along with the canonical ones, so an alias still finds the vehicle
+var fields = []struct {
+	bit    field
+	name   string
+	weight float64
+	value  func(v internal.Vehicle) string
+}{
+	{fieldBrand, "brand", 2, func(v internal.Vehicle) string { return v.Brand + " " + v.BrandRaw }},
+	{fieldModel, "model", 2, func(v internal.Vehicle) string { return v.Model }},
+	{fieldColor, "color", 1.5, func(v internal.Vehicle) string { return v.Color + " " + v.ColorRaw }},
+	{fieldRegistration, "registration", 1, func(v internal.Vehicle) string { return v.Registration }},
+	{fieldVIN, "vin", 1, func(v internal.Vehicle) string { return v.VIN }},
+}
+
+// NewVehicleIndex is a function that returns a new instance of VehicleIndex holding the vehicles of db
+func NewVehicleIndex(db map[int]internal.Vehicle) *VehicleIndex {
+	ix := &VehicleIndex{
+		terms: make(map[string]map[int]field),
+		docs:  make(map[int]map[string]field),
+	}
+	for _, v := range db {
+		ix.add(v)
+	}
+	return ix
+}
+
+// VehicleIndex is a struct that implements the VehicleIndex interface with an in-memory inverted index.
+// Terms are folded to lower case without diacritics, a query term matches an indexed term exactly,
+// as a prefix, or within a small edit distance of the term or of its prefix.
+type VehicleIndex struct {
+	// mu guards the maps
+	mu sync.RWMutex
+	// terms are the postings of each term, the vehicle ids with the mask of the fields holding the term
+	terms map[string]map[int]field
+	// docs are the terms of each vehicle, so they can be removed when it changes
+	docs map[int]map[string]field
+}
+
+// Publish is a method that applies a vehicle change to the index
+func (ix *VehicleIndex) Publish(c internal.VehicleChange) {
+	ix.mu.Lock()
+	defer ix.mu.Unlock()
+
+	ix.remove(c.Vehicle.Id)
+	if c.Type != internal.VehicleDeleted {
+		ix.add(c.Vehicle)
+	}
+}
+
+// add is a method that indexes the terms of a vehicle
+func (ix *VehicleIndex) add(v internal.Vehicle) {
+	doc := make(map[string]field)
+	for _, f := range fields {
+		for _, term := range tokenize(f.value(v)) {
+			doc[term] |= f.bit
+		}
+	}
+	for term, mask := range doc {
+		postings, ok := ix.terms[term]
+		if !ok {
+			postings = make(map[int]field)
+			ix.terms[term] = postings
+		}
+		postings[v.Id] = mask
+	}
+	ix.docs[v.Id] = doc
+}
+
+// remove is a method that drops the terms of a vehicle from the index
+func (ix *VehicleIndex) remove(id int) {
+	for term := range ix.docs[id] {
+		delete(ix.terms[term], id)
+		if len(ix.terms[term]) == 0 {
+			delete(ix.terms, term)
+		}
+	}
+	delete(ix.docs, id)
+}
+
+// Search is a method that returns up to limit vehicles matching the terms of the query, the most relevant first.
+// Each query term adds the best score of the vehicle terms it matches, weighted by the match quality,
+// the rarity of the term and the field holding it. The total is scaled by the share of query terms matched.
+func (ix *VehicleIndex) Search(query string, limit int) []internal.VehicleSearchHit {
+	tokens := tokenize(query)
+	if len(tokens) == 0 {
+		return nil
+	}
+
+	ix.mu.RLock()
+	defer ix.mu.RUnlock()
+
+	type score struct {
+		total   float64
+		matched int
+		fields  field
+	}
+	scores := make(map[int]*score)
+	n := float64(len(ix.docs))
+	for _, token := range tokens {
+		type best struct {
+			score float64
+			mask  field
+		}
+		bests := make(map[int]best)
+		for term, postings := range ix.terms {
+			quality := match(token, term)
+			if quality == 0 {
+				continue
+			}
+			idf := math.Log(1 + n/float64(len(postings)))
+			for id, mask := range postings {
+				if s := quality * idf * weight(mask); s > bests[id].score {
+					bests[id] = best{score: s, mask: mask}
+				}
+			}
+		}
+		for id, b := range bests {
+			s, ok := scores[id]
+			if !ok {
+				s = &score{}
+				scores[id] = s
+			}
+			s.total += b.score
+			s.matched++
+			s.fields |= b.mask
+		}
+	}
+
+	hits := make([]internal.VehicleSearchHit, 0, len(scores))
+	for id, s := range scores {
+		hit := internal.VehicleSearchHit{Id: id, Score: s.total * float64(s.matched) / float64(len(tokens))}
+		for _, f := range fields {
+			if s.fields&f.bit != 0 {
+				hit.Fields = append(hit.Fields, f.name)
+			}
+		}
+		hits = append(hits, hit)
+	}
+	sort.Slice(hits, func(i, j int) bool {
+		if hits[i].Score != hits[j].Score {
+			return hits[i].Score > hits[j].Score
+		}
+		return hits[i].Id < hits[j].Id
+	})
+	if limit > 0 && len(hits) > limit {
+		hits = hits[:limit]
+	}
+	return hits
+}
+
+// weight is a function that returns the weight of the heaviest field of a mask
+func weight(mask field) (w float64) {
+	for _, f := range fields {
+		if mask&f.bit != 0 {
+			w = max(w, f.weight)
+		}
+	}
+	return
+}
+
+// match is a function that returns the quality of the match of a query token with an indexed term,
+// from 1 for an exact match down to 0 for no match
+func match(token, term string) float64 {
+	switch {
+	case token == term:
+		return 1
+	case len(token) >= 2 && strings.HasPrefix(term, token):
+		return 0.8
+	}
+
+	// typos
+	// - short tokens must match exactly, longer ones tolerate more edits
+	a, b := []rune(token), []rune(term)
+	maxDist := 0
+	switch {
+	case len(a) >= 8:
+		maxDist = 2
+	case len(a) >= 4:
+		maxDist = 1
+	}
+	if maxDist == 0 {
+		return 0
+	}
+	if d := distance(a, b, maxDist); d <= maxDist {
+		return 0.6 - 0.15*float64(d-1)
+	}
+	// - a typo in the first letters of a longer term, e.g. chevy for chevrolet
+	if len(b) > len(a) {
+		if d := distance(a, b[:len(a)], maxDist); d <= maxDist {
+			return 0.5 - 0.15*float64(d-1)
+		}
+	}
+	return 0
+}
+
+// distance is a function that returns the optimal string alignment distance between a and b,
+// the number of insertions, deletions, substitutions and transpositions of adjacent runes.
+// It returns maxDist+1 as soon as the distance is known to exceed maxDist.
+func distance(a, b []rune, maxDist int) int {
+	if abs(len(a)-len(b)) > maxDist {
+		return maxDist + 1
+	}
+	prev2 := make([]int, len(b)+1)
+	prev := make([]int, len(b)+1)
+	curr := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		curr[0] = i
+		rowMin := curr[0]
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			curr[j] = min(prev[j]+1, curr[j-1]+1, prev[j-1]+cost)
+			if i > 1 && j > 1 && a[i-1] == b[j-2] && a[i-2] == b[j-1] {
+				curr[j] = min(curr[j], prev2[j-2]+1)
+			}
+			rowMin = min(rowMin, curr[j])
+		}
+		if rowMin > maxDist {
+			return maxDist + 1
+		}
+		prev2, prev, curr = prev, curr, prev2
+	}
+	return prev[len(b)]
+}
+
+// abs is a function that returns the absolute value of n
+func abs(n int) int {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
+
+// Terms is a method that returns the distinct folded terms of the query
+func (ix *VehicleIndex) Terms(query string) []string {
+	return tokenize(query)
+}
+
+// tokenize is a function that splits a text in distinct folded terms
+func tokenize(s string) (tokens []string) {
+	seen := make(map[string]bool)
+	for _, token := range strings.FieldsFunc(Fold(s), func(r rune) bool { return !unicode.IsLetter(r) && !unicode.IsDigit(r) }) {
+		if !seen[token] {
+			seen[token] = true
+			tokens = append(tokens, token)
+		}
+	}
+	return
+}
+
+// Fold is a function that returns a text in lower case without diacritics, e.g. Citroën becomes citroen
+func Fold(s string) string {
+	var b strings.Builder
+	for _, r := range norm.NFD.String(s) {
+		if unicode.Is(unicode.Mn, r) {
+			continue
+		}
+		b.WriteRune(unicode.ToLower(r))
+	}
+	return b.String()
+}
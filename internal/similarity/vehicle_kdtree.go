@@ -0,0 +1,244 @@
+package similarity
+
+import (
+	"app/internal"
+	"container/heap"
+	"math"
+	"sort"
+	"sync"
+)
+
+// point is a struct that represents a vehicle in the tree
+// - coords are its numeric fields scaled to the range of the fleet, in VehicleSimilarityNumericFields order
+// - axis is the coordinate the point splits its subtree on
+type point struct {
+	id     int
+	coords []float64
+	labels []string
+	axis   int
+}
+
+// NewVehicleKDTree is a function that returns a new instance of VehicleKDTree holding the vehicles of db
+func NewVehicleKDTree(db map[int]internal.Vehicle) *VehicleKDTree {
+	t := &VehicleKDTree{vehicles: make(map[int]internal.Vehicle, len(db)), dirty: true}
+	for id, v := range db {
+		t.vehicles[id] = v
+	}
+	return t
+}
+
+// VehicleKDTree is a struct that implements the VehicleNeighborIndex interface with a k-d tree over the
+// scaled numeric fields of the vehicles. The categorical fields only add to the distance, so the tree
+// prunes with the numeric part, which never exceeds the whole distance, and any weights can be queried.
+// Changes mark the tree stale, it is rebuilt by the next query.
+type VehicleKDTree struct {
+	// mu guards the vehicles and the tree
+	mu sync.RWMutex
+	// vehicles are the indexed vehicles keyed by id
+	vehicles map[int]internal.Vehicle
+	// dirty reports whether the tree misses changes of the vehicles
+	dirty bool
+	// points are the nodes of the tree, the root of the points of a range is its middle point
+	points []point
+	// min and span are the smallest value and the range of each numeric field, 1 for a constant field
+	min, span []float64
+}
+
+// Publish is a method that applies a vehicle change to the index
+func (t *VehicleKDTree) Publish(c internal.VehicleChange) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if c.Type == internal.VehicleDeleted {
+		delete(t.vehicles, c.Vehicle.Id)
+	} else {
+		t.vehicles[c.Vehicle.Id] = c.Vehicle
+	}
+	t.dirty = true
+}
+
+// Nearest is a method that returns up to k vehicles closest to v by the weighted distance, closest first
+// - ties are broken by id
+func (t *VehicleKDTree) Nearest(v internal.Vehicle, k int, w internal.VehicleSimilarityWeights) []internal.VehicleNeighbor {
+	if k <= 0 {
+		return nil
+	}
+	t.mu.RLock()
+	if t.dirty {
+		t.mu.RUnlock()
+		t.mu.Lock()
+		if t.dirty {
+			t.build()
+		}
+		t.mu.Unlock()
+		t.mu.RLock()
+	}
+	defer t.mu.RUnlock()
+
+	q := t.point(v)
+	s := search{
+		q:           q,
+		numeric:     weights(w, internal.VehicleSimilarityNumericFields),
+		categorical: weights(w, internal.VehicleSimilarityCategoricalFields),
+		k:           k,
+	}
+	s.visit(t.points)
+
+	neighbors := make([]internal.VehicleNeighbor, len(s.best))
+	for i := len(s.best) - 1; i >= 0; i-- {
+		c := heap.Pop(&s.best).(candidate)
+		neighbors[i] = internal.VehicleNeighbor{Id: c.id, Distance: math.Sqrt(c.dist)}
+	}
+	return neighbors
+}
+
+// build is a method that scales the vehicles to the range of the fleet and rebuilds the tree
+func (t *VehicleKDTree) build() {
+	n := len(internal.VehicleSimilarityNumericFields)
+	t.min, t.span = make([]float64, n), make([]float64, n)
+	for i, field := range internal.VehicleSimilarityNumericFields {
+		value := internal.VehicleNumericFields[field]
+		lo, hi := math.Inf(1), math.Inf(-1)
+		for _, v := range t.vehicles {
+			lo, hi = min(lo, value(v)), max(hi, value(v))
+		}
+		t.min[i], t.span[i] = lo, hi-lo
+		if len(t.vehicles) == 0 || t.span[i] == 0 {
+			t.min[i], t.span[i] = 0, 1
+		}
+	}
+
+	t.points = make([]point, 0, len(t.vehicles))
+	for _, v := range t.vehicles {
+		t.points = append(t.points, t.point(v))
+	}
+	// - a stable input order builds the same tree, hence the same ties, for the same fleet
+	sort.Slice(t.points, func(i, j int) bool { return t.points[i].id < t.points[j].id })
+	split(t.points, 0)
+	t.dirty = false
+}
+
+// point is a method that returns the point of a vehicle, scaled to the range of the fleet
+func (t *VehicleKDTree) point(v internal.Vehicle) point {
+	p := point{
+		id:     v.Id,
+		coords: make([]float64, len(internal.VehicleSimilarityNumericFields)),
+		labels: make([]string, len(internal.VehicleSimilarityCategoricalFields)),
+	}
+	for i, field := range internal.VehicleSimilarityNumericFields {
+		p.coords[i] = (internal.VehicleNumericFields[field](v) - t.min[i]) / t.span[i]
+	}
+	for i, field := range internal.VehicleSimilarityCategoricalFields {
+		p.labels[i] = internal.VehicleCategoricalFields[field](v)
+	}
+	return p
+}
+
+// split is a function that arranges points as a tree, the median on the axis of the depth
+// at the middle and the smaller and larger points on each side
+func split(points []point, depth int) {
+	if len(points) == 0 {
+		return
+	}
+	axis := depth % len(points[0].coords)
+	sort.SliceStable(points, func(i, j int) bool { return points[i].coords[axis] < points[j].coords[axis] })
+	mid := len(points) / 2
+	points[mid].axis = axis
+	split(points[:mid], depth+1)
+	split(points[mid+1:], depth+1)
+}
+
+// weights is a function that returns the weights of fields in order, 0 for a field without weight
+func weights(w internal.VehicleSimilarityWeights, fields []string) []float64 {
+	ws := make([]float64, len(fields))
+	for i, field := range fields {
+		ws[i] = w[field]
+	}
+	return ws
+}
+
+// search is a struct that represents a k nearest neighbors search
+type search struct {
+	q           point
+	numeric     []float64
+	categorical []float64
+	k           int
+	// best are the k closest points found so far, the farthest on top
+	best candidates
+}
+
+// visit is a method that searches a subtree, skipping the side of a split farther than the k-th best point
+func (s *search) visit(points []point) {
+	if len(points) == 0 {
+		return
+	}
+	mid := len(points) / 2
+	p := points[mid]
+	if p.id != s.q.id {
+		s.offer(candidate{id: p.id, dist: s.distance(p)})
+	}
+
+	diff := s.q.coords[p.axis] - p.coords[p.axis]
+	near, far := points[:mid], points[mid+1:]
+	if diff > 0 {
+		near, far = far, near
+	}
+	s.visit(near)
+	if len(s.best) < s.k || s.numeric[p.axis]*diff*diff <= s.best[0].dist {
+		s.visit(far)
+	}
+}
+
+// distance is a method that returns the squared weighted distance between the query and a point
+func (s *search) distance(p point) (d float64) {
+	for i, w := range s.numeric {
+		diff := s.q.coords[i] - p.coords[i]
+		d += w * diff * diff
+	}
+	for i, w := range s.categorical {
+		if s.q.labels[i] != p.labels[i] {
+			d += w
+		}
+	}
+	return
+}
+
+// offer is a method that keeps a candidate when it is among the k closest found so far
+func (s *search) offer(c candidate) {
+	if len(s.best) < s.k {
+		heap.Push(&s.best, c)
+		return
+	}
+	if c.less(s.best[0]) {
+		s.best[0] = c
+		heap.Fix(&s.best, 0)
+	}
+}
+
+// candidate is a struct that represents a point found by a search with its squared distance
+type candidate struct {
+	id   int
+	dist float64
+}
+
+// less is a method that reports whether c is closer than o, the lower id first on a tie
+func (c candidate) less(o candidate) bool {
+	if c.dist != o.dist {
+		return c.dist < o.dist
+	}
+	return c.id < o.id
+}
+
+// candidates is a max-heap of candidates, the farthest first
+type candidates []candidate
+
+func (h candidates) Len() int           { return len(h) }
+func (h candidates) Less(i, j int) bool { return h[j].less(h[i]) }
+func (h candidates) Swap(i, j int)      { h[i], h[j] = h[j], h[i] }
+func (h *candidates) Push(x any)        { *h = append(*h, x.(candidate)) }
+func (h *candidates) Pop() any {
+	old := *h
+	c := old[len(old)-1]
+	*h = old[:len(old)-1]
+	return c
+}
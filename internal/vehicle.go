@@ -1,5 +1,6 @@
 package internal
 
+// Dimensions are the dimensions of a vehicle in m
 type Dimensions struct {
 	Height float64
 	Length float64
@@ -13,11 +14,18 @@ type VehicleAttributes struct {
 	Color           string
 	FabricationYear int
 	Capacity        int
-	MaxSpeed        float64
-	FuelType        string
-	Transmission    string
-	Weight          float64
+	// MaxSpeed is in km/h and Weight in kg, see VehicleQuantityFields
+	MaxSpeed     float64
+	FuelType     string
+	Transmission string
+	Weight       float64
 	Dimensions
+	// VIN is the vehicle identification number in upper case, empty when it is unknown
+	VIN string
+	// BrandRaw and ColorRaw are the values received before their normalization,
+	// empty when they were already canonical
+	BrandRaw string
+	ColorRaw string
 }
 
 type Vehicle struct {
@@ -0,0 +1,55 @@
+package vehicle
+
+import (
+	"app/internal"
+	"fmt"
+	"sort"
+)
+
+// NewVehicleBackupDefault is a function that returns a new instance of VehicleBackupDefault
+func NewVehicleBackupDefault(sv internal.VehicleService, st internal.VehicleBackupStore) *VehicleBackupDefault {
+	return &VehicleBackupDefault{sv: sv, st: st}
+}
+
+// VehicleBackupDefault is a struct that represents the default service for backups of the fleet
+type VehicleBackupDefault struct {
+	// sv is the service of the backed up fleet, restores go through it so the changes are published
+	sv internal.VehicleService
+	// st is the storage of the backups
+	st internal.VehicleBackupStore
+}
+
+// Backup is a method that stores a copy of the fleet taken at a single point in time
+func (s *VehicleBackupDefault) Backup() (b internal.VehicleBackup, err error) {
+	db, err := s.sv.FindAll()
+	if err != nil {
+		return
+	}
+	return s.st.Save(db)
+}
+
+// Restore is a method that verifies a backup and replaces the fleet with its vehicles
+// - a vehicle that can't be stored rejects the whole restore, the fleet is left as it is
+func (s *VehicleBackupDefault) Restore(id string) (b internal.VehicleBackup, ops []internal.VehicleOperation, err error) {
+	db, b, err := s.st.Load(id)
+	if err != nil {
+		return
+	}
+	ids := make([]int, 0, len(db))
+	for id := range db {
+		ids = append(ids, id)
+	}
+	sort.Ints(ids)
+	for _, id := range ids {
+		if verr := ValidateVehicle(db[id]); verr != nil {
+			return b, nil, fmt.Errorf("%w: vehicle %d of backup %s: %s", internal.ErrVehicleOperationInvalid, id, b.Id, verr)
+		}
+	}
+	ops, err = s.sv.Replace(db)
+	return
+}
+
+// List is a method that returns the stored backups in creation order
+func (s *VehicleBackupDefault) List() (b []internal.VehicleBackup, err error) {
+	return s.st.List()
+}
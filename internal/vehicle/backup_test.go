@@ -0,0 +1,64 @@
+package vehicle
+
+import (
+	"app/internal"
+	"app/internal/backup"
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestVehicleBackupDefault_Restore(t *testing.T) {
+	v1, v2 := car(1, "AAA1111"), car(2, "BBB2222")
+	v1.Model, v2.Model = "Corolla", "Yaris"
+	rp := NewVehicleMap(map[int]internal.Vehicle{1: v1, 2: v2})
+	sv := NewVehicleBackupDefault(NewVehicleDefault(rp, nil), backup.NewVehicleBackupDir(t.TempDir()))
+	b, err := sv.Backup()
+	if err != nil {
+		t.Fatalf("backup: %v", err)
+	}
+	want, _ := rp.FindAll()
+
+	// changes after the backup are undone
+	if _, err = rp.Delete(1); err != nil {
+		t.Fatalf("delete: %v", err)
+	}
+	v3 := car(3, "CCC3333")
+	v3.Model = "Ka"
+	if err = rp.Create(v3); err != nil {
+		t.Fatalf("create: %v", err)
+	}
+	restored, ops, err := sv.Restore(b.Id)
+	if err != nil {
+		t.Fatalf("restore: %v", err)
+	}
+	if restored.Id != b.Id || len(ops) != 2 {
+		t.Errorf("restore = %+v with %d changes, want backup %s with 2 changes", restored, len(ops), b.Id)
+	}
+	if got, _ := rp.FindAll(); !reflect.DeepEqual(got, want) {
+		t.Errorf("fleet = %+v, want %+v", got, want)
+	}
+}
+
+func TestVehicleBackupDefault_RestoreInvalid(t *testing.T) {
+	// - a fleet loaded from a file is not validated, so it can hold a vehicle a restore can't store
+	v1, v2 := car(1, "AAA1111"), car(2, "BBB2222")
+	v1.Model = "Corolla"
+	rp := NewVehicleMap(map[int]internal.Vehicle{1: v1, 2: v2})
+	sv := NewVehicleBackupDefault(NewVehicleDefault(rp, nil), backup.NewVehicleBackupDir(t.TempDir()))
+	b, err := sv.Backup()
+	if err != nil {
+		t.Fatalf("backup: %v", err)
+	}
+	if _, err = rp.Delete(2); err != nil {
+		t.Fatalf("delete: %v", err)
+	}
+
+	_, ops, err := sv.Restore(b.Id)
+	if !errors.Is(err, internal.ErrVehicleOperationInvalid) || ops != nil {
+		t.Fatalf("restore = %v, %v, want ErrVehicleOperationInvalid", ops, err)
+	}
+	if got, _ := rp.FindAll(); len(got) != 1 {
+		t.Errorf("fleet = %+v, want it unchanged", got)
+	}
+}
@@ -0,0 +1,117 @@
+package vehicle
+
+import (
+	"app/internal"
+	"fmt"
+	"sort"
+)
+
+// planVehicleBatch is a function that validates the operations in order against db without changing it.
+// Each valid operation is staged so the following ones see its effect, e.g. a create and then an
+// update of the same vehicle. ok reports whether every operation is valid.
+// - unique is the unique index of db, a create or update taking the registration or the VIN of another vehicle is invalid
+func planVehicleBatch(db map[int]internal.Vehicle, unique uniqueIndex, ops []internal.VehicleOperation) (results []internal.VehicleOperationResult, ok bool) {
+	// staged holds the state of the touched ids, nil when deleted
+	staged := make(map[int]*internal.Vehicle)
+	lookup := func(id int) (v internal.Vehicle, exists bool) {
+		if p, touched := staged[id]; touched {
+			if p == nil {
+				return
+			}
+			return *p, true
+		}
+		v, exists = db[id]
+		return
+	}
+	// keys holds the ids staged with a key of a unique field
+	keys := make(uniqueIndex, len(uniqueFields))
+	for i := range keys {
+		keys[i] = make(map[string][]int)
+	}
+	stage := func(v internal.Vehicle) {
+		staged[v.Id] = &v
+		for i, f := range uniqueFields {
+			if key := f.key(v); key != "" {
+				keys[i][key] = append(keys[i][key], v.Id)
+			}
+		}
+	}
+
+	ok = true
+	results = make([]internal.VehicleOperationResult, len(ops))
+	for i, op := range ops {
+		res := internal.VehicleOperationResult{Type: op.Type, Vehicle: op.Vehicle}
+		id := op.Vehicle.Id
+
+		switch op.Type {
+		case internal.VehicleOperationCreate:
+			if _, exists := lookup(id); exists {
+				res.Err = vehicleError(internal.ErrVehicleExists, "vehicle with ID: %v, already exists", id)
+				break
+			}
+			if res.Err = unique.holder(op.Vehicle, lookup, keys); res.Err != nil {
+				break
+			}
+			stage(op.Vehicle)
+		case internal.VehicleOperationUpdate:
+			if _, exists := lookup(id); !exists {
+				res.Err = vehicleError(internal.ErrVehicleNotFound, "vehicle with ID: %v, not found", id)
+				break
+			}
+			if res.Err = unique.holder(op.Vehicle, lookup, keys); res.Err != nil {
+				break
+			}
+			stage(op.Vehicle)
+		case internal.VehicleOperationPatch:
+			v, exists := lookup(id)
+			if !exists {
+				res.Err = vehicleError(internal.ErrVehicleNotFound, "vehicle with ID: %v, not found", id)
+				break
+			}
+			if op.Patch == nil {
+				res.Err = fmt.Errorf("%w: patch needs a change", internal.ErrVehicleOperationInvalid)
+				break
+			}
+			// - a patch keeps the registration and the VIN, it cannot take the ones of another vehicle
+			res.Vehicle = op.Patch.Apply(v)
+			stage(res.Vehicle)
+		case internal.VehicleOperationDelete:
+			v, exists := lookup(id)
+			if !exists {
+				res.Err = vehicleError(internal.ErrVehicleNotFound, "vehicle with ID: %v, not found", id)
+				break
+			}
+			res.Vehicle = v
+			staged[id] = nil
+		default:
+			res.Err = fmt.Errorf("invalid operation %q", op.Type)
+		}
+
+		if res.Err != nil {
+			ok = false
+		}
+		results[i] = res
+	}
+	return
+}
+
+// diffVehicles is a function that returns the operations turning prev into next in id order.
+// A delete carries the removed vehicle.
+func diffVehicles(prev, next map[int]internal.Vehicle) (ops []internal.VehicleOperation) {
+	for id, v := range next {
+		old, ok := prev[id]
+		switch {
+		case !ok:
+			ops = append(ops, internal.VehicleOperation{Type: internal.VehicleOperationCreate, Vehicle: v})
+		case old != v:
+			ops = append(ops, internal.VehicleOperation{Type: internal.VehicleOperationUpdate, Vehicle: v})
+		}
+	}
+	for id, v := range prev {
+		if _, ok := next[id]; !ok {
+			ops = append(ops, internal.VehicleOperation{Type: internal.VehicleOperationDelete, Vehicle: v})
+		}
+	}
+	sort.Slice(ops, func(i, j int) bool { return ops[i].Vehicle.Id < ops[j].Vehicle.Id })
+	return
+}
@@ -0,0 +1,221 @@
+package vehicle
+
+import (
+	"app/internal"
+	"errors"
+	"reflect"
+	"sync"
+	"testing"
+)
+
+// car is a function that returns a vehicle with an id and a registration
+func car(id int, registration string) internal.Vehicle {
+	v := internal.Vehicle{Id: id}
+	v.Brand, v.Registration = "Toyota", registration
+	return v
+}
+
+// op is a function that returns a batch operation
+func op(typ internal.VehicleOperationType, v internal.Vehicle) internal.VehicleOperation {
+	return internal.VehicleOperation{Type: typ, Vehicle: v}
+}
+
+func TestPlanVehicleBatch_Staged(t *testing.T) {
+	rp := NewVehicleMap(map[int]internal.Vehicle{1: car(1, "AAA1111")})
+	cases := []struct {
+		name string
+		ops  []internal.VehicleOperation
+		errs []error
+	}{
+		{
+			name: "create then update and delete the same id",
+			ops: []internal.VehicleOperation{
+				op(internal.VehicleOperationCreate, car(2, "BBB2222")),
+				op(internal.VehicleOperationUpdate, car(2, "CCC3333")),
+				op(internal.VehicleOperationDelete, car(2, "")),
+			},
+			errs: []error{nil, nil, nil},
+		},
+		{
+			name: "delete then create the same id",
+			ops: []internal.VehicleOperation{
+				op(internal.VehicleOperationDelete, car(1, "")),
+				op(internal.VehicleOperationCreate, car(1, "AAA1111")),
+			},
+			errs: []error{nil, nil},
+		},
+		{
+			name: "an operation on an id deleted earlier in the batch",
+			ops: []internal.VehicleOperation{
+				op(internal.VehicleOperationDelete, car(1, "")),
+				op(internal.VehicleOperationUpdate, car(1, "AAA1111")),
+			},
+			errs: []error{nil, internal.ErrVehicleNotFound},
+		},
+		{
+			name: "two creates with the same registration",
+			ops: []internal.VehicleOperation{
+				op(internal.VehicleOperationCreate, car(2, "BBB2222")),
+				op(internal.VehicleOperationCreate, car(3, "bbb-2222")),
+			},
+			errs: []error{nil, internal.ErrVehicleRegistrationExists},
+		},
+		{
+			name: "a registration freed earlier in the batch",
+			ops: []internal.VehicleOperation{
+				op(internal.VehicleOperationUpdate, car(1, "ZZZ9999")),
+				op(internal.VehicleOperationCreate, car(2, "AAA1111")),
+			},
+			errs: []error{nil, nil},
+		},
+		{
+			name: "a duplicate id",
+			ops: []internal.VehicleOperation{
+				op(internal.VehicleOperationCreate, car(1, "DDD4444")),
+			},
+			errs: []error{internal.ErrVehicleExists},
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			results, ok := planVehicleBatch(rp.db, rp.unique, c.ops)
+			wantOk := true
+			for i, want := range c.errs {
+				if want == nil && results[i].Err != nil || want != nil && !errors.Is(results[i].Err, want) {
+					t.Errorf("operation %d error = %v, want %v", i, results[i].Err, want)
+				}
+				wantOk = wantOk && want == nil
+			}
+			if ok != wantOk {
+				t.Errorf("ok = %v, want %v", ok, wantOk)
+			}
+		})
+	}
+
+	// planning does not touch the repository
+	if db, _ := rp.FindAll(); !reflect.DeepEqual(db, map[int]internal.Vehicle{1: car(1, "AAA1111")}) {
+		t.Errorf("repository after planning = %+v, want it unchanged", db)
+	}
+}
+
+func TestVehicleMap_ApplyBatch(t *testing.T) {
+	ops := []internal.VehicleOperation{
+		op(internal.VehicleOperationCreate, car(2, "BBB2222")),
+		op(internal.VehicleOperationUpdate, car(9, "CCC3333")),
+		op(internal.VehicleOperationDelete, car(1, "")),
+	}
+
+	t.Run("atomic aborts on any error", func(t *testing.T) {
+		rp := NewVehicleMap(map[int]internal.Vehicle{1: car(1, "AAA1111")})
+		results, err := rp.ApplyBatch(ops, true)
+		if !errors.Is(err, internal.ErrVehicleBatchAborted) {
+			t.Fatalf("error = %v, want ErrVehicleBatchAborted", err)
+		}
+		for i, res := range results {
+			if res.Applied {
+				t.Errorf("operation %d applied in an aborted batch", i)
+			}
+		}
+		if !errors.Is(results[1].Err, internal.ErrVehicleNotFound) {
+			t.Errorf("operation 1 error = %v, want ErrVehicleNotFound", results[1].Err)
+		}
+		if db, _ := rp.FindAll(); !reflect.DeepEqual(db, map[int]internal.Vehicle{1: car(1, "AAA1111")}) {
+			t.Errorf("repository = %+v, want it unchanged", db)
+		}
+	})
+
+	t.Run("best effort applies the valid operations", func(t *testing.T) {
+		rp := NewVehicleMap(map[int]internal.Vehicle{1: car(1, "AAA1111")})
+		results, err := rp.ApplyBatch(ops, false)
+		if err != nil {
+			t.Fatalf("error = %v, want nil", err)
+		}
+		applied := []bool{results[0].Applied, results[1].Applied, results[2].Applied}
+		if !reflect.DeepEqual(applied, []bool{true, false, true}) {
+			t.Errorf("applied = %v, want [true false true]", applied)
+		}
+		if db, _ := rp.FindAll(); !reflect.DeepEqual(db, map[int]internal.Vehicle{2: car(2, "BBB2222")}) {
+			t.Errorf("repository = %+v, want only vehicle 2", db)
+		}
+		if results[2].Vehicle.Registration != "AAA1111" {
+			t.Errorf("deleted vehicle = %+v, want the removed vehicle", results[2].Vehicle)
+		}
+	})
+}
+
+func TestVehicleApplyBatch_ConcurrentPatches(t *testing.T) {
+	repositories := map[string]func() internal.VehicleRepository{
+		"map": func() internal.VehicleRepository {
+			return NewVehicleMap(map[int]internal.Vehicle{1: car(1, "AAA1111")})
+		},
+		"event sourced": func() internal.VehicleRepository {
+			rp := newEventSourced(t, t.TempDir(), 0)
+			if err := rp.Create(car(1, "AAA1111")); err != nil {
+				t.Fatalf("create: %v", err)
+			}
+			return rp
+		},
+	}
+
+	for name, newRepository := range repositories {
+		t.Run(name, func(t *testing.T) {
+			rp := newRepository()
+
+			// one patch changes the speed while another changes the fuel type, none may undo the other
+			const n = 50
+			var wg sync.WaitGroup
+			for i := 1; i <= n; i++ {
+				speed, fuel := float64(100+i), "diesel"
+				patches := []internal.VehiclePatch{{MaxSpeed: &speed}, {FuelType: &fuel}}
+				for _, p := range patches {
+					wg.Add(1)
+					go func(p internal.VehiclePatch) {
+						defer wg.Done()
+						o := internal.VehicleOperation{Type: internal.VehicleOperationPatch, Vehicle: internal.Vehicle{Id: 1}, Patch: &p}
+						if _, err := rp.ApplyBatch([]internal.VehicleOperation{o}, true); err != nil {
+							t.Errorf("patch: %v", err)
+						}
+					}(p)
+				}
+			}
+			wg.Wait()
+
+			vehicles, _ := rp.FindById(1)
+			if len(vehicles) != 1 || vehicles[0].FuelType != "diesel" || vehicles[0].MaxSpeed <= 100 {
+				t.Fatalf("vehicle = %+v, want both patches kept", vehicles)
+			}
+			if vehicles[0].Registration != "AAA1111" || vehicles[0].Brand != "Toyota" {
+				t.Errorf("vehicle = %+v, want the other attributes kept", vehicles[0])
+			}
+		})
+	}
+}
+
+func TestPlanVehicleBatch_Patch(t *testing.T) {
+	rp := NewVehicleMap(map[int]internal.Vehicle{1: car(1, "AAA1111")})
+	fuel := "electric"
+	cases := []struct {
+		name string
+		op   internal.VehicleOperation
+		err  error
+	}{
+		{"patch", internal.VehicleOperation{Type: internal.VehicleOperationPatch, Vehicle: internal.Vehicle{Id: 1}, Patch: &internal.VehiclePatch{FuelType: &fuel}}, nil},
+		{"missing vehicle", internal.VehicleOperation{Type: internal.VehicleOperationPatch, Vehicle: internal.Vehicle{Id: 2}, Patch: &internal.VehiclePatch{FuelType: &fuel}}, internal.ErrVehicleNotFound},
+		{"no change", internal.VehicleOperation{Type: internal.VehicleOperationPatch, Vehicle: internal.Vehicle{Id: 1}}, internal.ErrVehicleOperationInvalid},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			results, _ := planVehicleBatch(rp.db, rp.unique, []internal.VehicleOperation{c.op})
+			if c.err == nil && results[0].Err != nil || c.err != nil && !errors.Is(results[0].Err, c.err) {
+				t.Fatalf("error = %v, want %v", results[0].Err, c.err)
+			}
+			want := car(1, "AAA1111")
+			want.FuelType = fuel
+			if c.err == nil && results[0].Vehicle != want {
+				t.Errorf("vehicle = %+v, want %+v", results[0].Vehicle, want)
+			}
+		})
+	}
+}
@@ -0,0 +1,192 @@
+package vehicle
+
+import (
+	"app/internal"
+	"app/internal/loader"
+	"errors"
+	"fmt"
+	"io"
+	"sync/atomic"
+	"time"
+)
+
+// maxJobErrors is the number of rejected rows kept in a job
+const maxJobErrors = 1000
+
+// NewVehicleImporter is a function that returns a new instance of VehicleImporter
+// - chunkSize is the number of rows inserted per batch
+func NewVehicleImporter(sv internal.VehicleService, jobs internal.JobRepository, chunkSize int) *VehicleImporter {
+	// default values
+	if chunkSize <= 0 {
+		chunkSize = 1000
+	}
+	return &VehicleImporter{sv: sv, jobs: jobs, chunkSize: chunkSize}
+}
+
+// VehicleImporter is a struct that implements the VehicleImporter interface.
+// The input is decoded row by row while it is read and inserted in best-effort
+// batches through the service, so it never sits in memory or on disk as a whole.
+type VehicleImporter struct {
+	// sv is the service the vehicles are inserted with
+	sv internal.VehicleService
+	// jobs is the repository of import jobs
+	jobs internal.JobRepository
+	// chunkSize is the number of rows inserted per batch
+	chunkSize int
+}
+
+// Import is a method that starts the import job, the input is read by the job as it goes
+func (i *VehicleImporter) Import(r io.Reader, opts internal.VehicleImportOptions) (j internal.Job, done <-chan struct{}, err error) {
+	switch opts.Format {
+	case "ndjson", "csv":
+	default:
+		return j, nil, fmt.Errorf("unsupported import format %q", opts.Format)
+	}
+
+	// decoder
+	// - the header of a CSV input is checked before accepting the job
+	cr := &countingReader{r: r}
+	var dec internal.VehicleDecoder
+	switch opts.Format {
+	case "ndjson":
+		dec = loader.NewVehicleNDJSONDecoder(cr)
+	case "csv":
+		dec, err = loader.NewVehicleCSVDecoder(cr, &loader.ConfigVehicleCSV{
+			Delimiter:        opts.CSVDelimiter,
+			DecimalSeparator: opts.CSVDecimalSeparator,
+		})
+	}
+	if err != nil {
+		return
+	}
+
+	j = internal.Job{
+		Kind:       "vehicles_import",
+		Status:     internal.JobRunning,
+		BytesTotal: max(opts.Size, 0),
+		CreatedAt:  time.Now().UTC(),
+	}
+	if err = i.jobs.Create(&j); err != nil {
+		return
+	}
+
+	ch := make(chan struct{})
+	go func(j internal.Job) {
+		defer close(ch)
+		i.run(j, dec, cr)
+	}(j)
+	return j, ch, nil
+}
+
+// FindJob is a method that returns the state of an import job
+func (i *VehicleImporter) FindJob(id int) (j internal.Job, err error) {
+	return i.jobs.FindById(id)
+}
+
+// run is a method that decodes the rows and inserts them chunk by chunk, updating the job after each chunk
+func (i *VehicleImporter) run(j internal.Job, dec internal.VehicleDecoder, cr *countingReader) {
+	reject := func(line int, msg string) {
+		j.Failed++
+		if len(j.Errors) < maxJobErrors {
+			j.Errors = append(j.Errors, internal.JobRowError{Line: line, Message: msg})
+		} else {
+			j.ErrorsTruncated = true
+		}
+	}
+
+	ops := make([]internal.VehicleOperation, 0, i.chunkSize)
+	lines := make([]int, 0, i.chunkSize)
+	flush := func() error {
+		if len(ops) == 0 {
+			return nil
+		}
+		results, err := i.sv.ApplyBatch(ops, false)
+		if err != nil {
+			return err
+		}
+		for k, res := range results {
+			if res.Applied {
+				j.Succeeded++
+				continue
+			}
+			reject(lines[k], res.Err.Error())
+		}
+		ops, lines = ops[:0], lines[:0]
+		j.BytesRead = cr.n.Load()
+		return i.jobs.Update(j)
+	}
+
+	var err error
+	for {
+		v, line, derr := dec.Next()
+		if derr == io.EOF {
+			break
+		}
+		var re loader.RowError
+		if errors.As(derr, &re) {
+			j.Processed++
+			reject(re.Line, re.Err.Error())
+			continue
+		}
+		if derr != nil {
+			err = derr
+			break
+		}
+
+		j.Processed++
+		if verr := ValidateVehicle(v); verr != nil {
+			reject(line, verr.Error())
+			continue
+		}
+		ops = append(ops, internal.VehicleOperation{Type: internal.VehicleOperationCreate, Vehicle: v})
+		lines = append(lines, line)
+		if len(ops) == i.chunkSize {
+			if err = flush(); err != nil {
+				break
+			}
+		}
+	}
+	if err == nil {
+		err = flush()
+	}
+
+	// finish
+	j.FinishedAt = time.Now().UTC()
+	j.BytesRead = cr.n.Load()
+	j.Status = internal.JobSucceeded
+	if err != nil {
+		j.Status = internal.JobFailed
+		j.Error = err.Error()
+	}
+	_ = i.jobs.Update(j)
+}
+
+// ValidateVehicle is a function that checks the attributes required to store a vehicle
+func ValidateVehicle(v internal.Vehicle) error {
+	switch {
+	case v.Id <= 0:
+		return errors.New("id must be positive")
+	case v.Brand == "":
+		return errors.New("brand is required")
+	case v.Model == "":
+		return errors.New("model is required")
+	case v.FabricationYear < 0 || v.Capacity < 0:
+		return errors.New("year and passengers can't be negative")
+	case v.MaxSpeed < 0 || v.Weight < 0 || v.Height < 0 || v.Length < 0 || v.Width < 0:
+		return errors.New("max speed, weight and dimensions can't be negative")
+	}
+	return nil
+}
+
+// countingReader is a struct that counts the bytes read through it
+type countingReader struct {
+	r io.Reader
+	n atomic.Int64
+}
+
+// Read is a method that reads from the underlying reader
+func (c *countingReader) Read(p []byte) (n int, err error) {
+	n, err = c.r.Read(p)
+	c.n.Add(int64(n))
+	return
+}
@@ -0,0 +1,122 @@
+package vehicle
+
+import (
+	"app/internal"
+	"sort"
+)
+
+// NewVehicleNormalized is a function that returns a new instance of VehicleNormalized
+func NewVehicleNormalized(sv internal.VehicleService, nm internal.VehicleNormalizer) *VehicleNormalized {
+	return &VehicleNormalized{VehicleService: sv, nm: nm}
+}
+
+// VehicleNormalized is a struct that decorates a vehicle service to normalize the brand and color
+// of the vehicles it creates, updates or replaces the fleet with
+type VehicleNormalized struct {
+	internal.VehicleService
+	// nm is the normalizer of the vehicles
+	nm internal.VehicleNormalizer
+}
+
+// Create is a method that creates a normalized vehicle
+func (s *VehicleNormalized) Create(v internal.Vehicle) error {
+	return s.VehicleService.Create(s.nm.Normalize(v))
+}
+
+// CreateBatch is a method that creates normalized vehicles
+func (s *VehicleNormalized) CreateBatch(vehicles []internal.Vehicle) error {
+	normalized := make([]internal.Vehicle, len(vehicles))
+	for i, v := range vehicles {
+		normalized[i] = s.nm.Normalize(v)
+	}
+	return s.VehicleService.CreateBatch(normalized)
+}
+
+// ApplyBatch is a method that applies a batch with the vehicles of its creates and updates normalized
+func (s *VehicleNormalized) ApplyBatch(ops []internal.VehicleOperation, atomic bool) ([]internal.VehicleOperationResult, error) {
+	normalized := make([]internal.VehicleOperation, len(ops))
+	for i, op := range ops {
+		if op.Type == internal.VehicleOperationCreate || op.Type == internal.VehicleOperationUpdate {
+			op.Vehicle = s.nm.Normalize(op.Vehicle)
+		}
+		normalized[i] = op
+	}
+	return s.VehicleService.ApplyBatch(normalized, atomic)
+}
+
+// Replace is a method that swaps the whole fleet for the normalized vehicles of db
+func (s *VehicleNormalized) Replace(db map[int]internal.Vehicle) ([]internal.VehicleOperation, error) {
+	return s.VehicleService.Replace(NormalizeVehicles(s.nm, db))
+}
+
+// NormalizeVehicles is a function that normalizes the vehicles of db in place and returns it
+func NormalizeVehicles(nm internal.VehicleNormalizer, db map[int]internal.Vehicle) map[int]internal.Vehicle {
+	for id, v := range db {
+		db[id] = nm.Normalize(v)
+	}
+	return db
+}
+
+// NewVehicleNormalizationDefault is a function that returns a new instance of VehicleNormalizationDefault
+func NewVehicleNormalizationDefault(sv internal.VehicleService, nm internal.VehicleNormalizer) *VehicleNormalizationDefault {
+	return &VehicleNormalizationDefault{sv: sv, nm: nm}
+}
+
+// VehicleNormalizationDefault is a struct that implements the VehicleNormalizationService interface
+type VehicleNormalizationDefault struct {
+	// sv is the vehicle service holding the fleet to normalize
+	sv internal.VehicleService
+	// nm is the normalizer of the vehicles
+	nm internal.VehicleNormalizer
+}
+
+// Entries is a method that returns the entries of the dictionary of a field
+func (s *VehicleNormalizationDefault) Entries(field string) ([]internal.VehicleNormalizationEntry, error) {
+	return s.nm.Entries(field)
+}
+
+// SetEntry is a method that creates or replaces an entry of the dictionary of a field.
+// The stored vehicles are left as they are until they are normalized again.
+func (s *VehicleNormalizationDefault) SetEntry(field string, e internal.VehicleNormalizationEntry) error {
+	return s.nm.SetEntry(field, e)
+}
+
+// DeleteEntry is a method that deletes an entry of the dictionary of a field
+func (s *VehicleNormalizationDefault) DeleteEntry(field, canonical string) error {
+	return s.nm.DeleteEntry(field, canonical)
+}
+
+// Renormalize is a method that normalizes the stored vehicles again from the values they were received with
+// and updates the ones that changed in a single non atomic batch
+// - a vehicle deleted in the meantime is skipped
+func (s *VehicleNormalizationDefault) Renormalize() (n int, err error) {
+	db, err := s.sv.FindAll()
+	if err != nil {
+		return
+	}
+	var ops []internal.VehicleOperation
+	for _, v := range db {
+		w := v
+		if w.BrandRaw != "" {
+			w.Brand, w.BrandRaw = w.BrandRaw, ""
+		}
+		if w.ColorRaw != "" {
+			w.Color, w.ColorRaw = w.ColorRaw, ""
+		}
+		if w = s.nm.Normalize(w); w != v {
+			ops = append(ops, internal.VehicleOperation{Type: internal.VehicleOperationUpdate, Vehicle: w})
+		}
+	}
+	if len(ops) == 0 {
+		return
+	}
+	sort.Slice(ops, func(i, j int) bool { return ops[i].Vehicle.Id < ops[j].Vehicle.Id })
+
+	results, err := s.sv.ApplyBatch(ops, false)
+	for _, res := range results {
+		if res.Applied {
+			n++
+		}
+	}
+	return
+}
@@ -0,0 +1,59 @@
+package vehicle
+
+import "app/internal"
+
+// NewVehicleRegistrationValidated is a function that returns a new instance of VehicleRegistrationValidated
+func NewVehicleRegistrationValidated(sv internal.VehicleService, vl internal.VehicleRegistrationValidator) *VehicleRegistrationValidated {
+	return &VehicleRegistrationValidated{VehicleService: sv, vl: vl}
+}
+
+// VehicleRegistrationValidated is a struct that decorates a vehicle service to reject the vehicles it creates
+// or updates with a registration matching none of the configured plate formats.
+// The fleet replaced by a reload or a restore is stored as it is, and an update keeping the registration
+// a vehicle already has is not checked, so a fleet loaded before the formats were configured can still be updated.
+type VehicleRegistrationValidated struct {
+	internal.VehicleService
+	// vl is the validator of the registrations
+	vl internal.VehicleRegistrationValidator
+}
+
+// Create is a method that creates a vehicle with a valid registration
+func (s *VehicleRegistrationValidated) Create(v internal.Vehicle) error {
+	if err := s.vl.Validate(v.Registration); err != nil {
+		return err
+	}
+	return s.VehicleService.Create(v)
+}
+
+// CreateBatch is a method that creates vehicles with valid registrations, none when one is invalid
+func (s *VehicleRegistrationValidated) CreateBatch(vehicles []internal.Vehicle) error {
+	for _, v := range vehicles {
+		if err := s.vl.Validate(v.Registration); err != nil {
+			return err
+		}
+	}
+	return s.VehicleService.CreateBatch(vehicles)
+}
+
+// ApplyBatch is a method that applies a batch whose creates and updates with an invalid registration fail.
+// An atomic batch with such an operation is aborted, otherwise the other operations are applied.
+func (s *VehicleRegistrationValidated) ApplyBatch(ops []internal.VehicleOperation, atomic bool) ([]internal.VehicleOperationResult, error) {
+	return internal.ApplyCheckedVehicleBatch(s.VehicleService, ops, atomic, func(_ int, op *internal.VehicleOperation) error {
+		if op.Type == internal.VehicleOperationDelete || op.Type == internal.VehicleOperationPatch || s.kept(*op) {
+			return nil
+		}
+		return s.vl.Validate(op.Vehicle.Registration)
+	})
+}
+
+// kept is a method that reports whether an operation is the update of a vehicle keeping its registration
+func (s *VehicleRegistrationValidated) kept(op internal.VehicleOperation) bool {
+	if op.Type != internal.VehicleOperationUpdate {
+		return false
+	}
+	vehicles, err := s.VehicleService.FindById(op.Vehicle.Id)
+	if err != nil || len(vehicles) == 0 {
+		return false
+	}
+	return internal.VehicleRegistrationKey(vehicles[0].Registration) == internal.VehicleRegistrationKey(op.Vehicle.Registration)
+}
@@ -3,6 +3,9 @@ package vehicle
 import (
 	"app/internal"
 	"fmt"
+	"maps"
+	"sort"
+	"sync"
 )
 
 // NewVehicleMap is a function that returns a new instance of VehicleMap
@@ -12,17 +15,26 @@ func NewVehicleMap(db map[int]internal.Vehicle) *VehicleMap {
 	if db != nil {
 		defaultDb = db
 	}
-	return &VehicleMap{db: defaultDb}
+	r := &VehicleMap{db: defaultDb}
+	r.reindex()
+	return r
 }
 
 // VehicleMap is a struct that represents a vehicle repository
 type VehicleMap struct {
+	// mu guards db against concurrent requests
+	mu sync.RWMutex
 	// db is a map of vehicles
 	db map[int]internal.Vehicle
+	// unique indexes the ids of the vehicles by the keys of their registration and VIN
+	unique uniqueIndex
 }
 
 // FindAll is a method that returns a map of all vehicles
 func (r *VehicleMap) FindAll() (v map[int]internal.Vehicle, err error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
 	v = make(map[int]internal.Vehicle)
 
 	// copy db
@@ -34,45 +46,64 @@ func (r *VehicleMap) FindAll() (v map[int]internal.Vehicle, err error) {
 }
 
 func (r *VehicleMap) Create(v internal.Vehicle) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
 	if _, exists := r.db[v.Id]; exists {
-		return fmt.Errorf("vehicle with ID: %v, already exists", v.Id)
+		return vehicleError(internal.ErrVehicleExists, "vehicle with ID: %v, already exists", v.Id)
+	}
+	if err := r.checkUnique(v); err != nil {
+		return err
 	}
-	r.db[v.Id] = v
+	r.put(v)
 	return nil
 }
 
-func (r *VehicleMap) Delete(id int) error {
-	if _, exists := r.db[id]; !exists {
-		return fmt.Errorf("vehicle with ID: %v, not found", id)
+func (r *VehicleMap) Delete(id int) (internal.Vehicle, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	v, exists := r.db[id]
+	if !exists {
+		return internal.Vehicle{}, vehicleError(internal.ErrVehicleNotFound, "vehicle with ID: %v, not found", id)
 	}
-	delete(r.db, id)
-	return nil
+	r.remove(id)
+	return v, nil
 }
 
-func (r *VehicleMap) UpdateSpeed(id int, speed float64) error {
+func (r *VehicleMap) UpdateSpeed(id int, speed float64) (internal.Vehicle, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
 	v, exists := r.db[id]
 	if !exists {
-		return fmt.Errorf("vehicle with ID: %v, does not found", id)
+		return internal.Vehicle{}, vehicleError(internal.ErrVehicleNotFound, "vehicle with ID: %v, does not found", id)
 	}
 
 	v.MaxSpeed = speed
 	r.db[id] = v
-	return nil
+	return v, nil
 }
 
-func (r *VehicleMap) UpdateFuelType(id int, fuelType string) error {
+func (r *VehicleMap) UpdateFuelType(id int, fuelType string) (internal.Vehicle, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
 	v, exists := r.db[id]
 	if !exists {
-		return fmt.Errorf("vehicle with ID: %v, does not found", id)
+		return internal.Vehicle{}, vehicleError(internal.ErrVehicleNotFound, "vehicle with ID: %v, does not found", id)
 	}
 
 	v.FuelType = fuelType
 	r.db[id] = v
 
-	return nil
+	return v, nil
 }
 
 func (r *VehicleMap) FindByFuelType(fuelType string) ([]internal.Vehicle, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
 	var result []internal.Vehicle
 	for _, v := range r.db {
 		if v.FuelType == fuelType {
@@ -87,6 +118,9 @@ func (r *VehicleMap) FindByFuelType(fuelType string) ([]internal.Vehicle, error)
 }
 
 func (r *VehicleMap) FindByTransmissionType(transmission string) ([]internal.Vehicle, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
 	var result []internal.Vehicle
 	for _, v := range r.db {
 		if v.Transmission == transmission {
@@ -101,6 +135,9 @@ func (r *VehicleMap) FindByTransmissionType(transmission string) ([]internal.Veh
 }
 
 func (r *VehicleMap) FindByColorAndYear(color string, year int) ([]internal.Vehicle, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
 	var result []internal.Vehicle
 
 	for _, v := range r.db {
@@ -116,18 +153,27 @@ func (r *VehicleMap) FindByColorAndYear(color string, year int) ([]internal.Vehi
 }
 
 func (r *VehicleMap) CreateBatch(vehicles []internal.Vehicle) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
 	for _, v := range vehicles {
 		if _, exists := r.db[v.Id]; exists {
-			return fmt.Errorf("vehicle with ID: %v already exists", v.Id)
+			return vehicleError(internal.ErrVehicleExists, "vehicle with ID: %v already exists", v.Id)
 		}
 	}
+	if err := r.checkUnique(vehicles...); err != nil {
+		return err
+	}
 	for _, v := range vehicles {
-		r.db[v.Id] = v
+		r.put(v)
 	}
 	return nil
 }
 
 func (r *VehicleMap) FindByBrandAndBetweenYear(brand string, start, end int) ([]internal.Vehicle, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
 	var result []internal.Vehicle
 
 	for _, v := range r.db {
@@ -142,6 +188,9 @@ func (r *VehicleMap) FindByBrandAndBetweenYear(brand string, start, end int) ([]
 }
 
 func (r *VehicleMap) FindById(id int) ([]internal.Vehicle, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
 	var result []internal.Vehicle
 
 	for _, v := range r.db {
@@ -150,12 +199,15 @@ func (r *VehicleMap) FindById(id int) ([]internal.Vehicle, error) {
 		}
 	}
 	if len(result) == 0 {
-		return nil, fmt.Errorf("vehicle not found")
+		return nil, internal.ErrVehicleNotFound
 	}
 	return result, nil
 }
 
 func (r *VehicleMap) FindByBrandAverageSpeed(brand string) (float64, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
 	var total float64
 	var count int
 
@@ -171,7 +223,10 @@ func (r *VehicleMap) FindByBrandAverageSpeed(brand string) (float64, error) {
 	return total / float64(count), nil
 }
 
-func (r *VehicleMap) FindByBrandAverageCapacity(brand string) (int, error) {
+func (r *VehicleMap) FindByBrandAverageCapacity(brand string) (float64, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
 	var total int
 	var count int
 
@@ -184,10 +239,13 @@ func (r *VehicleMap) FindByBrandAverageCapacity(brand string) (int, error) {
 	if count == 0 {
 		return 0, fmt.Errorf("brand not found")
 	}
-	return total / int(count), nil
+	return float64(total) / float64(count), nil
 }
 
 func (r *VehicleMap) FindByDimensions(lengthMin, lengthMax, widthMin, widthMax float64) ([]internal.Vehicle, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
 	var result []internal.Vehicle
 	for _, v := range r.db {
 		if v.Length >= lengthMin && v.Length <= lengthMax &&
@@ -202,6 +260,9 @@ func (r *VehicleMap) FindByDimensions(lengthMin, lengthMax, widthMin, widthMax f
 }
 
 func (r *VehicleMap) FindByWeight(min, max float64) ([]internal.Vehicle, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
 	var result []internal.Vehicle
 	for _, v := range r.db {
 		if v.Weight >= min && v.Weight <= max {
@@ -215,6 +276,9 @@ func (r *VehicleMap) FindByWeight(min, max float64) ([]internal.Vehicle, error)
 }
 
 func (r *VehicleMap) FindByColor(color string) ([]internal.Vehicle, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
 	var result []internal.Vehicle
 
 	for _, v := range r.db {
@@ -228,3 +292,145 @@ func (r *VehicleMap) FindByColor(color string) ([]internal.Vehicle, error) {
 	}
 	return result, nil
 }
+
+// FindByFilter is a method that returns the vehicles matching the filter sorted by id
+func (r *VehicleMap) FindByFilter(f internal.VehicleFilter) ([]internal.Vehicle, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	result := make([]internal.Vehicle, 0)
+	for _, v := range r.db {
+		if f.Match(v) {
+			result = append(result, v)
+		}
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].Id < result[j].Id })
+	return result, nil
+}
+
+// forEachChunk is the number of vehicles read under a single lock by ForEach
+const forEachChunk = 256
+
+// ForEach is a method that calls fn for each vehicle matching the filter in id order.
+// The lock is only held while reading each chunk so a slow fn does not block writers.
+func (r *VehicleMap) ForEach(f internal.VehicleFilter, fn func(v internal.Vehicle) error) error {
+	// ids
+	r.mu.RLock()
+	ids := make([]int, 0)
+	for id, v := range r.db {
+		if f.Match(v) {
+			ids = append(ids, id)
+		}
+	}
+	r.mu.RUnlock()
+	sort.Ints(ids)
+
+	// chunks
+	chunk := make([]internal.Vehicle, 0, forEachChunk)
+	for start := 0; start < len(ids); start += forEachChunk {
+		end := start + forEachChunk
+		if end > len(ids) {
+			end = len(ids)
+		}
+
+		chunk = chunk[:0]
+		r.mu.RLock()
+		for _, id := range ids[start:end] {
+			// the vehicle may have been deleted or updated since the ids were read
+			if v, ok := r.db[id]; ok && f.Match(v) {
+				chunk = append(chunk, v)
+			}
+		}
+		r.mu.RUnlock()
+
+		for _, v := range chunk {
+			if err := fn(v); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// ApplyBatch is a method that applies the operations in order under a single lock
+func (r *VehicleMap) ApplyBatch(ops []internal.VehicleOperation, atomic bool) ([]internal.VehicleOperationResult, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	results, ok := planVehicleBatch(r.db, r.unique, ops)
+	if atomic && !ok {
+		return results, internal.ErrVehicleBatchAborted
+	}
+	for i, res := range results {
+		if res.Err != nil {
+			continue
+		}
+		switch res.Type {
+		case internal.VehicleOperationCreate, internal.VehicleOperationUpdate, internal.VehicleOperationPatch:
+			r.put(res.Vehicle)
+		case internal.VehicleOperationDelete:
+			r.remove(res.Vehicle.Id)
+		}
+		results[i].Applied = true
+	}
+	return results, nil
+}
+
+// Replace is a method that swaps the whole content of the repository and returns the changes it made
+func (r *VehicleMap) Replace(db map[int]internal.Vehicle) (ops []internal.VehicleOperation, err error) {
+	db = maps.Clone(db)
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	ops = diffVehicles(r.db, db)
+	r.swap(db)
+	return
+}
+
+// swap is a method that installs db as the content of the repository, the caller must hold mu.
+// Its registrations and VINs are indexed as they are, shared ones included.
+func (r *VehicleMap) swap(db map[int]internal.Vehicle) {
+	if db == nil {
+		db = make(map[int]internal.Vehicle)
+	}
+	r.db = db
+	r.reindex()
+}
+
+// exists is a method that reports whether a vehicle with the given id is stored
+func (r *VehicleMap) exists(id int) bool {
+	_, ok := r.get(id)
+	return ok
+}
+
+// get is a method that returns the stored vehicle with the given id under the read lock
+func (r *VehicleMap) get(id int) (v internal.Vehicle, ok bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	return r.lookup(id)
+}
+
+// repositoryError is a struct that represents an error of the repository matched by its kind with errors.Is
+type repositoryError struct {
+	// kind is the sentinel error matched by the error
+	kind error
+	// msg is the message of the error
+	msg string
+}
+
+// Error is a method that returns the error message
+func (e *repositoryError) Error() string {
+	return e.msg
+}
+
+// Unwrap is a method that returns the sentinel error
+func (e *repositoryError) Unwrap() error {
+	return e.kind
+}
+
+// vehicleError is a function that returns an error with a formatted message matched by kind
+func vehicleError(kind error, format string, args ...any) error {
+	return &repositoryError{kind: kind, msg: fmt.Sprintf(format, args...)}
+}
@@ -0,0 +1,317 @@
+package vehicle
+
+import (
+	"app/internal"
+	"sync"
+	"time"
+)
+
+// NewVehicleEventSourced is a function that returns a new instance of VehicleEventSourced
+// - snapshotEvery is the number of events between snapshots, 0 disables snapshotting
+func NewVehicleEventSourced(st internal.VehicleEventStore, ss internal.VehicleSnapshotStore, snapshotEvery int) *VehicleEventSourced {
+	return &VehicleEventSourced{
+		VehicleMap:    NewVehicleMap(nil),
+		st:            st,
+		ss:            ss,
+		snapshotEvery: snapshotEvery,
+	}
+}
+
+// VehicleEventSourced is a struct that represents a vehicle repository whose source of truth
+// is a stream of events. The embedded VehicleMap is the projection used to answer queries.
+type VehicleEventSourced struct {
+	*VehicleMap
+	// st is the event stream
+	st internal.VehicleEventStore
+	// ss is the snapshot store, it may be nil
+	ss internal.VehicleSnapshotStore
+	// snapshotEvery is the number of events between snapshots
+	snapshotEvery int
+	// mu serializes mutations so the stream and the projection stay in the same order
+	mu sync.Mutex
+	// sequence is the sequence of the last event applied to the projection
+	sequence uint64
+	// pending is the number of events applied since the last snapshot
+	pending int
+}
+
+// Replay is a method that rebuilds the projection from the latest snapshot and the events after it
+func (r *VehicleEventSourced) Replay() (err error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	db := make(map[int]internal.Vehicle)
+	var seq uint64
+	if r.ss != nil {
+		sn, ok, err := r.ss.Latest()
+		if err != nil {
+			return err
+		}
+		if ok {
+			db, seq = sn.Vehicles, sn.Sequence
+		}
+	}
+
+	events, err := r.st.Load(seq)
+	if err != nil {
+		return
+	}
+	r.project(db, seq, events)
+	return
+}
+
+// Rebuild is a method that rebuilds the projection from the whole stream, ignoring snapshots,
+// and saves a fresh snapshot of the result
+func (r *VehicleEventSourced) Rebuild() (events int, err error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	evs, err := r.st.Load(0)
+	if err != nil {
+		return
+	}
+	r.project(make(map[int]internal.Vehicle), 0, evs)
+	events = len(evs)
+
+	if r.ss != nil {
+		err = r.snapshot()
+	}
+	return
+}
+
+// Sequence is a method that returns the sequence of the last event applied to the projection
+func (r *VehicleEventSourced) Sequence() uint64 {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return r.sequence
+}
+
+// project is a method that applies the events to db and installs it as the projection
+func (r *VehicleEventSourced) project(db map[int]internal.Vehicle, seq uint64, events []internal.VehicleEvent) {
+	for _, e := range events {
+		applyVehicleEvent(db, e)
+		seq = e.Sequence
+	}
+	r.VehicleMap.mu.Lock()
+	r.VehicleMap.swap(db)
+	r.VehicleMap.mu.Unlock()
+	r.sequence = seq
+	r.pending = len(events)
+}
+
+func (r *VehicleEventSourced) Create(v internal.Vehicle) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.VehicleMap.exists(v.Id) {
+		return vehicleError(internal.ErrVehicleExists, "vehicle with ID: %v, already exists", v.Id)
+	}
+	if err := r.VehicleMap.checkUniqueLocked(v); err != nil {
+		return err
+	}
+	return r.record(internal.VehicleEvent{Type: internal.VehicleRegistered, VehicleId: v.Id, Vehicle: v})
+}
+
+func (r *VehicleEventSourced) Delete(id int) (internal.Vehicle, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	v, ok := r.VehicleMap.get(id)
+	if !ok {
+		return v, vehicleError(internal.ErrVehicleNotFound, "vehicle with ID: %v, not found", id)
+	}
+	return v, r.record(internal.VehicleEvent{Type: internal.VehicleRemoved, VehicleId: id})
+}
+
+func (r *VehicleEventSourced) UpdateSpeed(id int, speed float64) (internal.Vehicle, error) {
+	return r.update(internal.VehicleEvent{Type: internal.SpeedChanged, VehicleId: id, MaxSpeed: speed})
+}
+
+func (r *VehicleEventSourced) UpdateFuelType(id int, fuelType string) (internal.Vehicle, error) {
+	return r.update(internal.VehicleEvent{Type: internal.FuelTypeChanged, VehicleId: id, FuelType: fuelType})
+}
+
+// update is a method that records an attribute change of a vehicle and returns the vehicle it left.
+// The projection is read before mu is released, so no other mutation is applied in between.
+func (r *VehicleEventSourced) update(e internal.VehicleEvent) (internal.Vehicle, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if !r.VehicleMap.exists(e.VehicleId) {
+		return internal.Vehicle{}, vehicleError(internal.ErrVehicleNotFound, "vehicle with ID: %v, does not found", e.VehicleId)
+	}
+	if err := r.record(e); err != nil {
+		return internal.Vehicle{}, err
+	}
+	v, _ := r.VehicleMap.get(e.VehicleId)
+	return v, nil
+}
+
+func (r *VehicleEventSourced) CreateBatch(vehicles []internal.Vehicle) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, v := range vehicles {
+		if r.VehicleMap.exists(v.Id) {
+			return vehicleError(internal.ErrVehicleExists, "vehicle with ID: %v already exists", v.Id)
+		}
+	}
+	if err := r.VehicleMap.checkUniqueLocked(vehicles...); err != nil {
+		return err
+	}
+	events := make([]internal.VehicleEvent, 0, len(vehicles))
+	for _, v := range vehicles {
+		events = append(events, internal.VehicleEvent{Type: internal.VehicleRegistered, VehicleId: v.Id, Vehicle: v})
+	}
+	return r.record(events...)
+}
+
+// ApplyBatch is a method that records the operations of the batch as a single append
+func (r *VehicleEventSourced) ApplyBatch(ops []internal.VehicleOperation, atomic bool) ([]internal.VehicleOperationResult, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.VehicleMap.mu.RLock()
+	results, ok := planVehicleBatch(r.VehicleMap.db, r.VehicleMap.unique, ops)
+	r.VehicleMap.mu.RUnlock()
+	if atomic && !ok {
+		return results, internal.ErrVehicleBatchAborted
+	}
+
+	events := make([]internal.VehicleEvent, 0, len(results))
+	for _, res := range results {
+		if res.Err != nil {
+			continue
+		}
+		e := internal.VehicleEvent{VehicleId: res.Vehicle.Id, Vehicle: res.Vehicle}
+		switch res.Type {
+		case internal.VehicleOperationCreate:
+			e.Type = internal.VehicleRegistered
+		case internal.VehicleOperationUpdate, internal.VehicleOperationPatch:
+			e.Type = internal.AttributesChanged
+		case internal.VehicleOperationDelete:
+			e.Type, e.Vehicle = internal.VehicleRemoved, internal.Vehicle{}
+		}
+		events = append(events, e)
+	}
+	if err := r.record(events...); err != nil {
+		return results, err
+	}
+	for i := range results {
+		results[i].Applied = results[i].Err == nil
+	}
+	return results, nil
+}
+
+// Replace is a method that records the events turning the projection into db
+func (r *VehicleEventSourced) Replace(db map[int]internal.Vehicle) ([]internal.VehicleOperation, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.VehicleMap.mu.RLock()
+	ops := diffVehicles(r.VehicleMap.db, db)
+	r.VehicleMap.mu.RUnlock()
+
+	events := make([]internal.VehicleEvent, 0, len(ops))
+	for _, op := range ops {
+		e := internal.VehicleEvent{VehicleId: op.Vehicle.Id, Vehicle: op.Vehicle}
+		switch op.Type {
+		case internal.VehicleOperationCreate:
+			e.Type = internal.VehicleRegistered
+		case internal.VehicleOperationUpdate:
+			e.Type = internal.AttributesChanged
+		case internal.VehicleOperationDelete:
+			e.Type, e.Vehicle = internal.VehicleRemoved, internal.Vehicle{}
+		}
+		events = append(events, e)
+	}
+	if err := r.record(events...); err != nil {
+		return nil, err
+	}
+	return ops, nil
+}
+
+// record is a method that appends the events to the stream and applies them to the projection
+// - the caller must hold mu and have validated the events against the projection
+func (r *VehicleEventSourced) record(events ...internal.VehicleEvent) (err error) {
+	if len(events) == 0 {
+		return
+	}
+	now := time.Now().UTC()
+	for i := range events {
+		events[i].OccurredAt = now
+	}
+
+	stored, err := r.st.Append(events...)
+	if err != nil {
+		return
+	}
+
+	r.VehicleMap.mu.Lock()
+	for _, e := range stored {
+		r.VehicleMap.apply(e)
+		r.sequence = e.Sequence
+	}
+	r.VehicleMap.mu.Unlock()
+
+	// snapshot
+	// - the events are already durable, a failed snapshot is retried on the next mutation
+	r.pending += len(stored)
+	if r.ss != nil && r.snapshotEvery > 0 && r.pending >= r.snapshotEvery {
+		_ = r.snapshot()
+	}
+	return
+}
+
+// snapshot is a method that saves the current projection, the caller must hold mu
+func (r *VehicleEventSourced) snapshot() (err error) {
+	db, err := r.VehicleMap.FindAll()
+	if err != nil {
+		return
+	}
+	err = r.ss.Save(internal.VehicleSnapshot{
+		Sequence: r.sequence,
+		TakenAt:  time.Now().UTC(),
+		Vehicles: db,
+	})
+	if err != nil {
+		return
+	}
+	r.pending = 0
+	return
+}
+
+// apply is a method that applies an event to the vehicles, keeping the registration index in sync.
+// The caller must hold mu.
+func (r *VehicleMap) apply(e internal.VehicleEvent) {
+	switch e.Type {
+	case internal.VehicleRegistered, internal.AttributesChanged:
+		r.put(e.Vehicle)
+	case internal.VehicleRemoved:
+		r.remove(e.VehicleId)
+	default:
+		// - the speed and fuel type changes leave the registration as it is
+		applyVehicleEvent(r.db, e)
+	}
+}
+
+// applyVehicleEvent is a function that applies an event to a vehicle map
+func applyVehicleEvent(db map[int]internal.Vehicle, e internal.VehicleEvent) {
+	switch e.Type {
+	case internal.VehicleRegistered, internal.AttributesChanged:
+		db[e.VehicleId] = e.Vehicle
+	case internal.SpeedChanged:
+		if v, ok := db[e.VehicleId]; ok {
+			v.MaxSpeed = e.MaxSpeed
+			db[e.VehicleId] = v
+		}
+	case internal.FuelTypeChanged:
+		if v, ok := db[e.VehicleId]; ok {
+			v.FuelType = e.FuelType
+			db[e.VehicleId] = v
+		}
+	case internal.VehicleRemoved:
+		delete(db, e.VehicleId)
+	}
+}
@@ -0,0 +1,120 @@
+package vehicle
+
+import (
+	"app/internal"
+	"app/internal/eventstore"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+// newEventSourced is a function that returns an event sourced repository over files in dir
+func newEventSourced(t *testing.T, dir string, snapshotEvery int) *VehicleEventSourced {
+	t.Helper()
+	rp := NewVehicleEventSourced(
+		eventstore.NewVehicleEventJSONFile(filepath.Join(dir, "events.jsonl")),
+		eventstore.NewVehicleSnapshotJSONFile(filepath.Join(dir, "snapshot.json")),
+		snapshotEvery,
+	)
+	if err := rp.Replay(); err != nil {
+		t.Fatalf("replay: %v", err)
+	}
+	return rp
+}
+
+// mutate is a function that applies a series of changes crossing several snapshots
+func mutate(t *testing.T, rp *VehicleEventSourced) {
+	t.Helper()
+	for id := 1; id <= 5; id++ {
+		v := internal.Vehicle{Id: id}
+		v.Brand, v.Registration, v.MaxSpeed, v.FuelType = "Toyota", "ABC100"+string(rune('0'+id)), 150, "gasoline"
+		if err := rp.Create(v); err != nil {
+			t.Fatalf("create %d: %v", id, err)
+		}
+	}
+	if _, err := rp.UpdateSpeed(2, 210); err != nil {
+		t.Fatalf("update speed: %v", err)
+	}
+	if _, err := rp.UpdateFuelType(3, "diesel"); err != nil {
+		t.Fatalf("update fuel type: %v", err)
+	}
+	if _, err := rp.Delete(4); err != nil {
+		t.Fatalf("delete: %v", err)
+	}
+	v := internal.Vehicle{Id: 5}
+	v.Brand, v.Registration, v.MaxSpeed = "Ford", "XYZ9876", 190
+	if _, err := rp.ApplyBatch([]internal.VehicleOperation{{Type: internal.VehicleOperationUpdate, Vehicle: v}}, true); err != nil {
+		t.Fatalf("apply batch: %v", err)
+	}
+	// - the last event is after the last snapshot
+	if _, err := rp.UpdateSpeed(1, 175); err != nil {
+		t.Fatalf("update speed: %v", err)
+	}
+}
+
+func TestVehicleEventSourced_ReplayAfterSnapshot(t *testing.T) {
+	dir := t.TempDir()
+	rp := newEventSourced(t, dir, 3)
+	mutate(t, rp)
+	want, _ := rp.FindAll()
+
+	// the snapshot is older than the last event
+	sn, ok, err := eventstore.NewVehicleSnapshotJSONFile(filepath.Join(dir, "snapshot.json")).Latest()
+	if err != nil || !ok {
+		t.Fatalf("snapshot: ok %v, error %v", ok, err)
+	}
+	if sn.Sequence == 0 || sn.Sequence >= rp.Sequence() {
+		t.Fatalf("snapshot sequence = %d, want between 0 and %d", sn.Sequence, rp.Sequence())
+	}
+
+	restarted := newEventSourced(t, dir, 3)
+	got, _ := restarted.FindAll()
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("replayed projection = %+v, want %+v", got, want)
+	}
+	if restarted.Sequence() != rp.Sequence() {
+		t.Errorf("sequence = %d, want %d", restarted.Sequence(), rp.Sequence())
+	}
+	if got[2].MaxSpeed != 210 || got[3].FuelType != "diesel" || got[5].Brand != "Ford" {
+		t.Errorf("projection = %+v, want the changes applied", got)
+	}
+	if _, ok := got[4]; ok {
+		t.Error("deleted vehicle 4 is back after replay")
+	}
+
+	// the registration index is rebuilt with the projection
+	if found, err := restarted.FindByRegistration("XYZ9876"); err != nil || len(found) != 1 || found[0].Id != 5 {
+		t.Errorf("find by registration after replay = %+v, %v, want vehicle 5", found, err)
+	}
+	dup := internal.Vehicle{Id: 9}
+	dup.Registration = "XYZ9876"
+	if err := restarted.Create(dup); err == nil {
+		t.Error("create with a taken registration after replay succeeded")
+	}
+}
+
+func TestVehicleEventSourced_Rebuild(t *testing.T) {
+	dir := t.TempDir()
+	rp := newEventSourced(t, dir, 3)
+	mutate(t, rp)
+	want, _ := rp.FindAll()
+
+	rebuilt := newEventSourced(t, dir, 3)
+	n, err := rebuilt.Rebuild()
+	if err != nil {
+		t.Fatalf("rebuild: %v", err)
+	}
+	if n != int(rp.Sequence()) {
+		t.Errorf("rebuild replayed %d events, want %d", n, rp.Sequence())
+	}
+	got, _ := rebuilt.FindAll()
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("rebuilt projection = %+v, want %+v", got, want)
+	}
+
+	// the fresh snapshot covers the whole log
+	sn, ok, err := eventstore.NewVehicleSnapshotJSONFile(filepath.Join(dir, "snapshot.json")).Latest()
+	if err != nil || !ok || sn.Sequence != rp.Sequence() || !reflect.DeepEqual(sn.Vehicles, want) {
+		t.Errorf("snapshot = %+v (ok %v, error %v), want the projection at %d", sn, ok, err, rp.Sequence())
+	}
+}
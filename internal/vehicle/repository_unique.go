@@ -0,0 +1,199 @@
+package vehicle
+
+import (
+	"app/internal"
+	"sort"
+)
+
+// uniqueField is a struct that represents an attribute whose value can only be held by a single vehicle
+// - normalize returns the form a value is indexed and compared in
+// - kind is the sentinel error matched when the value is already held by another vehicle
+type uniqueField struct {
+	name      string
+	value     func(v internal.Vehicle) string
+	normalize func(value string) string
+	kind      error
+}
+
+// key is a method that returns the key of the field of a vehicle, empty when the vehicle has no value
+func (f uniqueField) key(v internal.Vehicle) string {
+	return f.normalize(f.value(v))
+}
+
+const (
+	// uniqueRegistration is the position of the registration in uniqueFields
+	uniqueRegistration = iota
+	// uniqueVIN is the position of the VIN in uniqueFields
+	uniqueVIN
+)
+
+// uniqueFields are the attributes whose values can only be held by a single vehicle, by position
+var uniqueFields = []uniqueField{
+	uniqueRegistration: {"registration", func(v internal.Vehicle) string { return v.Registration }, internal.VehicleRegistrationKey, internal.ErrVehicleRegistrationExists},
+	uniqueVIN:          {"vin", func(v internal.Vehicle) string { return v.VIN }, internal.VehicleVINKey, internal.ErrVehicleVINExists},
+}
+
+// uniqueIndex is the index of the ids of the vehicles by key for each unique field, by position in uniqueFields.
+// The ids of a key are in id order, a key only has more than one id for the values shared by a loaded fleet.
+type uniqueIndex []map[string][]int
+
+// FindByRegistration is a method that returns the vehicles holding a registration, regardless of case and separators,
+// in id order. More than one vehicle is only returned for the shared registrations of a loaded fleet.
+func (r *VehicleMap) FindByRegistration(registration string) ([]internal.Vehicle, error) {
+	return r.findUnique(uniqueRegistration, registration)
+}
+
+// FindByVIN is a method that returns the vehicles holding a VIN, regardless of case, spaces and hyphens,
+// in id order. More than one vehicle is only returned for the shared VINs of a loaded fleet.
+func (r *VehicleMap) FindByVIN(vin string) ([]internal.Vehicle, error) {
+	return r.findUnique(uniqueVIN, vin)
+}
+
+// findUnique is a method that returns the vehicles holding a value of a unique field, in id order
+func (r *VehicleMap) findUnique(field int, value string) ([]internal.Vehicle, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	result := make([]internal.Vehicle, 0)
+	key := uniqueFields[field].normalize(value)
+	if key == "" {
+		return result, nil
+	}
+	for _, id := range r.unique[field][key] {
+		result = append(result, r.db[id])
+	}
+	return result, nil
+}
+
+// put is a method that stores a vehicle and keeps the unique index in sync, the caller must hold mu
+func (r *VehicleMap) put(v internal.Vehicle) {
+	if old, ok := r.db[v.Id]; ok {
+		r.unique.remove(old)
+	}
+	r.db[v.Id] = v
+	r.unique.add(v)
+}
+
+// remove is a method that deletes a vehicle and keeps the unique index in sync, the caller must hold mu
+func (r *VehicleMap) remove(id int) {
+	if old, ok := r.db[id]; ok {
+		r.unique.remove(old)
+		delete(r.db, id)
+	}
+}
+
+// reindex is a method that rebuilds the unique index from db, the caller must hold mu
+func (r *VehicleMap) reindex() {
+	r.unique = make(uniqueIndex, len(uniqueFields))
+	for i := range r.unique {
+		r.unique[i] = make(map[string][]int)
+	}
+	for _, v := range r.db {
+		r.unique.add(v)
+	}
+}
+
+// add is a method that adds a vehicle to the index, keeping the ids of a key in order
+func (ix uniqueIndex) add(v internal.Vehicle) {
+	for i, f := range uniqueFields {
+		key := f.key(v)
+		if key == "" {
+			continue
+		}
+		ids := ix[i][key]
+		j := sort.SearchInts(ids, v.Id)
+		if j < len(ids) && ids[j] == v.Id {
+			continue
+		}
+		ids = append(ids, 0)
+		copy(ids[j+1:], ids[j:])
+		ids[j] = v.Id
+		ix[i][key] = ids
+	}
+}
+
+// remove is a method that removes a vehicle from the index
+func (ix uniqueIndex) remove(v internal.Vehicle) {
+	for i, f := range uniqueFields {
+		key := f.key(v)
+		ids := ix[i][key]
+		j := sort.SearchInts(ids, v.Id)
+		if j == len(ids) || ids[j] != v.Id {
+			continue
+		}
+		if len(ids) == 1 {
+			delete(ix[i], key)
+			continue
+		}
+		ix[i][key] = append(ids[:j:j], ids[j+1:]...)
+	}
+}
+
+// lookup is a method that returns the stored vehicle with the given id, the caller must hold mu
+func (r *VehicleMap) lookup(id int) (v internal.Vehicle, ok bool) {
+	v, ok = r.db[id]
+	return
+}
+
+// checkUnique is a method that returns an error matched by the kind of a unique field when one of the
+// vehicles to store takes a value held by a stored vehicle or by a vehicle before it in the list.
+// The caller must hold mu.
+func (r *VehicleMap) checkUnique(vehicles ...internal.Vehicle) error {
+	staged := make(map[int]internal.Vehicle)
+	lookup := func(id int) (internal.Vehicle, bool) {
+		if v, ok := staged[id]; ok {
+			return v, true
+		}
+		return r.lookup(id)
+	}
+	keys := make(uniqueIndex, len(uniqueFields))
+	for i := range keys {
+		keys[i] = make(map[string][]int)
+	}
+	for _, v := range vehicles {
+		if err := r.unique.holder(v, lookup, keys); err != nil {
+			return err
+		}
+		staged[v.Id] = v
+		for i, f := range uniqueFields {
+			if key := f.key(v); key != "" {
+				keys[i][key] = append(keys[i][key], v.Id)
+			}
+		}
+	}
+	return nil
+}
+
+// checkUniqueLocked is a method that checks the unique fields of the vehicles to store under the read lock
+func (r *VehicleMap) checkUniqueLocked(vehicles ...internal.Vehicle) error {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	return r.checkUnique(vehicles...)
+}
+
+// holder is a method that returns the error for the first unique field of v whose value is held by another vehicle,
+// nil when none is.
+// - lookup returns the current state of a vehicle, the ids of ix and of staged may hold the values
+// - a vehicle keeping the value it already has is not a clash, so the vehicles of a fleet loaded
+// with shared values can still be updated
+func (ix uniqueIndex) holder(v internal.Vehicle, lookup func(id int) (internal.Vehicle, bool), staged uniqueIndex) error {
+	cur, exists := lookup(v.Id)
+	for i, f := range uniqueFields {
+		key := f.key(v)
+		if key == "" || (exists && f.key(cur) == key) {
+			continue
+		}
+		for _, ids := range [][]int{ix[i][key], staged[i][key]} {
+			for _, id := range ids {
+				if id == v.Id {
+					continue
+				}
+				if other, ok := lookup(id); ok && f.key(other) == key {
+					return vehicleError(f.kind, "%s: %v, already belongs to vehicle with ID: %v", f.name, f.value(v), id)
+				}
+			}
+		}
+	}
+	return nil
+}
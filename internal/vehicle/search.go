@@ -0,0 +1,37 @@
+package vehicle
+
+import "app/internal"
+
+// NewVehicleSearchDefault is a function that returns a new instance of VehicleSearchDefault
+func NewVehicleSearchDefault(sv internal.VehicleService, ix internal.VehicleIndex) *VehicleSearchDefault {
+	return &VehicleSearchDefault{sv: sv, ix: ix}
+}
+
+// VehicleSearchDefault is a struct that implements the VehicleSearchService interface
+// on top of a full-text index, reading the vehicles it finds from the vehicle service
+type VehicleSearchDefault struct {
+	// sv is the vehicle service
+	sv internal.VehicleService
+	// ix is the full-text index of the vehicles
+	ix internal.VehicleIndex
+}
+
+// Search is a method that returns up to limit vehicles matching the terms of the query, the most relevant first
+// - a query without any term the index can read, e.g. "!!!", is invalid instead of matching nothing
+// - a vehicle deleted since the index returned it is skipped
+func (s *VehicleSearchDefault) Search(query string, limit int) (results []internal.VehicleSearchResult, err error) {
+	if len(s.ix.Terms(query)) == 0 {
+		return nil, internal.ErrVehicleSearchInvalid
+	}
+
+	hits := s.ix.Search(query, limit)
+	results = make([]internal.VehicleSearchResult, 0, len(hits))
+	for _, hit := range hits {
+		vehicles, err := s.sv.FindById(hit.Id)
+		if err != nil || len(vehicles) == 0 {
+			continue
+		}
+		results = append(results, internal.VehicleSearchResult{Vehicle: vehicles[0], Score: hit.Score, Fields: hit.Fields})
+	}
+	return
+}
@@ -0,0 +1,36 @@
+package vehicle
+
+import (
+	"app/internal"
+	"app/internal/search"
+	"errors"
+	"testing"
+)
+
+func TestVehicleSearchDefault_Search(t *testing.T) {
+	db := map[int]internal.Vehicle{1: car(1, "AAA1111")}
+	sv := NewVehicleSearchDefault(NewVehicleDefault(NewVehicleMap(db), nil), search.NewVehicleIndex(db))
+	cases := []struct {
+		query string
+		err   error
+		found int
+	}{
+		{"", internal.ErrVehicleSearchInvalid, 0},
+		{"   ", internal.ErrVehicleSearchInvalid, 0},
+		{"!!!", internal.ErrVehicleSearchInvalid, 0},
+		{"- / ?", internal.ErrVehicleSearchInvalid, 0},
+		{"toyota!", nil, 1},
+	}
+
+	for _, c := range cases {
+		t.Run(c.query, func(t *testing.T) {
+			results, err := sv.Search(c.query, 10)
+			if c.err == nil && err != nil || c.err != nil && !errors.Is(err, c.err) {
+				t.Fatalf("error = %v, want %v", err, c.err)
+			}
+			if len(results) != c.found {
+				t.Errorf("results = %+v, want %d", results, c.found)
+			}
+		})
+	}
+}
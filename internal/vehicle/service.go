@@ -5,14 +5,17 @@ import (
 )
 
 // NewVehicleDefault is a function that returns a new instance of VehicleDefault
-func NewVehicleDefault(rp internal.VehicleRepository) *VehicleDefault {
-	return &VehicleDefault{rp: rp}
+// - pb may be nil when no one listens to the vehicle changes
+func NewVehicleDefault(rp internal.VehicleRepository, pb internal.VehiclePublisher) *VehicleDefault {
+	return &VehicleDefault{rp: rp, pb: pb}
 }
 
 // VehicleDefault is a struct that represents the default service for vehicles
 type VehicleDefault struct {
 	// rp is the repository that will be used by the service
 	rp internal.VehicleRepository
+	// pb is the publisher notified after each successful mutation
+	pb internal.VehiclePublisher
 }
 
 // FindAll is a method that returns a map of all vehicles
@@ -22,7 +25,11 @@ func (s *VehicleDefault) FindAll() (v map[int]internal.Vehicle, err error) {
 }
 
 func (s *VehicleDefault) Create(v internal.Vehicle) error {
-	return s.rp.Create(v)
+	if err := s.rp.Create(v); err != nil {
+		return err
+	}
+	s.publish(internal.VehicleCreated, v)
+	return nil
 }
 
 func (s *VehicleDefault) FindByColorAndYear(color string, year int) ([]internal.Vehicle, error) {
@@ -31,18 +38,31 @@ func (s *VehicleDefault) FindByColorAndYear(color string, year int) ([]internal.
 }
 
 func (s *VehicleDefault) Delete(id int) error {
-
-	return s.rp.Delete(id)
+	// the vehicle is published as the repository removed it
+	v, err := s.rp.Delete(id)
+	if err != nil {
+		return err
+	}
+	s.publish(internal.VehicleDeleted, v)
+	return nil
 }
 
 func (s *VehicleDefault) UpdateSpeed(id int, speed float64) error {
-
-	return s.rp.UpdateSpeed(id, speed)
+	v, err := s.rp.UpdateSpeed(id, speed)
+	if err != nil {
+		return err
+	}
+	s.publish(internal.VehicleUpdated, v)
+	return nil
 }
 
 func (s *VehicleDefault) UpdateFuelType(id int, fuelType string) error {
-
-	return s.rp.UpdateFuelType(id, fuelType)
+	v, err := s.rp.UpdateFuelType(id, fuelType)
+	if err != nil {
+		return err
+	}
+	s.publish(internal.VehicleUpdated, v)
+	return nil
 }
 
 func (s *VehicleDefault) FindByFuelType(fuelType string) ([]internal.Vehicle, error) {
@@ -56,8 +76,13 @@ func (s *VehicleDefault) FindByTransmissionType(transmission string) ([]internal
 }
 
 func (s *VehicleDefault) CreateBatch(vehicles []internal.Vehicle) error {
-
-	return s.rp.CreateBatch(vehicles)
+	if err := s.rp.CreateBatch(vehicles); err != nil {
+		return err
+	}
+	for _, v := range vehicles {
+		s.publish(internal.VehicleCreated, v)
+	}
+	return nil
 }
 
 func (s *VehicleDefault) FindByBrandAndBetweenYear(brand string, start, end int) ([]internal.Vehicle, error) {
@@ -76,7 +101,7 @@ func (s *VehicleDefault) FindByBrandAverageSpeed(brand string) (float64, error)
 	return s.rp.FindByBrandAverageSpeed(brand)
 }
 
-func (s *VehicleDefault) FindByBrandAverageCapacity(brand string) (int, error) {
+func (s *VehicleDefault) FindByBrandAverageCapacity(brand string) (float64, error) {
 	return s.rp.FindByBrandAverageCapacity(brand)
 }
 
@@ -91,3 +116,87 @@ func (s *VehicleDefault) FindByWeight(min, max float64) ([]internal.Vehicle, err
 func (s *VehicleDefault) FindByColor(color string) ([]internal.Vehicle, error) {
 	return s.rp.FindByColor(color)
 }
+
+func (s *VehicleDefault) FindByFilter(f internal.VehicleFilter) ([]internal.Vehicle, error) {
+	return s.rp.FindByFilter(f)
+}
+
+func (s *VehicleDefault) FindByRegistration(registration string) ([]internal.Vehicle, error) {
+	return s.rp.FindByRegistration(registration)
+}
+
+func (s *VehicleDefault) FindByVIN(vin string) ([]internal.Vehicle, error) {
+	return s.rp.FindByVIN(vin)
+}
+
+func (s *VehicleDefault) ForEach(f internal.VehicleFilter, fn func(v internal.Vehicle) error) error {
+	return s.rp.ForEach(f, fn)
+}
+
+func (s *VehicleDefault) ApplyBatch(ops []internal.VehicleOperation, atomic bool) ([]internal.VehicleOperationResult, error) {
+	results, err := s.rp.ApplyBatch(ops, atomic)
+	for _, res := range results {
+		if !res.Applied {
+			continue
+		}
+		switch res.Type {
+		case internal.VehicleOperationCreate:
+			s.publish(internal.VehicleCreated, res.Vehicle)
+		case internal.VehicleOperationUpdate, internal.VehicleOperationPatch:
+			s.publish(internal.VehicleUpdated, res.Vehicle)
+		case internal.VehicleOperationDelete:
+			s.publish(internal.VehicleDeleted, res.Vehicle)
+		}
+	}
+	return results, err
+}
+
+// Replace is a method that swaps the whole fleet and publishes each change it made
+func (s *VehicleDefault) Replace(db map[int]internal.Vehicle) ([]internal.VehicleOperation, error) {
+	ops, err := s.rp.Replace(db)
+	if err != nil {
+		return nil, err
+	}
+	for _, op := range ops {
+		switch op.Type {
+		case internal.VehicleOperationCreate:
+			s.publish(internal.VehicleCreated, op.Vehicle)
+		case internal.VehicleOperationUpdate:
+			s.publish(internal.VehicleUpdated, op.Vehicle)
+		case internal.VehicleOperationDelete:
+			s.publish(internal.VehicleDeleted, op.Vehicle)
+		}
+	}
+	return ops, nil
+}
+
+// publish is a method that publishes a change if there is a publisher
+func (s *VehicleDefault) publish(t internal.VehicleChangeType, v internal.Vehicle) {
+	if s.pb == nil {
+		return
+	}
+	s.pb.Publish(internal.VehicleChange{Type: t, Vehicle: v})
+}
+
+// Stats is a method that computes aggregates of the vehicles in the repository
+func (s *VehicleDefault) Stats(q internal.VehicleStatsQuery) ([]internal.VehicleStatsGroup, error) {
+	return s.rp.Stats(q)
+}
+
+// Distribution is a method that computes the distribution of a numeric field of the vehicles in the repository
+func (s *VehicleDefault) Distribution(q internal.VehicleDistributionQuery) ([]internal.VehicleDistribution, error) {
+	return s.rp.Distribution(q)
+}
+
+// Composition is a method that computes the composition of the fleet over its fabrication years
+func (s *VehicleDefault) Composition(q internal.VehicleCompositionQuery) (internal.VehicleComposition, error) {
+	b := internal.NewVehicleCompositionBuilder(q)
+	err := s.rp.ForEach(q.Filter, func(v internal.Vehicle) error {
+		b.Add(v)
+		return nil
+	})
+	if err != nil {
+		return internal.VehicleComposition{}, err
+	}
+	return b.Build(), nil
+}
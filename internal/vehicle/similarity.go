@@ -0,0 +1,53 @@
+package vehicle
+
+import (
+	"app/internal"
+)
+
+// NewVehicleSimilarityDefault is a function that returns a new instance of VehicleSimilarityDefault
+// - w are the weights used when a query has none, nil for the default weights
+func NewVehicleSimilarityDefault(sv internal.VehicleService, ix internal.VehicleNeighborIndex, w internal.VehicleSimilarityWeights) *VehicleSimilarityDefault {
+	if w == nil {
+		w = internal.DefaultVehicleSimilarityWeights()
+	}
+	return &VehicleSimilarityDefault{sv: sv, ix: ix, w: w}
+}
+
+// VehicleSimilarityDefault is a struct that implements the VehicleSimilarityService interface
+// on top of a nearest neighbor index, reading the vehicles it finds from the vehicle service
+type VehicleSimilarityDefault struct {
+	// sv is the vehicle service
+	sv internal.VehicleService
+	// ix is the nearest neighbor index of the vehicles
+	ix internal.VehicleNeighborIndex
+	// w are the configured weights
+	w internal.VehicleSimilarityWeights
+}
+
+// Similar is a method that returns up to k vehicles closest to the vehicle with the given id, closest first
+// - the weights of w override the configured ones
+// - a vehicle deleted since the index returned it is skipped
+func (s *VehicleSimilarityDefault) Similar(id, k int, w internal.VehicleSimilarityWeights) (results []internal.VehicleSimilarResult, err error) {
+	w = s.w.With(w)
+	if err = w.Validate(); err != nil {
+		return
+	}
+	vehicles, err := s.sv.FindById(id)
+	if err != nil {
+		return
+	}
+	if len(vehicles) == 0 {
+		return nil, internal.ErrVehicleNotFound
+	}
+
+	neighbors := s.ix.Nearest(vehicles[0], k, w)
+	results = make([]internal.VehicleSimilarResult, 0, len(neighbors))
+	for _, n := range neighbors {
+		vehicles, err := s.sv.FindById(n.Id)
+		if err != nil || len(vehicles) == 0 {
+			continue
+		}
+		results = append(results, internal.VehicleSimilarResult{Vehicle: vehicles[0], Distance: n.Distance})
+	}
+	return
+}
@@ -0,0 +1,117 @@
+package vehicle
+
+import (
+	"app/internal"
+	"slices"
+	"strings"
+)
+
+// Stats is a method that computes the metrics of the query over the vehicles matching its filter, per group.
+// Only the values of the fields the metrics need are copied while the lock is held.
+func (r *VehicleMap) Stats(q internal.VehicleStatsQuery) ([]internal.VehicleStatsGroup, error) {
+	if err := q.Validate(); err != nil {
+		return nil, err
+	}
+
+	// fields
+	// - the values of each numeric field are gathered once, whatever the number of metrics over it
+	var fields []string
+	for _, m := range q.Metrics {
+		if m.Field != "" && !slices.Contains(fields, m.Field) {
+			fields = append(fields, m.Field)
+		}
+	}
+
+	type group struct {
+		key    []string
+		count  int
+		values map[string][]float64
+	}
+	groups := make(map[string]*group)
+	if len(q.GroupBy) == 0 {
+		groups[""] = &group{key: []string{}, values: make(map[string][]float64)}
+	}
+
+	// gather
+	r.mu.RLock()
+	for _, v := range r.db {
+		if !q.Filter.Match(v) {
+			continue
+		}
+		key := make([]string, len(q.GroupBy))
+		for i, field := range q.GroupBy {
+			key[i] = internal.VehicleCategoricalFields[field](v)
+		}
+		id := strings.Join(key, "\x00")
+		g, ok := groups[id]
+		if !ok {
+			g = &group{key: key, values: make(map[string][]float64)}
+			groups[id] = g
+		}
+		g.count++
+		for _, field := range fields {
+			g.values[field] = append(g.values[field], internal.VehicleNumericFields[field](v))
+		}
+	}
+	r.mu.RUnlock()
+
+	// compute
+	result := make([]internal.VehicleStatsGroup, 0, len(groups))
+	for _, g := range groups {
+		s := internal.VehicleStatsGroup{Key: g.key, Count: g.count, Values: make(map[string]float64, len(q.Metrics))}
+		for _, m := range q.Metrics {
+			if m.Func == "count" {
+				s.Values[m.String()] = float64(g.count)
+				continue
+			}
+			s.Values[m.String()] = m.Compute(g.values[m.Field])
+		}
+		result = append(result, s)
+	}
+	slices.SortFunc(result, func(a, b internal.VehicleStatsGroup) int { return slices.Compare(a.Key, b.Key) })
+	return result, nil
+}
+
+// Distribution is a method that computes the distribution of a numeric field over the vehicles matching
+// the filter of the query, per segment in segment order
+func (r *VehicleMap) Distribution(q internal.VehicleDistributionQuery) ([]internal.VehicleDistribution, error) {
+	if err := q.Validate(); err != nil {
+		return nil, err
+	}
+	value := internal.VehicleNumericFields[q.Field]
+	segment := func(v internal.Vehicle) string { return "" }
+	if q.SegmentBy != "" {
+		segment = internal.VehicleCategoricalFields[q.SegmentBy]
+	}
+
+	// gather
+	var all []float64
+	segments := make(map[string][]float64)
+	if q.SegmentBy == "" {
+		segments[""] = nil
+	}
+	r.mu.RLock()
+	for _, v := range r.db {
+		if !q.Filter.Match(v) {
+			continue
+		}
+		x := value(v)
+		all = append(all, x)
+		segments[segment(v)] = append(segments[segment(v)], x)
+	}
+	r.mu.RUnlock()
+
+	// compute
+	bounds, err := internal.HistogramBounds(q, all)
+	if err != nil {
+		return nil, err
+	}
+	result := make([]internal.VehicleDistribution, 0, len(segments))
+	for name, values := range segments {
+		d := internal.NewVehicleDistribution(values, bounds, q.Quantiles)
+		d.Segment = name
+		result = append(result, d)
+	}
+	slices.SortFunc(result, func(a, b internal.VehicleDistribution) int { return strings.Compare(a.Segment, b.Segment) })
+	return result, nil
+}
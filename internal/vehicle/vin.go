@@ -0,0 +1,126 @@
+package vehicle
+
+import (
+	"app/internal"
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// NewVehicleVINChecked is a function that returns a new instance of VehicleVINChecked
+func NewVehicleVINChecked(sv internal.VehicleService, dc internal.VehicleVINDecoder) *VehicleVINChecked {
+	return &VehicleVINChecked{VehicleService: sv, dc: dc}
+}
+
+// VehicleVINChecked is a struct that decorates a vehicle service to check the VIN of the vehicles it creates or updates.
+// The VIN is stored in its canonical form once its check digit is validated, and the brand and model year it decodes to
+// are cross-checked against the declared brand and fabrication year of the vehicles it creates.
+// The fleet replaced by a reload or a restore is stored as it is, and an update keeping the VIN a vehicle
+// already has is not checked.
+type VehicleVINChecked struct {
+	internal.VehicleService
+	// dc is the decoder of the VINs
+	dc internal.VehicleVINDecoder
+}
+
+// Create is a method that creates a vehicle whose VIN, if any, is valid and agrees with its brand and fabrication year
+func (s *VehicleVINChecked) Create(v internal.Vehicle) error {
+	if err := s.check(&v, true); err != nil {
+		return err
+	}
+	return s.VehicleService.Create(v)
+}
+
+// CreateBatch is a method that creates vehicles whose VINs are valid, none when one is not
+func (s *VehicleVINChecked) CreateBatch(vehicles []internal.Vehicle) error {
+	checked := make([]internal.Vehicle, len(vehicles))
+	for i, v := range vehicles {
+		if err := s.check(&v, true); err != nil {
+			return err
+		}
+		checked[i] = v
+	}
+	return s.VehicleService.CreateBatch(checked)
+}
+
+// ApplyBatch is a method that applies a batch whose creates and updates with an invalid VIN fail.
+// An atomic batch with such an operation is aborted, otherwise the other operations are applied.
+func (s *VehicleVINChecked) ApplyBatch(ops []internal.VehicleOperation, atomic bool) ([]internal.VehicleOperationResult, error) {
+	return internal.ApplyCheckedVehicleBatch(s.VehicleService, ops, atomic, func(_ int, op *internal.VehicleOperation) error {
+		switch op.Type {
+		case internal.VehicleOperationCreate:
+			return s.check(&op.Vehicle, true)
+		case internal.VehicleOperationUpdate:
+			if s.kept(op.Vehicle) {
+				op.Vehicle.VIN = internal.VehicleVINKey(op.Vehicle.VIN)
+				return nil
+			}
+			return s.check(&op.Vehicle, false)
+		}
+		return nil
+	})
+}
+
+// check is a method that validates the VIN of a vehicle and sets it in its canonical form.
+// - cross checks the decoded brand and model year against the declared ones when create is true
+func (s *VehicleVINChecked) check(v *internal.Vehicle, create bool) error {
+	if v.VIN == "" {
+		return nil
+	}
+	d, err := s.dc.Decode(v.VIN)
+	if err != nil {
+		return err
+	}
+	v.VIN = d.VIN
+	if !create {
+		return nil
+	}
+
+	// brand
+	if v.Brand != "" && len(d.Brands) > 0 {
+		match := brandKey(v.Brand) == brandKey(d.Manufacturer)
+		for _, b := range d.Brands {
+			match = match || brandKey(v.Brand) == brandKey(b)
+		}
+		if !match {
+			return fmt.Errorf("%w: %s decodes to the brand %s, declared %s", internal.ErrVehicleVINMismatch,
+				d.VIN, strings.Join(d.Brands, " or "), v.Brand)
+		}
+	}
+
+	// model year
+	// - the model year of a vehicle may be the year after its fabrication
+	if v.FabricationYear != 0 && len(d.ModelYears) > 0 {
+		match := false
+		years := make([]string, len(d.ModelYears))
+		for i, y := range d.ModelYears {
+			match = match || v.FabricationYear == y || v.FabricationYear == y-1
+			years[i] = strconv.Itoa(y)
+		}
+		if !match {
+			return fmt.Errorf("%w: %s decodes to the model year %s, declared fabrication year %d", internal.ErrVehicleVINMismatch,
+				d.VIN, strings.Join(years, " or "), v.FabricationYear)
+		}
+	}
+	return nil
+}
+
+// kept is a method that reports whether a vehicle is stored with the same VIN
+func (s *VehicleVINChecked) kept(v internal.Vehicle) bool {
+	vehicles, err := s.VehicleService.FindById(v.Id)
+	if err != nil || len(vehicles) == 0 {
+		return false
+	}
+	return internal.VehicleVINKey(vehicles[0].VIN) == internal.VehicleVINKey(v.VIN)
+}
+
+// brandKey is a function that returns the form a brand is compared in, its letters and digits in lower case
+func brandKey(brand string) string {
+	return strings.Map(func(r rune) rune {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			return unicode.ToLower(r)
+		}
+		return -1
+	}, brand)
+}
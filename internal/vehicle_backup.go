@@ -0,0 +1,48 @@
+package internal
+
+import (
+	"errors"
+	"time"
+)
+
+var (
+	// ErrBackupNotFound is returned when a backup does not exist
+	ErrBackupNotFound = errors.New("backup not found")
+	// ErrBackupCorrupt is returned when a backup does not match its checksum or can't be decoded
+	ErrBackupCorrupt = errors.New("backup corrupt")
+)
+
+// VehicleBackup is a struct that represents a stored copy of the whole fleet
+type VehicleBackup struct {
+	// Id identifies the backup, it sorts in creation order
+	Id string
+	// CreatedAt is the time the backup was taken
+	CreatedAt time.Time
+	// Vehicles is the number of vehicles in the backup
+	Vehicles int
+	// Size is the size of the compressed backup in bytes
+	Size int64
+	// Checksum is the SHA-256 of the compressed backup in hex
+	Checksum string
+}
+
+// VehicleBackupStore is an interface that represents the storage of backups
+type VehicleBackupStore interface {
+	// Save stores a backup of db
+	Save(db map[int]Vehicle) (b VehicleBackup, err error)
+	// Load verifies the checksum of a backup and returns its vehicles
+	Load(id string) (db map[int]Vehicle, b VehicleBackup, err error)
+	// List returns the backups in creation order
+	List() (b []VehicleBackup, err error)
+}
+
+// VehicleBackupService is an interface that represents the backup and restore of the fleet
+type VehicleBackupService interface {
+	// Backup stores a consistent copy of the fleet
+	Backup() (b VehicleBackup, err error)
+	// Restore replaces the fleet with the vehicles of a backup in one step
+	// and returns the changes it made
+	Restore(id string) (b VehicleBackup, ops []VehicleOperation, err error)
+	// List returns the backups in creation order
+	List() (b []VehicleBackup, err error)
+}
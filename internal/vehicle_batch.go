@@ -0,0 +1,100 @@
+package internal
+
+import "errors"
+
+var (
+	// ErrVehicleBatchAborted is returned when an atomic batch is not applied because an operation is invalid
+	ErrVehicleBatchAborted = errors.New("vehicle batch aborted")
+	// ErrVehicleOperationInvalid is matched by the errors for an operation of a batch that misses what it needs,
+	// e.g. a create or an update without a vehicle, or for a vehicle of a restored backup that can't be stored
+	ErrVehicleOperationInvalid = errors.New("vehicle operation invalid")
+)
+
+// VehicleOperationType is the kind of operation of a vehicle batch
+type VehicleOperationType string
+
+const (
+	// VehicleOperationCreate creates a vehicle
+	VehicleOperationCreate VehicleOperationType = "create"
+	// VehicleOperationUpdate replaces the attributes of an existing vehicle
+	VehicleOperationUpdate VehicleOperationType = "update"
+	// VehicleOperationDelete deletes a vehicle
+	VehicleOperationDelete VehicleOperationType = "delete"
+	// VehicleOperationPatch changes some attributes of an existing vehicle, read and written in the same step
+	VehicleOperationPatch VehicleOperationType = "patch"
+)
+
+// VehiclePatch is a struct that represents the attributes changed by a patch, nil ones are kept
+type VehiclePatch struct {
+	// MaxSpeed is the new max speed in km/h
+	MaxSpeed *float64
+	// FuelType is the new fuel type
+	FuelType *string
+}
+
+// Apply is a method that returns v with the attributes of the patch changed
+func (p VehiclePatch) Apply(v Vehicle) Vehicle {
+	if p.MaxSpeed != nil {
+		v.MaxSpeed = *p.MaxSpeed
+	}
+	if p.FuelType != nil {
+		v.FuelType = *p.FuelType
+	}
+	return v
+}
+
+// VehicleOperation is a struct that represents an operation of a vehicle batch
+type VehicleOperation struct {
+	// Type is the kind of operation
+	Type VehicleOperationType
+	// Vehicle is the vehicle to create or update, only its Id is used on delete and patch
+	Vehicle Vehicle
+	// Patch is the change of a patch, nil for the other operations
+	Patch *VehiclePatch
+}
+
+// VehicleOperationResult is a struct that represents the outcome of an operation of a batch
+type VehicleOperationResult struct {
+	// Type is the kind of operation
+	Type VehicleOperationType
+	// Applied reports whether the operation was applied
+	Applied bool
+	// Err is the reason the operation is invalid, nil when it is valid
+	// - a valid operation may still not be applied when an atomic batch is aborted
+	Err error
+	// Vehicle is the vehicle after a create, update or patch, or before a delete
+	Vehicle Vehicle
+}
+
+// ApplyCheckedVehicleBatch is a function that applies a batch through sv, failing the operations rejected by check
+// without sending them. An atomic batch with a rejected operation is aborted, otherwise the other operations are applied.
+// - check receives the position of the operation, it may change the operation it accepts, e.g. to store
+// a value in its canonical form
+func ApplyCheckedVehicleBatch(sv VehicleService, ops []VehicleOperation, atomic bool, check func(i int, op *VehicleOperation) error) ([]VehicleOperationResult, error) {
+	results := make([]VehicleOperationResult, len(ops))
+	valid := make([]VehicleOperation, 0, len(ops))
+	positions := make([]int, 0, len(ops))
+	for i, op := range ops {
+		if err := check(i, &op); err != nil {
+			results[i] = VehicleOperationResult{Type: op.Type, Vehicle: op.Vehicle, Err: err}
+			continue
+		}
+		valid = append(valid, op)
+		positions = append(positions, i)
+	}
+	if len(valid) == len(ops) {
+		return sv.ApplyBatch(valid, atomic)
+	}
+	if atomic {
+		for k, i := range positions {
+			results[i] = VehicleOperationResult{Type: valid[k].Type, Vehicle: valid[k].Vehicle}
+		}
+		return results, ErrVehicleBatchAborted
+	}
+
+	applied, err := sv.ApplyBatch(valid, false)
+	for k, res := range applied {
+		results[positions[k]] = res
+	}
+	return results, err
+}
@@ -0,0 +1,139 @@
+package internal
+
+import (
+	"sort"
+	"time"
+)
+
+// VehicleCompositionQuery is a struct that represents a fleet composition report to compute
+// over the vehicles matching a filter
+// - Decade buckets the fabrication years by decade instead of by year
+// - Reference is the date the ages of the vehicles are computed at
+type VehicleCompositionQuery struct {
+	Filter    VehicleFilter
+	Decade    bool
+	Reference time.Time
+}
+
+// VehicleCompositionPeriod is a struct that represents the vehicles made within [From, To] fabrication years
+// - AverageAge is 0 for a period without vehicles
+type VehicleCompositionPeriod struct {
+	From          int
+	To            int
+	Count         int
+	AverageAge    float64
+	FuelTypes     map[string]int
+	Transmissions map[string]int
+}
+
+// VehicleComposition is a struct that represents the composition of the fleet over its fabrication years
+// - Periods are in year order and contiguous, periods without vehicles included
+// - FuelTypes and Transmissions are the totals of the whole fleet
+type VehicleComposition struct {
+	Reference     time.Time
+	Decade        bool
+	Count         int
+	AverageAge    float64
+	FuelTypes     map[string]int
+	Transmissions map[string]int
+	Periods       []VehicleCompositionPeriod
+}
+
+// NewVehicleCompositionBuilder is a function that returns a new instance of VehicleCompositionBuilder
+func NewVehicleCompositionBuilder(q VehicleCompositionQuery) *VehicleCompositionBuilder {
+	if q.Reference.IsZero() {
+		q.Reference = time.Now()
+	}
+	return &VehicleCompositionBuilder{
+		q:         q,
+		periods:   make(map[int]*VehicleCompositionPeriod),
+		periodAge: make(map[int]float64),
+		c: VehicleComposition{
+			Reference:     q.Reference,
+			Decade:        q.Decade,
+			FuelTypes:     make(map[string]int),
+			Transmissions: make(map[string]int),
+		},
+	}
+}
+
+// VehicleCompositionBuilder is a struct that builds a composition report one vehicle at a time
+type VehicleCompositionBuilder struct {
+	// q is the query of the report
+	q VehicleCompositionQuery
+	// c is the report being built
+	c VehicleComposition
+	// periods are the periods with vehicles keyed by their first year
+	periods map[int]*VehicleCompositionPeriod
+	// age is the sum of the ages of the vehicles
+	age float64
+	// periodAge is the sum of the ages of the vehicles of each period keyed by its first year
+	periodAge map[int]float64
+}
+
+// Add is a method that accounts a vehicle in the report
+func (b *VehicleCompositionBuilder) Add(v Vehicle) {
+	from, to := v.FabricationYear, v.FabricationYear
+	if b.q.Decade {
+		from = v.FabricationYear - ((v.FabricationYear%10)+10)%10
+		to = from + 9
+	}
+	p, ok := b.periods[from]
+	if !ok {
+		p = &VehicleCompositionPeriod{From: from, To: to, FuelTypes: make(map[string]int), Transmissions: make(map[string]int)}
+		b.periods[from] = p
+	}
+
+	age := VehicleAge(v, b.q.Reference)
+	p.Count++
+	p.FuelTypes[v.FuelType]++
+	p.Transmissions[v.Transmission]++
+	b.periodAge[from] += age
+	b.c.Count++
+	b.c.FuelTypes[v.FuelType]++
+	b.c.Transmissions[v.Transmission]++
+	b.age += age
+}
+
+// Build is a method that returns the report
+func (b *VehicleCompositionBuilder) Build() VehicleComposition {
+	c := b.c
+	if c.Count > 0 {
+		c.AverageAge = b.age / float64(c.Count)
+	}
+
+	// periods
+	// - the gaps between the first and the last period are filled with empty periods
+	starts := make([]int, 0, len(b.periods))
+	for from := range b.periods {
+		starts = append(starts, from)
+	}
+	sort.Ints(starts)
+	step := 1
+	if b.q.Decade {
+		step = 10
+	}
+	c.Periods = make([]VehicleCompositionPeriod, 0, len(starts))
+	for i := 0; i < len(starts); i++ {
+		if i > 0 {
+			for from := starts[i-1] + step; from < starts[i]; from += step {
+				c.Periods = append(c.Periods, VehicleCompositionPeriod{
+					From: from, To: from + step - 1, FuelTypes: map[string]int{}, Transmissions: map[string]int{},
+				})
+			}
+		}
+		p := *b.periods[starts[i]]
+		p.AverageAge = b.periodAge[p.From] / float64(p.Count)
+		c.Periods = append(c.Periods, p)
+	}
+	return c
+}
+
+// VehicleAge is a function that returns the age in years of a vehicle at the reference date.
+// A vehicle is assumed to be made in the middle of its fabrication year.
+func VehicleAge(v Vehicle, reference time.Time) float64 {
+	start := time.Date(reference.Year(), time.January, 1, 0, 0, 0, 0, reference.Location())
+	end := start.AddDate(1, 0, 0)
+	year := float64(reference.Year()) + float64(reference.Sub(start))/float64(end.Sub(start))
+	return year - (float64(v.FabricationYear) + 0.5)
+}
@@ -0,0 +1,187 @@
+package internal
+
+import (
+	"fmt"
+	"math"
+	"slices"
+)
+
+// maxHistogramBuckets is the maximum number of buckets of a histogram
+const maxHistogramBuckets = 1000
+
+// DefaultQuantiles are the quantiles of a distribution when the query asks for none
+var DefaultQuantiles = []float64{0.05, 0.25, 0.5, 0.75, 0.95}
+
+// VehicleDistributionQuery is a struct that represents the distribution of a numeric field to compute
+// over the vehicles matching a filter
+// - SegmentBy is a categorical field, empty to compute a single distribution
+// - BucketWidth wins over Buckets, both zero means 10 buckets
+// - Quantiles are between 0 and 1, empty means DefaultQuantiles
+type VehicleDistributionQuery struct {
+	Filter      VehicleFilter
+	Field       string
+	SegmentBy   string
+	Buckets     int
+	BucketWidth float64
+	Quantiles   []float64
+}
+
+// Validate is a method that checks the fields of the query
+func (q VehicleDistributionQuery) Validate() error {
+	if _, ok := VehicleNumericFields[q.Field]; !ok {
+		return fmt.Errorf("%w: unknown numeric field %q", ErrVehicleStatsInvalid, q.Field)
+	}
+	if _, ok := VehicleCategoricalFields[q.SegmentBy]; q.SegmentBy != "" && !ok {
+		return fmt.Errorf("%w: unknown segment field %q", ErrVehicleStatsInvalid, q.SegmentBy)
+	}
+	if q.Buckets < 0 || q.Buckets > maxHistogramBuckets {
+		return fmt.Errorf("%w: buckets must be between 1 and %d", ErrVehicleStatsInvalid, maxHistogramBuckets)
+	}
+	if q.BucketWidth < 0 || math.IsNaN(q.BucketWidth) || math.IsInf(q.BucketWidth, 0) {
+		return fmt.Errorf("%w: bucket width must be positive", ErrVehicleStatsInvalid)
+	}
+	for _, p := range q.Quantiles {
+		if !(p >= 0 && p <= 1) {
+			return fmt.Errorf("%w: quantile %v is not between 0 and 1", ErrVehicleStatsInvalid, p)
+		}
+	}
+	return nil
+}
+
+// HistogramBucket is a struct that represents the values within [Min, Max), the last bucket includes Max
+type HistogramBucket struct {
+	Min   float64
+	Max   float64
+	Count int
+}
+
+// Quantile is a struct that represents the value below which a fraction P of the values fall
+type Quantile struct {
+	P     float64
+	Value float64
+}
+
+// VehicleDistribution is a struct that represents the distribution of a numeric field over a set of vehicles
+// - Stddev is the sample standard deviation, 0 for a single value
+// - every statistic is NaN and Histogram is empty when there are no values
+type VehicleDistribution struct {
+	Segment   string
+	Count     int
+	Mean      float64
+	Stddev    float64
+	Min       float64
+	Max       float64
+	Median    float64
+	Q1        float64
+	Q3        float64
+	IQR       float64
+	Quantiles []Quantile
+	Histogram []HistogramBucket
+}
+
+// HistogramBounds is a function that returns the empty buckets covering values of the query,
+// shared by every segment so their histograms can be compared
+func HistogramBounds(q VehicleDistributionQuery, values []float64) (buckets []HistogramBucket, err error) {
+	if len(values) == 0 {
+		return
+	}
+	lo, hi := slices.Min(values), slices.Max(values)
+
+	// width and number of buckets
+	width, n := q.BucketWidth, q.Buckets
+	if width > 0 {
+		lo = math.Floor(lo/width) * width
+		buckets := math.Floor((hi-lo)/width) + 1
+		if buckets > maxHistogramBuckets+1 {
+			return nil, fmt.Errorf("%w: bucket width gives more than %d buckets", ErrVehicleStatsInvalid, maxHistogramBuckets)
+		}
+		n = int(buckets)
+		if n > 1 && lo+float64(n-1)*width == hi {
+			// - the maximum closes the last bucket instead of opening a new one
+			n--
+		}
+	} else {
+		if n == 0 {
+			n = 10
+		}
+		width = (hi - lo) / float64(n)
+	}
+	if width == 0 {
+		return []HistogramBucket{{Min: lo, Max: hi}}, nil
+	}
+	if n > maxHistogramBuckets {
+		return nil, fmt.Errorf("%w: bucket width gives more than %d buckets", ErrVehicleStatsInvalid, maxHistogramBuckets)
+	}
+	buckets = make([]HistogramBucket, n)
+	for i := range buckets {
+		buckets[i] = HistogramBucket{Min: lo + float64(i)*width, Max: lo + float64(i+1)*width}
+	}
+	buckets[n-1].Max = max(buckets[n-1].Max, hi)
+	return
+}
+
+// NewVehicleDistribution is a function that returns the distribution of values over the histogram bounds
+func NewVehicleDistribution(values []float64, bounds []HistogramBucket, quantiles []float64) (d VehicleDistribution) {
+	if len(quantiles) == 0 {
+		quantiles = DefaultQuantiles
+	}
+	d.Count = len(values)
+	d.Quantiles = make([]Quantile, 0, len(quantiles))
+	d.Histogram = make([]HistogramBucket, 0, len(bounds))
+	if len(values) == 0 {
+		nan := math.NaN()
+		d.Mean, d.Stddev, d.Min, d.Max, d.Median, d.Q1, d.Q3, d.IQR = nan, nan, nan, nan, nan, nan, nan, nan
+		for _, p := range quantiles {
+			d.Quantiles = append(d.Quantiles, Quantile{P: p, Value: nan})
+		}
+		return
+	}
+
+	sorted := slices.Clone(values)
+	slices.Sort(sorted)
+
+	// descriptive
+	var sum float64
+	for _, v := range sorted {
+		sum += v
+	}
+	d.Mean = sum / float64(len(sorted))
+	if len(sorted) > 1 {
+		var squares float64
+		for _, v := range sorted {
+			squares += (v - d.Mean) * (v - d.Mean)
+		}
+		d.Stddev = math.Sqrt(squares / float64(len(sorted)-1))
+	}
+	d.Min, d.Max = sorted[0], sorted[len(sorted)-1]
+	d.Median = quantile(sorted, 0.5)
+	d.Q1, d.Q3 = quantile(sorted, 0.25), quantile(sorted, 0.75)
+	d.IQR = d.Q3 - d.Q1
+	for _, p := range quantiles {
+		d.Quantiles = append(d.Quantiles, Quantile{P: p, Value: quantile(sorted, p)})
+	}
+
+	// histogram
+	d.Histogram = append(d.Histogram, bounds...)
+	for _, v := range sorted {
+		i, _ := slices.BinarySearchFunc(d.Histogram, v, func(b HistogramBucket, v float64) int {
+			switch {
+			case v < b.Min:
+				return 1
+			case v >= b.Max:
+				return -1
+			}
+			return 0
+		})
+		d.Histogram[min(i, len(d.Histogram)-1)].Count++
+	}
+	return
+}
+
+// quantile is a function that returns the p quantile of sorted values, interpolated between the closest ranks
+func quantile(sorted []float64, p float64) float64 {
+	rank := p * float64(len(sorted)-1)
+	lo := int(math.Floor(rank))
+	hi := int(math.Ceil(rank))
+	return sorted[lo] + (sorted[hi]-sorted[lo])*(rank-float64(lo))
+}
@@ -0,0 +1,76 @@
+package internal
+
+import (
+	"errors"
+	"time"
+)
+
+var (
+	// ErrVehicleMergeInvalid is matched by the errors for a merge of a vehicle with itself
+	ErrVehicleMergeInvalid = errors.New("vehicle merge invalid")
+	// ErrVehicleDuplicateInvalid is matched by the errors for a similarity threshold out of range
+	ErrVehicleDuplicateInvalid = errors.New("vehicle duplicate invalid")
+)
+
+// VehicleDuplicateKind is the reason vehicles are flagged as duplicates
+type VehicleDuplicateKind string
+
+const (
+	// VehicleDuplicateRegistration flags the vehicles sharing a registration
+	VehicleDuplicateRegistration VehicleDuplicateKind = "registration"
+	// VehicleDuplicateNear flags two vehicles whose attributes are nearly the same
+	VehicleDuplicateNear VehicleDuplicateKind = "near"
+)
+
+// VehicleDuplicate is a struct that represents vehicles that are likely the same vehicle
+// - Ids are in order, every vehicle sharing the registration for a registration clash, a pair for a near duplicate
+// - Key is the shared registration of a registration clash
+// - Score is the similarity of a near duplicate, from 0 to 1, 1 for a registration clash
+// - Matched are the JSON names of the fields with the same value, for a near duplicate
+type VehicleDuplicate struct {
+	Kind    VehicleDuplicateKind
+	Ids     []int
+	Key     string
+	Score   float64
+	Matched []string
+}
+
+// VehicleDuplicateQuery is a struct that represents a duplicate detection over the vehicles matching a filter
+// - Threshold is the smallest similarity of a near duplicate, from 0 to 1 excluded, 0 for the default
+type VehicleDuplicateQuery struct {
+	Filter    VehicleFilter
+	Threshold float64
+}
+
+// VehicleMerge is a struct that represents the consolidation of a duplicate vehicle into the vehicle kept
+// - Merged is the duplicate as it was before it was deleted
+// - Filled are the JSON names of the fields of the kept vehicle filled from the duplicate
+type VehicleMerge struct {
+	Id       int
+	KeptId   int
+	MergedId int
+	Merged   Vehicle
+	Filled   []string
+	At       time.Time
+}
+
+// VehicleMergeRepository is an interface that represents the audit log of the merges of vehicles
+type VehicleMergeRepository interface {
+	// Save records a merge and sets its id
+	Save(m *VehicleMerge) error
+	// FindAll returns the merges in id order
+	FindAll() ([]VehicleMerge, error)
+	// FindByVehicle returns the merges that kept or merged a vehicle, in id order
+	FindByVehicle(id int) ([]VehicleMerge, error)
+}
+
+// VehicleDuplicateService is an interface that represents the detection and the merge of duplicate vehicles
+type VehicleDuplicateService interface {
+	// Duplicates returns the registration clashes, then the near duplicates from the most similar
+	Duplicates(q VehicleDuplicateQuery) ([]VehicleDuplicate, error)
+	// Merge consolidates a duplicate into the vehicle kept, whose empty fields are filled from the duplicate,
+	// deletes the duplicate and records the merge, in a single atomic batch
+	Merge(keptId, mergedId int) (VehicleMerge, Vehicle, error)
+	// Merges returns the recorded merges in id order, the ones involving a vehicle when id is not 0
+	Merges(id int) ([]VehicleMerge, error)
+}
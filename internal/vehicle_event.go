@@ -0,0 +1,63 @@
+package internal
+
+import "time"
+
+// VehicleEventType is the kind of change recorded by a VehicleEvent
+type VehicleEventType string
+
+const (
+	// VehicleRegistered is recorded when a vehicle is added to the fleet
+	VehicleRegistered VehicleEventType = "vehicle_registered"
+	// SpeedChanged is recorded when the max speed of a vehicle is updated
+	SpeedChanged VehicleEventType = "speed_changed"
+	// FuelTypeChanged is recorded when the fuel type of a vehicle is updated
+	FuelTypeChanged VehicleEventType = "fuel_type_changed"
+	// VehicleRemoved is recorded when a vehicle is deleted from the fleet
+	VehicleRemoved VehicleEventType = "vehicle_removed"
+	// AttributesChanged is recorded when all the attributes of a vehicle are replaced
+	AttributesChanged VehicleEventType = "attributes_changed"
+)
+
+// VehicleEvent is a struct that represents a domain event of the vehicle stream
+type VehicleEvent struct {
+	// Sequence is the position of the event in the stream, assigned by the store
+	Sequence uint64
+	// Type is the kind of change
+	Type VehicleEventType
+	// VehicleId is the id of the vehicle affected by the change
+	VehicleId int
+	// OccurredAt is the moment the change happened
+	OccurredAt time.Time
+	// Vehicle is the registered or replaced vehicle (VehicleRegistered and AttributesChanged only)
+	Vehicle Vehicle
+	// MaxSpeed is the new max speed (SpeedChanged only)
+	MaxSpeed float64
+	// FuelType is the new fuel type (FuelTypeChanged only)
+	FuelType string
+}
+
+// VehicleSnapshot is a struct that represents the state of the fleet at a given sequence
+type VehicleSnapshot struct {
+	// Sequence is the sequence of the last event included in the snapshot
+	Sequence uint64
+	// TakenAt is the moment the snapshot was taken
+	TakenAt time.Time
+	// Vehicles is the state of the fleet
+	Vehicles map[int]Vehicle
+}
+
+// VehicleEventStore is an interface that represents an append-only stream of vehicle events
+type VehicleEventStore interface {
+	// Append is a method that appends the events to the stream, assigning their sequence
+	Append(e ...VehicleEvent) (stored []VehicleEvent, err error)
+	// Load is a method that returns the events with a sequence greater than after
+	Load(after uint64) (e []VehicleEvent, err error)
+}
+
+// VehicleSnapshotStore is an interface that represents the storage of vehicle snapshots
+type VehicleSnapshotStore interface {
+	// Save is a method that stores a snapshot, replacing the previous one
+	Save(s VehicleSnapshot) (err error)
+	// Latest is a method that returns the last saved snapshot, ok is false if there is none
+	Latest() (s VehicleSnapshot, ok bool, err error)
+}
@@ -0,0 +1,53 @@
+package internal
+
+import "time"
+
+// VehicleChangeType is the kind of change published on the vehicle feed
+type VehicleChangeType string
+
+const (
+	// VehicleCreated is published when a vehicle is created
+	VehicleCreated VehicleChangeType = "created"
+	// VehicleUpdated is published when a vehicle is updated
+	VehicleUpdated VehicleChangeType = "updated"
+	// VehicleDeleted is published when a vehicle is deleted
+	VehicleDeleted VehicleChangeType = "deleted"
+)
+
+// VehicleChange is a struct that represents a change published on the vehicle feed
+type VehicleChange struct {
+	// Id is the position of the change in the feed, assigned on publish
+	Id uint64
+	// Type is the kind of change
+	Type VehicleChangeType
+	// OccurredAt is the moment the change was published
+	OccurredAt time.Time
+	// Vehicle is the state of the vehicle after the change (before it, for deletes)
+	Vehicle Vehicle
+}
+
+// VehicleSubscription is a struct that represents a subscription to the vehicle feed
+type VehicleSubscription struct {
+	// Backlog is the list of buffered changes after the requested id
+	Backlog []VehicleChange
+	// Missed reports that some changes after the requested id are no longer buffered
+	Missed bool
+	// Changes receives the live changes, it is closed when the subscriber falls behind
+	Changes <-chan VehicleChange
+	// Cancel releases the subscription
+	Cancel func()
+}
+
+// VehiclePublisher is an interface that represents the publisher of vehicle changes
+type VehiclePublisher interface {
+	// Publish is a method that publishes a change
+	Publish(c VehicleChange)
+}
+
+// VehicleFeed is an interface that represents a feed of vehicle changes
+type VehicleFeed interface {
+	VehiclePublisher
+	// Subscribe is a method that subscribes to the feed
+	// - when resume is true the changes buffered after lastId are returned as backlog
+	Subscribe(lastId uint64, resume bool) (s VehicleSubscription)
+}
@@ -0,0 +1,67 @@
+package internal
+
+import "strings"
+
+// VehicleFilter is a struct that represents a set of criteria over the vehicle attributes
+// - zero values are ignored, text criteria are compared case-insensitively
+// - ranges are inclusive
+type VehicleFilter struct {
+	Brand        string
+	Model        string
+	Color        string
+	FuelType     string
+	Transmission string
+	YearMin      int
+	YearMax      int
+	CapacityMin  int
+	CapacityMax  int
+	SpeedMin     float64
+	SpeedMax     float64
+	WeightMin    float64
+	WeightMax    float64
+	LengthMin    float64
+	LengthMax    float64
+	WidthMin     float64
+	WidthMax     float64
+}
+
+// Match is a method that reports whether the vehicle meets every criterion of the filter
+func (f VehicleFilter) Match(v Vehicle) bool {
+	switch {
+	case f.Brand != "" && !strings.EqualFold(v.Brand, f.Brand):
+		return false
+	case f.Model != "" && !strings.EqualFold(v.Model, f.Model):
+		return false
+	case f.Color != "" && !strings.EqualFold(v.Color, f.Color):
+		return false
+	case f.FuelType != "" && !strings.EqualFold(v.FuelType, f.FuelType):
+		return false
+	case f.Transmission != "" && !strings.EqualFold(v.Transmission, f.Transmission):
+		return false
+	case f.YearMin != 0 && v.FabricationYear < f.YearMin:
+		return false
+	case f.YearMax != 0 && v.FabricationYear > f.YearMax:
+		return false
+	case f.CapacityMin != 0 && v.Capacity < f.CapacityMin:
+		return false
+	case f.CapacityMax != 0 && v.Capacity > f.CapacityMax:
+		return false
+	case f.SpeedMin != 0 && v.MaxSpeed < f.SpeedMin:
+		return false
+	case f.SpeedMax != 0 && v.MaxSpeed > f.SpeedMax:
+		return false
+	case f.WeightMin != 0 && v.Weight < f.WeightMin:
+		return false
+	case f.WeightMax != 0 && v.Weight > f.WeightMax:
+		return false
+	case f.LengthMin != 0 && v.Length < f.LengthMin:
+		return false
+	case f.LengthMax != 0 && v.Length > f.LengthMax:
+		return false
+	case f.WidthMin != 0 && v.Width < f.WidthMin:
+		return false
+	case f.WidthMax != 0 && v.Width > f.WidthMax:
+		return false
+	}
+	return true
+}
@@ -0,0 +1,76 @@
+package internal
+
+// VehicleJSON is a struct that represents a vehicle in the JSON documents of the service: the API, the webhook
+// payloads, the vehicle files, the exports, the backups and the event and merge logs.
+// - the fields with a unit are written as a number in the stored unit of their quantity, or in the unit system
+// of the document when it has one, and may be read as a string with a unit, e.g. "2645 lb"
+// - the raw brand and color are the values the vehicle was received with before its normalization
+type VehicleJSON struct {
+	Id              int           `json:"id"`
+	Brand           string        `json:"brand"`
+	Model           string        `json:"model"`
+	Registration    string        `json:"registration"`
+	Color           string        `json:"color"`
+	FabricationYear int           `json:"year"`
+	Capacity        int           `json:"passengers"`
+	MaxSpeed        VehicleSpeed  `json:"max_speed"`
+	FuelType        string        `json:"fuel_type"`
+	Transmission    string        `json:"transmission"`
+	Weight          VehicleMass   `json:"weight"`
+	Height          VehicleLength `json:"height"`
+	Length          VehicleLength `json:"length"`
+	Width           VehicleLength `json:"width"`
+	VIN             string        `json:"vin,omitempty"`
+	BrandRaw        string        `json:"brand_raw,omitempty"`
+	ColorRaw        string        `json:"color_raw,omitempty"`
+}
+
+// NewVehicleJSON is a function that returns the JSON representation of a vehicle, its values unchanged
+func NewVehicleJSON(v Vehicle) VehicleJSON {
+	return VehicleJSON{
+		Id:              v.Id,
+		Brand:           v.Brand,
+		Model:           v.Model,
+		Registration:    v.Registration,
+		Color:           v.Color,
+		FabricationYear: v.FabricationYear,
+		Capacity:        v.Capacity,
+		MaxSpeed:        VehicleSpeed(v.MaxSpeed),
+		FuelType:        v.FuelType,
+		Transmission:    v.Transmission,
+		Weight:          VehicleMass(v.Weight),
+		Height:          VehicleLength(v.Height),
+		Length:          VehicleLength(v.Length),
+		Width:           VehicleLength(v.Width),
+		VIN:             v.VIN,
+		BrandRaw:        v.BrandRaw,
+		ColorRaw:        v.ColorRaw,
+	}
+}
+
+// Vehicle is a method that returns the vehicle represented, its values unchanged
+func (vh VehicleJSON) Vehicle() Vehicle {
+	return Vehicle{
+		Id: vh.Id,
+		VehicleAttributes: VehicleAttributes{
+			Brand:           vh.Brand,
+			Model:           vh.Model,
+			Registration:    vh.Registration,
+			Color:           vh.Color,
+			FabricationYear: vh.FabricationYear,
+			Capacity:        vh.Capacity,
+			MaxSpeed:        float64(vh.MaxSpeed),
+			FuelType:        vh.FuelType,
+			Transmission:    vh.Transmission,
+			Weight:          float64(vh.Weight),
+			Dimensions: Dimensions{
+				Height: float64(vh.Height),
+				Length: float64(vh.Length),
+				Width:  float64(vh.Width),
+			},
+			VIN:      vh.VIN,
+			BrandRaw: vh.BrandRaw,
+			ColorRaw: vh.ColorRaw,
+		},
+	}
+}
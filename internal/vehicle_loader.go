@@ -4,4 +4,11 @@ package internal
 type VehicleLoader interface {
 	// Load is a method that loads the vehicles
 	Load() (v map[int]Vehicle, err error)
-}
\ No newline at end of file
+}
+
+// VehicleDecoder is an interface that represents a row by row decoder of vehicles
+type VehicleDecoder interface {
+	// Next is a method that decodes the next vehicle and returns its line, io.EOF ends the stream.
+	// Other errors are row errors, decoding may go on after them.
+	Next() (v Vehicle, line int, err error)
+}
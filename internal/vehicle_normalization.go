@@ -0,0 +1,58 @@
+package internal
+
+import "errors"
+
+const (
+	// VehicleNormalizationBrand is the dictionary of the brands
+	VehicleNormalizationBrand = "brand"
+	// VehicleNormalizationColor is the dictionary of the colors
+	VehicleNormalizationColor = "color"
+)
+
+// VehicleNormalizationFields are the vehicle fields with a normalization dictionary
+var VehicleNormalizationFields = []string{VehicleNormalizationBrand, VehicleNormalizationColor}
+
+var (
+	// ErrVehicleNormalizationInvalid is matched by the errors for an unknown dictionary or an invalid entry
+	ErrVehicleNormalizationInvalid = errors.New("vehicle normalization invalid")
+	// ErrVehicleNormalizationConflict is matched by the errors for an alias that belongs to another canonical value
+	ErrVehicleNormalizationConflict = errors.New("vehicle normalization conflict")
+	// ErrVehicleNormalizationNotFound is matched by the errors for a canonical value without entry
+	ErrVehicleNormalizationNotFound = errors.New("vehicle normalization not found")
+)
+
+// VehicleNormalizationEntry is a struct that represents a canonical value of a dictionary and its aliases.
+// Values are compared regardless of case, diacritics and punctuation, so the case variants of the
+// canonical value and of its aliases need no entry of their own.
+type VehicleNormalizationEntry struct {
+	Canonical string
+	Aliases   []string
+}
+
+// VehicleNormalizer is an interface that represents the dictionaries mapping the brands and colors
+// of the vehicles to their canonical values
+type VehicleNormalizer interface {
+	// Normalize returns the vehicle with its brand and color replaced by their canonical values.
+	// A replaced value is kept as the raw value, a raw value that still maps to the canonical one is kept as is.
+	// Values without entry are left unchanged.
+	Normalize(v Vehicle) Vehicle
+	// Entries returns the entries of the dictionary of a field in canonical value order
+	Entries(field string) ([]VehicleNormalizationEntry, error)
+	// SetEntry creates an entry or replaces the entry with the same canonical value, spelling included
+	SetEntry(field string, e VehicleNormalizationEntry) error
+	// DeleteEntry deletes the entry of a canonical value
+	DeleteEntry(field, canonical string) error
+}
+
+// VehicleNormalizationService is an interface that represents the management of the normalization dictionaries
+type VehicleNormalizationService interface {
+	// Entries returns the entries of the dictionary of a field in canonical value order
+	Entries(field string) ([]VehicleNormalizationEntry, error)
+	// SetEntry creates an entry or replaces the entry with the same canonical value
+	SetEntry(field string, e VehicleNormalizationEntry) error
+	// DeleteEntry deletes the entry of a canonical value
+	DeleteEntry(field, canonical string) error
+	// Renormalize normalizes the stored vehicles again from their raw values with the current dictionaries
+	// and returns the number of vehicles changed
+	Renormalize() (int, error)
+}
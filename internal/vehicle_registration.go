@@ -0,0 +1,36 @@
+package internal
+
+import (
+	"errors"
+	"strings"
+	"unicode"
+)
+
+var (
+	// ErrVehicleRegistrationExists is matched by the errors for a registration already held by another vehicle
+	ErrVehicleRegistrationExists = errors.New("vehicle registration already exists")
+	// ErrVehicleRegistrationInvalid is matched by the errors for a registration that matches none of the configured formats
+	ErrVehicleRegistrationInvalid = errors.New("vehicle registration invalid")
+)
+
+// VehicleRegistrationKey is a function that returns the form a registration is indexed and compared in,
+// upper case without spaces, hyphens, dots or other separators, e.g. "abc-1d23" and "ABC 1D23" are both "ABC1D23"
+func VehicleRegistrationKey(registration string) string {
+	var b strings.Builder
+	for _, r := range registration {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			b.WriteRune(unicode.ToUpper(r))
+		}
+	}
+	return b.String()
+}
+
+// VehicleRegistrationValidator is an interface that represents the check of a registration against
+// the plate formats accepted by the fleet
+type VehicleRegistrationValidator interface {
+	// Validate returns an error matched by ErrVehicleRegistrationInvalid when the registration matches none of the formats.
+	// An empty registration is valid, a vehicle is not required to have one.
+	Validate(registration string) error
+	// Formats returns the names of the accepted formats
+	Formats() []string
+}
@@ -1,20 +1,56 @@
 package internal
 
+import "errors"
+
+var (
+	// ErrVehicleNotFound is matched by the errors for a vehicle id that is not in the fleet
+	ErrVehicleNotFound = errors.New("vehicle not found")
+	// ErrVehicleExists is matched by the errors for a vehicle id that is already in the fleet
+	ErrVehicleExists = errors.New("vehicle already exists")
+)
+
 type VehicleRepository interface {
 	FindAll() (v map[int]Vehicle, err error)
 	Create(v Vehicle) error
 	FindByColorAndYear(color string, year int) ([]Vehicle, error)
-	Delete(id int) error
-	UpdateSpeed(id int, speed float64) error
-	UpdateFuelType(id int, fuelType string) error
+	// Delete removes a vehicle and returns it as it was before the delete
+	Delete(id int) (Vehicle, error)
+	// UpdateSpeed and UpdateFuelType change an attribute of a vehicle and return the vehicle as they left it,
+	// read in the same step as the write so concurrent mutations cannot interleave
+	UpdateSpeed(id int, speed float64) (Vehicle, error)
+	UpdateFuelType(id int, fuelType string) (Vehicle, error)
 	FindByFuelType(fuelType string) ([]Vehicle, error)
 	FindByTransmissionType(transmission string) ([]Vehicle, error)
 	CreateBatch([]Vehicle) error
 	FindByBrandAndBetweenYear(brand string, start, end int) ([]Vehicle, error)
 	FindById(id int) ([]Vehicle, error)
 	FindByBrandAverageSpeed(brand string) (float64, error)
-	FindByBrandAverageCapacity(brand string) (int, error)
+	// FindByBrandAverageCapacity returns the average capacity of the vehicles of a brand, not rounded
+	FindByBrandAverageCapacity(brand string) (float64, error)
 	FindByDimensions(lengthMin, lengthMax, widthMin, widthMax float64) ([]Vehicle, error)
 	FindByWeight(min, max float64) ([]Vehicle, error)
 	FindByColor(color string) ([]Vehicle, error)
+	// FindByFilter returns the vehicles matching the filter, an empty list is not an error
+	FindByFilter(f VehicleFilter) ([]Vehicle, error)
+	// FindByRegistration returns the vehicles holding a registration, regardless of case and separators, in id order.
+	// An empty list is not an error, more than one vehicle is only returned for the shared registrations of a loaded fleet.
+	FindByRegistration(registration string) ([]Vehicle, error)
+	// FindByVIN returns the vehicles holding a VIN, regardless of case, spaces and hyphens, in id order.
+	// An empty list is not an error, more than one vehicle is only returned for the shared VINs of a loaded fleet.
+	FindByVIN(vin string) ([]Vehicle, error)
+	// ForEach calls fn for each vehicle matching the filter in id order, without copying the whole fleet.
+	// Vehicles deleted during the iteration are skipped, an error from fn stops it.
+	ForEach(f VehicleFilter, fn func(v Vehicle) error) error
+	// ApplyBatch applies the operations in order, isolated from other mutations.
+	// When atomic is true either every operation is applied or none is.
+	ApplyBatch(ops []VehicleOperation, atomic bool) ([]VehicleOperationResult, error)
+	// Replace swaps the whole fleet for db in one step and returns the changes it made in id order,
+	// a delete carries the removed vehicle
+	Replace(db map[int]Vehicle) ([]VehicleOperation, error)
+	// Stats computes the metrics of the query over the vehicles matching its filter, per group in key order.
+	// Without group fields it returns a single group, even when no vehicle matches.
+	Stats(q VehicleStatsQuery) ([]VehicleStatsGroup, error)
+	// Distribution computes the distribution of a numeric field over the vehicles matching the filter of the query,
+	// per segment in segment order. Every segment shares the same histogram buckets.
+	Distribution(q VehicleDistributionQuery) ([]VehicleDistribution, error)
 }
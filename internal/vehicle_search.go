@@ -0,0 +1,38 @@
+package internal
+
+import "errors"
+
+// ErrVehicleSearchInvalid is matched by the errors for a search without any term,
+// e.g. an empty query or one made only of punctuation
+var ErrVehicleSearchInvalid = errors.New("vehicle search invalid")
+
+// VehicleSearchHit is a struct that represents a vehicle matching a search
+// - Fields are the JSON names of the fields holding the matched terms
+type VehicleSearchHit struct {
+	Id     int
+	Score  float64
+	Fields []string
+}
+
+// VehicleIndex is an interface that represents a full-text index over the textual attributes of the vehicles.
+// It is kept up to date by publishing every vehicle change to it.
+type VehicleIndex interface {
+	VehiclePublisher
+	// Search returns up to limit vehicles matching the terms of the query, the most relevant first
+	Search(query string, limit int) []VehicleSearchHit
+	// Terms returns the terms the index reads in the query, none when it has no letters or digits
+	Terms(query string) []string
+}
+
+// VehicleSearchResult is a struct that represents a vehicle found by a search along with its relevance
+type VehicleSearchResult struct {
+	Vehicle Vehicle
+	Score   float64
+	Fields  []string
+}
+
+// VehicleSearchService is an interface that represents the full-text search of vehicles
+type VehicleSearchService interface {
+	// Search returns up to limit vehicles matching the terms of the query, the most relevant first
+	Search(query string, limit int) ([]VehicleSearchResult, error)
+}
@@ -13,8 +13,34 @@ type VehicleService interface {
 	FindByBrandAndBetweenYear(brand string, start, end int) ([]Vehicle, error)
 	FindById(id int) ([]Vehicle, error)
 	FindByBrandAverageSpeed(brand string) (float64, error)
-	FindByBrandAverageCapacity(brand string) (int, error)
+	FindByBrandAverageCapacity(brand string) (float64, error)
 	FindByDimensions(lengthMin, lengthMax, widthMin, widthMax float64) ([]Vehicle, error)
 	FindByWeight(min, max float64) ([]Vehicle, error)
 	FindByColor(color string) ([]Vehicle, error)
+	// FindByFilter returns the vehicles matching the filter, an empty list is not an error
+	FindByFilter(f VehicleFilter) ([]Vehicle, error)
+	// FindByRegistration returns the vehicles holding a registration, regardless of case and separators, in id order.
+	// An empty list is not an error, more than one vehicle is only returned for the shared registrations of a loaded fleet.
+	FindByRegistration(registration string) ([]Vehicle, error)
+	// FindByVIN returns the vehicles holding a VIN, regardless of case, spaces and hyphens, in id order.
+	// An empty list is not an error, more than one vehicle is only returned for the shared VINs of a loaded fleet.
+	FindByVIN(vin string) ([]Vehicle, error)
+	// ForEach calls fn for each vehicle matching the filter in id order, without copying the whole fleet.
+	// Vehicles deleted during the iteration are skipped, an error from fn stops it.
+	ForEach(f VehicleFilter, fn func(v Vehicle) error) error
+	// ApplyBatch applies the operations in order, isolated from other mutations.
+	// When atomic is true either every operation is applied or none is.
+	ApplyBatch(ops []VehicleOperation, atomic bool) ([]VehicleOperationResult, error)
+	// Replace swaps the whole fleet for db in one step and returns the changes it made in id order,
+	// a delete carries the removed vehicle
+	Replace(db map[int]Vehicle) ([]VehicleOperation, error)
+	// Stats computes the metrics of the query over the vehicles matching its filter, per group in key order.
+	// Without group fields it returns a single group, even when no vehicle matches.
+	Stats(q VehicleStatsQuery) ([]VehicleStatsGroup, error)
+	// Distribution computes the distribution of a numeric field over the vehicles matching the filter of the query,
+	// per segment in segment order. Every segment shares the same histogram buckets.
+	Distribution(q VehicleDistributionQuery) ([]VehicleDistribution, error)
+	// Composition computes the composition of the fleet matching the filter of the query per fabrication period,
+	// with the counts per fuel type and transmission and the average age at the reference date
+	Composition(q VehicleCompositionQuery) (VehicleComposition, error)
 }
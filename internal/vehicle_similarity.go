@@ -0,0 +1,124 @@
+package internal
+
+import (
+	"errors"
+	"fmt"
+	"maps"
+	"math"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// ErrVehicleSimilarityInvalid is matched by the errors for an unknown or negative similarity weight
+var ErrVehicleSimilarityInvalid = errors.New("vehicle similarity invalid")
+
+var (
+	// VehicleSimilarityNumericFields are the numeric fields, named as in JSON, compared by the similarity of vehicles.
+	// Their values are scaled to the range of the fleet, so a weight of 1 counts a gap from the smallest
+	// to the largest value as much as a categorical mismatch.
+	VehicleSimilarityNumericFields = []string{"max_speed", "passengers", "weight", "height", "length", "width", "year"}
+	// VehicleSimilarityCategoricalFields are the categorical fields, named as in JSON, compared by the similarity
+	// of vehicles. A mismatch adds the weight of the field.
+	VehicleSimilarityCategoricalFields = []string{"fuel_type", "transmission", "brand"}
+)
+
+// VehicleSimilarityWeights are the weights of the fields in the distance between two vehicles, keyed by field name.
+// A field without weight or with a zero weight is ignored.
+type VehicleSimilarityWeights map[string]float64
+
+// DefaultVehicleSimilarityWeights is a function that returns the default weights of the similarity of vehicles
+func DefaultVehicleSimilarityWeights() VehicleSimilarityWeights {
+	return VehicleSimilarityWeights{
+		"max_speed": 1, "passengers": 1, "weight": 1, "height": 0.5, "length": 0.5, "width": 0.5, "year": 1,
+		"fuel_type": 1, "transmission": 0.5, "brand": 0.5,
+	}
+}
+
+// ParseVehicleSimilarityWeights is a function that parses weights written as field:weight separated by commas,
+// e.g. max_speed:2,brand:0
+func ParseVehicleSimilarityWeights(s string) (w VehicleSimilarityWeights, err error) {
+	w = make(VehicleSimilarityWeights)
+	for _, pair := range strings.Split(s, ",") {
+		if strings.TrimSpace(pair) == "" {
+			continue
+		}
+		field, value, ok := strings.Cut(pair, ":")
+		if !ok {
+			return nil, fmt.Errorf("%w: %q, expected field:weight", ErrVehicleSimilarityInvalid, pair)
+		}
+		field = strings.TrimSpace(field)
+		if w[field], err = strconv.ParseFloat(strings.TrimSpace(value), 64); err != nil {
+			return nil, fmt.Errorf("%w: weight %q of %s is not a number", ErrVehicleSimilarityInvalid, value, field)
+		}
+		if err = validateWeight(field, w[field]); err != nil {
+			return nil, err
+		}
+	}
+	return
+}
+
+// With is a method that returns a copy of the weights overridden by the weights of o
+func (w VehicleSimilarityWeights) With(o VehicleSimilarityWeights) VehicleSimilarityWeights {
+	c := maps.Clone(w)
+	if c == nil {
+		c = make(VehicleSimilarityWeights, len(o))
+	}
+	maps.Copy(c, o)
+	return c
+}
+
+// Validate is a method that checks every weight is a known field with a finite non-negative weight,
+// and that at least one weight is positive
+func (w VehicleSimilarityWeights) Validate() error {
+	positive := false
+	for field, weight := range w {
+		if err := validateWeight(field, weight); err != nil {
+			return err
+		}
+		positive = positive || weight > 0
+	}
+	if !positive {
+		return fmt.Errorf("%w: every weight is zero", ErrVehicleSimilarityInvalid)
+	}
+	return nil
+}
+
+// validateWeight is a function that checks the field of a weight is known and its weight finite and non-negative
+func validateWeight(field string, weight float64) error {
+	if !slices.Contains(VehicleSimilarityNumericFields, field) && !slices.Contains(VehicleSimilarityCategoricalFields, field) {
+		return fmt.Errorf("%w: unknown field %q", ErrVehicleSimilarityInvalid, field)
+	}
+	if weight < 0 || math.IsNaN(weight) || math.IsInf(weight, 0) {
+		return fmt.Errorf("%w: weight of %s must be a non-negative number", ErrVehicleSimilarityInvalid, field)
+	}
+	return nil
+}
+
+// VehicleNeighbor is a struct that represents a vehicle close to another one
+// - Distance is the weighted distance between the vehicles, 0 for identical vehicles
+type VehicleNeighbor struct {
+	Id       int
+	Distance float64
+}
+
+// VehicleNeighborIndex is an interface that represents an index of the vehicles answering nearest neighbor queries.
+// It is kept up to date by publishing every vehicle change to it.
+type VehicleNeighborIndex interface {
+	VehiclePublisher
+	// Nearest returns up to k vehicles closest to v by the weighted distance, closest first, v itself excluded
+	Nearest(v Vehicle, k int, w VehicleSimilarityWeights) []VehicleNeighbor
+}
+
+// VehicleSimilarResult is a struct that represents a vehicle similar to another one along with its distance
+type VehicleSimilarResult struct {
+	Vehicle  Vehicle
+	Distance float64
+}
+
+// VehicleSimilarityService is an interface that represents the recommendation of vehicles similar to another one
+type VehicleSimilarityService interface {
+	// Similar returns up to k vehicles closest to the vehicle with the given id, closest first.
+	// The weights of w override the configured ones, a nil w uses them as they are.
+	Similar(id, k int, w VehicleSimilarityWeights) ([]VehicleSimilarResult, error)
+}
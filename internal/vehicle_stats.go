@@ -0,0 +1,150 @@
+package internal
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// ErrVehicleStatsInvalid is matched by the errors for a stats query with an unknown field or function
+var ErrVehicleStatsInvalid = errors.New("vehicle stats invalid")
+
+// VehicleNumericFields are the numeric fields of a vehicle, named as in JSON, that metrics aggregate
+var VehicleNumericFields = map[string]func(v Vehicle) float64{
+	"year":       func(v Vehicle) float64 { return float64(v.FabricationYear) },
+	"passengers": func(v Vehicle) float64 { return float64(v.Capacity) },
+	"max_speed":  func(v Vehicle) float64 { return v.MaxSpeed },
+	"weight":     func(v Vehicle) float64 { return v.Weight },
+	"height":     func(v Vehicle) float64 { return v.Height },
+	"length":     func(v Vehicle) float64 { return v.Length },
+	"width":      func(v Vehicle) float64 { return v.Width },
+}
+
+// VehicleCategoricalFields are the fields of a vehicle, named as in JSON, that vehicles are grouped by
+var VehicleCategoricalFields = map[string]func(v Vehicle) string{
+	"brand":        func(v Vehicle) string { return v.Brand },
+	"model":        func(v Vehicle) string { return v.Model },
+	"color":        func(v Vehicle) string { return v.Color },
+	"fuel_type":    func(v Vehicle) string { return v.FuelType },
+	"transmission": func(v Vehicle) string { return v.Transmission },
+	"year":         func(v Vehicle) string { return strconv.Itoa(v.FabricationYear) },
+	"passengers":   func(v Vehicle) string { return strconv.Itoa(v.Capacity) },
+}
+
+// VehicleMetric is a struct that represents an aggregate over a numeric field
+// - Func is count, sum, avg, min, max, median or a percentile p1 to p99
+// - Field is empty for count
+type VehicleMetric struct {
+	Func  string
+	Field string
+}
+
+// ParseVehicleMetric is a function that parses a metric written as func:field, or count
+func ParseVehicleMetric(s string) (m VehicleMetric, err error) {
+	fn, field, _ := strings.Cut(strings.TrimSpace(s), ":")
+	m = VehicleMetric{Func: strings.ToLower(fn), Field: field}
+	if m.Func == "count" {
+		if m.Field != "" {
+			return m, fmt.Errorf("%w: count takes no field", ErrVehicleStatsInvalid)
+		}
+		return
+	}
+	if _, ok := m.percentile(); !ok && !slices.Contains([]string{"sum", "avg", "min", "max", "median"}, m.Func) {
+		return m, fmt.Errorf("%w: unknown function %q", ErrVehicleStatsInvalid, fn)
+	}
+	if _, ok := VehicleNumericFields[m.Field]; !ok {
+		return m, fmt.Errorf("%w: unknown numeric field %q", ErrVehicleStatsInvalid, field)
+	}
+	return
+}
+
+// String is a method that returns the metric as written in a query, e.g. avg:max_speed
+func (m VehicleMetric) String() string {
+	if m.Field == "" {
+		return m.Func
+	}
+	return m.Func + ":" + m.Field
+}
+
+// percentile is a method that returns the percentile of a pNN or median metric
+func (m VehicleMetric) percentile() (p float64, ok bool) {
+	if m.Func == "median" {
+		return 50, true
+	}
+	if !strings.HasPrefix(m.Func, "p") {
+		return
+	}
+	n, err := strconv.Atoi(m.Func[1:])
+	if err != nil || n < 1 || n > 99 {
+		return
+	}
+	return float64(n), true
+}
+
+// Compute is a method that returns the metric over the values of its field, count returns their number.
+// It returns NaN for an empty set of values, except for count and sum.
+func (m VehicleMetric) Compute(values []float64) float64 {
+	if m.Func == "count" {
+		return float64(len(values))
+	}
+	if len(values) == 0 {
+		if m.Func == "sum" {
+			return 0
+		}
+		return math.NaN()
+	}
+	switch m.Func {
+	case "sum", "avg":
+		var sum float64
+		for _, v := range values {
+			sum += v
+		}
+		if m.Func == "avg" {
+			return sum / float64(len(values))
+		}
+		return sum
+	case "min":
+		return slices.Min(values)
+	case "max":
+		return slices.Max(values)
+	}
+
+	// percentiles
+	p, _ := m.percentile()
+	sorted := slices.Clone(values)
+	slices.Sort(sorted)
+	return quantile(sorted, p/100)
+}
+
+// VehicleStatsQuery is a struct that represents the aggregates to compute over the vehicles matching a filter
+// - without GroupBy every matching vehicle falls in a single group
+type VehicleStatsQuery struct {
+	Filter  VehicleFilter
+	GroupBy []string
+	Metrics []VehicleMetric
+}
+
+// Validate is a method that checks the fields of the query
+func (q VehicleStatsQuery) Validate() error {
+	for _, field := range q.GroupBy {
+		if _, ok := VehicleCategoricalFields[field]; !ok {
+			return fmt.Errorf("%w: unknown group field %q", ErrVehicleStatsInvalid, field)
+		}
+	}
+	if len(q.Metrics) == 0 {
+		return fmt.Errorf("%w: no metric", ErrVehicleStatsInvalid)
+	}
+	return nil
+}
+
+// VehicleStatsGroup is a struct that represents the aggregates of a group of vehicles
+// - Key holds the value of each group field, in the order of the query
+// - Values holds each metric keyed as written in the query, e.g. avg:max_speed
+type VehicleStatsGroup struct {
+	Key    []string
+	Count  int
+	Values map[string]float64
+}
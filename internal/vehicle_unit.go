@@ -0,0 +1,219 @@
+package internal
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// ErrVehicleUnitInvalid is matched by the errors for an unknown unit system, an unknown unit
+// or a unit of another quantity than the one of the field
+var ErrVehicleUnitInvalid = errors.New("vehicle unit invalid")
+
+// VehicleQuantity is the physical quantity measured by a numeric field of a vehicle
+type VehicleQuantity string
+
+const (
+	// VehicleQuantitySpeed is the quantity of the max speed, stored in km/h
+	VehicleQuantitySpeed VehicleQuantity = "speed"
+	// VehicleQuantityMass is the quantity of the weight, stored in kg
+	VehicleQuantityMass VehicleQuantity = "mass"
+	// VehicleQuantityLength is the quantity of the dimensions, stored in m
+	VehicleQuantityLength VehicleQuantity = "length"
+)
+
+// VehicleQuantityFields are the numeric fields of a vehicle with a unit, named as in JSON, and their quantity.
+// The other numeric fields, the year and the passengers, are counts without unit.
+var VehicleQuantityFields = map[string]VehicleQuantity{
+	"max_speed": VehicleQuantitySpeed,
+	"weight":    VehicleQuantityMass,
+	"height":    VehicleQuantityLength,
+	"length":    VehicleQuantityLength,
+	"width":     VehicleQuantityLength,
+}
+
+// VehicleUnit is a struct that represents a unit of a quantity
+// - Factor is the value of one unit in the stored unit of the quantity, e.g. 0.45359237 for a pound
+// - Aliases are the other symbols the unit is read from, in lower case
+type VehicleUnit struct {
+	Symbol   string
+	Aliases  []string
+	Quantity VehicleQuantity
+	Factor   float64
+}
+
+// VehicleUnits are the units values can be written in. The first unit of each quantity is the stored one,
+// the SI unit for mass and length and the km/h, accepted for use with the SI, for speed.
+var VehicleUnits = []VehicleUnit{
+	{Symbol: "km/h", Aliases: []string{"kmh", "kph", "kmph"}, Quantity: VehicleQuantitySpeed, Factor: 1},
+	{Symbol: "m/s", Aliases: []string{"mps"}, Quantity: VehicleQuantitySpeed, Factor: 3.6},
+	{Symbol: "mph", Aliases: []string{"mi/h"}, Quantity: VehicleQuantitySpeed, Factor: 1.609344},
+	{Symbol: "kn", Aliases: []string{"kt", "knot", "knots"}, Quantity: VehicleQuantitySpeed, Factor: 1.852},
+	{Symbol: "kg", Aliases: []string{"kgs", "kilogram", "kilograms"}, Quantity: VehicleQuantityMass, Factor: 1},
+	{Symbol: "g", Aliases: []string{"gram", "grams"}, Quantity: VehicleQuantityMass, Factor: 0.001},
+	{Symbol: "t", Aliases: []string{"tonne", "tonnes"}, Quantity: VehicleQuantityMass, Factor: 1000},
+	{Symbol: "lb", Aliases: []string{"lbs", "pound", "pounds"}, Quantity: VehicleQuantityMass, Factor: 0.45359237},
+	{Symbol: "m", Aliases: []string{"meter", "meters", "metre", "metres"}, Quantity: VehicleQuantityLength, Factor: 1},
+	{Symbol: "cm", Quantity: VehicleQuantityLength, Factor: 0.01},
+	{Symbol: "mm", Quantity: VehicleQuantityLength, Factor: 0.001},
+	{Symbol: "in", Aliases: []string{"inch", "inches", "\""}, Quantity: VehicleQuantityLength, Factor: 0.0254},
+	{Symbol: "ft", Aliases: []string{"foot", "feet", "'"}, Quantity: VehicleQuantityLength, Factor: 0.3048},
+	{Symbol: "yd", Aliases: []string{"yard", "yards"}, Quantity: VehicleQuantityLength, Factor: 0.9144},
+}
+
+// VehicleUnitSystem is a system of units values are written in
+type VehicleUnitSystem string
+
+const (
+	// VehicleUnitsMetric writes values in their stored units, km/h, kg and m, the default
+	VehicleUnitsMetric VehicleUnitSystem = "metric"
+	// VehicleUnitsImperial writes values in mph, lb and ft
+	VehicleUnitsImperial VehicleUnitSystem = "imperial"
+)
+
+// vehicleUnitSystems are the symbols of the unit of each quantity by system
+var vehicleUnitSystems = map[VehicleUnitSystem]map[VehicleQuantity]string{
+	VehicleUnitsMetric:   {VehicleQuantitySpeed: "km/h", VehicleQuantityMass: "kg", VehicleQuantityLength: "m"},
+	VehicleUnitsImperial: {VehicleQuantitySpeed: "mph", VehicleQuantityMass: "lb", VehicleQuantityLength: "ft"},
+}
+
+// ParseVehicleUnitSystem is a function that parses the name of a unit system regardless of case, empty is metric
+func ParseVehicleUnitSystem(s string) (VehicleUnitSystem, error) {
+	name := VehicleUnitSystem(strings.ToLower(strings.TrimSpace(s)))
+	if name == "" {
+		return VehicleUnitsMetric, nil
+	}
+	if _, ok := vehicleUnitSystems[name]; !ok {
+		return "", fmt.Errorf("%w: unknown unit system %q, expected %s or %s", ErrVehicleUnitInvalid, s, VehicleUnitsMetric, VehicleUnitsImperial)
+	}
+	return name, nil
+}
+
+// Unit is a method that returns the unit a quantity is written in by the system
+func (s VehicleUnitSystem) Unit(q VehicleQuantity) VehicleUnit {
+	u, _ := lookupVehicleUnit(vehicleUnitSystems[s][q])
+	return u
+}
+
+// Convert is a method that converts a value of a quantity from its stored unit to the unit of the system,
+// rounded to 6 decimals to hide the floating point noise of the conversions, e.g. 2645 lb stored as
+// 1199.7518186500001 kg is written back as 2645 lb
+func (s VehicleUnitSystem) Convert(value float64, q VehicleQuantity) float64 {
+	if math.IsNaN(value) || math.IsInf(value, 0) {
+		return value
+	}
+	return math.Round(value/s.Unit(q).Factor*1e6) / 1e6
+}
+
+// Stored is a method that converts a value of a quantity from the unit of the system to its stored unit,
+// the inverse of Convert
+func (s VehicleUnitSystem) Stored(value float64, q VehicleQuantity) float64 {
+	return value * s.Unit(q).Factor
+}
+
+// Vehicle is a method that returns a copy of a vehicle with the values of its fields with a unit in the units of the system
+func (s VehicleUnitSystem) Vehicle(v Vehicle) Vehicle {
+	v.MaxSpeed = s.Convert(v.MaxSpeed, VehicleQuantitySpeed)
+	v.Weight = s.Convert(v.Weight, VehicleQuantityMass)
+	v.Height = s.Convert(v.Height, VehicleQuantityLength)
+	v.Length = s.Convert(v.Length, VehicleQuantityLength)
+	v.Width = s.Convert(v.Width, VehicleQuantityLength)
+	return v
+}
+
+// ParseVehicleQuantity is a function that parses a value of a quantity written as a number followed by an
+// optional unit, e.g. "1200 kg", "120mph" or "4.5", and returns it in the stored unit of the quantity.
+// A number without unit is already in the stored unit.
+func ParseVehicleQuantity(s string, q VehicleQuantity) (float64, error) {
+	return VehicleUnitsMetric.Parse(s, q)
+}
+
+// Parse is a method that parses a value of a quantity written as a number followed by an optional unit,
+// and returns it in the stored unit of the quantity. A number without unit is in the unit of the system,
+// e.g. "62" is 62 mph in imperial, and "100 km/h" is 100 km/h in any system.
+func (sys VehicleUnitSystem) Parse(s string, q VehicleQuantity) (float64, error) {
+	s = strings.TrimSpace(s)
+	i := strings.LastIndexFunc(s, func(r rune) bool { return unicode.IsDigit(r) || r == '.' }) + 1
+	number, symbol := strings.TrimSpace(s[:i]), strings.TrimSpace(s[i:])
+	value, err := strconv.ParseFloat(number, 64)
+	if err != nil {
+		return 0, fmt.Errorf("%w: %q is not a number with an optional unit", ErrVehicleUnitInvalid, s)
+	}
+	if symbol == "" {
+		return sys.Stored(value, q), nil
+	}
+	u, ok := lookupVehicleUnit(symbol)
+	if !ok {
+		return 0, fmt.Errorf("%w: unknown unit %q", ErrVehicleUnitInvalid, symbol)
+	}
+	if u.Quantity != q {
+		return 0, fmt.Errorf("%w: %s is a unit of %s, expected a unit of %s", ErrVehicleUnitInvalid, u.Symbol, u.Quantity, q)
+	}
+	return value * u.Factor, nil
+}
+
+// lookupVehicleUnit is a function that returns the unit with a symbol or an alias, regardless of case
+func lookupVehicleUnit(symbol string) (VehicleUnit, bool) {
+	symbol = strings.ToLower(symbol)
+	for _, u := range VehicleUnits {
+		if strings.ToLower(u.Symbol) == symbol {
+			return u, true
+		}
+		for _, alias := range u.Aliases {
+			if alias == symbol {
+				return u, true
+			}
+		}
+	}
+	return VehicleUnit{}, false
+}
+
+// VehicleSpeed, VehicleMass and VehicleLength are the values of the fields with a unit in the JSON documents
+// of the service. They are read either as a number in the stored unit of their quantity or as a string with
+// a unit, e.g. "120 mph", and always written as a number.
+type (
+	VehicleSpeed  float64
+	VehicleMass   float64
+	VehicleLength float64
+)
+
+// UnmarshalJSON is a method that reads a speed, see ParseVehicleQuantity
+func (v *VehicleSpeed) UnmarshalJSON(data []byte) error {
+	return unmarshalVehicleQuantity(data, VehicleQuantitySpeed, (*float64)(v))
+}
+
+// UnmarshalJSON is a method that reads a mass, see ParseVehicleQuantity
+func (v *VehicleMass) UnmarshalJSON(data []byte) error {
+	return unmarshalVehicleQuantity(data, VehicleQuantityMass, (*float64)(v))
+}
+
+// UnmarshalJSON is a method that reads a length, see ParseVehicleQuantity
+func (v *VehicleLength) UnmarshalJSON(data []byte) error {
+	return unmarshalVehicleQuantity(data, VehicleQuantityLength, (*float64)(v))
+}
+
+// unmarshalVehicleQuantity is a function that reads a JSON number, or a JSON string with a unit, of a quantity into dst.
+// null leaves dst unchanged.
+func unmarshalVehicleQuantity(data []byte, q VehicleQuantity, dst *float64) (err error) {
+	switch {
+	case string(data) == "null":
+		return nil
+	case len(data) > 0 && data[0] == '"':
+		var s string
+		if err = json.Unmarshal(data, &s); err != nil {
+			return
+		}
+		var f float64
+		if f, err = ParseVehicleQuantity(s, q); err != nil {
+			return
+		}
+		*dst = f
+		return nil
+	default:
+		return json.Unmarshal(data, dst)
+	}
+}
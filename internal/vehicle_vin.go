@@ -0,0 +1,45 @@
+package internal
+
+import (
+	"errors"
+	"strings"
+)
+
+var (
+	// ErrVehicleVINInvalid is matched by the errors for a VIN with an invalid length, character or check digit
+	ErrVehicleVINInvalid = errors.New("vehicle vin invalid")
+	// ErrVehicleVINExists is matched by the errors for a VIN already held by another vehicle
+	ErrVehicleVINExists = errors.New("vehicle vin already exists")
+	// ErrVehicleVINMismatch is matched by the errors for a VIN whose decoded brand or model year
+	// disagrees with the declared brand or fabrication year
+	ErrVehicleVINMismatch = errors.New("vehicle vin mismatch")
+)
+
+// VehicleVINKey is a function that returns the form a VIN is stored, indexed and compared in,
+// upper case without spaces or hyphens
+func VehicleVINKey(vin string) string {
+	return strings.ToUpper(strings.NewReplacer(" ", "", "-", "").Replace(vin))
+}
+
+// VehicleVIN is a struct that represents the data decoded from a VIN
+// - WMI is the world manufacturer identifier, the first 3 characters
+// - Manufacturer and Brands are empty when the WMI is not in the table of the decoder
+// - Country and Region come from the first 2 characters, empty when they are not assigned
+// - ModelYears are the model years the 10th character may stand for, as it repeats every 30 years,
+// the most likely first
+type VehicleVIN struct {
+	VIN          string
+	WMI          string
+	Manufacturer string
+	Brands       []string
+	Country      string
+	Region       string
+	ModelYears   []int
+}
+
+// VehicleVINDecoder is an interface that represents the offline decoding of VINs
+type VehicleVINDecoder interface {
+	// Decode checks the length, the characters and the check digit of a VIN and decodes it.
+	// The errors are matched by ErrVehicleVINInvalid.
+	Decode(vin string) (VehicleVIN, error)
+}
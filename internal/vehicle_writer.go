@@ -0,0 +1,9 @@
+package internal
+
+// VehicleWriter is an interface that represents a streaming encoder of vehicles
+type VehicleWriter interface {
+	// Write is a method that encodes a vehicle
+	Write(v Vehicle) (err error)
+	// Close is a method that writes the trailer of the format and flushes the output
+	Close() (err error)
+}
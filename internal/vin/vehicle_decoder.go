@@ -0,0 +1,199 @@
+package vin
+
+import (
+	"app/internal"
+	_ "embed"
+	"encoding/csv"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// wmiTable is the table of the world manufacturer identifiers, one per row as wmi,manufacturer,brands
+// with the brands separated by |
+//
+//go:embed wmi.csv
+var wmiTable string
+
+// alphabet are the characters of a VIN, the letters I, O and Q are left out as they read like 1 and 0
+const alphabet = "0123456789ABCDEFGHJKLMNPRSTUVWXYZ"
+
+// values are the values of the characters in the check digit, per ISO 3779 and 49 CFR 565
+var values = map[byte]int{
+	'A': 1, 'B': 2, 'C': 3, 'D': 4, 'E': 5, 'F': 6, 'G': 7, 'H': 8,
+	'J': 1, 'K': 2, 'L': 3, 'M': 4, 'N': 5, 'P': 7, 'R': 9,
+	'S': 2, 'T': 3, 'U': 4, 'V': 5, 'W': 6, 'X': 7, 'Y': 8, 'Z': 9,
+}
+
+// weights are the weights of the positions in the check digit, the 9th position is the check digit itself
+var weights = [17]int{8, 7, 6, 5, 4, 3, 2, 10, 0, 9, 8, 7, 6, 5, 4, 3, 2}
+
+// years are the codes of the 10th character, the model year 1980 plus their index, repeating every 30 years
+const years = "ABCDEFGHJKLMNPRSTVWXY123456789"
+
+// sequence is the order of the characters in the ranges assigned to the countries, letters, digits and then 0
+const sequence = "ABCDEFGHJKLMNPRSTUVWXYZ1234567890"
+
+// country is a struct that represents the range of the first 2 characters assigned to a country
+// - from and to are the bounds of the 2nd character in sequence order
+type country struct {
+	first    byte
+	from, to byte
+	name     string
+}
+
+// countries are the ranges of the first 2 characters assigned to the countries with a car industry
+var countries = []country{
+	{'1', 'A', '0', "United States"},
+	{'4', 'A', '0', "United States"},
+	{'5', 'A', '0', "United States"},
+	{'2', 'A', '0', "Canada"},
+	{'3', 'A', 'W', "Mexico"},
+	{'6', 'A', 'W', "Australia"},
+	{'7', 'A', 'E', "New Zealand"},
+	{'8', 'A', 'E', "Argentina"},
+	{'9', 'A', 'E', "Brazil"},
+	{'9', '3', '9', "Brazil"},
+	{'J', 'A', '0', "Japan"},
+	{'K', 'L', 'R', "South Korea"},
+	{'L', 'A', '0', "China"},
+	{'M', 'A', 'E', "India"},
+	{'S', 'A', 'M', "United Kingdom"},
+	{'T', 'A', 'H', "Switzerland"},
+	{'T', 'J', 'P', "Czech Republic"},
+	{'T', 'R', 'V', "Hungary"},
+	{'V', 'A', 'E', "Austria"},
+	{'V', 'F', 'R', "France"},
+	{'V', 'S', 'W', "Spain"},
+	{'W', 'A', '0', "Germany"},
+	{'X', 'S', 'W', "Russia"},
+	{'Y', 'A', 'E', "Belgium"},
+	{'Y', 'S', 'W', "Sweden"},
+	{'Z', 'A', 'R', "Italy"},
+}
+
+// wmi is a struct that represents a row of the table of the world manufacturer identifiers
+type wmi struct {
+	manufacturer string
+	brands       []string
+}
+
+// NewVehicleVINDecoder is a function that returns a new instance of VehicleVINDecoder
+// with the embedded table of the world manufacturer identifiers
+func NewVehicleVINDecoder() (*VehicleVINDecoder, error) {
+	rows, err := csv.NewReader(strings.NewReader(wmiTable)).ReadAll()
+	if err != nil {
+		return nil, fmt.Errorf("vin: wmi table: %w", err)
+	}
+	d := &VehicleVINDecoder{wmis: make(map[string]wmi, len(rows)), now: time.Now}
+	for i, row := range rows {
+		if i == 0 {
+			continue
+		}
+		if len(row) != 3 || len(row[0]) != 3 {
+			return nil, fmt.Errorf("vin: wmi table: line %d: expected wmi,manufacturer,brands", i+1)
+		}
+		d.wmis[row[0]] = wmi{manufacturer: row[1], brands: strings.Split(row[2], "|")}
+	}
+	return d, nil
+}
+
+// VehicleVINDecoder is a struct that implements the VehicleVINDecoder interface offline,
+// from an embedded table of world manufacturer identifiers
+type VehicleVINDecoder struct {
+	// wmis are the manufacturers by world manufacturer identifier
+	wmis map[string]wmi
+	// now returns the current time, the latest model year is the next year
+	now func() time.Time
+}
+
+// Decode is a method that checks a VIN and decodes its manufacturer, country and model years.
+// The VIN is read regardless of case, spaces and hyphens.
+// The check digit of the 9th position is validated as in ISO 3779 and 49 CFR 565.
+func (d *VehicleVINDecoder) Decode(vin string) (v internal.VehicleVIN, err error) {
+	key := internal.VehicleVINKey(vin)
+	if len(key) != 17 {
+		return v, fmt.Errorf("%w: %q must have 17 characters", internal.ErrVehicleVINInvalid, vin)
+	}
+	sum := 0
+	for i := 0; i < len(key); i++ {
+		c := key[i]
+		if !strings.ContainsRune(alphabet, rune(c)) {
+			return v, fmt.Errorf("%w: %q has an invalid character %q at position %d", internal.ErrVehicleVINInvalid, vin, c, i+1)
+		}
+		value, ok := values[c]
+		if !ok {
+			value = int(c - '0')
+		}
+		sum += value * weights[i]
+	}
+	check := byte('0' + sum%11)
+	if sum%11 == 10 {
+		check = 'X'
+	}
+	// - the check digit is mandatory in North America and China, elsewhere a letter other than X
+	// in its position tells the VIN has none
+	if key[8] != check && (checked(key[0]) || key[8] == 'X' || (key[8] >= '0' && key[8] <= '9')) {
+		return v, fmt.Errorf("%w: %q has the check digit %q, expected %q", internal.ErrVehicleVINInvalid, vin, key[8], check)
+	}
+
+	v = internal.VehicleVIN{VIN: key, WMI: key[:3], Region: region(key[0]), Country: countryOf(key[0], key[1])}
+	if m, ok := d.wmis[v.WMI]; ok {
+		v.Manufacturer, v.Brands = m.manufacturer, m.brands
+	}
+	v.ModelYears = d.modelYears(key)
+	return
+}
+
+// modelYears is a method that returns the model years the 10th character of a VIN may stand for, the most likely first.
+// North American vehicles tell the cycle apart with the 7th character, a digit up to 2009 and a letter since 2010,
+// for the others the latest year not after the next one comes first.
+func (d *VehicleVINDecoder) modelYears(key string) (list []int) {
+	i := strings.IndexByte(years, key[9])
+	if i < 0 {
+		return nil
+	}
+	latest := d.now().Year() + 1
+	for y := 1980 + i; y <= latest; y += 30 {
+		list = append([]int{y}, list...)
+	}
+	if len(list) == 2 && region(key[0]) == "North America" && key[6] >= '0' && key[6] <= '9' {
+		list[0], list[1] = list[1], list[0]
+	}
+	return
+}
+
+// checked is a function that reports whether the check digit is mandatory for the first character of a VIN
+func checked(c byte) bool {
+	return (c >= '1' && c <= '5') || c == 'L'
+}
+
+// region is a function that returns the region of the first character of a VIN
+func region(c byte) string {
+	switch {
+	case c >= 'A' && c <= 'H':
+		return "Africa"
+	case c >= 'J' && c <= 'R':
+		return "Asia"
+	case c >= 'S' && c <= 'Z':
+		return "Europe"
+	case c >= '1' && c <= '5':
+		return "North America"
+	case c == '6' || c == '7':
+		return "Oceania"
+	case c == '8' || c == '9' || c == '0':
+		return "South America"
+	}
+	return ""
+}
+
+// countryOf is a function that returns the country the first 2 characters of a VIN are assigned to, empty when unknown
+func countryOf(first, second byte) string {
+	order := func(c byte) int { return strings.IndexByte(sequence, c) }
+	for _, c := range countries {
+		if c.first == first && order(second) >= order(c.from) && order(second) <= order(c.to) {
+			return c.name
+		}
+	}
+	return ""
+}
@@ -0,0 +1,97 @@
+package internal
+
+import (
+	"errors"
+	"time"
+)
+
+// WebhookDeliveryStatus is the state of a webhook delivery
+type WebhookDeliveryStatus string
+
+const (
+	// WebhookDeliveryPending is a delivery waiting for its next attempt
+	WebhookDeliveryPending WebhookDeliveryStatus = "pending"
+	// WebhookDeliverySucceeded is a delivery acknowledged by the receiver
+	WebhookDeliverySucceeded WebhookDeliveryStatus = "succeeded"
+	// WebhookDeliveryDead is a delivery that exhausted its attempts, it is kept in the dead-letter list
+	WebhookDeliveryDead WebhookDeliveryStatus = "dead"
+)
+
+var (
+	// ErrWebhookNotFound is returned when a webhook or delivery does not exist
+	ErrWebhookNotFound = errors.New("webhook not found")
+	// ErrWebhookInvalid is returned when a webhook subscription is not valid
+	ErrWebhookInvalid = errors.New("webhook invalid")
+)
+
+// Webhook is a struct that represents a subscription of a partner system to vehicle changes
+type Webhook struct {
+	Id int
+	// URL is the endpoint that receives the deliveries
+	URL string
+	// Secret is the key used to sign the deliveries with HMAC-SHA256
+	Secret string
+	// Events is the list of change types delivered to the webhook
+	Events []VehicleChangeType
+	// Units is the unit system of the vehicles of the deliveries, metric by default
+	Units VehicleUnitSystem
+	// CreatedAt is the moment the webhook was created
+	CreatedAt time.Time
+}
+
+// WebhookDelivery is a struct that represents a notification sent to a webhook
+type WebhookDelivery struct {
+	Id        int
+	WebhookId int
+	// Event is the change type delivered
+	Event VehicleChangeType
+	// VehicleId is the id of the vehicle affected by the change
+	VehicleId int
+	// Payload is the body sent to the webhook
+	Payload []byte
+	// Status is the state of the delivery
+	Status WebhookDeliveryStatus
+	// Attempts is the number of attempts made so far
+	Attempts int
+	// LastStatusCode is the http status of the last attempt, 0 when there was no response
+	LastStatusCode int
+	// LastError is the error of the last attempt
+	LastError string
+	// NextAttemptAt is the moment of the next attempt of a pending delivery
+	NextAttemptAt time.Time
+	CreatedAt     time.Time
+	UpdatedAt     time.Time
+}
+
+// WebhookRepository is an interface that represents the storage of webhooks and their deliveries
+type WebhookRepository interface {
+	// FindAll is a method that returns all the webhooks
+	FindAll() (w []Webhook, err error)
+	// FindById is a method that returns a webhook
+	FindById(id int) (w Webhook, err error)
+	// Create is a method that stores a webhook, assigning its id
+	Create(w *Webhook) (err error)
+	// Delete is a method that removes a webhook along with its deliveries
+	Delete(id int) (err error)
+	// SaveDelivery is a method that stores a delivery, assigning its id when it is zero
+	SaveDelivery(d *WebhookDelivery) (err error)
+	// FindDelivery is a method that returns a delivery
+	FindDelivery(id int) (d WebhookDelivery, err error)
+	// FindDeliveries is a method that returns the delivery log of a webhook, newest first
+	FindDeliveries(webhookId int) (d []WebhookDelivery, err error)
+	// FindDeadLetters is a method that returns the dead deliveries of all webhooks, newest first
+	FindDeadLetters() (d []WebhookDelivery, err error)
+}
+
+// WebhookService is an interface that represents the management and delivery of webhooks
+type WebhookService interface {
+	FindAll() (w []Webhook, err error)
+	FindById(id int) (w Webhook, err error)
+	// Create is a method that validates and stores a webhook, generating its secret if empty
+	Create(w *Webhook) (err error)
+	Delete(id int) (err error)
+	FindDeliveries(webhookId int) (d []WebhookDelivery, err error)
+	FindDeadLetters() (d []WebhookDelivery, err error)
+	// Redeliver is a method that moves a dead delivery back to the queue
+	Redeliver(deliveryId int) (err error)
+}
@@ -0,0 +1,321 @@
+package webhook
+
+import (
+	"app/internal"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+)
+
+// ConfigWebhookMap is a struct that represents the configuration for WebhookMap
+type ConfigWebhookMap struct {
+	// LogSize is the number of succeeded deliveries kept in the log
+	LogSize int
+	// DeadLetters is the number of dead deliveries kept, the oldest are dropped first
+	DeadLetters int
+	// Path is the file the webhooks are saved to and read back from on creation,
+	// empty keeps them in memory only, so they are lost on restart.
+	// The deliveries are always kept in memory only.
+	Path string
+}
+
+// WebhookJSON is a struct that represents a webhook in the webhooks file
+type WebhookJSON struct {
+	Id        int                          `json:"id"`
+	URL       string                       `json:"url"`
+	Secret    string                       `json:"secret"`
+	Events    []internal.VehicleChangeType `json:"events"`
+	Units     internal.VehicleUnitSystem   `json:"units,omitempty"`
+	CreatedAt time.Time                    `json:"created_at"`
+}
+
+// WebhookFileJSON is a struct that represents the webhooks file
+type WebhookFileJSON struct {
+	LastId   int           `json:"last_id"`
+	Webhooks []WebhookJSON `json:"webhooks"`
+}
+
+// NewWebhookMap is a function that returns a new instance of WebhookMap
+// - the webhooks file is read when cfg has a path, a missing file starts with no webhooks
+func NewWebhookMap(cfg *ConfigWebhookMap) (r *WebhookMap, err error) {
+	// default values
+	defaultConfig := &ConfigWebhookMap{
+		LogSize:     1000,
+		DeadLetters: 1000,
+	}
+	if cfg != nil {
+		if cfg.LogSize > 0 {
+			defaultConfig.LogSize = cfg.LogSize
+		}
+		if cfg.DeadLetters > 0 {
+			defaultConfig.DeadLetters = cfg.DeadLetters
+		}
+		defaultConfig.Path = cfg.Path
+	}
+
+	r = &WebhookMap{
+		webhooks:    make(map[int]internal.Webhook),
+		deliveries:  make(map[int]internal.WebhookDelivery),
+		logSize:     defaultConfig.LogSize,
+		deadLetters: defaultConfig.DeadLetters,
+		path:        defaultConfig.Path,
+	}
+	if r.path == "" {
+		return
+	}
+
+	data, err := os.ReadFile(r.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return r, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var fj WebhookFileJSON
+	if err = json.Unmarshal(data, &fj); err != nil {
+		return nil, fmt.Errorf("webhooks: %s: %w", r.path, err)
+	}
+	r.lastId = fj.LastId
+	for _, wj := range fj.Webhooks {
+		r.webhooks[wj.Id] = internal.Webhook{
+			Id:        wj.Id,
+			URL:       wj.URL,
+			Secret:    wj.Secret,
+			Events:    wj.Events,
+			Units:     wj.Units,
+			CreatedAt: wj.CreatedAt,
+		}
+		r.lastId = max(r.lastId, wj.Id)
+	}
+	return
+}
+
+// WebhookMap is a struct that implements the WebhookRepository interface in memory,
+// optionally saving the webhooks to a file
+type WebhookMap struct {
+	// mu guards the fields below
+	mu sync.RWMutex
+	// webhooks is a map of webhooks
+	webhooks map[int]internal.Webhook
+	// lastId is the id of the last created webhook
+	lastId int
+	// path is the file the webhooks are saved to, empty when they are not
+	path string
+	// deliveries is a map of deliveries
+	deliveries map[int]internal.WebhookDelivery
+	// lastDeliveryId is the id of the last created delivery
+	lastDeliveryId int
+	// finished is the list of succeeded delivery ids, oldest first
+	finished []int
+	// logSize is the number of succeeded deliveries kept
+	logSize int
+	// dead is the list of dead delivery ids, oldest first
+	dead []int
+	// deadLetters is the number of dead deliveries kept
+	deadLetters int
+}
+
+// FindAll is a method that returns all the webhooks sorted by id
+func (r *WebhookMap) FindAll() (w []internal.Webhook, err error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	w = make([]internal.Webhook, 0, len(r.webhooks))
+	for _, value := range r.webhooks {
+		w = append(w, value)
+	}
+	sort.Slice(w, func(i, j int) bool { return w[i].Id < w[j].Id })
+	return
+}
+
+// FindById is a method that returns a webhook
+func (r *WebhookMap) FindById(id int) (w internal.Webhook, err error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	w, ok := r.webhooks[id]
+	if !ok {
+		err = fmt.Errorf("%w: id %d", internal.ErrWebhookNotFound, id)
+	}
+	return
+}
+
+// Create is a method that stores a webhook, assigning its id
+func (r *WebhookMap) Create(w *internal.Webhook) (err error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	w.Id = r.lastId + 1
+	r.webhooks[w.Id] = *w
+	if err = r.save(w.Id); err != nil {
+		delete(r.webhooks, w.Id)
+		w.Id = 0
+		return
+	}
+	r.lastId++
+	return
+}
+
+// Delete is a method that removes a webhook along with its deliveries
+func (r *WebhookMap) Delete(id int) (err error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	w, ok := r.webhooks[id]
+	if !ok {
+		return fmt.Errorf("%w: id %d", internal.ErrWebhookNotFound, id)
+	}
+	delete(r.webhooks, id)
+	if err = r.save(r.lastId); err != nil {
+		r.webhooks[id] = w
+		return
+	}
+
+	// - pending deliveries still queued are skipped as they are no longer found
+	for key, d := range r.deliveries {
+		if d.WebhookId == id {
+			delete(r.deliveries, key)
+		}
+	}
+	r.finished = r.stored(r.finished, internal.WebhookDeliverySucceeded)
+	r.dead = r.stored(r.dead, internal.WebhookDeliveryDead)
+	return
+}
+
+// SaveDelivery is a method that stores a delivery, assigning its id when it is zero
+// - a delivery of a deleted webhook is not stored
+func (r *WebhookMap) SaveDelivery(d *internal.WebhookDelivery) (err error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.webhooks[d.WebhookId]; !ok {
+		return fmt.Errorf("%w: id %d", internal.ErrWebhookNotFound, d.WebhookId)
+	}
+	if d.Id == 0 {
+		r.lastDeliveryId++
+		d.Id = r.lastDeliveryId
+	}
+	previous := r.deliveries[d.Id]
+	r.deliveries[d.Id] = *d
+
+	// trim the log
+	switch {
+	case d.Status == internal.WebhookDeliverySucceeded:
+		r.finished = r.trim(append(r.finished, d.Id), r.logSize)
+	case d.Status == internal.WebhookDeliveryDead && previous.Status != internal.WebhookDeliveryDead:
+		r.dead = r.trim(append(r.dead, d.Id), r.deadLetters)
+	case d.Status != internal.WebhookDeliveryDead && previous.Status == internal.WebhookDeliveryDead:
+		// - a redelivered dead letter is pending again
+		r.dead = r.stored(r.dead, internal.WebhookDeliveryDead)
+	}
+	return
+}
+
+// FindDelivery is a method that returns a delivery
+func (r *WebhookMap) FindDelivery(id int) (d internal.WebhookDelivery, err error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	d, ok := r.deliveries[id]
+	if !ok {
+		err = fmt.Errorf("%w: delivery %d", internal.ErrWebhookNotFound, id)
+	}
+	return
+}
+
+// FindDeliveries is a method that returns the delivery log of a webhook, newest first
+func (r *WebhookMap) FindDeliveries(webhookId int) (d []internal.WebhookDelivery, err error) {
+	return r.filterDeliveries(func(value internal.WebhookDelivery) bool {
+		return value.WebhookId == webhookId
+	}), nil
+}
+
+// FindDeadLetters is a method that returns the dead deliveries of all webhooks, newest first
+func (r *WebhookMap) FindDeadLetters() (d []internal.WebhookDelivery, err error) {
+	return r.filterDeliveries(func(value internal.WebhookDelivery) bool {
+		return value.Status == internal.WebhookDeliveryDead
+	}), nil
+}
+
+// filterDeliveries is a method that returns the deliveries accepted by keep, newest first
+func (r *WebhookMap) filterDeliveries(keep func(internal.WebhookDelivery) bool) (d []internal.WebhookDelivery) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	d = make([]internal.WebhookDelivery, 0)
+	for _, value := range r.deliveries {
+		if keep(value) {
+			d = append(d, value)
+		}
+	}
+	sort.Slice(d, func(i, j int) bool { return d[i].Id > d[j].Id })
+	return
+}
+
+// trim is a method that drops the oldest deliveries of ids beyond size
+func (r *WebhookMap) trim(ids []int, size int) []int {
+	for len(ids) > size {
+		delete(r.deliveries, ids[0])
+		ids = ids[1:]
+	}
+	return ids
+}
+
+// stored is a method that returns the ids of ids still stored with the given status, in the same order
+func (r *WebhookMap) stored(ids []int, status internal.WebhookDeliveryStatus) []int {
+	kept := ids[:0]
+	for _, id := range ids {
+		if d, ok := r.deliveries[id]; ok && d.Status == status {
+			kept = append(kept, id)
+		}
+	}
+	return kept
+}
+
+// save is a method that writes the webhooks to the file, replacing it atomically
+// - lastId is written so that the ids of deleted webhooks are not reused after a restart
+// - the file holds the secrets of the webhooks, so it is readable by its owner only
+func (r *WebhookMap) save(lastId int) (err error) {
+	if r.path == "" {
+		return
+	}
+	fj := WebhookFileJSON{LastId: lastId, Webhooks: make([]WebhookJSON, 0, len(r.webhooks))}
+	for _, w := range r.webhooks {
+		fj.Webhooks = append(fj.Webhooks, WebhookJSON{
+			Id:        w.Id,
+			URL:       w.URL,
+			Secret:    w.Secret,
+			Events:    w.Events,
+			Units:     w.Units,
+			CreatedAt: w.CreatedAt,
+		})
+	}
+	sort.Slice(fj.Webhooks, func(i, j int) bool { return fj.Webhooks[i].Id < fj.Webhooks[j].Id })
+	data, err := json.MarshalIndent(fj, "", "  ")
+	if err != nil {
+		return
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(r.path), filepath.Base(r.path)+".*.tmp")
+	if err != nil {
+		return
+	}
+	defer os.Remove(tmp.Name())
+	if _, err = tmp.Write(data); err != nil {
+		tmp.Close()
+		return
+	}
+	if err = tmp.Sync(); err != nil {
+		tmp.Close()
+		return
+	}
+	if err = tmp.Close(); err != nil {
+		return
+	}
+	return os.Rename(tmp.Name(), r.path)
+}
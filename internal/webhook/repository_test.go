@@ -0,0 +1,122 @@
+package webhook
+
+import (
+	"app/internal"
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestWebhookMap_DeadLettersCapped(t *testing.T) {
+	rp, _ := NewWebhookMap(&ConfigWebhookMap{DeadLetters: 2})
+	w := internal.Webhook{URL: "http://example.com"}
+	if err := rp.Create(&w); err != nil {
+		t.Fatalf("create: %v", err)
+	}
+	for i := 0; i < 3; i++ {
+		d := internal.WebhookDelivery{WebhookId: w.Id, Status: internal.WebhookDeliveryDead}
+		if err := rp.SaveDelivery(&d); err != nil {
+			t.Fatalf("save delivery: %v", err)
+		}
+	}
+
+	dead, _ := rp.FindDeadLetters()
+	if len(dead) != 2 || dead[0].Id != 3 || dead[1].Id != 2 {
+		t.Fatalf("dead letters = %+v, want deliveries 3 and 2", dead)
+	}
+	if _, err := rp.FindDelivery(1); !errors.Is(err, internal.ErrWebhookNotFound) {
+		t.Errorf("oldest dead letter: %v, want ErrWebhookNotFound", err)
+	}
+
+	// a redelivered dead letter frees its place
+	d := dead[1]
+	d.Status = internal.WebhookDeliveryPending
+	if err := rp.SaveDelivery(&d); err != nil {
+		t.Fatalf("save delivery: %v", err)
+	}
+	d4 := internal.WebhookDelivery{WebhookId: w.Id, Status: internal.WebhookDeliveryDead}
+	if err := rp.SaveDelivery(&d4); err != nil {
+		t.Fatalf("save delivery: %v", err)
+	}
+	if _, err := rp.FindDelivery(3); err != nil {
+		t.Errorf("dead letter 3: %v, want it kept", err)
+	}
+	if got, _ := rp.FindDelivery(2); got.Status != internal.WebhookDeliveryPending {
+		t.Errorf("redelivered delivery = %+v, want pending", got)
+	}
+}
+
+func TestWebhookMap_DeleteDropsDeliveries(t *testing.T) {
+	rp, _ := NewWebhookMap(nil)
+	kept, deleted := internal.Webhook{URL: "http://a.example.com"}, internal.Webhook{URL: "http://b.example.com"}
+	for _, w := range []*internal.Webhook{&kept, &deleted} {
+		if err := rp.Create(w); err != nil {
+			t.Fatalf("create: %v", err)
+		}
+		for _, status := range []internal.WebhookDeliveryStatus{internal.WebhookDeliveryPending, internal.WebhookDeliverySucceeded, internal.WebhookDeliveryDead} {
+			d := internal.WebhookDelivery{WebhookId: w.Id, Status: status}
+			if err := rp.SaveDelivery(&d); err != nil {
+				t.Fatalf("save delivery: %v", err)
+			}
+		}
+	}
+
+	if err := rp.Delete(deleted.Id); err != nil {
+		t.Fatalf("delete: %v", err)
+	}
+	if d, _ := rp.FindDeliveries(deleted.Id); len(d) != 0 {
+		t.Errorf("deliveries of the deleted webhook = %+v, want none", d)
+	}
+	if d, _ := rp.FindDeliveries(kept.Id); len(d) != 3 {
+		t.Errorf("deliveries of the kept webhook = %d, want 3", len(d))
+	}
+	if dead, _ := rp.FindDeadLetters(); len(dead) != 1 || dead[0].WebhookId != kept.Id {
+		t.Errorf("dead letters = %+v, want the one of the kept webhook", dead)
+	}
+
+	// an attempt that finishes after the delete does not bring its delivery back
+	d := internal.WebhookDelivery{Id: 4, WebhookId: deleted.Id, Status: internal.WebhookDeliverySucceeded}
+	if err := rp.SaveDelivery(&d); !errors.Is(err, internal.ErrWebhookNotFound) {
+		t.Errorf("save delivery of a deleted webhook: %v, want ErrWebhookNotFound", err)
+	}
+}
+
+func TestWebhookMap_Persisted(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "webhooks.json")
+	rp, err := NewWebhookMap(&ConfigWebhookMap{Path: path})
+	if err != nil {
+		t.Fatalf("new: %v", err)
+	}
+	first := internal.Webhook{URL: "http://a.example.com", Secret: "s1", Events: []internal.VehicleChangeType{internal.VehicleCreated}, Units: internal.VehicleUnitsImperial}
+	second := internal.Webhook{URL: "http://b.example.com", Secret: "s2", Events: []internal.VehicleChangeType{internal.VehicleDeleted}}
+	for _, w := range []*internal.Webhook{&first, &second} {
+		if err = rp.Create(w); err != nil {
+			t.Fatalf("create: %v", err)
+		}
+	}
+	if err = rp.Delete(second.Id); err != nil {
+		t.Fatalf("delete: %v", err)
+	}
+	if info, err := os.Stat(path); err != nil || info.Mode().Perm() != 0o600 {
+		t.Errorf("webhooks file = %v, %v, want readable by its owner only", info, err)
+	}
+
+	// a restart reads the subscriptions back and does not reuse the id of the deleted one
+	restarted, err := NewWebhookMap(&ConfigWebhookMap{Path: path})
+	if err != nil {
+		t.Fatalf("restart: %v", err)
+	}
+	got, _ := restarted.FindAll()
+	if !reflect.DeepEqual(got, []internal.Webhook{first}) {
+		t.Errorf("webhooks after restart = %+v, want %+v", got, first)
+	}
+	third := internal.Webhook{URL: "http://c.example.com"}
+	if err = restarted.Create(&third); err != nil {
+		t.Fatalf("create: %v", err)
+	}
+	if third.Id != 3 {
+		t.Errorf("id after restart = %d, want 3", third.Id)
+	}
+}
@@ -0,0 +1,414 @@
+package webhook
+
+import (
+	"app/internal"
+	"bytes"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	mathrand "math/rand"
+	"net/http"
+	"net/url"
+	"strconv"
+	"sync"
+	"time"
+)
+
+const (
+	// HeaderEvent is the header carrying the change type of a delivery
+	HeaderEvent = "X-Garage-Event"
+	// HeaderDelivery is the header carrying the id of a delivery
+	HeaderDelivery = "X-Garage-Delivery"
+	// HeaderTimestamp is the header carrying the unix time the delivery was signed at
+	HeaderTimestamp = "X-Garage-Timestamp"
+	// HeaderSignature is the header carrying "sha256=" and the hex HMAC-SHA256 of "<timestamp>.<body>"
+	HeaderSignature = "X-Garage-Signature"
+)
+
+// UnitsJSON is a struct that represents the unit of each field with a unit of the vehicle of a webhook payload
+type UnitsJSON struct {
+	MaxSpeed string `json:"max_speed"`
+	Weight   string `json:"weight"`
+	Height   string `json:"height"`
+	Length   string `json:"length"`
+	Width    string `json:"width"`
+}
+
+// PayloadJSON is a struct that represents the body of a webhook delivery
+// - the vehicle is in the unit system of the webhook, given by units
+type PayloadJSON struct {
+	Event      string               `json:"event"`
+	OccurredAt time.Time            `json:"occurred_at"`
+	Vehicle    internal.VehicleJSON `json:"vehicle"`
+	Units      UnitsJSON            `json:"units"`
+}
+
+// ConfigWebhookDefault is a struct that represents the configuration for WebhookDefault
+type ConfigWebhookDefault struct {
+	// Workers is the number of concurrent deliveries
+	Workers int
+	// MaxAttempts is the number of attempts before a delivery is dead-lettered
+	MaxAttempts int
+	// BackoffBase is the delay before the first retry, it doubles on each attempt
+	BackoffBase time.Duration
+	// BackoffMax is the maximum delay between attempts
+	BackoffMax time.Duration
+	// Client is the http client used for the deliveries
+	Client *http.Client
+}
+
+// NewWebhookDefault is a function that returns a new instance of WebhookDefault
+func NewWebhookDefault(rp internal.WebhookRepository, cfg *ConfigWebhookDefault) *WebhookDefault {
+	// default values
+	defaultConfig := &ConfigWebhookDefault{
+		Workers:     4,
+		MaxAttempts: 6,
+		BackoffBase: time.Second,
+		BackoffMax:  5 * time.Minute,
+		Client:      &http.Client{Timeout: 10 * time.Second},
+	}
+	if cfg != nil {
+		if cfg.Workers > 0 {
+			defaultConfig.Workers = cfg.Workers
+		}
+		if cfg.MaxAttempts > 0 {
+			defaultConfig.MaxAttempts = cfg.MaxAttempts
+		}
+		if cfg.BackoffBase > 0 {
+			defaultConfig.BackoffBase = cfg.BackoffBase
+		}
+		if cfg.BackoffMax > 0 {
+			defaultConfig.BackoffMax = cfg.BackoffMax
+		}
+		if cfg.Client != nil {
+			defaultConfig.Client = cfg.Client
+		}
+	}
+
+	return &WebhookDefault{
+		rp:          rp,
+		workers:     defaultConfig.Workers,
+		maxAttempts: defaultConfig.MaxAttempts,
+		backoffBase: defaultConfig.BackoffBase,
+		backoffMax:  defaultConfig.BackoffMax,
+		client:      defaultConfig.Client,
+		queue:       make(chan int, 1024),
+		stopped:     make(chan struct{}),
+	}
+}
+
+// WebhookDefault is a struct that implements the WebhookService and VehiclePublisher interfaces.
+// Published changes become deliveries that are sent asynchronously by a pool of workers.
+type WebhookDefault struct {
+	// rp is the repository of webhooks and deliveries
+	rp internal.WebhookRepository
+	// workers is the number of concurrent deliveries
+	workers int
+	// maxAttempts is the number of attempts before a delivery is dead-lettered
+	maxAttempts int
+	// backoffBase is the delay before the first retry
+	backoffBase time.Duration
+	// backoffMax is the maximum delay between attempts
+	backoffMax time.Duration
+	// client is the http client used for the deliveries
+	client *http.Client
+	// queue receives the ids of the deliveries due for an attempt
+	queue chan int
+	// stopped is closed once the workers stop, it releases the deliveries waiting for room in queue
+	stopped chan struct{}
+	// stop closes stopped once
+	stop sync.Once
+}
+
+// Start is a method that launches the delivery workers, they stop when done is closed
+func (s *WebhookDefault) Start(done <-chan struct{}) {
+	go func() {
+		<-done
+		s.stop.Do(func() { close(s.stopped) })
+	}()
+	for i := 0; i < s.workers; i++ {
+		go func() {
+			for {
+				select {
+				case <-done:
+					return
+				case id := <-s.queue:
+					s.attempt(id)
+				}
+			}
+		}()
+	}
+}
+
+// FindAll is a method that returns all the webhooks
+func (s *WebhookDefault) FindAll() (w []internal.Webhook, err error) {
+	return s.rp.FindAll()
+}
+
+// FindById is a method that returns a webhook
+func (s *WebhookDefault) FindById(id int) (w internal.Webhook, err error) {
+	return s.rp.FindById(id)
+}
+
+// Create is a method that validates and stores a webhook, generating its secret if empty
+func (s *WebhookDefault) Create(w *internal.Webhook) (err error) {
+	// validate
+	u, err := url.Parse(w.URL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return fmt.Errorf("%w: url must be an absolute http(s) url", internal.ErrWebhookInvalid)
+	}
+	if len(w.Events) == 0 {
+		w.Events = []internal.VehicleChangeType{internal.VehicleCreated, internal.VehicleDeleted}
+	}
+	for _, e := range w.Events {
+		switch e {
+		case internal.VehicleCreated, internal.VehicleUpdated, internal.VehicleDeleted:
+		default:
+			return fmt.Errorf("%w: unknown event %q", internal.ErrWebhookInvalid, e)
+		}
+	}
+	units, err := internal.ParseVehicleUnitSystem(string(w.Units))
+	if err != nil {
+		return fmt.Errorf("%w: unknown units %q", internal.ErrWebhookInvalid, w.Units)
+	}
+	w.Units = units
+
+	// secret
+	if w.Secret == "" {
+		key := make([]byte, 32)
+		if _, err = rand.Read(key); err != nil {
+			return
+		}
+		w.Secret = hex.EncodeToString(key)
+	}
+	w.CreatedAt = time.Now().UTC()
+
+	err = s.rp.Create(w)
+	return
+}
+
+// Delete is a method that removes a webhook along with its delivery log and dead letters
+func (s *WebhookDefault) Delete(id int) (err error) {
+	return s.rp.Delete(id)
+}
+
+// FindDeliveries is a method that returns the delivery log of a webhook
+func (s *WebhookDefault) FindDeliveries(webhookId int) (d []internal.WebhookDelivery, err error) {
+	if _, err = s.rp.FindById(webhookId); err != nil {
+		return
+	}
+	return s.rp.FindDeliveries(webhookId)
+}
+
+// FindDeadLetters is a method that returns the dead deliveries
+func (s *WebhookDefault) FindDeadLetters() (d []internal.WebhookDelivery, err error) {
+	return s.rp.FindDeadLetters()
+}
+
+// Redeliver is a method that moves a dead delivery back to the queue with a fresh set of attempts
+func (s *WebhookDefault) Redeliver(deliveryId int) (err error) {
+	d, err := s.rp.FindDelivery(deliveryId)
+	if err != nil {
+		return
+	}
+	if d.Status != internal.WebhookDeliveryDead {
+		return fmt.Errorf("%w: delivery %d is not dead", internal.ErrWebhookInvalid, deliveryId)
+	}
+	d.Status = internal.WebhookDeliveryPending
+	d.Attempts = 0
+	d.NextAttemptAt = time.Now().UTC()
+	d.UpdatedAt = d.NextAttemptAt
+	if err = s.rp.SaveDelivery(&d); err != nil {
+		return
+	}
+	s.enqueue(d.Id)
+	return
+}
+
+// Publish is a method that creates a delivery for every webhook subscribed to the change
+// - the payload is built once per unit system
+func (s *WebhookDefault) Publish(c internal.VehicleChange) {
+	webhooks, err := s.rp.FindAll()
+	if err != nil {
+		return
+	}
+
+	occurredAt := c.OccurredAt
+	if occurredAt.IsZero() {
+		occurredAt = time.Now().UTC()
+	}
+	payloads := make(map[internal.VehicleUnitSystem][]byte)
+
+	for _, w := range webhooks {
+		if !subscribed(w, c.Type) {
+			continue
+		}
+		payload, ok := payloads[w.Units]
+		if !ok {
+			payload, err = json.Marshal(PayloadJSON{
+				Event:      "vehicle." + string(c.Type),
+				OccurredAt: occurredAt,
+				Vehicle:    vehicleToJSON(c.Vehicle, w.Units),
+				Units:      unitsToJSON(w.Units),
+			})
+			if err != nil {
+				continue
+			}
+			payloads[w.Units] = payload
+		}
+		now := time.Now().UTC()
+		d := internal.WebhookDelivery{
+			WebhookId:     w.Id,
+			Event:         c.Type,
+			VehicleId:     c.Vehicle.Id,
+			Payload:       payload,
+			Status:        internal.WebhookDeliveryPending,
+			NextAttemptAt: now,
+			CreatedAt:     now,
+			UpdatedAt:     now,
+		}
+		if err := s.rp.SaveDelivery(&d); err != nil {
+			continue
+		}
+		s.enqueue(d.Id)
+	}
+}
+
+// enqueue is a method that hands a delivery to the workers without blocking the caller.
+// When the queue is full it waits for room in the background, until the workers stop,
+// and then the delivery is left pending with the reason it was not attempted.
+func (s *WebhookDefault) enqueue(id int) {
+	select {
+	case s.queue <- id:
+	default:
+		go func() {
+			select {
+			case s.queue <- id:
+			case <-s.stopped:
+				s.abandon(id)
+			}
+		}()
+	}
+}
+
+// abandon is a method that records on a pending delivery that it was not attempted as the workers stopped
+func (s *WebhookDefault) abandon(id int) {
+	d, err := s.rp.FindDelivery(id)
+	if err != nil || d.Status != internal.WebhookDeliveryPending {
+		return
+	}
+	d.LastError = "not attempted, the delivery workers stopped"
+	d.UpdatedAt = time.Now().UTC()
+	_ = s.rp.SaveDelivery(&d)
+}
+
+// attempt is a method that sends a delivery and schedules a retry or dead-letters it on failure
+func (s *WebhookDefault) attempt(id int) {
+	d, err := s.rp.FindDelivery(id)
+	if err != nil || d.Status != internal.WebhookDeliveryPending {
+		return
+	}
+	// - the deliveries of a deleted webhook are dropped with it
+	w, err := s.rp.FindById(d.WebhookId)
+	if err != nil {
+		return
+	}
+
+	d.Attempts++
+	d.LastStatusCode, err = s.send(w, d)
+	d.UpdatedAt = time.Now().UTC()
+	switch {
+	case err == nil:
+		d.Status = internal.WebhookDeliverySucceeded
+		d.LastError = ""
+	case d.Attempts >= s.maxAttempts:
+		d.Status = internal.WebhookDeliveryDead
+		d.LastError = err.Error()
+	default:
+		d.LastError = err.Error()
+		delay := s.backoff(d.Attempts)
+		d.NextAttemptAt = d.UpdatedAt.Add(delay)
+		time.AfterFunc(delay, func() { s.enqueue(d.Id) })
+	}
+	_ = s.rp.SaveDelivery(&d)
+}
+
+// send is a method that posts the signed payload to the webhook
+func (s *WebhookDefault) send(w internal.Webhook, d internal.WebhookDelivery) (code int, err error) {
+	req, err := http.NewRequest(http.MethodPost, w.URL, bytes.NewReader(d.Payload))
+	if err != nil {
+		return
+	}
+	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "garage-service-webhooks")
+	req.Header.Set(HeaderEvent, "vehicle."+string(d.Event))
+	req.Header.Set(HeaderDelivery, strconv.Itoa(d.Id))
+	req.Header.Set(HeaderTimestamp, timestamp)
+	req.Header.Set(HeaderSignature, "sha256="+Sign(w.Secret, timestamp, d.Payload))
+
+	res, err := s.client.Do(req)
+	if err != nil {
+		return
+	}
+	defer res.Body.Close()
+	_, _ = io.Copy(io.Discard, io.LimitReader(res.Body, 64*1024))
+
+	code = res.StatusCode
+	if code < 200 || code > 299 {
+		err = errors.New("receiver responded " + res.Status)
+	}
+	return
+}
+
+// backoff is a method that returns the delay before the next attempt, with up to 20% jitter
+func (s *WebhookDefault) backoff(attempts int) time.Duration {
+	delay := float64(s.backoffBase) * math.Pow(2, float64(attempts-1))
+	if delay > float64(s.backoffMax) {
+		delay = float64(s.backoffMax)
+	}
+	delay += delay * 0.2 * mathrand.Float64()
+	return time.Duration(delay)
+}
+
+// Sign is a function that returns the hex HMAC-SHA256 of "<timestamp>.<body>" with the secret.
+// Receivers verify a delivery by computing it from the X-Garage-Timestamp header and the raw body.
+func Sign(secret, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// subscribed is a function that reports whether the webhook listens to the change type
+func subscribed(w internal.Webhook, t internal.VehicleChangeType) bool {
+	for _, e := range w.Events {
+		if e == t {
+			return true
+		}
+	}
+	return false
+}
+
+// vehicleToJSON is a function that converts a vehicle to its payload representation in the unit system
+func vehicleToJSON(v internal.Vehicle, s internal.VehicleUnitSystem) internal.VehicleJSON {
+	return internal.NewVehicleJSON(s.Vehicle(v))
+}
+
+// unitsToJSON is a function that returns the units of the fields of a vehicle in the unit system
+func unitsToJSON(s internal.VehicleUnitSystem) UnitsJSON {
+	return UnitsJSON{
+		MaxSpeed: s.Unit(internal.VehicleQuantitySpeed).Symbol,
+		Weight:   s.Unit(internal.VehicleQuantityMass).Symbol,
+		Height:   s.Unit(internal.VehicleQuantityLength).Symbol,
+		Length:   s.Unit(internal.VehicleQuantityLength).Symbol,
+		Width:    s.Unit(internal.VehicleQuantityLength).Symbol,
+	}
+}
@@ -0,0 +1,350 @@
+package webhook
+
+import (
+	"app/internal"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"runtime"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// receiver is a struct that records the deliveries posted to a local httptest server
+type receiver struct {
+	srv *httptest.Server
+	// status is the status code the receiver responds with
+	status atomic.Int32
+	mu     sync.Mutex
+	reqs   []received
+}
+
+// received is a delivery as seen by the receiver
+type received struct {
+	header http.Header
+	body   []byte
+}
+
+func newReceiver(t *testing.T, status int) *receiver {
+	rc := &receiver{}
+	rc.status.Store(int32(status))
+	rc.srv = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		rc.mu.Lock()
+		rc.reqs = append(rc.reqs, received{header: r.Header.Clone(), body: body})
+		rc.mu.Unlock()
+		w.WriteHeader(int(rc.status.Load()))
+	}))
+	t.Cleanup(rc.srv.Close)
+	return rc
+}
+
+func (rc *receiver) requests() []received {
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+	return append([]received(nil), rc.reqs...)
+}
+
+// newService is a function that returns a started service with fast retries, stopped at the end of the test
+func newService(t *testing.T, maxAttempts int) (*WebhookDefault, *WebhookMap) {
+	rp, _ := NewWebhookMap(nil)
+	sv := NewWebhookDefault(rp, &ConfigWebhookDefault{
+		Workers:     2,
+		MaxAttempts: maxAttempts,
+		BackoffBase: time.Millisecond,
+		BackoffMax:  5 * time.Millisecond,
+	})
+	done := make(chan struct{})
+	sv.Start(done)
+	t.Cleanup(func() { close(done) })
+	return sv, rp
+}
+
+// subscribe is a function that creates a webhook of the receiver for the created vehicles
+func subscribe(t *testing.T, sv *WebhookDefault, rc *receiver, secret string) internal.Webhook {
+	w := internal.Webhook{URL: rc.srv.URL, Secret: secret, Events: []internal.VehicleChangeType{internal.VehicleCreated}}
+	if err := sv.Create(&w); err != nil {
+		t.Fatalf("create webhook: %v", err)
+	}
+	return w
+}
+
+// waitDelivery is a function that waits until the only delivery of a webhook has the status
+func waitDelivery(t *testing.T, sv *WebhookDefault, webhookId int, status internal.WebhookDeliveryStatus) internal.WebhookDelivery {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		deliveries, err := sv.FindDeliveries(webhookId)
+		if err != nil {
+			t.Fatalf("find deliveries: %v", err)
+		}
+		if len(deliveries) == 1 && deliveries[0].Status == status {
+			return deliveries[0]
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("delivery did not become %s: %+v", status, deliveries)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func TestSign(t *testing.T) {
+	body := []byte(`{"event":"vehicle.created"}`)
+	mac := hmac.New(sha256.New, []byte("secret"))
+	mac.Write([]byte("1700000000." + string(body)))
+	want := hex.EncodeToString(mac.Sum(nil))
+
+	if got := Sign("secret", "1700000000", body); got != want {
+		t.Errorf("Sign = %s, want %s", got, want)
+	}
+	if Sign("other", "1700000000", body) == want {
+		t.Error("Sign with another secret gives the same signature")
+	}
+	if Sign("secret", "1700000001", body) == want {
+		t.Error("Sign with another timestamp gives the same signature")
+	}
+}
+
+func TestWebhookDefault_SignatureHeader(t *testing.T) {
+	rc := newReceiver(t, http.StatusOK)
+	sv, _ := newService(t, 3)
+	w := subscribe(t, sv, rc, "s3cret")
+
+	sv.Publish(internal.VehicleChange{Type: internal.VehicleCreated, Vehicle: internal.Vehicle{Id: 7}})
+	d := waitDelivery(t, sv, w.Id, internal.WebhookDeliverySucceeded)
+
+	reqs := rc.requests()
+	if len(reqs) != 1 {
+		t.Fatalf("receiver got %d requests, want 1", len(reqs))
+	}
+	req := reqs[0]
+	timestamp := req.header.Get(HeaderTimestamp)
+	mac := hmac.New(sha256.New, []byte("s3cret"))
+	mac.Write([]byte(timestamp + "." + string(req.body)))
+	if got, want := req.header.Get(HeaderSignature), "sha256="+hex.EncodeToString(mac.Sum(nil)); got != want {
+		t.Errorf("signature header = %s, want %s", got, want)
+	}
+	if got := req.header.Get(HeaderEvent); got != "vehicle.created" {
+		t.Errorf("event header = %s, want vehicle.created", got)
+	}
+	var payload PayloadJSON
+	if err := json.Unmarshal(req.body, &payload); err != nil {
+		t.Fatalf("payload: %v", err)
+	}
+	if payload.Vehicle.Id != 7 || payload.Event != "vehicle.created" {
+		t.Errorf("payload = %+v", payload)
+	}
+	if d.Attempts != 1 || d.LastStatusCode != http.StatusOK {
+		t.Errorf("delivery = %+v, want 1 attempt with status 200", d)
+	}
+}
+
+func TestWebhookDefault_PayloadUnits(t *testing.T) {
+	sv, _ := newService(t, 3)
+	metric, imperial := newReceiver(t, http.StatusOK), newReceiver(t, http.StatusOK)
+	wm := subscribe(t, sv, metric, "secret")
+	wi := internal.Webhook{URL: imperial.srv.URL, Events: []internal.VehicleChangeType{internal.VehicleCreated}, Units: "Imperial"}
+	if err := sv.Create(&wi); err != nil {
+		t.Fatalf("create webhook: %v", err)
+	}
+	if wm.Units != internal.VehicleUnitsMetric || wi.Units != internal.VehicleUnitsImperial {
+		t.Errorf("units = %q and %q, want metric and imperial", wm.Units, wi.Units)
+	}
+
+	v := internal.Vehicle{Id: 7}
+	v.MaxSpeed, v.Weight, v.Height = 160.9344, 1000, 1.524
+	sv.Publish(internal.VehicleChange{Type: internal.VehicleCreated, Vehicle: v})
+	waitDelivery(t, sv, wm.Id, internal.WebhookDeliverySucceeded)
+	waitDelivery(t, sv, wi.Id, internal.WebhookDeliverySucceeded)
+
+	cases := []struct {
+		name string
+		rc   *receiver
+		want PayloadJSON
+	}{
+		{"metric", metric, PayloadJSON{
+			Vehicle: internal.VehicleJSON{MaxSpeed: 160.9344, Weight: 1000, Height: 1.524},
+			Units:   UnitsJSON{MaxSpeed: "km/h", Weight: "kg", Height: "m", Length: "m", Width: "m"},
+		}},
+		{"imperial", imperial, PayloadJSON{
+			Vehicle: internal.VehicleJSON{MaxSpeed: 100, Weight: 2204.622622, Height: 5},
+			Units:   UnitsJSON{MaxSpeed: "mph", Weight: "lb", Height: "ft", Length: "ft", Width: "ft"},
+		}},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			var payload PayloadJSON
+			if err := json.Unmarshal(tc.rc.requests()[0].body, &payload); err != nil {
+				t.Fatalf("payload: %v", err)
+			}
+			got := payload.Vehicle
+			if got.MaxSpeed != tc.want.Vehicle.MaxSpeed || got.Weight != tc.want.Vehicle.Weight || got.Height != tc.want.Vehicle.Height {
+				t.Errorf("vehicle = %+v, want %+v", got, tc.want.Vehicle)
+			}
+			if payload.Units != tc.want.Units {
+				t.Errorf("units = %+v, want %+v", payload.Units, tc.want.Units)
+			}
+		})
+	}
+
+	invalid := internal.Webhook{URL: metric.srv.URL, Units: "furlongs"}
+	if err := sv.Create(&invalid); !errors.Is(err, internal.ErrWebhookInvalid) {
+		t.Errorf("create with unknown units: error = %v, want ErrWebhookInvalid", err)
+	}
+}
+
+func TestWebhookDefault_RetryDeadLetter(t *testing.T) {
+	rc := newReceiver(t, http.StatusInternalServerError)
+	sv, _ := newService(t, 3)
+	w := subscribe(t, sv, rc, "s3cret")
+
+	sv.Publish(internal.VehicleChange{Type: internal.VehicleCreated, Vehicle: internal.Vehicle{Id: 7}})
+	d := waitDelivery(t, sv, w.Id, internal.WebhookDeliveryDead)
+
+	if d.Attempts != 3 || d.LastStatusCode != http.StatusInternalServerError || d.LastError == "" {
+		t.Errorf("delivery = %+v, want 3 attempts ending in 500", d)
+	}
+	if n := len(rc.requests()); n != 3 {
+		t.Errorf("receiver got %d requests, want 3", n)
+	}
+	dead, err := sv.FindDeadLetters()
+	if err != nil || len(dead) != 1 || dead[0].Id != d.Id {
+		t.Errorf("dead letters = %+v, %v, want the delivery", dead, err)
+	}
+}
+
+func TestWebhookDefault_Backoff(t *testing.T) {
+	rp, _ := NewWebhookMap(nil)
+	sv := NewWebhookDefault(rp, &ConfigWebhookDefault{BackoffBase: time.Second, BackoffMax: 5 * time.Second})
+	cases := []struct {
+		attempts int
+		min, max time.Duration
+	}{
+		{1, time.Second, 1200 * time.Millisecond},
+		{2, 2 * time.Second, 2400 * time.Millisecond},
+		{3, 4 * time.Second, 4800 * time.Millisecond},
+		{10, 5 * time.Second, 6 * time.Second},
+	}
+	for _, c := range cases {
+		if got := sv.backoff(c.attempts); got < c.min || got > c.max {
+			t.Errorf("backoff(%d) = %s, want within [%s, %s]", c.attempts, got, c.min, c.max)
+		}
+	}
+}
+
+func TestWebhookDefault_Redeliver(t *testing.T) {
+	rc := newReceiver(t, http.StatusBadGateway)
+	sv, _ := newService(t, 2)
+	w := subscribe(t, sv, rc, "s3cret")
+
+	sv.Publish(internal.VehicleChange{Type: internal.VehicleCreated, Vehicle: internal.Vehicle{Id: 7}})
+	d := waitDelivery(t, sv, w.Id, internal.WebhookDeliveryDead)
+
+	rc.status.Store(http.StatusNoContent)
+	if err := sv.Redeliver(d.Id); err != nil {
+		t.Fatalf("redeliver: %v", err)
+	}
+	d = waitDelivery(t, sv, w.Id, internal.WebhookDeliverySucceeded)
+	if d.Attempts != 1 || d.LastStatusCode != http.StatusNoContent || d.LastError != "" {
+		t.Errorf("delivery = %+v, want a fresh attempt acknowledged with 204", d)
+	}
+	if n := len(rc.requests()); n != 3 {
+		t.Errorf("receiver got %d requests, want 3", n)
+	}
+
+	// only dead deliveries can be redelivered
+	if err := sv.Redeliver(d.Id); !errors.Is(err, internal.ErrWebhookInvalid) {
+		t.Errorf("redeliver a succeeded delivery: %v, want ErrWebhookInvalid", err)
+	}
+	if err := sv.Redeliver(999); !errors.Is(err, internal.ErrWebhookNotFound) {
+		t.Errorf("redeliver an unknown delivery: %v, want ErrWebhookNotFound", err)
+	}
+}
+
+func TestWebhookDefault_DeliveryLog(t *testing.T) {
+	rc := newReceiver(t, http.StatusOK)
+	sv, _ := newService(t, 3)
+	w := subscribe(t, sv, rc, "s3cret")
+
+	// the webhook only listens to created vehicles
+	sv.Publish(internal.VehicleChange{Type: internal.VehicleUpdated, Vehicle: internal.Vehicle{Id: 1}})
+	sv.Publish(internal.VehicleChange{Type: internal.VehicleCreated, Vehicle: internal.Vehicle{Id: 2}})
+	waitDelivery(t, sv, w.Id, internal.WebhookDeliverySucceeded)
+	sv.Publish(internal.VehicleChange{Type: internal.VehicleCreated, Vehicle: internal.Vehicle{Id: 3}})
+
+	deadline := time.Now().Add(5 * time.Second)
+	var deliveries []internal.WebhookDelivery
+	for {
+		var err error
+		if deliveries, err = sv.FindDeliveries(w.Id); err != nil {
+			t.Fatalf("find deliveries: %v", err)
+		}
+		if len(deliveries) == 2 && deliveries[0].Status == internal.WebhookDeliverySucceeded {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("deliveries = %+v, want 2 succeeded", deliveries)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+
+	// newest first
+	if deliveries[0].VehicleId != 3 || deliveries[1].VehicleId != 2 {
+		t.Errorf("log vehicles = %d, %d, want 3, 2", deliveries[0].VehicleId, deliveries[1].VehicleId)
+	}
+	for _, d := range deliveries {
+		if d.Event != internal.VehicleCreated || len(d.Payload) == 0 {
+			t.Errorf("delivery = %+v, want a created payload", d)
+		}
+	}
+	if _, err := sv.FindDeliveries(999); !errors.Is(err, internal.ErrWebhookNotFound) {
+		t.Errorf("log of an unknown webhook: %v, want ErrWebhookNotFound", err)
+	}
+}
+
+func TestWebhookDefault_EnqueueAfterStop(t *testing.T) {
+	rp, _ := NewWebhookMap(nil)
+	sv := NewWebhookDefault(rp, nil)
+	sv.queue = make(chan int)
+	done := make(chan struct{})
+	sv.Start(done)
+	close(done)
+	<-sv.stopped
+
+	w := internal.Webhook{URL: "http://127.0.0.1:1"}
+	if err := rp.Create(&w); err != nil {
+		t.Fatalf("create: %v", err)
+	}
+	d := internal.WebhookDelivery{WebhookId: w.Id, Status: internal.WebhookDeliveryPending}
+	if err := rp.SaveDelivery(&d); err != nil {
+		t.Fatalf("save delivery: %v", err)
+	}
+	before := runtime.NumGoroutine()
+	sv.enqueue(d.Id)
+
+	// the background send gives up and records why the delivery was not attempted
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		got, err := rp.FindDelivery(d.Id)
+		if err != nil {
+			t.Fatalf("find delivery: %v", err)
+		}
+		if got.LastError != "" && runtime.NumGoroutine() <= before {
+			if got.Status != internal.WebhookDeliveryPending || got.Attempts != 0 {
+				t.Errorf("delivery = %+v, want pending without attempts", got)
+			}
+			return
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("enqueue after stop left a goroutine behind: %+v", got)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}